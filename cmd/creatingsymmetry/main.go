@@ -0,0 +1,418 @@
+// Command creatingsymmetry transforms a source image into a symmetry pattern using a formula file.
+//
+//	creatingsymmetry -in example/rainbow_stripe.png -f data/formula.yml -out out/example_image.png -size 200x200
+//
+// Use - as a filename to read the source image or the formula from standard input,
+// or to write the output image to standard output.
+//
+// Images named in the formula, like masks and backgrounds, are found relative to the formula file.
+//
+// Formulas with a domain coloring or gradient eyedropper color the pattern without a source image, so -in can be left out.
+//
+// Use -stream to write very large PNG images a few rows at a time, so the whole image never has to fit in memory.
+//
+// Use -suggest-threshold to print a coordinate threshold for the formula instead of rendering:
+//
+//	creatingsymmetry -f data/formula.yml -size 200x200 -suggest-threshold 1,99
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/chadius/creatingsymmetry"
+	"github.com/chadius/creatingsymmetry/entities/command"
+	"gopkg.in/yaml.v2"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	exitCodeSuccess      = 0
+	exitCodeRenderFailed = 1
+	exitCodeBadArguments = 2
+
+	standardStreamFilename = "-"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// cliOptions stores the parsed command line flags.
+type cliOptions struct {
+	sourceFilename  string
+	formulaFilename string
+	outputFilename  string
+	outputWidth     int
+	outputHeight    int
+	workerCount     int
+	timeout         time.Duration
+	outputFormat    command.OutputFormat
+	jpegQuality     int
+	supersampling   int
+	adaptive        bool
+	stream          bool
+
+	suggestThreshold bool
+	lowerPercentile  float64
+	upperPercentile  float64
+}
+
+// run parses the arguments, renders the image and returns the exit code.
+func run(arguments []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	options, err := parseArguments(arguments, stderr)
+	if err == flag.ErrHelp {
+		return exitCodeSuccess
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "creatingsymmetry: %v\n", err)
+		return exitCodeBadArguments
+	}
+
+	formulaData, err := readInput(options.formulaFilename, stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "creatingsymmetry: cannot read formula: %v\n", err)
+		return exitCodeRenderFailed
+	}
+	if err := checkSourceImageIsGiven(options, formulaData); err != nil {
+		fmt.Fprintf(stderr, "creatingsymmetry: %v\n", err)
+		return exitCodeBadArguments
+	}
+
+	if options.suggestThreshold {
+		err = suggestThreshold(options, formulaData, stdout)
+	} else {
+		err = render(options, formulaData, stdin, stdout)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "creatingsymmetry: %v\n", err)
+		return exitCodeRenderFailed
+	}
+	return exitCodeSuccess
+}
+
+func parseArguments(arguments []string, stderr io.Writer) (*cliOptions, error) {
+	options := &cliOptions{}
+	var outputSize string
+	var outputFormat string
+	var suggestedPercentiles string
+
+	flags := flag.NewFlagSet("creatingsymmetry", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&options.sourceFilename, "in", "", "Source filename, the image you want to transform. Use - to read from standard input. Not needed by domain coloring or gradient eyedroppers.")
+	flags.StringVar(&options.sourceFilename, "source", "", "Alias for -in.")
+	flags.StringVar(&options.formulaFilename, "f", "data/formula.yml", "Formula filename, YAML or JSON. Use - to read from standard input.")
+	flags.StringVar(&options.formulaFilename, "formula", "data/formula.yml", "Alias for -f.")
+	flags.StringVar(&options.outputFilename, "out", "", "Output filename, where the result is stored. Use - to write to standard output.")
+	flags.StringVar(&outputSize, "size", "200x200", "Output resolution, the width and height of the final image separated by an x.")
+	flags.StringVar(&outputFormat, "format", "", "Output image format: png, jpeg or gif. Guessed from the -out extension if not set, otherwise png.")
+	flags.IntVar(&options.jpegQuality, "quality", 0, "JPEG quality from 1 to 100. Only used by jpeg output.")
+	flags.DurationVar(&options.timeout, "timeout", 0, "Stop rendering after this much time, like 30s or 5m. 0 means there is no limit.")
+	flags.IntVar(&options.workerCount, "workers", 0, "Number of workers rendering the image at the same time. 0 uses one worker per available CPU.")
+	flags.IntVar(&options.supersampling, "supersampling", 1, "Take N by N samples in each pixel and average them to smooth jagged edges, from 1 to 16.")
+	flags.BoolVar(&options.adaptive, "adaptive", false, "Only supersample pixels where the pattern changes quickly. Needs -supersampling 2 or more.")
+	flags.BoolVar(&options.stream, "stream", false, "Render and write a few rows at a time so very large images fit in memory. Only used by png output.")
+	flags.StringVar(&suggestedPercentiles, "suggest-threshold", "", "Print a coordinate_threshold keeping the transformed coordinates between two percentiles, like 1,99, instead of rendering. -in and -out are not needed.")
+
+	if err := flags.Parse(arguments); err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+
+	if suggestedPercentiles != "" {
+		lowerPercentile, upperPercentile, err := parsePercentiles(suggestedPercentiles)
+		if err != nil {
+			return nil, err
+		}
+		options.suggestThreshold = true
+		options.lowerPercentile = lowerPercentile
+		options.upperPercentile = upperPercentile
+	}
+
+	if options.formulaFilename == "" {
+		return nil, errors.New("missing formula, use -f to name the file")
+	}
+	if options.outputFilename == "" && !options.suggestThreshold {
+		return nil, errors.New("missing output image, use -out to name the file")
+	}
+	if options.timeout < 0 {
+		return nil, fmt.Errorf("invalid -timeout %v, expected 0 or more", options.timeout)
+	}
+	if options.workerCount < 0 {
+		return nil, fmt.Errorf("invalid -workers %d, expected 0 or more", options.workerCount)
+	}
+	if options.supersampling < command.MinimumSupersampling || options.supersampling > command.MaximumSupersampling {
+		return nil, fmt.Errorf("invalid -supersampling %d, expected a number from %d to %d", options.supersampling, command.MinimumSupersampling, command.MaximumSupersampling)
+	}
+	if options.adaptive && options.supersampling < 2 {
+		return nil, errors.New("-adaptive needs -supersampling 2 or more")
+	}
+	if options.stream && options.adaptive {
+		return nil, errors.New("-stream cannot be used with -adaptive, it compares pixels across the whole image")
+	}
+	if options.sourceFilename == standardStreamFilename && options.formulaFilename == standardStreamFilename {
+		return nil, errors.New("only one of -in and -f can read from standard input")
+	}
+
+	width, height, err := parseOutputSize(outputSize)
+	if err != nil {
+		return nil, err
+	}
+	options.outputWidth = width
+	options.outputHeight = height
+
+	options.outputFormat, err = chooseOutputFormat(outputFormat, options.outputFilename)
+	if err != nil {
+		return nil, err
+	}
+	if options.jpegQuality != 0 && options.outputFormat != command.JPEGFormat {
+		return nil, fmt.Errorf("-quality is only used by jpeg output, not %s", options.outputFormat)
+	}
+	if options.jpegQuality != 0 && (options.jpegQuality < command.MinimumJPEGQuality || options.jpegQuality > command.MaximumJPEGQuality) {
+		return nil, fmt.Errorf("invalid -quality %d, expected a number from %d to %d", options.jpegQuality, command.MinimumJPEGQuality, command.MaximumJPEGQuality)
+	}
+	if options.stream && options.outputFormat != command.PNGFormat {
+		return nil, fmt.Errorf("-stream is only used by png output, not %s", options.outputFormat)
+	}
+	return options, nil
+}
+
+// chooseOutputFormat uses the -format flag if it was set, otherwise it guesses using the output filename.
+func chooseOutputFormat(formatFlag, outputFilename string) (command.OutputFormat, error) {
+	if formatFlag != "" {
+		outputFormat := command.OutputFormat(strings.ToLower(formatFlag))
+		if outputFormat == "jpg" {
+			outputFormat = command.JPEGFormat
+		}
+		if !outputFormat.IsValid() {
+			return "", fmt.Errorf("invalid -format %q, expected png, jpeg or gif", formatFlag)
+		}
+		return outputFormat, nil
+	}
+
+	if outputFormat, found := command.OutputFormatForFilename(outputFilename); found {
+		return outputFormat, nil
+	}
+	return command.PNGFormat, nil
+}
+
+// parseOutputSize reads a resolution like 200x100 and returns the width and height.
+func parseOutputSize(outputSize string) (int, int, error) {
+	sizeError := fmt.Errorf("invalid -size %q, expected two positive integers separated by an x, like 200x100", outputSize)
+
+	dimensions := strings.Split(strings.ToLower(outputSize), "x")
+	if len(dimensions) != 2 {
+		return 0, 0, sizeError
+	}
+
+	width, widthErr := strconv.Atoi(strings.TrimSpace(dimensions[0]))
+	height, heightErr := strconv.Atoi(strings.TrimSpace(dimensions[1]))
+	if widthErr != nil || heightErr != nil || width <= 0 || height <= 0 {
+		return 0, 0, sizeError
+	}
+	return width, height, nil
+}
+
+// parsePercentiles reads two percentiles like 1,99 and returns the lower and upper percentile.
+func parsePercentiles(percentiles string) (float64, float64, error) {
+	percentilesError := fmt.Errorf("invalid -suggest-threshold %q, expected two percentiles from 0 to 100 separated by a comma, like 1,99", percentiles)
+
+	values := strings.Split(percentiles, ",")
+	if len(values) != 2 {
+		return 0, 0, percentilesError
+	}
+
+	lowerPercentile, lowerErr := strconv.ParseFloat(strings.TrimSpace(values[0]), 64)
+	upperPercentile, upperErr := strconv.ParseFloat(strings.TrimSpace(values[1]), 64)
+	if lowerErr != nil || upperErr != nil || lowerPercentile < 0 || upperPercentile > 100 || lowerPercentile >= upperPercentile {
+		return 0, 0, percentilesError
+	}
+	return lowerPercentile, upperPercentile, nil
+}
+
+// outputSettingsData converts the command line flags into output settings the transformer can read.
+func outputSettingsData(options *cliOptions) ([]byte, error) {
+	return json.Marshal(command.OutputSettingsBuilderMarshal{
+		OutputWidth:  options.outputWidth,
+		OutputHeight: options.outputHeight,
+		WorkerCount:  options.workerCount,
+		OutputFormat: options.outputFormat,
+		JPEGQuality:  options.jpegQuality,
+
+		Supersampling:         options.supersampling,
+		AdaptiveSupersampling: options.adaptive,
+		Streaming:             options.stream,
+	})
+}
+
+// contextWithTimeout stops after the -timeout, if there is one.
+func contextWithTimeout(options *cliOptions) (context.Context, context.CancelFunc) {
+	if options.timeout > 0 {
+		return context.WithTimeout(context.Background(), options.timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// checkSourceImageIsGiven returns an error if the formula samples a source image but -in was not given.
+//   Formulas that cannot be read are left for the render to report.
+func checkSourceImageIsGiven(options *cliOptions, formulaData []byte) error {
+	if options.sourceFilename != "" || options.suggestThreshold {
+		return nil
+	}
+	wallpaperCommand, err := command.NewCreateWallpaperCommandFromData(formulaData)
+	if err != nil || !wallpaperCommand.NeedsSourceImage() {
+		return nil
+	}
+	return errors.New("missing source image, use -in to name the file, only domain coloring and gradient eyedroppers can draw without one")
+}
+
+func render(options *cliOptions, formulaData []byte, stdin io.Reader, stdout io.Writer) error {
+	var sourceImage io.Reader
+	if options.sourceFilename != "" {
+		sourceImageData, err := readInput(options.sourceFilename, stdin)
+		if err != nil {
+			return fmt.Errorf("cannot read source image: %v", err)
+		}
+		sourceImage = bytes.NewReader(sourceImageData)
+	}
+
+	outputSettingsData, err := outputSettingsData(options)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := contextWithTimeout(options)
+	defer cancel()
+
+	transformer := creatingsymmetry.FileTransformer{
+		OpenImage: namedImageOpener(options.formulaFilename),
+	}
+	renderTo := func(output io.Writer) error {
+		err := transformer.ApplyFormulaToTransformImageWithContext(
+			ctx,
+			sourceImage,
+			bytes.NewReader(formulaData),
+			bytes.NewReader(outputSettingsData),
+			output,
+			nil,
+		)
+		if err == context.DeadlineExceeded {
+			return fmt.Errorf("render stopped after -timeout %v", options.timeout)
+		}
+		return err
+	}
+
+	if options.stream {
+		return streamOutput(options.outputFilename, stdout, renderTo)
+	}
+
+	var outputImageData bytes.Buffer
+	if err := renderTo(&outputImageData); err != nil {
+		return err
+	}
+	return writeOutput(options.outputFilename, stdout, outputImageData.Bytes())
+}
+
+// suggestThreshold prints a coordinate threshold for the formula as YAML, ready to copy into the formula file.
+func suggestThreshold(options *cliOptions, formulaData []byte, stdout io.Writer) error {
+	outputSettingsData, err := outputSettingsData(options)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := contextWithTimeout(options)
+	defer cancel()
+
+	suggestion, err := creatingsymmetry.SuggestCoordinateThreshold(
+		ctx,
+		bytes.NewReader(formulaData),
+		bytes.NewReader(outputSettingsData),
+		options.lowerPercentile,
+		options.upperPercentile,
+	)
+	if err == context.DeadlineExceeded {
+		return fmt.Errorf("suggestion stopped after -timeout %v", options.timeout)
+	}
+	if err != nil {
+		return err
+	}
+
+	suggestionData, err := yaml.Marshal(struct {
+		CoordinateThreshold *command.CoordinateThresholdSettings `yaml:"coordinate_threshold"`
+	}{
+		CoordinateThreshold: suggestion,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = stdout.Write(suggestionData)
+	return err
+}
+
+// namedImageOpener opens images named in the formula relative to the formula file's folder.
+//   Formulas read from standard input use the current folder.
+func namedImageOpener(formulaFilename string) func(filename string) (io.ReadCloser, error) {
+	folder := "."
+	if formulaFilename != standardStreamFilename {
+		folder = filepath.Dir(formulaFilename)
+	}
+	return func(filename string) (io.ReadCloser, error) {
+		if filepath.IsAbs(filename) {
+			return os.Open(filename)
+		}
+		return os.Open(filepath.Join(folder, filename))
+	}
+}
+
+func readInput(filename string, stdin io.Reader) ([]byte, error) {
+	if filename == standardStreamFilename {
+		return ioutil.ReadAll(stdin)
+	}
+	return ioutil.ReadFile(filename)
+}
+
+// writeOutput is called after rendering so a failed render does not leave an empty file behind.
+func writeOutput(filename string, stdout io.Writer, data []byte) error {
+	if filename == standardStreamFilename {
+		_, err := stdout.Write(data)
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0644)
+}
+
+// streamOutput writes the rows straight to the output file as they are rendered, instead of keeping the image in memory.
+//   The partly written file is removed if the render fails.
+func streamOutput(filename string, stdout io.Writer, renderTo func(output io.Writer) error) error {
+	if filename == standardStreamFilename {
+		return renderTo(stdout)
+	}
+
+	outputFile, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	bufferedOutput := bufio.NewWriter(outputFile)
+	err = renderTo(bufferedOutput)
+	if err == nil {
+		err = bufferedOutput.Flush()
+	}
+	if closeErr := outputFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(filename)
+	}
+	return err
+}
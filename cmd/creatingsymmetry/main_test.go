@@ -0,0 +1,448 @@
+package main
+
+import (
+	"bytes"
+	"github.com/chadius/creatingsymmetry/entities/command"
+	. "gopkg.in/check.v1"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type CommandLineSuite struct {
+	directory       string
+	sourceImageData []byte
+	formulaFilename string
+}
+
+var _ = Suite(&CommandLineSuite{})
+
+func (suite *CommandLineSuite) SetUpTest(checker *C) {
+	suite.directory = checker.MkDir()
+
+	sourceImage := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	sourceImage.Set(0, 0, color.NRGBA{R: 255, G: 0, B: 0, A: 255})
+	var sourceImageData bytes.Buffer
+	png.Encode(&sourceImageData, sourceImage)
+	suite.sourceImageData = sourceImageData.Bytes()
+
+	suite.formulaFilename = suite.writeFile(checker, "formula.yml", []byte(`pattern_viewport:
+  x_min: 0
+  y_min: 0
+  x_max: 10
+  y_max: 10
+formula:
+  type: identity
+`))
+}
+
+func (suite *CommandLineSuite) writeFile(checker *C, filename string, data []byte) string {
+	path := filepath.Join(suite.directory, filename)
+	checker.Assert(ioutil.WriteFile(path, data, 0644), IsNil)
+	return path
+}
+
+func (suite *CommandLineSuite) TestRendersFilesToOutputFile(checker *C) {
+	sourceFilename := suite.writeFile(checker, "source.png", suite.sourceImageData)
+	outputFilename := filepath.Join(suite.directory, "output.png")
+	var stdout, stderr bytes.Buffer
+
+	exitCode := run(
+		[]string{"-in", sourceFilename, "-f", suite.formulaFilename, "-out", outputFilename, "-size", "3x2", "-workers", "2"},
+		bytes.NewReader(nil),
+		&stdout,
+		&stderr,
+	)
+
+	checker.Assert(exitCode, Equals, exitCodeSuccess)
+	checker.Assert(stderr.String(), Equals, "")
+
+	outputFile, err := os.Open(outputFilename)
+	checker.Assert(err, IsNil)
+	defer outputFile.Close()
+	outputImage, err := png.Decode(outputFile)
+	checker.Assert(err, IsNil)
+	checker.Assert(outputImage.Bounds().Max.X, Equals, 3)
+	checker.Assert(outputImage.Bounds().Max.Y, Equals, 2)
+}
+
+func (suite *CommandLineSuite) TestReadsStandardInputAndWritesStandardOutput(checker *C) {
+	var stdout, stderr bytes.Buffer
+
+	exitCode := run(
+		[]string{"-source", "-", "-formula", suite.formulaFilename, "-out", "-", "-size", "2x1"},
+		bytes.NewReader(suite.sourceImageData),
+		&stdout,
+		&stderr,
+	)
+
+	checker.Assert(exitCode, Equals, exitCodeSuccess)
+	outputImage, err := png.Decode(&stdout)
+	checker.Assert(err, IsNil)
+	checker.Assert(outputImage.Bounds().Max.X, Equals, 2)
+	checker.Assert(outputImage.Bounds().Max.Y, Equals, 1)
+}
+
+func (suite *CommandLineSuite) TestReadsJSONFormulaFromStandardInput(checker *C) {
+	sourceFilename := suite.writeFile(checker, "source.png", suite.sourceImageData)
+	var stdout, stderr bytes.Buffer
+
+	exitCode := run(
+		[]string{"-in", sourceFilename, "-f", "-", "-out", "-", "-size", "2x1"},
+		bytes.NewReader([]byte("{\n\t\"pattern_viewport\": {\"x_max\": 10, \"y_max\": 10},\n\t\"formula\": {\"type\": \"identity\"}\n}")),
+		&stdout,
+		&stderr,
+	)
+
+	checker.Assert(exitCode, Equals, exitCodeSuccess)
+	checker.Assert(stderr.String(), Equals, "")
+	_, err := png.Decode(&stdout)
+	checker.Assert(err, IsNil)
+}
+
+func (suite *CommandLineSuite) TestMissingSourceIsABadArgument(checker *C) {
+	outputFilename := filepath.Join(suite.directory, "output.png")
+	var stdout, stderr bytes.Buffer
+
+	exitCode := run([]string{"-f", suite.formulaFilename, "-out", outputFilename}, bytes.NewReader(nil), &stdout, &stderr)
+
+	checker.Assert(exitCode, Equals, exitCodeBadArguments)
+	checker.Assert(stderr.String(), Matches, "creatingsymmetry: missing source image, use -in to name the file.*\n")
+	_, err := os.Stat(outputFilename)
+	checker.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (suite *CommandLineSuite) TestMissingSourceIsFoundInAFormulaFromStandardInput(checker *C) {
+	formulaData, err := ioutil.ReadFile(suite.formulaFilename)
+	checker.Assert(err, IsNil)
+	var stdout, stderr bytes.Buffer
+
+	exitCode := run([]string{"-f", "-", "-out", "-"}, bytes.NewReader(formulaData), &stdout, &stderr)
+
+	checker.Assert(exitCode, Equals, exitCodeBadArguments)
+	checker.Assert(stderr.String(), Matches, "creatingsymmetry: missing source image.*\n")
+	checker.Assert(stdout.Len(), Equals, 0)
+}
+
+func (suite *CommandLineSuite) TestDomainColoringDoesNotNeedASource(checker *C) {
+	formulaFilename := suite.writeFile(checker, "domain_coloring.yml", []byte(`pattern_viewport:
+  x_min: -1
+  y_min: -1
+  x_max: 1
+  y_max: 1
+eyedropper:
+  shape: domain_coloring
+formula:
+  type: identity
+`))
+	var stdout, stderr bytes.Buffer
+
+	exitCode := run([]string{"-f", formulaFilename, "-out", "-", "-size", "2x2"}, bytes.NewReader(nil), &stdout, &stderr)
+
+	checker.Assert(exitCode, Equals, exitCodeSuccess)
+	checker.Assert(stderr.String(), Equals, "")
+	outputImage, err := png.Decode(&stdout)
+	checker.Assert(err, IsNil)
+	checker.Assert(outputImage.Bounds().Max.X, Equals, 2)
+}
+
+func (suite *CommandLineSuite) TestBothInputsCannotUseStandardInput(checker *C) {
+	var stdout, stderr bytes.Buffer
+
+	exitCode := run([]string{"-in", "-", "-f", "-", "-out", "-"}, bytes.NewReader(nil), &stdout, &stderr)
+
+	checker.Assert(exitCode, Equals, exitCodeBadArguments)
+	checker.Assert(stderr.String(), Matches, ".*only one of -in and -f can read from standard input\n")
+}
+
+func (suite *CommandLineSuite) TestInvalidSizeIsABadArgument(checker *C) {
+	var stdout, stderr bytes.Buffer
+
+	exitCode := run([]string{"-in", "-", "-out", "-", "-size", "200by100"}, bytes.NewReader(nil), &stdout, &stderr)
+
+	checker.Assert(exitCode, Equals, exitCodeBadArguments)
+	checker.Assert(stderr.String(), Matches, `creatingsymmetry: invalid -size "200by100".*\n`)
+}
+
+func (suite *CommandLineSuite) TestNegativeWorkersIsABadArgument(checker *C) {
+	var stdout, stderr bytes.Buffer
+
+	exitCode := run([]string{"-in", "-", "-out", "-", "-workers", "-1"}, bytes.NewReader(nil), &stdout, &stderr)
+
+	checker.Assert(exitCode, Equals, exitCodeBadArguments)
+	checker.Assert(stderr.String(), Matches, `creatingsymmetry: invalid -workers -1.*\n`)
+}
+
+func (suite *CommandLineSuite) TestAdaptiveNeedsSupersampling(checker *C) {
+	var stdout, stderr bytes.Buffer
+
+	exitCode := run([]string{"-in", "-", "-out", "-", "-adaptive"}, bytes.NewReader(nil), &stdout, &stderr)
+
+	checker.Assert(exitCode, Equals, exitCodeBadArguments)
+	checker.Assert(stderr.String(), Equals, "creatingsymmetry: -adaptive needs -supersampling 2 or more\n")
+
+	stderr.Reset()
+	exitCode = run([]string{"-in", "-", "-out", "-", "-supersampling", "17"}, bytes.NewReader(nil), &stdout, &stderr)
+
+	checker.Assert(exitCode, Equals, exitCodeBadArguments)
+	checker.Assert(stderr.String(), Matches, `creatingsymmetry: invalid -supersampling 17.*\n`)
+}
+
+func (suite *CommandLineSuite) TestSupersampledRender(checker *C) {
+	sourceFilename := suite.writeFile(checker, "source.png", suite.sourceImageData)
+	outputFilename := filepath.Join(suite.directory, "output.png")
+	var stdout, stderr bytes.Buffer
+
+	exitCode := run(
+		[]string{"-in", sourceFilename, "-f", suite.formulaFilename, "-out", outputFilename, "-size", "3x2", "-supersampling", "3", "-adaptive"},
+		bytes.NewReader(nil),
+		&stdout,
+		&stderr,
+	)
+
+	checker.Assert(stderr.String(), Equals, "")
+	checker.Assert(exitCode, Equals, exitCodeSuccess)
+	_, err := os.Stat(outputFilename)
+	checker.Assert(err, IsNil)
+}
+
+func (suite *CommandLineSuite) TestStreamedRenderMatchesTheWholeImage(checker *C) {
+	sourceFilename := suite.writeFile(checker, "source.png", suite.sourceImageData)
+	wholeFilename := filepath.Join(suite.directory, "whole.png")
+	streamedFilename := filepath.Join(suite.directory, "streamed.png")
+	var stdout, stderr bytes.Buffer
+
+	exitCode := run([]string{"-in", sourceFilename, "-f", suite.formulaFilename, "-out", wholeFilename, "-size", "7x5", "-supersampling", "2"}, bytes.NewReader(nil), &stdout, &stderr)
+	checker.Assert(exitCode, Equals, exitCodeSuccess)
+	exitCode = run([]string{"-in", sourceFilename, "-f", suite.formulaFilename, "-out", streamedFilename, "-size", "7x5", "-supersampling", "2", "-stream"}, bytes.NewReader(nil), &stdout, &stderr)
+	checker.Assert(stderr.String(), Equals, "")
+	checker.Assert(exitCode, Equals, exitCodeSuccess)
+
+	wholeData, err := ioutil.ReadFile(wholeFilename)
+	checker.Assert(err, IsNil)
+	wholeImage, err := png.Decode(bytes.NewReader(wholeData))
+	checker.Assert(err, IsNil)
+	streamedData, err := ioutil.ReadFile(streamedFilename)
+	checker.Assert(err, IsNil)
+	streamedImage, err := png.Decode(bytes.NewReader(streamedData))
+	checker.Assert(err, IsNil)
+	checker.Assert(streamedImage.Bounds(), Equals, wholeImage.Bounds())
+	for y := 0; y < 5; y++ {
+		for x := 0; x < 7; x++ {
+			checker.Assert(color.NRGBAModel.Convert(streamedImage.At(x, y)), Equals, color.NRGBAModel.Convert(wholeImage.At(x, y)))
+		}
+	}
+}
+
+func (suite *CommandLineSuite) TestStreamNeedsPNGOutputWithoutAdaptiveSupersampling(checker *C) {
+	var stdout, stderr bytes.Buffer
+
+	exitCode := run([]string{"-in", "-", "-out", "out.jpg", "-stream"}, bytes.NewReader(nil), &stdout, &stderr)
+	checker.Assert(exitCode, Equals, exitCodeBadArguments)
+	checker.Assert(stderr.String(), Equals, "creatingsymmetry: -stream is only used by png output, not jpeg\n")
+
+	stderr.Reset()
+	exitCode = run([]string{"-in", "-", "-out", "out.png", "-stream", "-supersampling", "2", "-adaptive"}, bytes.NewReader(nil), &stdout, &stderr)
+	checker.Assert(exitCode, Equals, exitCodeBadArguments)
+	checker.Assert(stderr.String(), Matches, "creatingsymmetry: -stream cannot be used with -adaptive.*\n")
+}
+
+func (suite *CommandLineSuite) TestStoppedStreamRemovesThePartialFile(checker *C) {
+	sourceFilename := suite.writeFile(checker, "source.png", suite.sourceImageData)
+	outputFilename := filepath.Join(suite.directory, "output.png")
+	var stdout, stderr bytes.Buffer
+
+	exitCode := run(
+		[]string{"-in", sourceFilename, "-f", suite.formulaFilename, "-out", outputFilename, "-stream", "-timeout", "1ns"},
+		bytes.NewReader(nil),
+		&stdout,
+		&stderr,
+	)
+
+	checker.Assert(exitCode, Equals, exitCodeRenderFailed)
+	checker.Assert(stderr.String(), Equals, "creatingsymmetry: render stopped after -timeout 1ns\n")
+	_, err := os.Stat(outputFilename)
+	checker.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (suite *CommandLineSuite) TestTimeoutStopsRender(checker *C) {
+	sourceFilename := suite.writeFile(checker, "source.png", suite.sourceImageData)
+	outputFilename := filepath.Join(suite.directory, "output.png")
+	var stdout, stderr bytes.Buffer
+
+	exitCode := run(
+		[]string{"-in", sourceFilename, "-f", suite.formulaFilename, "-out", outputFilename, "-timeout", "1ns"},
+		bytes.NewReader(nil),
+		&stdout,
+		&stderr,
+	)
+
+	checker.Assert(exitCode, Equals, exitCodeRenderFailed)
+	checker.Assert(stderr.String(), Equals, "creatingsymmetry: render stopped after -timeout 1ns\n")
+	_, err := os.Stat(outputFilename)
+	checker.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (suite *CommandLineSuite) TestGuessesOutputFormatFromExtension(checker *C) {
+	sourceFilename := suite.writeFile(checker, "source.png", suite.sourceImageData)
+	outputFilename := filepath.Join(suite.directory, "output.jpg")
+	var stdout, stderr bytes.Buffer
+
+	exitCode := run(
+		[]string{"-in", sourceFilename, "-f", suite.formulaFilename, "-out", outputFilename, "-size", "4x4", "-quality", "50"},
+		bytes.NewReader(nil),
+		&stdout,
+		&stderr,
+	)
+
+	checker.Assert(exitCode, Equals, exitCodeSuccess)
+	outputFile, err := os.Open(outputFilename)
+	checker.Assert(err, IsNil)
+	defer outputFile.Close()
+	_, err = jpeg.Decode(outputFile)
+	checker.Assert(err, IsNil)
+}
+
+func (suite *CommandLineSuite) TestChooseOutputFormat(checker *C) {
+	outputFormat, err := chooseOutputFormat("", "-")
+	checker.Assert(err, IsNil)
+	checker.Assert(outputFormat, Equals, command.PNGFormat)
+
+	outputFormat, err = chooseOutputFormat("JPG", "preview.png")
+	checker.Assert(err, IsNil)
+	checker.Assert(outputFormat, Equals, command.JPEGFormat)
+
+	outputFormat, err = chooseOutputFormat("", "animation.gif")
+	checker.Assert(err, IsNil)
+	checker.Assert(outputFormat, Equals, command.GIFFormat)
+
+	_, err = chooseOutputFormat("bmp", "-")
+	checker.Assert(err, ErrorMatches, `invalid -format "bmp".*`)
+}
+
+func (suite *CommandLineSuite) TestQualityNeedsJPEGOutput(checker *C) {
+	var stdout, stderr bytes.Buffer
+
+	exitCode := run([]string{"-in", "-", "-out", "out.png", "-quality", "50"}, bytes.NewReader(nil), &stdout, &stderr)
+
+	checker.Assert(exitCode, Equals, exitCodeBadArguments)
+	checker.Assert(stderr.String(), Equals, "creatingsymmetry: -quality is only used by jpeg output, not png\n")
+}
+
+func (suite *CommandLineSuite) TestMissingFormulaFileFailsRender(checker *C) {
+	sourceFilename := suite.writeFile(checker, "source.png", suite.sourceImageData)
+	outputFilename := filepath.Join(suite.directory, "output.png")
+	var stdout, stderr bytes.Buffer
+
+	exitCode := run(
+		[]string{"-in", sourceFilename, "-f", filepath.Join(suite.directory, "missing.yml"), "-out", outputFilename},
+		bytes.NewReader(nil),
+		&stdout,
+		&stderr,
+	)
+
+	checker.Assert(exitCode, Equals, exitCodeRenderFailed)
+	checker.Assert(stderr.String(), Matches, "creatingsymmetry: cannot read formula: .*missing.yml.*\n")
+	_, err := os.Stat(outputFilename)
+	checker.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (suite *CommandLineSuite) TestInvalidSourceImageFailsRender(checker *C) {
+	var stdout, stderr bytes.Buffer
+
+	exitCode := run(
+		[]string{"-in", "-", "-f", suite.formulaFilename, "-out", "-"},
+		bytes.NewReader([]byte("this is not an image")),
+		&stdout,
+		&stderr,
+	)
+
+	checker.Assert(exitCode, Equals, exitCodeRenderFailed)
+	checker.Assert(stdout.Len(), Equals, 0)
+	checker.Assert(stderr.String(), Equals, "creatingsymmetry: source image format is not recognized, use a PNG, JPEG or GIF instead\n")
+}
+
+func (suite *CommandLineSuite) TestParseOutputSize(checker *C) {
+	width, height, err := parseOutputSize("300X50")
+	checker.Assert(err, IsNil)
+	checker.Assert(width, Equals, 300)
+	checker.Assert(height, Equals, 50)
+
+	_, _, err = parseOutputSize("0x50")
+	checker.Assert(err, NotNil)
+	_, _, err = parseOutputSize("300")
+	checker.Assert(err, NotNil)
+}
+
+func (suite *CommandLineSuite) TestMaskImagesAreFoundNextToTheFormula(checker *C) {
+	maskImage := image.NewGray(image.Rect(0, 0, 2, 1))
+	maskImage.SetGray(0, 0, color.Gray{Y: 255})
+	var maskImageData bytes.Buffer
+	png.Encode(&maskImageData, maskImage)
+	checker.Assert(os.Mkdir(filepath.Join(suite.directory, "masks"), 0755), IsNil)
+	suite.writeFile(checker, filepath.Join("masks", "left.png"), maskImageData.Bytes())
+
+	formulaFilename := suite.writeFile(checker, "masked.yml", []byte(`pattern_viewport:
+  x_min: 0
+  y_min: 0
+  x_max: 10
+  y_max: 10
+coordinate_threshold:
+  type: mask
+  mask: masks/left.png
+formula:
+  type: identity
+`))
+	var stdout, stderr bytes.Buffer
+
+	exitCode := run(
+		[]string{"-in", "-", "-f", formulaFilename, "-out", "-", "-size", "2x1"},
+		bytes.NewReader(suite.sourceImageData),
+		&stdout,
+		&stderr,
+	)
+
+	checker.Assert(exitCode, Equals, exitCodeSuccess, Commentf(stderr.String()))
+	outputImage, err := png.Decode(&stdout)
+	checker.Assert(err, IsNil)
+	checker.Assert(color.NRGBAModel.Convert(outputImage.At(0, 0)), Equals, color.NRGBA{R: 255, A: 255})
+	checker.Assert(color.NRGBAModel.Convert(outputImage.At(1, 0)), Equals, color.NRGBA{})
+}
+
+func (suite *CommandLineSuite) TestSuggestsAThresholdWithoutRendering(checker *C) {
+	var stdout, stderr bytes.Buffer
+
+	exitCode := run(
+		[]string{"-f", suite.formulaFilename, "-size", "2x2", "-suggest-threshold", "0,100"},
+		bytes.NewReader(nil),
+		&stdout,
+		&stderr,
+	)
+
+	checker.Assert(exitCode, Equals, exitCodeSuccess)
+	checker.Assert(stderr.String(), Equals, "")
+	checker.Assert(stdout.String(), Equals, `coordinate_threshold:
+  x_min: 2.5
+  y_min: 2.5
+  x_max: 7.5
+  y_max: 7.5
+`)
+}
+
+func (suite *CommandLineSuite) TestSuggestedPercentilesMustBeInOrder(checker *C) {
+	for _, percentiles := range []string{"99,1", "1", "-1,50", "1,101", "a,b"} {
+		var stdout, stderr bytes.Buffer
+
+		exitCode := run([]string{"-f", suite.formulaFilename, "-suggest-threshold", percentiles}, bytes.NewReader(nil), &stdout, &stderr)
+
+		checker.Assert(exitCode, Equals, exitCodeBadArguments, Commentf("percentiles %q", percentiles))
+		checker.Assert(stderr.String(), Matches, "creatingsymmetry: invalid -suggest-threshold.*\n")
+	}
+}
@@ -0,0 +1,101 @@
+package creatingsymmetry
+
+import (
+	"context"
+	"errors"
+	"github.com/chadius/creatingsymmetry/entities/command"
+	"github.com/chadius/creatingsymmetry/entities/transformer"
+	"image"
+	"io"
+	"io/ioutil"
+)
+
+// CoordinateMap is a formula applied to every pixel of the output and filtered by its coordinate threshold.
+//   Those stages do not use the source image, so the map can be colored with any number of source images
+//   without applying the formula again for each one.
+//   Several goroutines can color the same map at the same time.
+type CoordinateMap struct {
+	wallpaperCommand *command.CreateSymmetryPattern
+	outputSettings   *command.OutputSettings
+	namedImages      map[string]image.Image
+	coordinates      *transformer.CoordinateMap
+}
+
+// BuildCoordinateMapWithContext reads the formula and output settings and applies the formula to every pixel.
+//   Images named in the formula are read now, so masks and background images are only read once.
+//   Coordinate maps keep every coordinate in memory, so streaming output settings are an error.
+//   It stops as soon as the context is cancelled and returns the context's error.
+//   progress is optional, it is told about the progress of the viewport scaling, formula and threshold stages.
+func (f *FileTransformer) BuildCoordinateMapWithContext(ctx context.Context, formulaDataByteStream, outputSettingsDataByteStream io.Reader, progress ProgressReporter) (*CoordinateMap, error) {
+	wallpaperCommand, wallpaperErr := readWallpaperCommand(formulaDataByteStream)
+	if wallpaperErr != nil {
+		return nil, wallpaperErr
+	}
+	outputSettings, outputSettingsErr := readOutputSettings(outputSettingsDataByteStream)
+	if outputSettingsErr != nil {
+		return nil, outputSettingsErr
+	}
+	if outputSettings.Streaming() {
+		return nil, errors.New("coordinate maps keep every coordinate in memory, they cannot use streaming output settings")
+	}
+	namedImages, namedImagesErr := f.readNamedImages(append(
+		wallpaperCommand.CoordinateThreshold.MaskFilenames(),
+		wallpaperCommand.Background.ImageFilenames()...,
+	))
+	if namedImagesErr != nil {
+		return nil, namedImagesErr
+	}
+
+	transformerEntity := transformer.FormulaTransformer{}
+	coordinates, transformErr := transformerEntity.BuildCoordinateMapWithContext(ctx, newCoordinateSettings(namedImages, wallpaperCommand, outputSettings, progress))
+	if transformErr != nil {
+		return nil, transformErr
+	}
+	return &CoordinateMap{
+		wallpaperCommand: wallpaperCommand,
+		outputSettings:   outputSettings,
+		namedImages:      namedImages,
+		coordinates:      coordinates,
+	}, nil
+}
+
+// WithEyedropperData returns a map that colors the same coordinates with another eyedropper.
+//   The data is YAML or JSON with the eyedropper and eyedropper_range keys of a formula file,
+//   and replaces both of the formula's. The coordinates are shared with this map, they are not transformed again.
+func (c *CoordinateMap) WithEyedropperData(eyedropperDataByteStream io.Reader) (*CoordinateMap, error) {
+	eyedropperData, err := ioutil.ReadAll(eyedropperDataByteStream)
+	if err != nil {
+		return nil, err
+	}
+	eyedropperChoice, err := command.NewEyedropperChoiceFromData(eyedropperData)
+	if err != nil {
+		return nil, err
+	}
+	return &CoordinateMap{
+		wallpaperCommand: eyedropperChoice.ApplyTo(c.wallpaperCommand),
+		outputSettings:   c.outputSettings,
+		namedImages:      c.namedImages,
+		coordinates:      c.coordinates,
+	}, nil
+}
+
+// ApplyCoordinateMapWithContext colors the map using the source image and writes the image,
+//   like ApplyFormulaToTransformImageWithContext without applying the formula again.
+//   The source image stream can be nil when the map uses a domain coloring or gradient eyedropper.
+//   It stops as soon as the context is cancelled and returns the context's error, without writing to the output.
+//   progress is optional, it is told about the progress of the eyedropping and encoding stages.
+func (f *FileTransformer) ApplyCoordinateMapWithContext(ctx context.Context, coordinateMap *CoordinateMap, inputImageDataByteStream io.Reader, output io.Writer, progress ProgressReporter) error {
+	sourceImage, sourceImageErr := readSourceImage(inputImageDataByteStream, coordinateMap.wallpaperCommand)
+	if sourceImageErr != nil {
+		return sourceImageErr
+	}
+
+	settings := &transformer.Settings{Progress: progress}
+	addColoringSettings(settings, sourceImage, coordinateMap.namedImages, coordinateMap.wallpaperCommand, coordinateMap.outputSettings)
+	transformerEntity := transformer.FormulaTransformer{}
+	outputImage, transformErr := transformerEntity.ColorCoordinateMapWithContext(ctx, coordinateMap.coordinates, settings)
+	if transformErr != nil {
+		return transformErr
+	}
+	return encodeImageWithProgress(ctx, output, outputImage, coordinateMap.outputSettings, progress)
+}
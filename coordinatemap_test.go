@@ -0,0 +1,213 @@
+package creatingsymmetry_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/chadius/creatingsymmetry"
+	. "gopkg.in/check.v1"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"io/ioutil"
+)
+
+type CoordinateMapSuite struct {
+	formula            string
+	outputSettings     string
+	maskImageData      []byte
+	sourceImagesData   [][]byte
+	openedFilenames    []string
+	fileTransformer    *creatingsymmetry.FileTransformer
+	domainColoringData string
+}
+
+var _ = Suite(&CoordinateMapSuite{})
+
+func (suite *CoordinateMapSuite) SetUpTest(checker *C) {
+	suite.formula = `pattern_viewport:
+  x_min: -1
+  y_min: -1
+  x_max: 1
+  y_max: 1
+coordinate_threshold:
+  type: mask
+  mask: mask.png
+  mask_space: transformed
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+background:
+  filtered:
+    type: color
+    color: "#203040"
+formula:
+  type: rosette
+  terms:
+    - multiplier:
+        real: 1
+        imaginary: 0.5
+      power_n: 2
+      power_m: -1
+`
+	suite.outputSettings = "output_width: 9\noutput_height: 7\nsupersampling: 2\n"
+	suite.domainColoringData = "eyedropper:\n  shape: domain_coloring\n  phase_contours: 6\n"
+
+	maskImage := image.NewGray(image.Rect(0, 0, 2, 2))
+	maskImage.SetGray(0, 0, color.Gray{Y: 255})
+	maskImage.SetGray(1, 1, color.Gray{Y: 255})
+	var maskImageData bytes.Buffer
+	png.Encode(&maskImageData, maskImage)
+	suite.maskImageData = maskImageData.Bytes()
+
+	suite.sourceImagesData = [][]byte{}
+	for seed := 1; seed <= 3; seed++ {
+		sourceImage := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+		for index := range sourceImage.Pix {
+			sourceImage.Pix[index] = uint8(index*seed*41 + 255*((index+1)%4/3))
+		}
+		var sourceImageData bytes.Buffer
+		png.Encode(&sourceImageData, sourceImage)
+		suite.sourceImagesData = append(suite.sourceImagesData, sourceImageData.Bytes())
+	}
+
+	suite.openedFilenames = []string{}
+	suite.fileTransformer = &creatingsymmetry.FileTransformer{
+		OpenImage: func(filename string) (io.ReadCloser, error) {
+			suite.openedFilenames = append(suite.openedFilenames, filename)
+			return ioutil.NopCloser(bytes.NewReader(suite.maskImageData)), nil
+		},
+	}
+}
+
+func (suite *CoordinateMapSuite) buildMap(checker *C) *creatingsymmetry.CoordinateMap {
+	coordinateMap, err := suite.fileTransformer.BuildCoordinateMapWithContext(
+		context.Background(),
+		bytes.NewBufferString(suite.formula),
+		bytes.NewBufferString(suite.outputSettings),
+		nil,
+	)
+	checker.Assert(err, IsNil)
+	return coordinateMap
+}
+
+func (suite *CoordinateMapSuite) TestColoringTheMapMatchesApplyingTheFormula(checker *C) {
+	coordinateMap := suite.buildMap(checker)
+
+	for index, sourceImageData := range suite.sourceImagesData {
+		var expectedOutput bytes.Buffer
+		err := suite.fileTransformer.ApplyFormulaToTransformImage(
+			bytes.NewReader(sourceImageData),
+			bytes.NewBufferString(suite.formula),
+			bytes.NewBufferString(suite.outputSettings),
+			&expectedOutput,
+		)
+		checker.Assert(err, IsNil)
+
+		var output bytes.Buffer
+		err = suite.fileTransformer.ApplyCoordinateMapWithContext(context.Background(), coordinateMap, bytes.NewReader(sourceImageData), &output, nil)
+		checker.Assert(err, IsNil)
+		checker.Assert(output.Bytes(), DeepEquals, expectedOutput.Bytes(), Commentf("source image %d", index))
+	}
+}
+
+func (suite *CoordinateMapSuite) TestNamedImagesAreReadWhenTheMapIsBuilt(checker *C) {
+	coordinateMap := suite.buildMap(checker)
+	for _, sourceImageData := range suite.sourceImagesData {
+		var output bytes.Buffer
+		err := suite.fileTransformer.ApplyCoordinateMapWithContext(context.Background(), coordinateMap, bytes.NewReader(sourceImageData), &output, nil)
+		checker.Assert(err, IsNil)
+	}
+	checker.Assert(suite.openedFilenames, DeepEquals, []string{"mask.png"})
+}
+
+func (suite *CoordinateMapSuite) TestAnotherEyedropperColorsTheSameMap(checker *C) {
+	coordinateMap := suite.buildMap(checker)
+	domainColoringMap, err := coordinateMap.WithEyedropperData(bytes.NewBufferString(suite.domainColoringData))
+	checker.Assert(err, IsNil)
+
+	var output bytes.Buffer
+	err = suite.fileTransformer.ApplyCoordinateMapWithContext(context.Background(), domainColoringMap, nil, &output, nil)
+	checker.Assert(err, IsNil)
+
+	var expectedOutput bytes.Buffer
+	err = suite.fileTransformer.ApplyFormulaToTransformImage(
+		nil,
+		bytes.NewBufferString(suite.domainColoringData+suite.formula),
+		bytes.NewBufferString(suite.outputSettings),
+		&expectedOutput,
+	)
+	checker.Assert(err, IsNil)
+	checker.Assert(output.Bytes(), DeepEquals, expectedOutput.Bytes())
+
+	err = suite.fileTransformer.ApplyCoordinateMapWithContext(context.Background(), coordinateMap, nil, &output, nil)
+	checker.Assert(err, ErrorMatches, "missing source image.*")
+}
+
+func (suite *CoordinateMapSuite) TestInvalidEyedropperDataIsAnError(checker *C) {
+	coordinateMap := suite.buildMap(checker)
+	_, err := coordinateMap.WithEyedropperData(bytes.NewBufferString("eyedropper:\n  shape: triangle\n"))
+	checker.Assert(err, ErrorMatches, `found 1 problem:\nline 2: eyedropper.shape: .*`)
+}
+
+func (suite *CoordinateMapSuite) TestStreamingCannotBuildAMap(checker *C) {
+	_, err := suite.fileTransformer.BuildCoordinateMapWithContext(
+		context.Background(),
+		bytes.NewBufferString(suite.formula),
+		bytes.NewBufferString("output_width: 9\noutput_height: 7\nstreaming: true\n"),
+		nil,
+	)
+	checker.Assert(err, ErrorMatches, "coordinate maps keep every coordinate in memory, they cannot use streaming output settings")
+	checker.Assert(suite.openedFilenames, HasLen, 0)
+}
+
+func (suite *CoordinateMapSuite) TestBuildingAndColoringReportTheirOwnStages(checker *C) {
+	buildStages := []creatingsymmetry.Stage{}
+	coordinateMap, err := suite.fileTransformer.BuildCoordinateMapWithContext(
+		context.Background(),
+		bytes.NewBufferString(suite.formula),
+		bytes.NewBufferString(suite.outputSettings),
+		func(event creatingsymmetry.ProgressEvent) {
+			if event.Completed == event.Total {
+				buildStages = append(buildStages, event.Stage)
+			}
+		},
+	)
+	checker.Assert(err, IsNil)
+	checker.Assert(buildStages, DeepEquals, []creatingsymmetry.Stage{
+		creatingsymmetry.StageViewportScaling,
+		creatingsymmetry.StageFormulaEvaluation,
+		creatingsymmetry.StageThresholding,
+	})
+
+	applyStages := []creatingsymmetry.Stage{}
+	var output bytes.Buffer
+	err = suite.fileTransformer.ApplyCoordinateMapWithContext(
+		context.Background(),
+		coordinateMap,
+		bytes.NewReader(suite.sourceImagesData[0]),
+		&output,
+		func(event creatingsymmetry.ProgressEvent) {
+			if event.Completed == event.Total {
+				applyStages = append(applyStages, event.Stage)
+			}
+		},
+	)
+	checker.Assert(err, IsNil)
+	checker.Assert(applyStages, DeepEquals, []creatingsymmetry.Stage{
+		creatingsymmetry.StageEyedropping,
+		creatingsymmetry.StageEncoding,
+	})
+}
+
+func (suite *CoordinateMapSuite) TestCancelledContextDoesNotWriteOutput(checker *C) {
+	coordinateMap := suite.buildMap(checker)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var output bytes.Buffer
+	err := suite.fileTransformer.ApplyCoordinateMapWithContext(ctx, coordinateMap, bytes.NewReader(suite.sourceImagesData[0]), &output, nil)
+	checker.Assert(err, Equals, context.Canceled)
+	checker.Assert(output.Len(), Equals, 0)
+}
@@ -1,13 +1,21 @@
 package creatingsymmetry
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/chadius/creatingsymmetry/entities/command"
+	"github.com/chadius/creatingsymmetry/entities/imageinput"
 	"github.com/chadius/creatingsymmetry/entities/imageoutput"
 	"github.com/chadius/creatingsymmetry/entities/transformer"
 	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
 	"image/png"
 	"io"
 	"io/ioutil"
+	"math"
 )
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
@@ -16,16 +24,57 @@ import (
 // TransformerStrategy shapes the expected messages and the expected responses when running the rules.
 type TransformerStrategy interface {
 	ApplyFormulaToTransformImage(inputImageDataByteStream, formulaDataByteStream, outputSettingsDataByteStream io.Reader, output io.Writer) error
+	ApplyFormulaToTransformImageWithContext(ctx context.Context, inputImageDataByteStream, formulaDataByteStream, outputSettingsDataByteStream io.Reader, output io.Writer, progress ProgressReporter) error
 }
 
-type FileTransformer struct{}
+// CoordinateMapStrategy builds a CoordinateMap from the formula once and colors it with many source images.
+type CoordinateMapStrategy interface {
+	BuildCoordinateMapWithContext(ctx context.Context, formulaDataByteStream, outputSettingsDataByteStream io.Reader, progress ProgressReporter) (*CoordinateMap, error)
+	ApplyCoordinateMapWithContext(ctx context.Context, coordinateMap *CoordinateMap, inputImageDataByteStream io.Reader, output io.Writer, progress ProgressReporter) error
+}
+
+// Stage names a step in the rendering pipeline.
+type Stage = transformer.Stage
+
+// Stages are reported in this order.
+const (
+	StageViewportScaling   = transformer.StageViewportScaling
+	StageFormulaEvaluation = transformer.StageFormulaEvaluation
+	StageThresholding      = transformer.StageThresholding
+	StageEyedropping       = transformer.StageEyedropping
+	StageEncoding          = transformer.StageEncoding
+)
+
+// ProgressEvent describes how much of a stage has been completed.
+type ProgressEvent = transformer.ProgressEvent
+
+// ProgressReporter receives progress events while an image is rendered. It is never called at the same time.
+type ProgressReporter = transformer.ProgressReporter
+
+// FileTransformer reads the source image, formula and output settings from streams and writes the transformed image.
+type FileTransformer struct {
+	// OpenImage opens images named in the formula, like masks and background images.
+	//   It is optional, but formulas that name images cannot be rendered without it.
+	OpenImage func(filename string) (io.ReadCloser, error)
+}
 
+// ApplyFormulaToTransformImage reads the source image, formula and output settings and writes the transformed image.
+//   The source image stream can be nil when the formula uses a domain coloring or gradient eyedropper.
 func (f *FileTransformer) ApplyFormulaToTransformImage(inputImageDataByteStream, formulaDataByteStream, outputSettingsDataByteStream io.Reader, output io.Writer) error {
+	return f.ApplyFormulaToTransformImageWithContext(context.Background(), inputImageDataByteStream, formulaDataByteStream, outputSettingsDataByteStream, output, nil)
+}
+
+// ApplyFormulaToTransformImageWithContext transforms the image like ApplyFormulaToTransformImage.
+//   It stops as soon as the context is cancelled and returns the context's error, without writing to the output.
+//   Streaming output settings are the exception: rows are written as they are rendered,
+//   so part of the image may already be written when it stops.
+//   progress is optional, it is told about the progress of each stage.
+func (f *FileTransformer) ApplyFormulaToTransformImageWithContext(ctx context.Context, inputImageDataByteStream, formulaDataByteStream, outputSettingsDataByteStream io.Reader, output io.Writer, progress ProgressReporter) error {
 	wallpaperCommand, wallpaperErr := readWallpaperCommand(formulaDataByteStream)
 	if wallpaperErr != nil {
 		return wallpaperErr
 	}
-	sourceImage, sourceImageErr := readSourceImage(inputImageDataByteStream)
+	sourceImage, sourceImageErr := readSourceImage(inputImageDataByteStream, wallpaperCommand)
 	if sourceImageErr != nil {
 		return sourceImageErr
 	}
@@ -33,25 +82,110 @@ func (f *FileTransformer) ApplyFormulaToTransformImage(inputImageDataByteStream,
 	if outputSettingsErr != nil {
 		return outputSettingsErr
 	}
-	outputImage := transformImage(sourceImage, wallpaperCommand, outputSettings)
-	png.Encode(output, outputImage)
+	namedImages, namedImagesErr := f.readNamedImages(append(
+		wallpaperCommand.CoordinateThreshold.MaskFilenames(),
+		wallpaperCommand.Background.ImageFilenames()...,
+	))
+	if namedImagesErr != nil {
+		return namedImagesErr
+	}
+	if outputSettings.Streaming() {
+		return streamImage(ctx, sourceImage, namedImages, wallpaperCommand, outputSettings, output, progress)
+	}
+	outputImage, transformErr := transformImage(ctx, sourceImage, namedImages, wallpaperCommand, outputSettings, progress)
+	if transformErr != nil {
+		return transformErr
+	}
+	return encodeImageWithProgress(ctx, output, outputImage, outputSettings, progress)
+}
+
+// encodeImageWithProgress encodes the image, unless the context was cancelled, and reports the encoding stage.
+func encodeImageWithProgress(ctx context.Context, output io.Writer, outputImage image.Image, outputSettings *command.OutputSettings, progress ProgressReporter) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	reportProgress(progress, StageEncoding, 0)
+	encodeErr := encodeImage(output, outputImage, outputSettings)
+	if encodeErr != nil {
+		return encodeErr
+	}
+	reportProgress(progress, StageEncoding, 1)
 	return nil
 }
 
-func readSourceImage(input io.Reader) (image.Image, error) {
-	colorSourceImage, _, err := image.Decode(input)
-	if err != nil {
-		return nil, err
+// encodeImage writes the image to the output using the format in the output settings.
+func encodeImage(output io.Writer, outputImage image.Image, outputSettings *command.OutputSettings) error {
+	switch outputSettings.OutputFormat() {
+	case command.JPEGFormat:
+		return jpeg.Encode(output, outputImage, &jpeg.Options{Quality: outputSettings.JPEGQuality()})
+	case command.GIFFormat:
+		return gif.Encode(output, outputImage, &gif.Options{
+			NumColors: outputSettings.GIFColors(),
+			Quantizer: &imageoutput.MedianCutQuantizer{},
+			Drawer:    draw.FloydSteinberg,
+		})
+	}
+
+	encoder := png.Encoder{CompressionLevel: outputSettings.PNGCompressionLevel()}
+	return encoder.Encode(output, outputImage)
+}
+
+// reportProgress tells the reporter about a stage with one step, if there is a reporter.
+func reportProgress(progress ProgressReporter, stage Stage, completed int) {
+	if progress == nil {
+		return
+	}
+	progress(ProgressEvent{
+		Stage:     stage,
+		Completed: completed,
+		Total:     1,
+	})
+}
+
+// readSourceImage decodes the source image.
+//   The input can be nil if the pattern does not need a source image, then the image is nil too.
+func readSourceImage(input io.Reader, wallpaperCommand *command.CreateSymmetryPattern) (image.Image, error) {
+	if input == nil {
+		if wallpaperCommand.NeedsSourceImage() {
+			return nil, errors.New("missing source image, only domain coloring and gradient eyedroppers can draw without one")
+		}
+		return nil, nil
 	}
-	return colorSourceImage, nil
+	return imageinput.Decode(input)
+}
+
+// readNamedImages opens and decodes each image named in the formula, the same way as the source image.
+//   Images named more than once are only read once.
+func (f *FileTransformer) readNamedImages(filenames []string) (map[string]image.Image, error) {
+	namedImages := map[string]image.Image{}
+	for _, filename := range filenames {
+		if _, alreadyRead := namedImages[filename]; alreadyRead {
+			continue
+		}
+		if f.OpenImage == nil {
+			return nil, fmt.Errorf("cannot read image %q: this transformer cannot open images named in the formula", filename)
+		}
+		imageData, err := f.OpenImage(filename)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read image %q: %v", filename, err)
+		}
+		namedImage, err := imageinput.Decode(imageData)
+		imageData.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cannot read image %q: %v", filename, err)
+		}
+		namedImages[filename] = namedImage
+	}
+	return namedImages, nil
 }
 
 func readWallpaperCommand(input io.Reader) (*command.CreateSymmetryPattern, error) {
-	createWallpaperYAML, err := ioutil.ReadAll(input)
+	createWallpaperData, err := ioutil.ReadAll(input)
 	if err != nil {
 		return nil, err
 	}
-	wallpaperCommand, err := command.NewCreateWallpaperCommandFromYAML(createWallpaperYAML)
+	wallpaperCommand, err := command.NewCreateWallpaperCommandFromData(createWallpaperData)
 	if err != nil {
 		return nil, err
 	}
@@ -59,55 +193,304 @@ func readWallpaperCommand(input io.Reader) (*command.CreateSymmetryPattern, erro
 }
 
 func readOutputSettings(input io.Reader) (*command.OutputSettings, error) {
-	outputSettingsYAML, err := ioutil.ReadAll(input)
+	outputSettingsData, err := ioutil.ReadAll(input)
 	if err != nil {
 		return nil, err
 	}
 
-	outputSettings := command.NewOutputSettingsBuilder().WithYAML(outputSettingsYAML).Build()
-	return outputSettings, nil
+	return command.NewOutputSettingsFromData(outputSettingsData)
 }
 
-func transformImage(sourceImage image.Image, wallpaperCommand *command.CreateSymmetryPattern, outputSettings *command.OutputSettings) *image.NRGBA {
-	coordinateThreshold := imageoutput.CoordinateFilterBuilder().
-		WithMinimumX(wallpaperCommand.CoordinateThreshold.XMin).
-		WithMaximumX(wallpaperCommand.CoordinateThreshold.XMax).
-		WithMinimumY(wallpaperCommand.CoordinateThreshold.YMin).
-		WithMaximumY(wallpaperCommand.CoordinateThreshold.YMax).
-		Build()
+func transformImage(ctx context.Context, sourceImage image.Image, namedImages map[string]image.Image, wallpaperCommand *command.CreateSymmetryPattern, outputSettings *command.OutputSettings, progress ProgressReporter) (*image.NRGBA, error) {
+	transformerEntity := transformer.FormulaTransformer{}
+	return transformerEntity.TransformWithContext(ctx, newTransformerSettings(sourceImage, namedImages, wallpaperCommand, outputSettings, progress))
+}
 
-	var eyedropper *imageoutput.RectangularEyedropper
-	if wallpaperCommand.Eyedropper != nil {
-		eyedropper = imageoutput.EyedropperBuilder().
-			WithLeftSide(wallpaperCommand.Eyedropper.LeftSide).
-			WithRightSide(wallpaperCommand.Eyedropper.RightSide).
-			WithTopSide(wallpaperCommand.Eyedropper.TopSide).
-			WithBottomSide(wallpaperCommand.Eyedropper.BottomSide).
-			WithImage(sourceImage).
-			Build()
-	} else {
-		eyedropper = imageoutput.EyedropperBuilder().
-			WithLeftSide(sourceImage.Bounds().Min.X).
-			WithRightSide(sourceImage.Bounds().Max.X).
-			WithTopSide(sourceImage.Bounds().Min.Y).
-			WithBottomSide(sourceImage.Bounds().Max.Y).
-			WithImage(sourceImage).
-			Build()
+// streamImage renders the image a band of rows at a time and encodes each row as a PNG as soon as it is ready,
+//   so the whole image is never in memory.
+//   Settings that need every coordinate at once are reported before anything is written.
+func streamImage(ctx context.Context, sourceImage image.Image, namedImages map[string]image.Image, wallpaperCommand *command.CreateSymmetryPattern, outputSettings *command.OutputSettings, output io.Writer, progress ProgressReporter) error {
+	transformerEntity := transformer.FormulaTransformer{}
+	settings := newTransformerSettings(sourceImage, namedImages, wallpaperCommand, outputSettings, progress)
+	if err := transformerEntity.CanStream(settings); err != nil {
+		return err
 	}
 
-	transformerEntity := transformer.FormulaTransformer{}
+	encoder, err := imageoutput.NewPNGRowWriter(output, outputSettings.OutputWidth(), outputSettings.OutputHeight(), outputSettings.PNGCompressionLevel())
+	if err != nil {
+		return err
+	}
+	rowsEncoded := 0
+	reportEncodedRows := func() {
+		if progress == nil {
+			return
+		}
+		progress(ProgressEvent{
+			Stage:     StageEncoding,
+			Completed: rowsEncoded,
+			Total:     outputSettings.OutputHeight(),
+		})
+	}
+	reportEncodedRows()
+	err = transformerEntity.TransformRowsWithContext(ctx, settings, func(rows *image.NRGBA) error {
+		bounds := rows.Bounds()
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			rowStart := rows.PixOffset(bounds.Min.X, y)
+			if err := encoder.WriteRow(rows.Pix[rowStart : rowStart+bounds.Dx()*4]); err != nil {
+				return err
+			}
+			rowsEncoded++
+		}
+		reportEncodedRows()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return encoder.Close()
+}
 
-	outputImage := transformerEntity.Transform(&transformer.Settings{
+// newTransformerSettings creates the coordinate threshold, eyedropper and backgrounds described by the formula.
+func newTransformerSettings(sourceImage image.Image, namedImages map[string]image.Image, wallpaperCommand *command.CreateSymmetryPattern, outputSettings *command.OutputSettings, progress ProgressReporter) *transformer.Settings {
+	settings := newCoordinateSettings(namedImages, wallpaperCommand, outputSettings, progress)
+	addColoringSettings(settings, sourceImage, namedImages, wallpaperCommand, outputSettings)
+	return settings
+}
+
+// newCoordinateSettings creates the settings that place each sample in the pattern and filter it,
+//   which do not need the source image.
+func newCoordinateSettings(namedImages map[string]image.Image, wallpaperCommand *command.CreateSymmetryPattern, outputSettings *command.OutputSettings, progress ProgressReporter) *transformer.Settings {
+	return &transformer.Settings{
 		PatternViewportXMin: wallpaperCommand.PatternViewport.XMin,
 		PatternViewportXMax: wallpaperCommand.PatternViewport.XMax,
 		PatternViewportYMin: wallpaperCommand.PatternViewport.YMin,
 		PatternViewportYMax: wallpaperCommand.PatternViewport.YMax,
-		InputImage:          sourceImage,
 		Formula:             wallpaperCommand,
-		CoordinateThreshold: coordinateThreshold,
-		Eyedropper:          eyedropper,
+		CoordinateThreshold: newCoordinateThreshold(wallpaperCommand.CoordinateThreshold, namedImages, outputSettings),
 		OutputWidth:         outputSettings.OutputWidth(),
 		OutputHeight:        outputSettings.OutputHeight(),
+		WorkerCount:         outputSettings.WorkerCount(),
+		Progress:            progress,
+
+		Supersampling:         outputSettings.Supersampling(),
+		AdaptiveSupersampling: outputSettings.AdaptiveSupersampling(),
+		AdaptiveThreshold:     outputSettings.AdaptiveThreshold(),
+	}
+}
+
+// addColoringSettings adds the source image, eyedropper and backgrounds that color each sample.
+func addColoringSettings(settings *transformer.Settings, sourceImage image.Image, namedImages map[string]image.Image, wallpaperCommand *command.CreateSymmetryPattern, outputSettings *command.OutputSettings) {
+	settings.InputImage = sourceImage
+	settings.Eyedropper = newEyedropper(wallpaperCommand.Eyedropper, wallpaperCommand.EyedropperRange, sourceImage)
+	settings.FilteredBackground = newBackground(wallpaperCommand.Background.Filtered, sourceImage, namedImages, outputSettings)
+	settings.UnmappedBackground = newBackground(wallpaperCommand.Background.Unmapped, sourceImage, namedImages, outputSettings)
+}
+
+// SuggestCoordinateThreshold applies the formula at every output pixel and suggests a rectangular coordinate threshold
+//   that keeps the transformed coordinates between the lower and upper percentiles, from 0 to 100.
+//   Unlike an auto threshold, the suggestion can be copied into the formula so it stays the same at every output size.
+//   The formula's own coordinate threshold and eyedropper are not used, so no source image is needed.
+func SuggestCoordinateThreshold(ctx context.Context, formulaDataByteStream, outputSettingsDataByteStream io.Reader, lowerPercentile, upperPercentile float64) (*command.CoordinateThresholdSettings, error) {
+	wallpaperCommand, wallpaperErr := readWallpaperCommand(formulaDataByteStream)
+	if wallpaperErr != nil {
+		return nil, wallpaperErr
+	}
+	outputSettings, outputSettingsErr := readOutputSettings(outputSettingsDataByteStream)
+	if outputSettingsErr != nil {
+		return nil, outputSettingsErr
+	}
+
+	transformerEntity := transformer.FormulaTransformer{}
+	collection, transformErr := transformerEntity.TransformCoordinatesWithContext(ctx, &transformer.Settings{
+		PatternViewportXMin: wallpaperCommand.PatternViewport.XMin,
+		PatternViewportXMax: wallpaperCommand.PatternViewport.XMax,
+		PatternViewportYMin: wallpaperCommand.PatternViewport.YMin,
+		PatternViewportYMax: wallpaperCommand.PatternViewport.YMax,
+		Formula:             wallpaperCommand,
+		OutputWidth:         outputSettings.OutputWidth(),
+		OutputHeight:        outputSettings.OutputHeight(),
+		WorkerCount:         outputSettings.WorkerCount(),
 	})
-	return outputImage
+	if transformErr != nil {
+		return nil, transformErr
+	}
+
+	rectangle := imageoutput.SuggestRectangularCoordinateThreshold(collection, lowerPercentile, upperPercentile)
+	if rectangle == nil {
+		return nil, errors.New("cannot suggest a coordinate threshold, every transformed coordinate is at Infinity or NaN")
+	}
+	return &command.CoordinateThresholdSettings{
+		XMin: rectangle.MinimumX(),
+		XMax: rectangle.MaximumX(),
+		YMin: rectangle.MinimumY(),
+		YMax: rectangle.MaximumY(),
+	}, nil
+}
+
+// newBackground creates the background described by the fill. Transparent fills have no background.
+func newBackground(fill *command.BackgroundFill, sourceImage image.Image, namedImages map[string]image.Image, outputSettings *command.OutputSettings) imageoutput.Background {
+	if fill == nil {
+		return nil
+	}
+	switch fill.Type {
+	case command.ColorBackgroundFill:
+		fillColor, _ := fill.FillColor()
+		return imageoutput.NewSolidColorBackground(fillColor)
+	case command.ImageBackgroundFill:
+		return imageoutput.NewImageBackground(namedImages[fill.Image], outputSettings.OutputWidth(), outputSettings.OutputHeight())
+	case command.SourceBackgroundFill:
+		return imageoutput.NewImageBackground(sourceImage, outputSettings.OutputWidth(), outputSettings.OutputHeight())
+	}
+	return nil
+}
+
+// newCoordinateThreshold creates the coordinate threshold described by the settings.
+//   Shapes with a feather fade out across their edge. Masks use the decoded image with the same filename.
+func newCoordinateThreshold(thresholdSettings command.CoordinateThresholdSettings, namedImages map[string]image.Image, outputSettings *command.OutputSettings) imageoutput.CoordinateThreshold {
+	if thresholdSettings.ShapeType().IsComposite() {
+		return newCompositeCoordinateThreshold(thresholdSettings, namedImages, outputSettings)
+	}
+	if thresholdSettings.ShapeType() == command.MaskThreshold {
+		return newMaskCoordinateThreshold(thresholdSettings, namedImages[thresholdSettings.Mask], outputSettings)
+	}
+
+	threshold := newShapeCoordinateThreshold(thresholdSettings)
+	shape, canBeFeathered := threshold.(imageoutput.ShapeCoordinateThreshold)
+	if !canBeFeathered || thresholdSettings.Feather <= 0 {
+		return threshold
+	}
+	return imageoutput.NewFeatheredCoordinateThreshold(shape, thresholdSettings.Feather)
+}
+
+// newShapeCoordinateThreshold creates the coordinate threshold shape described by the settings.
+func newShapeCoordinateThreshold(thresholdSettings command.CoordinateThresholdSettings) imageoutput.CoordinateThreshold {
+	switch thresholdSettings.ShapeType() {
+	case command.DiscThreshold:
+		return imageoutput.NewDiscCoordinateThreshold(thresholdSettings.CenterX, thresholdSettings.CenterY, thresholdSettings.Radius)
+	case command.AnnulusThreshold:
+		return imageoutput.NewAnnulusCoordinateThreshold(thresholdSettings.CenterX, thresholdSettings.CenterY, thresholdSettings.InnerRadius, thresholdSettings.OuterRadius)
+	case command.SectorThreshold:
+		return imageoutput.NewSectorCoordinateThreshold(
+			thresholdSettings.CenterX,
+			thresholdSettings.CenterY,
+			thresholdSettings.StartAngle*math.Pi/180,
+			thresholdSettings.EndAngle*math.Pi/180,
+			thresholdSettings.InnerRadius,
+			thresholdSettings.OuterRadius,
+		)
+	case command.AutoThreshold:
+		return imageoutput.NewPercentileCoordinateThreshold(thresholdSettings.Percentiles())
+	case command.PolygonThreshold:
+		vertices := []complex128{}
+		for _, vertex := range thresholdSettings.Vertices {
+			vertices = append(vertices, complex(vertex.X(), vertex.Y()))
+		}
+		return imageoutput.NewPolygonCoordinateThreshold(vertices)
+	}
+
+	return imageoutput.CoordinateFilterBuilder().
+		WithMinimumX(thresholdSettings.XMin).
+		WithMaximumX(thresholdSettings.XMax).
+		WithMinimumY(thresholdSettings.YMin).
+		WithMaximumY(thresholdSettings.YMax).
+		Build()
+}
+
+// newMaskCoordinateThreshold places the mask image in output or transformed space.
+func newMaskCoordinateThreshold(thresholdSettings command.CoordinateThresholdSettings, maskImage image.Image, outputSettings *command.OutputSettings) imageoutput.CoordinateThreshold {
+	if thresholdSettings.Space() == command.TransformedMaskSpace {
+		return imageoutput.NewTransformedSpaceMaskCoordinateThreshold(maskImage, thresholdSettings.XMin, thresholdSettings.XMax, thresholdSettings.YMin, thresholdSettings.YMax)
+	}
+	return imageoutput.NewOutputSpaceMaskCoordinateThreshold(maskImage, outputSettings.OutputWidth(), outputSettings.OutputHeight())
+}
+
+// newCompositeCoordinateThreshold creates each child threshold, then combines them.
+func newCompositeCoordinateThreshold(thresholdSettings command.CoordinateThresholdSettings, namedImages map[string]image.Image, outputSettings *command.OutputSettings) imageoutput.CoordinateThreshold {
+	children := []imageoutput.CoordinateThreshold{}
+	for _, childSettings := range thresholdSettings.Children {
+		children = append(children, newCoordinateThreshold(childSettings, namedImages, outputSettings))
+	}
+
+	switch thresholdSettings.ShapeType() {
+	case command.IntersectionThreshold:
+		return imageoutput.NewIntersectionCoordinateThreshold(children...)
+	case command.DifferenceThreshold:
+		return imageoutput.NewDifferenceCoordinateThreshold(children[0], children[1:]...)
+	case command.InvertThreshold:
+		return imageoutput.NewInvertedCoordinateThreshold(children[0])
+	}
+	return imageoutput.NewUnionCoordinateThreshold(children...)
+}
+
+// newEyedropper creates the eyedropper described by the settings.
+//   Without settings, the eyedropper samples the entire source image.
+//   Domain coloring and gradient eyedroppers do not use the source image or the eyedropper range.
+//   Rectangular eyedroppers map the eyedropper range to their sides, if there is one.
+//   Polar eyedroppers only use its upper percentile.
+func newEyedropper(eyedropperSettings *command.EyedropperSettings, eyedropperRange *command.EyedropperRange, sourceImage image.Image) imageoutput.Eyedropper {
+	if eyedropperSettings != nil && eyedropperSettings.IsDomainColoring() {
+		return imageoutput.DomainColoringEyedropperBuilder().
+			WithModulusContours(eyedropperSettings.ModulusContours).
+			WithPhaseContours(eyedropperSettings.PhaseContours).
+			Build()
+	}
+	if eyedropperSettings != nil && eyedropperSettings.IsGradient() {
+		return newGradientEyedropper(eyedropperSettings)
+	}
+
+	if eyedropperSettings == nil {
+		eyedropperSettings = &command.EyedropperSettings{
+			LeftSide:   sourceImage.Bounds().Min.X,
+			RightSide:  sourceImage.Bounds().Max.X,
+			TopSide:    sourceImage.Bounds().Min.Y,
+			BottomSide: sourceImage.Bounds().Max.Y,
+		}
+	}
+
+	if eyedropperSettings.IsPolar() {
+		radiusX, radiusY := eyedropperSettings.PolarRadius()
+		builder := imageoutput.PolarEyedropperBuilder().
+			WithCenter(eyedropperSettings.CenterX, eyedropperSettings.CenterY).
+			WithRadius(radiusX, radiusY).
+			WithMaximumModulus(eyedropperSettings.MaximumModulus).
+			WithSampling(eyedropperSettings.Sampling).
+			WithImage(sourceImage)
+		if eyedropperRange != nil && eyedropperRange.UsesPercentiles() {
+			_, upperPercentile := eyedropperRange.Percentiles()
+			builder.WithModulusPercentile(upperPercentile)
+		}
+		return builder.Build()
+	}
+
+	builder := imageoutput.EyedropperBuilder().
+		WithLeftSide(eyedropperSettings.LeftSide).
+		WithRightSide(eyedropperSettings.RightSide).
+		WithTopSide(eyedropperSettings.TopSide).
+		WithBottomSide(eyedropperSettings.BottomSide).
+		WithSampling(eyedropperSettings.Sampling).
+		WithEdgeMode(eyedropperSettings.EdgeMode).
+		WithPeriod(eyedropperSettings.PeriodX, eyedropperSettings.PeriodY).
+		WithMappingCurves(eyedropperSettings.CurveX, eyedropperSettings.CurveY).
+		WithImage(sourceImage)
+	if eyedropperRange != nil && eyedropperRange.UsesPercentiles() {
+		builder.WithPercentileRange(eyedropperRange.Percentiles())
+	}
+	if eyedropperRange != nil && !eyedropperRange.UsesPercentiles() {
+		builder.WithTransformedRange(eyedropperRange.XMin, eyedropperRange.XMax, eyedropperRange.YMin, eyedropperRange.YMax)
+	}
+	return builder.Build()
+}
+
+// newGradientEyedropper creates a gradient eyedropper with the stops described by the settings.
+func newGradientEyedropper(eyedropperSettings *command.EyedropperSettings) imageoutput.Eyedropper {
+	stops := []imageoutput.GradientStop{}
+	for _, stopSettings := range eyedropperSettings.Stops {
+		stopColor, _ := stopSettings.StopColor()
+		stops = append(stops, imageoutput.NewGradientStop(stopSettings.Position, stopColor))
+	}
+	return imageoutput.GradientEyedropperBuilder().
+		WithScalar(eyedropperSettings.Scalar).
+		WithScalarRange(eyedropperSettings.ScalarMin, eyedropperSettings.ScalarMax).
+		WithStops(stops...).
+		Build()
 }
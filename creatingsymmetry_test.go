@@ -2,11 +2,19 @@ package creatingsymmetry_test
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"github.com/chadius/creatingsymmetry"
+	"github.com/chadius/creatingsymmetry/entities/command"
 	. "gopkg.in/check.v1"
 	"image"
 	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
 	"image/png"
+	"io"
+	"io/ioutil"
 	"testing"
 )
 
@@ -64,3 +72,487 @@ output_height: 1
 	checker.Assert(b0, Equals, uint32(0))
 	checker.Assert(a0, Equals, uint32(0xffff))
 }
+
+func (suite *ReadInputStreamsSuite) streams() (*bytes.Buffer, *bytes.Buffer, *bytes.Buffer) {
+	formulaData := bytes.NewBufferString(`pattern_viewport:
+  x_min: 0
+  y_min: 0
+  x_max: 10
+  y_max: 10
+formula:
+  type: identity
+`)
+	outputSettingsData := bytes.NewBufferString("output_width: 2\noutput_height: 1\n")
+
+	sourceImage := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	sourceImage.Set(0, 0, color.NRGBA{R: 255, A: 255})
+	inputImageData := new(bytes.Buffer)
+	png.Encode(inputImageData, sourceImage)
+	return inputImageData, formulaData, outputSettingsData
+}
+
+func (suite *ReadInputStreamsSuite) TestReportsProgressThroughEncoding(checker *C) {
+	inputImageData, formulaData, outputSettingsData := suite.streams()
+	var output bytes.Buffer
+	finishedStages := []creatingsymmetry.Stage{}
+
+	transformer := creatingsymmetry.FileTransformer{}
+	err := transformer.ApplyFormulaToTransformImageWithContext(
+		context.Background(),
+		inputImageData,
+		formulaData,
+		outputSettingsData,
+		&output,
+		func(event creatingsymmetry.ProgressEvent) {
+			if event.Completed == event.Total {
+				finishedStages = append(finishedStages, event.Stage)
+			}
+		},
+	)
+
+	checker.Assert(err, IsNil)
+	checker.Assert(finishedStages, DeepEquals, []creatingsymmetry.Stage{
+		creatingsymmetry.StageViewportScaling,
+		creatingsymmetry.StageFormulaEvaluation,
+		creatingsymmetry.StageThresholding,
+		creatingsymmetry.StageEyedropping,
+		creatingsymmetry.StageEncoding,
+	})
+	_, decodeError := png.Decode(&output)
+	checker.Assert(decodeError, IsNil)
+}
+
+func (suite *ReadInputStreamsSuite) TestCancelledContextDoesNotWriteOutput(checker *C) {
+	inputImageData, formulaData, outputSettingsData := suite.streams()
+	var output bytes.Buffer
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	transformer := creatingsymmetry.FileTransformer{}
+	err := transformer.ApplyFormulaToTransformImageWithContext(ctx, inputImageData, formulaData, outputSettingsData, &output, nil)
+
+	checker.Assert(err, Equals, context.Canceled)
+	checker.Assert(output.Len(), Equals, 0)
+}
+
+func (suite *ReadInputStreamsSuite) TestEncodesOutputFormats(checker *C) {
+	for _, outputFormat := range []string{"jpeg", "gif"} {
+		inputImageData, formulaData, _ := suite.streams()
+		outputSettingsData := bytes.NewBufferString("output_width: 2\noutput_height: 1\noutput_format: " + outputFormat + "\n")
+		var output bytes.Buffer
+
+		transformer := creatingsymmetry.FileTransformer{}
+		err := transformer.ApplyFormulaToTransformImage(inputImageData, formulaData, outputSettingsData, &output)
+		checker.Assert(err, IsNil)
+
+		var decodeError error
+		if outputFormat == "jpeg" {
+			_, decodeError = jpeg.Decode(&output)
+		} else {
+			_, decodeError = gif.Decode(&output)
+		}
+		checker.Assert(decodeError, IsNil, Commentf("%s", outputFormat))
+	}
+}
+
+type failingWriter struct{}
+
+func (f *failingWriter) Write(data []byte) (int, error) {
+	return 0, errors.New("disk is full")
+}
+
+func (suite *ReadInputStreamsSuite) TestReturnsEncoderErrors(checker *C) {
+	inputImageData, formulaData, outputSettingsData := suite.streams()
+
+	transformer := creatingsymmetry.FileTransformer{}
+	err := transformer.ApplyFormulaToTransformImage(inputImageData, formulaData, outputSettingsData, &failingWriter{})
+
+	checker.Assert(err, ErrorMatches, "disk is full")
+}
+
+func (suite *ReadInputStreamsSuite) TestPolarEyedropperSamplesTheEllipse(checker *C) {
+	formulaData := bytes.NewBufferString(`pattern_viewport:
+  x_min: -1
+  y_min: -1
+  x_max: 1
+  y_max: 1
+eyedropper:
+  shape: polar
+  center_x: 1.5
+  center_y: 1.5
+  radius_x: 1
+formula:
+  type: identity
+`)
+	outputSettingsData := bytes.NewBufferString("output_width: 3\noutput_height: 3\n")
+
+	sourceImage := image.NewNRGBA(image.Rect(0, 0, 3, 3))
+	sourceImage.Set(1, 1, color.NRGBA{R: 255, A: 255})
+	inputImageData := new(bytes.Buffer)
+	png.Encode(inputImageData, sourceImage)
+
+	var output bytes.Buffer
+	transformer := creatingsymmetry.FileTransformer{}
+	err := transformer.ApplyFormulaToTransformImage(inputImageData, formulaData, outputSettingsData, &output)
+	checker.Assert(err, IsNil)
+
+	outputImage, decodeError := png.Decode(&output)
+	checker.Assert(decodeError, IsNil)
+	checker.Assert(color.NRGBAModel.Convert(outputImage.At(1, 1)), Equals, color.NRGBA{R: 255, A: 255})
+	checker.Assert(color.NRGBAModel.Convert(outputImage.At(0, 0)), Equals, color.NRGBA{})
+}
+
+func (suite *ReadInputStreamsSuite) TestCombinedCoordinateThresholds(checker *C) {
+	formulaData := bytes.NewBufferString(`pattern_viewport:
+  x_min: -1
+  y_min: -1
+  x_max: 1
+  y_max: 1
+coordinate_threshold:
+  type: difference
+  children:
+    - type: disc
+      radius: 1
+    - x_min: 0.5
+      x_max: 2
+      y_min: -2
+      y_max: 2
+formula:
+  type: identity
+`)
+	outputSettingsData := bytes.NewBufferString("output_width: 3\noutput_height: 3\n")
+
+	sourceImage := image.NewNRGBA(image.Rect(0, 0, 3, 3))
+	draw.Draw(sourceImage, sourceImage.Bounds(), image.NewUniform(color.NRGBA{R: 255, A: 255}), image.Point{}, draw.Src)
+	inputImageData := new(bytes.Buffer)
+	png.Encode(inputImageData, sourceImage)
+
+	var output bytes.Buffer
+	transformer := creatingsymmetry.FileTransformer{}
+	err := transformer.ApplyFormulaToTransformImage(inputImageData, formulaData, outputSettingsData, &output)
+	checker.Assert(err, IsNil)
+
+	outputImage, decodeError := png.Decode(&output)
+	checker.Assert(decodeError, IsNil)
+	for outputY := 0; outputY < 3; outputY++ {
+		checker.Assert(color.NRGBAModel.Convert(outputImage.At(0, outputY)), Equals, color.NRGBA{R: 255, A: 255})
+		checker.Assert(color.NRGBAModel.Convert(outputImage.At(1, outputY)), Equals, color.NRGBA{R: 255, A: 255})
+		checker.Assert(color.NRGBAModel.Convert(outputImage.At(2, outputY)), Equals, color.NRGBA{})
+	}
+}
+
+func (suite *ReadInputStreamsSuite) TestFeatheredThresholdsFadeTheEdge(checker *C) {
+	formulaData := bytes.NewBufferString(`pattern_viewport:
+  x_min: -1
+  y_min: -1
+  x_max: 1
+  y_max: 1
+coordinate_threshold:
+  type: disc
+  radius: 0.5
+  feather: 1
+formula:
+  type: identity
+`)
+	outputSettingsData := bytes.NewBufferString("output_width: 3\noutput_height: 3\n")
+
+	sourceImage := image.NewNRGBA(image.Rect(0, 0, 3, 3))
+	draw.Draw(sourceImage, sourceImage.Bounds(), image.NewUniform(color.NRGBA{R: 255, A: 255}), image.Point{}, draw.Src)
+	inputImageData := new(bytes.Buffer)
+	png.Encode(inputImageData, sourceImage)
+
+	var output bytes.Buffer
+	transformer := creatingsymmetry.FileTransformer{}
+	err := transformer.ApplyFormulaToTransformImage(inputImageData, formulaData, outputSettingsData, &output)
+	checker.Assert(err, IsNil)
+
+	outputImage, decodeError := png.Decode(&output)
+	checker.Assert(decodeError, IsNil)
+	checker.Assert(color.NRGBAModel.Convert(outputImage.At(1, 1)), Equals, color.NRGBA{R: 255, A: 255})
+	checker.Assert(color.NRGBAModel.Convert(outputImage.At(2, 1)), Equals, color.NRGBA{R: 255, A: 85})
+	cornerAlpha := color.NRGBAModel.Convert(outputImage.At(0, 0)).(color.NRGBA).A
+	checker.Assert(cornerAlpha > 0 && cornerAlpha < 85, Equals, true)
+}
+
+func (suite *ReadInputStreamsSuite) TestMaskThresholdsReadTheirImage(checker *C) {
+	formula := `pattern_viewport:
+  x_min: -1
+  y_min: -1
+  x_max: 1
+  y_max: 1
+coordinate_threshold:
+  type: mask
+  mask: top.png
+  mask_space: transformed
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+formula:
+  type: identity
+`
+	maskImage := image.NewGray(image.Rect(0, 0, 1, 2))
+	maskImage.SetGray(0, 0, color.Gray{Y: 255})
+	var maskImageData bytes.Buffer
+	png.Encode(&maskImageData, maskImage)
+
+	sourceImage := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	draw.Draw(sourceImage, sourceImage.Bounds(), image.NewUniform(color.NRGBA{R: 255, A: 255}), image.Point{}, draw.Src)
+	var inputImageData bytes.Buffer
+	png.Encode(&inputImageData, sourceImage)
+	sourceImageBytes := inputImageData.Bytes()
+
+	openedFilenames := []string{}
+	transformer := creatingsymmetry.FileTransformer{
+		OpenImage: func(filename string) (io.ReadCloser, error) {
+			openedFilenames = append(openedFilenames, filename)
+			return ioutil.NopCloser(bytes.NewReader(maskImageData.Bytes())), nil
+		},
+	}
+	var output bytes.Buffer
+	err := transformer.ApplyFormulaToTransformImage(bytes.NewReader(sourceImageBytes), bytes.NewBufferString(formula), bytes.NewBufferString("output_width: 1\noutput_height: 4\n"), &output)
+	checker.Assert(err, IsNil)
+	checker.Assert(openedFilenames, DeepEquals, []string{"top.png"})
+
+	outputImage, decodeError := png.Decode(&output)
+	checker.Assert(decodeError, IsNil)
+	checker.Assert(color.NRGBAModel.Convert(outputImage.At(0, 0)).(color.NRGBA).A, Equals, uint8(255))
+	checker.Assert(color.NRGBAModel.Convert(outputImage.At(0, 3)).(color.NRGBA).A, Equals, uint8(0))
+
+	transformer = creatingsymmetry.FileTransformer{}
+	err = transformer.ApplyFormulaToTransformImage(bytes.NewReader(sourceImageBytes), bytes.NewBufferString(formula), bytes.NewBufferString("output_width: 1\noutput_height: 4\n"), &output)
+	checker.Assert(err, ErrorMatches, `cannot read image "top.png": this transformer cannot open images named in the formula`)
+}
+
+func (suite *ReadInputStreamsSuite) TestBackgroundFillsFilteredPixels(checker *C) {
+	formulaData := bytes.NewBufferString(`pattern_viewport:
+  x_min: -1
+  y_min: -1
+  x_max: 1
+  y_max: 1
+coordinate_threshold:
+  type: disc
+  radius: 0.5
+background:
+  filtered:
+    type: color
+    color: "#0000ff"
+formula:
+  type: identity
+`)
+	outputSettingsData := bytes.NewBufferString("output_width: 3\noutput_height: 3\n")
+
+	sourceImage := image.NewNRGBA(image.Rect(0, 0, 3, 3))
+	draw.Draw(sourceImage, sourceImage.Bounds(), image.NewUniform(color.NRGBA{R: 255, A: 255}), image.Point{}, draw.Src)
+	inputImageData := new(bytes.Buffer)
+	png.Encode(inputImageData, sourceImage)
+
+	var output bytes.Buffer
+	transformer := creatingsymmetry.FileTransformer{}
+	err := transformer.ApplyFormulaToTransformImage(inputImageData, formulaData, outputSettingsData, &output)
+	checker.Assert(err, IsNil)
+
+	outputImage, decodeError := png.Decode(&output)
+	checker.Assert(decodeError, IsNil)
+	checker.Assert(color.NRGBAModel.Convert(outputImage.At(1, 1)), Equals, color.NRGBA{R: 255, A: 255})
+	checker.Assert(color.NRGBAModel.Convert(outputImage.At(0, 0)), Equals, color.NRGBA{B: 255, A: 255})
+}
+
+func (suite *ReadInputStreamsSuite) TestEyedropperRangeFixesTheColorMapping(checker *C) {
+	formulaData := bytes.NewBufferString(`pattern_viewport:
+  x_min: -1
+  y_min: -1
+  x_max: 1
+  y_max: 1
+eyedropper_range:
+  x_min: -2
+  y_min: -2
+  x_max: 2
+  y_max: 2
+formula:
+  type: identity
+`)
+	outputSettingsData := bytes.NewBufferString("output_width: 2\noutput_height: 2\n")
+
+	columnColors := []color.NRGBA{
+		{R: 255, A: 255},
+		{G: 255, A: 255},
+		{B: 255, A: 255},
+		{R: 255, G: 255, A: 255},
+	}
+	sourceImage := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for x, columnColor := range columnColors {
+		draw.Draw(sourceImage, image.Rect(x, 0, x+1, 4), image.NewUniform(columnColor), image.Point{}, draw.Src)
+	}
+	inputImageData := new(bytes.Buffer)
+	png.Encode(inputImageData, sourceImage)
+
+	var output bytes.Buffer
+	transformer := creatingsymmetry.FileTransformer{}
+	err := transformer.ApplyFormulaToTransformImage(inputImageData, formulaData, outputSettingsData, &output)
+	checker.Assert(err, IsNil)
+
+	outputImage, decodeError := png.Decode(&output)
+	checker.Assert(decodeError, IsNil)
+	checker.Assert(color.NRGBAModel.Convert(outputImage.At(0, 0)), Equals, columnColors[1])
+	checker.Assert(color.NRGBAModel.Convert(outputImage.At(1, 0)), Equals, columnColors[2])
+}
+
+func (suite *ReadInputStreamsSuite) TestSuggestCoordinateThresholdUsesPercentiles(checker *C) {
+	formulaData := bytes.NewBufferString(`pattern_viewport:
+  x_min: -1
+  y_min: -1
+  x_max: 1
+  y_max: 1
+formula:
+  type: identity
+`)
+	outputSettingsData := bytes.NewBufferString("output_width: 4\noutput_height: 2\n")
+
+	suggestion, err := creatingsymmetry.SuggestCoordinateThreshold(context.Background(), formulaData, outputSettingsData, 0, 100)
+	checker.Assert(err, IsNil)
+	checker.Assert(*suggestion, DeepEquals, command.CoordinateThresholdSettings{XMin: -0.75, XMax: 0.75, YMin: -0.5, YMax: 0.5})
+}
+
+func (suite *ReadInputStreamsSuite) TestDomainColoringDoesNotNeedASourceImage(checker *C) {
+	formulaData := bytes.NewBufferString(`pattern_viewport:
+  x_min: -1
+  y_min: -1
+  x_max: 1
+  y_max: 1
+eyedropper:
+  shape: domain_coloring
+  phase_contours: 6
+formula:
+  type: identity
+`)
+	outputSettingsData := bytes.NewBufferString("output_width: 2\noutput_height: 2\n")
+
+	var output bytes.Buffer
+	transformer := creatingsymmetry.FileTransformer{}
+	err := transformer.ApplyFormulaToTransformImage(nil, formulaData, outputSettingsData, &output)
+	checker.Assert(err, IsNil)
+
+	outputImage, decodeError := png.Decode(&output)
+	checker.Assert(decodeError, IsNil)
+	quadrantColors := map[color.NRGBA]bool{}
+	for _, pixel := range []image.Point{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+		pixelColor := color.NRGBAModel.Convert(outputImage.At(pixel.X, pixel.Y)).(color.NRGBA)
+		checker.Assert(pixelColor.A, Equals, uint8(255))
+		quadrantColors[pixelColor] = true
+	}
+	checker.Assert(quadrantColors, HasLen, 4)
+}
+
+func (suite *ReadInputStreamsSuite) TestMissingSourceImageIsAnError(checker *C) {
+	for _, eyedropper := range []string{
+		"",
+		"eyedropper:\n  shape: domain_coloring\nbackground:\n  filtered:\n    type: source\n",
+	} {
+		formulaData := bytes.NewBufferString(`pattern_viewport:
+  x_min: -1
+  y_min: -1
+  x_max: 1
+  y_max: 1
+` + eyedropper + `formula:
+  type: identity
+`)
+		outputSettingsData := bytes.NewBufferString("output_width: 2\noutput_height: 2\n")
+
+		var output bytes.Buffer
+		transformer := creatingsymmetry.FileTransformer{}
+		err := transformer.ApplyFormulaToTransformImage(nil, formulaData, outputSettingsData, &output)
+		checker.Assert(err, ErrorMatches, "missing source image.*")
+		checker.Assert(output.Len(), Equals, 0)
+	}
+}
+
+func (suite *ReadInputStreamsSuite) TestGradientColorsTheRealPart(checker *C) {
+	formulaData := bytes.NewBufferString(`pattern_viewport:
+  x_min: 0
+  y_min: 0
+  x_max: 4
+  y_max: 4
+eyedropper:
+  shape: gradient
+  scalar: real
+  scalar_min: 0
+  scalar_max: 4
+  stops:
+    - position: 0
+      color: "#000000"
+    - position: 0.5
+      color: "#000000"
+    - position: 0.5
+      color: "#ffffff"
+    - position: 1
+      color: "#ffffff"
+formula:
+  type: identity
+`)
+	outputSettingsData := bytes.NewBufferString("output_width: 2\noutput_height: 2\n")
+
+	var output bytes.Buffer
+	transformer := creatingsymmetry.FileTransformer{}
+	err := transformer.ApplyFormulaToTransformImage(nil, formulaData, outputSettingsData, &output)
+	checker.Assert(err, IsNil)
+
+	outputImage, decodeError := png.Decode(&output)
+	checker.Assert(decodeError, IsNil)
+	checker.Assert(color.NRGBAModel.Convert(outputImage.At(0, 0)), Equals, color.NRGBA{A: 255})
+	checker.Assert(color.NRGBAModel.Convert(outputImage.At(1, 0)), Equals, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+}
+
+func (suite *ReadInputStreamsSuite) renderRosette(checker *C, outputSettingsData string, coordinateThreshold string) (*bytes.Buffer, error) {
+	formulaData := bytes.NewBufferString(`pattern_viewport:
+  x_min: -0.8
+  y_min: -0.8
+  x_max: 0.8
+  y_max: 0.8
+` + coordinateThreshold + `formula:
+  type: rosette
+  terms:
+    - multiplier:
+        real: -1.0
+        imaginary: 0.5
+      power_n: 4
+      power_m: 1
+`)
+	sourceImage, err := ioutil.ReadFile("example/rainbow_stripe.png")
+	checker.Assert(err, IsNil)
+
+	output := &bytes.Buffer{}
+	transformer := creatingsymmetry.FileTransformer{}
+	err = transformer.ApplyFormulaToTransformImage(bytes.NewBuffer(sourceImage), formulaData, bytes.NewBufferString(outputSettingsData), output)
+	return output, err
+}
+
+func (suite *ReadInputStreamsSuite) TestStreamingMatchesTheWholeImage(checker *C) {
+	coordinateThreshold := "coordinate_threshold:\n  x_min: -4\n  x_max: 4\n  y_min: -3\n  y_max: 5\n"
+	wholeOutput, err := suite.renderRosette(checker, "output_width: 31\noutput_height: 19\nsupersampling: 2\n", coordinateThreshold)
+	checker.Assert(err, IsNil)
+	streamedOutput, err := suite.renderRosette(checker, "output_width: 31\noutput_height: 19\nsupersampling: 2\nstreaming: true\n", coordinateThreshold)
+	checker.Assert(err, IsNil)
+
+	wholeImage, decodeError := png.Decode(wholeOutput)
+	checker.Assert(decodeError, IsNil)
+	streamedImage, decodeError := png.Decode(streamedOutput)
+	checker.Assert(decodeError, IsNil)
+	checker.Assert(streamedImage.Bounds(), Equals, wholeImage.Bounds())
+	for y := 0; y < 19; y++ {
+		for x := 0; x < 31; x++ {
+			checker.Assert(
+				color.NRGBAModel.Convert(streamedImage.At(x, y)),
+				Equals,
+				color.NRGBAModel.Convert(wholeImage.At(x, y)),
+				Commentf("pixel %d, %d", x, y),
+			)
+		}
+	}
+}
+
+func (suite *ReadInputStreamsSuite) TestStreamingRejectsAutoThresholdsBeforeWriting(checker *C) {
+	output, err := suite.renderRosette(checker, "output_width: 31\noutput_height: 19\nstreaming: true\n", "coordinate_threshold:\n  type: auto\n")
+	checker.Assert(err, ErrorMatches, "cannot stream: auto thresholds .*")
+	checker.Assert(output.Len(), Equals, 0)
+}
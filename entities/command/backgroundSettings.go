@@ -0,0 +1,140 @@
+package command
+
+import (
+	"fmt"
+	"github.com/chadius/creatingsymmetry/entities/validation"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// BackgroundFillType chooses what is drawn behind pixels without a pattern color.
+type BackgroundFillType string
+
+// Background fill types.
+const (
+	TransparentBackgroundFill BackgroundFillType = "transparent"
+	ColorBackgroundFill       BackgroundFillType = "color"
+	ImageBackgroundFill       BackgroundFillType = "image"
+	SourceBackgroundFill      BackgroundFillType = "source"
+)
+
+// BackgroundFillTypes returns every supported background fill type.
+func BackgroundFillTypes() []BackgroundFillType {
+	return []BackgroundFillType{TransparentBackgroundFill, ColorBackgroundFill, ImageBackgroundFill, SourceBackgroundFill}
+}
+
+// IsValid returns true if this is a supported background fill type.
+func (b BackgroundFillType) IsValid() bool {
+	for _, fillType := range BackgroundFillTypes() {
+		if b == fillType {
+			return true
+		}
+	}
+	return false
+}
+
+// BackgroundSettings choose what fills pixels the pattern does not color.
+//   Filtered pixels were removed by the coordinate threshold. Feathered edges blend the pattern with this fill.
+//   Unmapped pixels were sent to Infinity or NaN by the formula, like the center of many rosettes.
+type BackgroundSettings struct {
+	Filtered *BackgroundFill `json:"filtered,omitempty" yaml:"filtered,omitempty"`
+	Unmapped *BackgroundFill `json:"unmapped,omitempty" yaml:"unmapped,omitempty"`
+}
+
+// Validate returns every problem found in the background settings.
+func (b BackgroundSettings) Validate(path string) []validation.Problem {
+	problems := []validation.Problem{}
+	if b.Filtered != nil {
+		problems = append(problems, b.Filtered.Validate(validation.JoinPath(path, "filtered"))...)
+	}
+	if b.Unmapped != nil {
+		problems = append(problems, b.Unmapped.Validate(validation.JoinPath(path, "unmapped"))...)
+	}
+	return problems
+}
+
+// ImageFilenames returns the background image filenames, without repeats.
+func (b BackgroundSettings) ImageFilenames() []string {
+	filenames := []string{}
+	for _, fill := range []*BackgroundFill{b.Filtered, b.Unmapped} {
+		if fill == nil || fill.Type != ImageBackgroundFill || fill.Image == "" || containsString(filenames, fill.Image) {
+			continue
+		}
+		filenames = append(filenames, fill.Image)
+	}
+	return filenames
+}
+
+// UsesSourceImage returns true if either fill stretches the source image over the output.
+func (b BackgroundSettings) UsesSourceImage() bool {
+	for _, fill := range []*BackgroundFill{b.Filtered, b.Unmapped} {
+		if fill != nil && fill.Type == SourceBackgroundFill {
+			return true
+		}
+	}
+	return false
+}
+
+// BackgroundFill describes one fill.
+//   Color fills use Color, written as #rrggbb or #rrggbbaa.
+//   Image fills stretch the image named by Image over the output.
+//   Source fills stretch the source image over the output, so it appears untransformed.
+type BackgroundFill struct {
+	Type  BackgroundFillType `json:"type" yaml:"type"`
+	Color string             `json:"color,omitempty" yaml:"color,omitempty"`
+	Image string             `json:"image,omitempty" yaml:"image,omitempty"`
+}
+
+// Validate returns every problem found in the fill.
+func (b BackgroundFill) Validate(path string) []validation.Problem {
+	if !b.Type.IsValid() {
+		typeNames := []string{}
+		for _, fillType := range BackgroundFillTypes() {
+			typeNames = append(typeNames, string(fillType))
+		}
+		return []validation.Problem{
+			validation.NewProblemf(validation.JoinPath(path, "type"), "unknown background type %q, expected one of: %s", b.Type, strings.Join(typeNames, ", ")),
+		}
+	}
+
+	problems := []validation.Problem{}
+	if b.Type == ColorBackgroundFill {
+		if _, err := b.FillColor(); err != nil {
+			problems = append(problems, validation.NewProblem(validation.JoinPath(path, "color"), err.Error()))
+		}
+	} else if b.Color != "" {
+		problems = append(problems, validation.NewProblemf(validation.JoinPath(path, "color"), "color is only used by color backgrounds, not %s", b.Type))
+	}
+
+	if b.Type == ImageBackgroundFill && b.Image == "" {
+		problems = append(problems, validation.NewProblem(validation.JoinPath(path, "image"), "image backgrounds need the filename of an image"))
+	}
+	if b.Type != ImageBackgroundFill && b.Image != "" {
+		problems = append(problems, validation.NewProblemf(validation.JoinPath(path, "image"), "image is only used by image backgrounds, not %s", b.Type))
+	}
+	return problems
+}
+
+// FillColor reads the color, written as #rrggbb or #rrggbbaa.
+func (b BackgroundFill) FillColor() (color.NRGBA, error) {
+	return parseHexColor(b.Color)
+}
+
+// parseHexColor reads a color written as #rrggbb or #rrggbbaa.
+func parseHexColor(hexColor string) (color.NRGBA, error) {
+	hexDigits := strings.TrimPrefix(hexColor, "#")
+	if len(hexDigits) == 6 {
+		hexDigits += "ff"
+	}
+	value, err := strconv.ParseUint(hexDigits, 16, 32)
+	if err != nil || len(hexDigits) != 8 {
+		return color.NRGBA{}, fmt.Errorf("invalid color %q, expected #rrggbb or #rrggbbaa", hexColor)
+	}
+	return color.NRGBA{
+		R: uint8(value >> 24),
+		G: uint8(value >> 16),
+		B: uint8(value >> 8),
+		A: uint8(value),
+	}, nil
+}
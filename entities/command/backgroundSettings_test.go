@@ -0,0 +1,64 @@
+package command_test
+
+import (
+	"github.com/chadius/creatingsymmetry/entities/command"
+	. "gopkg.in/check.v1"
+	"image/color"
+)
+
+type BackgroundSettingsSuite struct{}
+
+var _ = Suite(&BackgroundSettingsSuite{})
+
+func (suite *BackgroundSettingsSuite) TestReadsFilteredAndUnmappedFills(checker *C) {
+	wallpaperCommand, err := command.NewCreateWallpaperCommandFromYAML([]byte(thresholdTestViewport + `background:
+  filtered:
+    type: color
+    color: "#ff8000"
+  unmapped:
+    type: image
+    image: paper.png
+`))
+	checker.Assert(err, IsNil)
+	background := wallpaperCommand.Background
+	checker.Assert(background.Filtered.Type, Equals, command.ColorBackgroundFill)
+	fillColor, colorErr := background.Filtered.FillColor()
+	checker.Assert(colorErr, IsNil)
+	checker.Assert(fillColor, Equals, color.NRGBA{R: 255, G: 128, A: 255})
+	checker.Assert(background.Unmapped.Type, Equals, command.ImageBackgroundFill)
+	checker.Assert(background.ImageFilenames(), DeepEquals, []string{"paper.png"})
+}
+
+func (suite *BackgroundSettingsSuite) TestBackgroundsAreOptional(checker *C) {
+	wallpaperCommand, err := command.NewCreateWallpaperCommandFromYAML([]byte(thresholdTestViewport))
+	checker.Assert(err, IsNil)
+	checker.Assert(wallpaperCommand.Background.Filtered, IsNil)
+	checker.Assert(wallpaperCommand.Background.Unmapped, IsNil)
+	checker.Assert(wallpaperCommand.Background.ImageFilenames(), HasLen, 0)
+}
+
+func (suite *BackgroundSettingsSuite) TestFillColorsCanBeTranslucent(checker *C) {
+	fillColor, err := command.BackgroundFill{Type: command.ColorBackgroundFill, Color: "#10203040"}.FillColor()
+	checker.Assert(err, IsNil)
+	checker.Assert(fillColor, Equals, color.NRGBA{R: 0x10, G: 0x20, B: 0x30, A: 0x40})
+}
+
+func (suite *BackgroundSettingsSuite) TestFillProblems(checker *C) {
+	_, err := command.NewCreateWallpaperCommandFromYAML([]byte(thresholdTestViewport + `background:
+  filtered:
+    type: paint
+  unmapped:
+    type: color
+    color: white
+`))
+	checker.Assert(err, ErrorMatches, `found 2 problems:\nline 8: background.filtered.type: unknown background type "paint", expected one of: transparent, color, image, source\nline 11: background.unmapped.color: invalid color "white", expected #rrggbb or #rrggbbaa`)
+
+	_, err = command.NewCreateWallpaperCommandFromYAML([]byte(thresholdTestViewport + `background:
+  filtered:
+    type: source
+    image: paper.png
+  unmapped:
+    type: image
+`))
+	checker.Assert(err, ErrorMatches, `found 2 problems:\nline 9: background.filtered.image: image is only used by image backgrounds, not source\nline 10: background.unmapped.image: image backgrounds need the filename of an image`)
+}
@@ -0,0 +1,353 @@
+package command
+
+import (
+	"github.com/chadius/creatingsymmetry/entities/validation"
+	"strings"
+)
+
+// CoordinateThresholdType chooses the shape of the coordinate threshold.
+type CoordinateThresholdType string
+
+// Coordinate threshold shapes.
+const (
+	RectangleThreshold CoordinateThresholdType = "rectangle"
+	DiscThreshold      CoordinateThresholdType = "disc"
+	AnnulusThreshold   CoordinateThresholdType = "annulus"
+	SectorThreshold    CoordinateThresholdType = "sector"
+	PolygonThreshold   CoordinateThresholdType = "polygon"
+	MaskThreshold      CoordinateThresholdType = "mask"
+	AutoThreshold      CoordinateThresholdType = "auto"
+)
+
+// Coordinate thresholds that combine their children.
+const (
+	UnionThreshold        CoordinateThresholdType = "union"
+	IntersectionThreshold CoordinateThresholdType = "intersection"
+	DifferenceThreshold   CoordinateThresholdType = "difference"
+	InvertThreshold       CoordinateThresholdType = "invert"
+)
+
+// CoordinateThresholdTypes returns every supported coordinate threshold type.
+func CoordinateThresholdTypes() []CoordinateThresholdType {
+	return []CoordinateThresholdType{
+		RectangleThreshold,
+		DiscThreshold,
+		AnnulusThreshold,
+		SectorThreshold,
+		PolygonThreshold,
+		MaskThreshold,
+		AutoThreshold,
+		UnionThreshold,
+		IntersectionThreshold,
+		DifferenceThreshold,
+		InvertThreshold,
+	}
+}
+
+// IsValid returns true if this is a supported coordinate threshold type.
+func (t CoordinateThresholdType) IsValid() bool {
+	for _, thresholdType := range CoordinateThresholdTypes() {
+		if t == thresholdType {
+			return true
+		}
+	}
+	return false
+}
+
+// IsComposite returns true if the threshold combines its children instead of describing a shape.
+func (t CoordinateThresholdType) IsComposite() bool {
+	return t == UnionThreshold || t == IntersectionThreshold || t == DifferenceThreshold || t == InvertThreshold
+}
+
+// MaskSpace chooses where a mask threshold's image is placed.
+type MaskSpace string
+
+// Mask spaces.
+const (
+	OutputMaskSpace      MaskSpace = "output"
+	TransformedMaskSpace MaskSpace = "transformed"
+)
+
+// MaskSpaces returns every supported mask space.
+func MaskSpaces() []MaskSpace {
+	return []MaskSpace{OutputMaskSpace, TransformedMaskSpace}
+}
+
+// IsValid returns true if this is a supported mask space.
+func (m MaskSpace) IsValid() bool {
+	for _, space := range MaskSpaces() {
+		if m == space {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldsUsedByThresholdType lists the fields each coordinate threshold shape reads.
+var fieldsUsedByThresholdType = map[CoordinateThresholdType][]string{
+	RectangleThreshold: {"x_min", "x_max", "y_min", "y_max", "feather"},
+	DiscThreshold:      {"center_x", "center_y", "radius", "feather"},
+	AnnulusThreshold:   {"center_x", "center_y", "inner_radius", "outer_radius", "feather"},
+	SectorThreshold:    {"center_x", "center_y", "start_angle", "end_angle", "inner_radius", "outer_radius", "feather"},
+	PolygonThreshold:   {"vertices", "feather"},
+	MaskThreshold:      {"mask", "mask_space", "x_min", "x_max", "y_min", "y_max"},
+	AutoThreshold:      {"lower_percentile", "upper_percentile"},
+
+	UnionThreshold:        {"children"},
+	IntersectionThreshold: {"children"},
+	DifferenceThreshold:   {"children"},
+	InvertThreshold:       {"children"},
+}
+
+// ThresholdVertex is a corner of a polygon threshold in transformed space, written as [x, y].
+//   A pair is used instead of x and y keys because YAML reads a bare y key as true.
+type ThresholdVertex [2]float64
+
+// X returns the vertex's real part.
+func (t ThresholdVertex) X() float64 {
+	return t[0]
+}
+
+// Y returns the vertex's imaginary part.
+func (t ThresholdVertex) Y() float64 {
+	return t[1]
+}
+
+// CoordinateThresholdSettings describe the shape in transformed space that keeps coordinates.
+//   Coordinates outside of the shape turn transparent.
+//   Rectangles, the default shape, use XMin, XMax, YMin and YMax. If all of them are 0, every coordinate is kept.
+//   Discs, annuli and sectors are measured from the center.
+//   Sector angles are in degrees, counterclockwise from the positive X axis.
+//   The sector runs counterclockwise from StartAngle to EndAngle. OuterRadius is optional for sectors.
+//   Polygons connect their vertices in order.
+//   Unions, intersections, differences and inversions combine the thresholds in Children, which may be combinations too.
+//   Differences keep the first child's coordinates that none of the other children keep.
+//   Shapes with a Feather fade out over that distance in transformed space, centered on their edge.
+//   Masks read the grayscale image named by Mask. White keeps coordinates and black removes them.
+//   Output space masks, the default, cover the output image.
+//   Transformed space masks cover the rectangle from XMin, YMin to XMax, YMax, with the top of the image at YMin.
+//   Auto thresholds keep the rectangle from the LowerPercentile to the UpperPercentile of the transformed coordinates,
+//   chosen again for every render. Without either percentile, the 1st to the 99th percentile is kept.
+type CoordinateThresholdSettings struct {
+	Type CoordinateThresholdType `json:"type,omitempty" yaml:"type,omitempty"`
+
+	XMin float64 `json:"x_min,omitempty" yaml:"x_min,omitempty"`
+	YMin float64 `json:"y_min,omitempty" yaml:"y_min,omitempty"`
+	XMax float64 `json:"x_max,omitempty" yaml:"x_max,omitempty"`
+	YMax float64 `json:"y_max,omitempty" yaml:"y_max,omitempty"`
+
+	CenterX     float64 `json:"center_x,omitempty" yaml:"center_x,omitempty"`
+	CenterY     float64 `json:"center_y,omitempty" yaml:"center_y,omitempty"`
+	Radius      float64 `json:"radius,omitempty" yaml:"radius,omitempty"`
+	InnerRadius float64 `json:"inner_radius,omitempty" yaml:"inner_radius,omitempty"`
+	OuterRadius float64 `json:"outer_radius,omitempty" yaml:"outer_radius,omitempty"`
+	StartAngle  float64 `json:"start_angle,omitempty" yaml:"start_angle,omitempty"`
+	EndAngle    float64 `json:"end_angle,omitempty" yaml:"end_angle,omitempty"`
+
+	Vertices []ThresholdVertex `json:"vertices,omitempty" yaml:"vertices,omitempty"`
+
+	Children []CoordinateThresholdSettings `json:"children,omitempty" yaml:"children,omitempty"`
+
+	Feather float64 `json:"feather,omitempty" yaml:"feather,omitempty"`
+
+	Mask      string    `json:"mask,omitempty" yaml:"mask,omitempty"`
+	MaskSpace MaskSpace `json:"mask_space,omitempty" yaml:"mask_space,omitempty"`
+
+	LowerPercentile float64 `json:"lower_percentile,omitempty" yaml:"lower_percentile,omitempty"`
+	UpperPercentile float64 `json:"upper_percentile,omitempty" yaml:"upper_percentile,omitempty"`
+}
+
+// ShapeType returns the shape of the threshold, using a rectangle if no type was given.
+func (c CoordinateThresholdSettings) ShapeType() CoordinateThresholdType {
+	if c.Type == "" {
+		return RectangleThreshold
+	}
+	return c.Type
+}
+
+// Space returns where the mask image is placed, using the output image if no space was given.
+func (c CoordinateThresholdSettings) Space() MaskSpace {
+	if c.MaskSpace == "" {
+		return OutputMaskSpace
+	}
+	return c.MaskSpace
+}
+
+// Percentiles returns the lower and upper percentiles kept by an auto threshold.
+func (c CoordinateThresholdSettings) Percentiles() (float64, float64) {
+	if c.LowerPercentile == 0 && c.UpperPercentile == 0 {
+		return DefaultLowerPercentile, DefaultUpperPercentile
+	}
+	return percentilesWithDefaults(c.LowerPercentile, c.UpperPercentile)
+}
+
+// MaskFilenames returns the mask image filename used by this threshold and each of its children, without repeats.
+func (c CoordinateThresholdSettings) MaskFilenames() []string {
+	filenames := []string{}
+	if c.ShapeType() == MaskThreshold && c.Mask != "" {
+		filenames = append(filenames, c.Mask)
+	}
+	for _, child := range c.Children {
+		for _, childFilename := range child.MaskFilenames() {
+			if !containsString(filenames, childFilename) {
+				filenames = append(filenames, childFilename)
+			}
+		}
+	}
+	return filenames
+}
+
+func containsString(values []string, value string) bool {
+	for _, existingValue := range values {
+		if existingValue == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate returns every problem found in the coordinate threshold.
+func (c CoordinateThresholdSettings) Validate(path string) []validation.Problem {
+	thresholdType := c.ShapeType()
+	if !thresholdType.IsValid() {
+		typeNames := []string{}
+		for _, knownType := range CoordinateThresholdTypes() {
+			typeNames = append(typeNames, string(knownType))
+		}
+		return []validation.Problem{
+			validation.NewProblemf(validation.JoinPath(path, "type"), "unknown coordinate threshold type %q, expected one of: %s", c.Type, strings.Join(typeNames, ", ")),
+		}
+	}
+
+	problems := c.validateUnusedFields(path)
+	if c.Feather < 0 {
+		problems = append(problems, validation.NewProblem(validation.JoinPath(path, "feather"), "feather must not be negative"))
+	}
+	switch thresholdType {
+	case RectangleThreshold:
+		if c.XMin > c.XMax {
+			problems = append(problems, validation.NewProblem(validation.JoinPath(path, "x_min"), "x_min must not be larger than x_max"))
+		}
+		if c.YMin > c.YMax {
+			problems = append(problems, validation.NewProblem(validation.JoinPath(path, "y_min"), "y_min must not be larger than y_max"))
+		}
+	case DiscThreshold:
+		if c.Radius <= 0 {
+			problems = append(problems, validation.NewProblem(validation.JoinPath(path, "radius"), "disc thresholds need a positive radius"))
+		}
+	case AnnulusThreshold:
+		if c.InnerRadius < 0 {
+			problems = append(problems, validation.NewProblem(validation.JoinPath(path, "inner_radius"), "inner_radius must not be negative"))
+		}
+		if c.OuterRadius <= c.InnerRadius {
+			problems = append(problems, validation.NewProblem(validation.JoinPath(path, "outer_radius"), "outer_radius must be larger than inner_radius"))
+		}
+	case SectorThreshold:
+		if c.StartAngle == c.EndAngle {
+			problems = append(problems, validation.NewProblem(validation.JoinPath(path, "end_angle"), "start_angle and end_angle must be different"))
+		}
+		if c.InnerRadius < 0 {
+			problems = append(problems, validation.NewProblem(validation.JoinPath(path, "inner_radius"), "inner_radius must not be negative"))
+		}
+		if c.OuterRadius != 0 && c.OuterRadius <= c.InnerRadius {
+			problems = append(problems, validation.NewProblem(validation.JoinPath(path, "outer_radius"), "outer_radius must be larger than inner_radius, leave it out to have no outer limit"))
+		}
+	case PolygonThreshold:
+		if len(c.Vertices) < 3 {
+			problems = append(problems, validation.NewProblemf(validation.JoinPath(path, "vertices"), "polygon thresholds need at least 3 vertices, found %d", len(c.Vertices)))
+		}
+	case MaskThreshold:
+		problems = append(problems, c.validateMask(path)...)
+	case AutoThreshold:
+		problems = append(problems, validatePercentiles(path, c.LowerPercentile, c.UpperPercentile)...)
+	case UnionThreshold, IntersectionThreshold, DifferenceThreshold, InvertThreshold:
+		problems = append(problems, c.validateChildren(path)...)
+	}
+	return problems
+}
+
+// validateMask checks the mask has an image and, in transformed space, a rectangle to cover.
+func (c CoordinateThresholdSettings) validateMask(path string) []validation.Problem {
+	problems := []validation.Problem{}
+	if c.Mask == "" {
+		problems = append(problems, validation.NewProblem(validation.JoinPath(path, "mask"), "mask thresholds need the filename of a mask image"))
+	}
+
+	if !c.Space().IsValid() {
+		spaceNames := []string{}
+		for _, space := range MaskSpaces() {
+			spaceNames = append(spaceNames, string(space))
+		}
+		return append(problems, validation.NewProblemf(validation.JoinPath(path, "mask_space"), "unknown mask_space %q, expected one of: %s", c.MaskSpace, strings.Join(spaceNames, ", ")))
+	}
+
+	if c.Space() == OutputMaskSpace {
+		rectangleFieldIsSet := map[string]bool{"x_min": c.XMin != 0, "x_max": c.XMax != 0, "y_min": c.YMin != 0, "y_max": c.YMax != 0}
+		for _, field := range []string{"x_min", "x_max", "y_min", "y_max"} {
+			if rectangleFieldIsSet[field] {
+				problems = append(problems, validation.NewProblemf(validation.JoinPath(path, field), "%s is only used by transformed space masks, add mask_space: transformed", field))
+			}
+		}
+		return problems
+	}
+
+	if c.XMin >= c.XMax {
+		problems = append(problems, validation.NewProblem(validation.JoinPath(path, "x_max"), "transformed space masks need x_max to be larger than x_min"))
+	}
+	if c.YMin >= c.YMax {
+		problems = append(problems, validation.NewProblem(validation.JoinPath(path, "y_max"), "transformed space masks need y_max to be larger than y_min"))
+	}
+	return problems
+}
+
+// validateChildren checks the number of children, then validates each child.
+func (c CoordinateThresholdSettings) validateChildren(path string) []validation.Problem {
+	problems := []validation.Problem{}
+	childrenPath := validation.JoinPath(path, "children")
+	if c.ShapeType() == InvertThreshold && len(c.Children) != 1 {
+		problems = append(problems, validation.NewProblemf(childrenPath, "invert thresholds need exactly 1 child, found %d", len(c.Children)))
+	}
+	if c.ShapeType() != InvertThreshold && len(c.Children) < 2 {
+		problems = append(problems, validation.NewProblemf(childrenPath, "%s thresholds need at least 2 children, found %d", c.ShapeType(), len(c.Children)))
+	}
+
+	for childIndex, child := range c.Children {
+		problems = append(problems, child.Validate(validation.IndexPath(childrenPath, childIndex))...)
+	}
+	return problems
+}
+
+func (c CoordinateThresholdSettings) validateUnusedFields(path string) []validation.Problem {
+	fieldIsSet := map[string]bool{
+		"x_min":        c.XMin != 0,
+		"x_max":        c.XMax != 0,
+		"y_min":        c.YMin != 0,
+		"y_max":        c.YMax != 0,
+		"center_x":     c.CenterX != 0,
+		"center_y":     c.CenterY != 0,
+		"radius":       c.Radius != 0,
+		"inner_radius": c.InnerRadius != 0,
+		"outer_radius": c.OuterRadius != 0,
+		"start_angle":  c.StartAngle != 0,
+		"end_angle":    c.EndAngle != 0,
+		"vertices":     len(c.Vertices) > 0,
+		"children":     len(c.Children) > 0,
+		"feather":      c.Feather != 0,
+		"mask":         c.Mask != "",
+		"mask_space":   c.MaskSpace != "",
+
+		"lower_percentile": c.LowerPercentile != 0,
+		"upper_percentile": c.UpperPercentile != 0,
+	}
+	for _, usedField := range fieldsUsedByThresholdType[c.ShapeType()] {
+		fieldIsSet[usedField] = false
+	}
+
+	problems := []validation.Problem{}
+	for _, field := range []string{"x_min", "x_max", "y_min", "y_max", "center_x", "center_y", "radius", "inner_radius", "outer_radius", "start_angle", "end_angle", "vertices", "children", "feather", "mask", "mask_space", "lower_percentile", "upper_percentile"} {
+		if fieldIsSet[field] {
+			problems = append(problems, validation.NewProblemf(validation.JoinPath(path, field), "%s is not used by %s thresholds", field, c.ShapeType()))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,261 @@
+package command_test
+
+import (
+	"github.com/chadius/creatingsymmetry/entities/command"
+	. "gopkg.in/check.v1"
+)
+
+type CoordinateThresholdSettingsSuite struct{}
+
+var _ = Suite(&CoordinateThresholdSettingsSuite{})
+
+const thresholdTestViewport = `pattern_viewport:
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+`
+
+func (suite *CoordinateThresholdSettingsSuite) TestRectangleIsTheDefaultType(checker *C) {
+	wallpaperCommand, err := command.NewCreateWallpaperCommandFromYAML([]byte(thresholdTestViewport + `coordinate_threshold:
+  x_min: -2
+  x_max: 2
+  y_min: -3
+  y_max: 3
+`))
+	checker.Assert(err, IsNil)
+	checker.Assert(wallpaperCommand.CoordinateThreshold.ShapeType(), Equals, command.RectangleThreshold)
+	checker.Assert(wallpaperCommand.CoordinateThreshold.XMax, Equals, 2.0)
+}
+
+func (suite *CoordinateThresholdSettingsSuite) TestReadsEachShape(checker *C) {
+	wallpaperCommand, err := command.NewCreateWallpaperCommandFromYAML([]byte(thresholdTestViewport + `coordinate_threshold:
+  type: sector
+  center_x: 1
+  start_angle: 30
+  end_angle: 90
+  inner_radius: 0.5
+`))
+	checker.Assert(err, IsNil)
+	checker.Assert(wallpaperCommand.CoordinateThreshold.ShapeType(), Equals, command.SectorThreshold)
+	checker.Assert(wallpaperCommand.CoordinateThreshold.StartAngle, Equals, 30.0)
+	checker.Assert(wallpaperCommand.CoordinateThreshold.InnerRadius, Equals, 0.5)
+
+	wallpaperCommand, err = command.NewCreateWallpaperCommandFromJSON([]byte(`{
+	"pattern_viewport": {"x_min": -1, "x_max": 1, "y_min": -1, "y_max": 1},
+	"coordinate_threshold": {
+		"type": "polygon",
+		"vertices": [[0, 0], [1, 0], [0, 1]]
+	}
+}`))
+	checker.Assert(err, IsNil)
+	checker.Assert(wallpaperCommand.CoordinateThreshold.Vertices, DeepEquals, []command.ThresholdVertex{{0, 0}, {1, 0}, {0, 1}})
+}
+
+func (suite *CoordinateThresholdSettingsSuite) TestUnknownTypeIsAnError(checker *C) {
+	_, err := command.NewCreateWallpaperCommandFromYAML([]byte(thresholdTestViewport + `coordinate_threshold:
+  type: circle
+  radius: 2
+`))
+	checker.Assert(err, ErrorMatches, `found 1 problem:\nline 7: coordinate_threshold.type: unknown coordinate threshold type "circle", expected one of: rectangle, disc, annulus, sector, polygon, mask, auto, union, intersection, difference, invert`)
+}
+
+func (suite *CoordinateThresholdSettingsSuite) TestShapesNeedTheirSizes(checker *C) {
+	_, err := command.NewCreateWallpaperCommandFromYAML([]byte(thresholdTestViewport + `coordinate_threshold:
+  type: disc
+  center_x: 1
+`))
+	checker.Assert(err, ErrorMatches, `found 1 problem:\nline 6: coordinate_threshold.radius: disc thresholds need a positive radius`)
+
+	_, err = command.NewCreateWallpaperCommandFromYAML([]byte(thresholdTestViewport + `coordinate_threshold:
+  type: annulus
+  inner_radius: 2
+  outer_radius: 1
+`))
+	checker.Assert(err, ErrorMatches, `found 1 problem:\nline 9: coordinate_threshold.outer_radius: outer_radius must be larger than inner_radius`)
+
+	_, err = command.NewCreateWallpaperCommandFromYAML([]byte(thresholdTestViewport + `coordinate_threshold:
+  type: sector
+  start_angle: 45
+  end_angle: 45
+`))
+	checker.Assert(err, ErrorMatches, `found 1 problem:\nline 9: coordinate_threshold.end_angle: start_angle and end_angle must be different`)
+
+	_, err = command.NewCreateWallpaperCommandFromYAML([]byte(thresholdTestViewport + `coordinate_threshold:
+  type: polygon
+  vertices:
+    - [0, 0]
+    - [1, 1]
+`))
+	checker.Assert(err, ErrorMatches, `found 1 problem:\nline 8: coordinate_threshold.vertices: polygon thresholds need at least 3 vertices, found 2`)
+}
+
+func (suite *CoordinateThresholdSettingsSuite) TestFieldsFromOtherShapesAreErrors(checker *C) {
+	_, err := command.NewCreateWallpaperCommandFromYAML([]byte(thresholdTestViewport + `coordinate_threshold:
+  type: disc
+  radius: 2
+  x_max: 4
+`))
+	checker.Assert(err, ErrorMatches, `found 1 problem:\nline 9: coordinate_threshold.x_max: x_max is not used by disc thresholds`)
+}
+
+func (suite *CoordinateThresholdSettingsSuite) TestReadsNestedCombinations(checker *C) {
+	wallpaperCommand, err := command.NewCreateWallpaperCommandFromYAML([]byte(thresholdTestViewport + `coordinate_threshold:
+  type: difference
+  children:
+    - type: annulus
+      inner_radius: 2
+      outer_radius: 5
+    - type: invert
+      children:
+        - x_min: -10
+          x_max: 0
+          y_min: -10
+          y_max: 10
+`))
+	checker.Assert(err, IsNil)
+	threshold := wallpaperCommand.CoordinateThreshold
+	checker.Assert(threshold.ShapeType(), Equals, command.DifferenceThreshold)
+	checker.Assert(threshold.Children, HasLen, 2)
+	checker.Assert(threshold.Children[0].OuterRadius, Equals, 5.0)
+	checker.Assert(threshold.Children[1].Children[0].ShapeType(), Equals, command.RectangleThreshold)
+	checker.Assert(threshold.Children[1].Children[0].XMin, Equals, -10.0)
+}
+
+func (suite *CoordinateThresholdSettingsSuite) TestCombinationsNeedChildren(checker *C) {
+	_, err := command.NewCreateWallpaperCommandFromYAML([]byte(thresholdTestViewport + `coordinate_threshold:
+  type: union
+  children:
+    - type: disc
+      radius: 1
+`))
+	checker.Assert(err, ErrorMatches, `found 1 problem:\nline 8: coordinate_threshold.children: union thresholds need at least 2 children, found 1`)
+
+	_, err = command.NewCreateWallpaperCommandFromYAML([]byte(thresholdTestViewport + `coordinate_threshold:
+  type: invert
+  radius: 1
+`))
+	checker.Assert(err, ErrorMatches, `found 2 problems:\nline 6: coordinate_threshold.children: invert thresholds need exactly 1 child, found 0\nline 8: coordinate_threshold.radius: radius is not used by invert thresholds`)
+}
+
+func (suite *CoordinateThresholdSettingsSuite) TestChildProblemsIncludeTheirPath(checker *C) {
+	_, err := command.NewCreateWallpaperCommandFromYAML([]byte(thresholdTestViewport + `coordinate_threshold:
+  type: intersection
+  children:
+    - type: disc
+      radius: 1
+    - type: sector
+      start_angle: 10
+      end_angle: 10
+`))
+	checker.Assert(err, ErrorMatches, `found 1 problem:\nline 13: coordinate_threshold.children\[1\].end_angle: start_angle and end_angle must be different`)
+}
+
+func (suite *CoordinateThresholdSettingsSuite) TestShapesCanBeFeathered(checker *C) {
+	wallpaperCommand, err := command.NewCreateWallpaperCommandFromYAML([]byte(thresholdTestViewport + `coordinate_threshold:
+  type: disc
+  radius: 2
+  feather: 0.5
+`))
+	checker.Assert(err, IsNil)
+	checker.Assert(wallpaperCommand.CoordinateThreshold.Feather, Equals, 0.5)
+
+	_, err = command.NewCreateWallpaperCommandFromYAML([]byte(thresholdTestViewport + `coordinate_threshold:
+  type: disc
+  radius: 2
+  feather: -1
+`))
+	checker.Assert(err, ErrorMatches, `found 1 problem:\nline 9: coordinate_threshold.feather: feather must not be negative`)
+
+	_, err = command.NewCreateWallpaperCommandFromYAML([]byte(thresholdTestViewport + `coordinate_threshold:
+  type: invert
+  feather: 1
+  children:
+    - type: disc
+      radius: 2
+`))
+	checker.Assert(err, ErrorMatches, `found 1 problem:\nline 8: coordinate_threshold.feather: feather is not used by invert thresholds`)
+}
+
+func (suite *CoordinateThresholdSettingsSuite) TestMasksNameTheirImage(checker *C) {
+	wallpaperCommand, err := command.NewCreateWallpaperCommandFromYAML([]byte(thresholdTestViewport + `coordinate_threshold:
+  type: union
+  children:
+    - type: mask
+      mask: medallion.png
+    - type: mask
+      mask: logo.png
+      mask_space: transformed
+      x_min: -2
+      x_max: 2
+      y_min: -1
+      y_max: 1
+    - type: mask
+      mask: medallion.png
+`))
+	checker.Assert(err, IsNil)
+	threshold := wallpaperCommand.CoordinateThreshold
+	checker.Assert(threshold.Children[0].Space(), Equals, command.OutputMaskSpace)
+	checker.Assert(threshold.Children[1].Space(), Equals, command.TransformedMaskSpace)
+	checker.Assert(threshold.MaskFilenames(), DeepEquals, []string{"medallion.png", "logo.png"})
+}
+
+func (suite *CoordinateThresholdSettingsSuite) TestMaskProblems(checker *C) {
+	_, err := command.NewCreateWallpaperCommandFromYAML([]byte(thresholdTestViewport + `coordinate_threshold:
+  type: mask
+  mask_space: pattern
+`))
+	checker.Assert(err, ErrorMatches, `found 2 problems:\nline 6: coordinate_threshold.mask: mask thresholds need the filename of a mask image\nline 8: coordinate_threshold.mask_space: unknown mask_space "pattern", expected one of: output, transformed`)
+
+	_, err = command.NewCreateWallpaperCommandFromYAML([]byte(thresholdTestViewport + `coordinate_threshold:
+  type: mask
+  mask: medallion.png
+  x_min: 1
+`))
+	checker.Assert(err, ErrorMatches, `found 1 problem:\nline 9: coordinate_threshold.x_min: x_min is only used by transformed space masks, add mask_space: transformed`)
+
+	_, err = command.NewCreateWallpaperCommandFromYAML([]byte(thresholdTestViewport + `coordinate_threshold:
+  type: mask
+  mask: medallion.png
+  mask_space: transformed
+  x_max: 1
+`))
+	checker.Assert(err, ErrorMatches, `found 1 problem:\nline 6: coordinate_threshold.y_max: transformed space masks need y_max to be larger than y_min`)
+}
+
+func (suite *CoordinateThresholdSettingsSuite) TestAutoThresholdsUsePercentiles(checker *C) {
+	wallpaperCommand, err := command.NewCreateWallpaperCommandFromYAML([]byte(thresholdTestViewport + `coordinate_threshold:
+  type: auto
+`))
+	checker.Assert(err, IsNil)
+	lowerPercentile, upperPercentile := wallpaperCommand.CoordinateThreshold.Percentiles()
+	checker.Assert(lowerPercentile, Equals, command.DefaultLowerPercentile)
+	checker.Assert(upperPercentile, Equals, command.DefaultUpperPercentile)
+
+	wallpaperCommand, err = command.NewCreateWallpaperCommandFromYAML([]byte(thresholdTestViewport + `coordinate_threshold:
+  type: auto
+  lower_percentile: 5
+`))
+	checker.Assert(err, IsNil)
+	lowerPercentile, upperPercentile = wallpaperCommand.CoordinateThreshold.Percentiles()
+	checker.Assert(lowerPercentile, Equals, 5.0)
+	checker.Assert(upperPercentile, Equals, 100.0)
+
+	_, err = command.NewCreateWallpaperCommandFromYAML([]byte(thresholdTestViewport + `coordinate_threshold:
+  type: auto
+  lower_percentile: 90
+  upper_percentile: 10
+  feather: 0.5
+`))
+	checker.Assert(err, ErrorMatches, `found 2 problems:
+line 9: coordinate_threshold.upper_percentile: upper_percentile must be larger than lower_percentile
+line 10: coordinate_threshold.feather: feather is not used by auto thresholds`)
+
+	_, err = command.NewCreateWallpaperCommandFromYAML([]byte(thresholdTestViewport + `coordinate_threshold:
+  type: disc
+  radius: 1
+  upper_percentile: 120
+`))
+	checker.Assert(err, ErrorMatches, `found 1 problem:
+line 9: coordinate_threshold.upper_percentile: upper_percentile is not used by disc thresholds`)
+}
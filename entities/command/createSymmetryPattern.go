@@ -1,10 +1,8 @@
 package command
 
 import (
-	"encoding/json"
 	"github.com/chadius/creatingsymmetry/entities/formula"
-	"github.com/chadius/creatingsymmetry/entities/utility"
-	"gopkg.in/yaml.v2"
+	"github.com/chadius/creatingsymmetry/entities/validation"
 )
 
 // ComplexNumberCorners notes the sides of a rectangle drawn in the complex space.
@@ -15,61 +13,119 @@ type ComplexNumberCorners struct {
 	YMax float64 `json:"y_max" yaml:"y_max"`
 }
 
-// PixelCorners note the sides of a rectangle in integer space.
-type PixelCorners struct {
-	LeftSide   int `json:"left" yaml:"left"`
-	RightSide  int `json:"right" yaml:"right"`
-	TopSide    int `json:"top" yaml:"top"`
-	BottomSide int `json:"bottom" yaml:"bottom"`
-}
-
 // CreateSymmetryPattern records the desired command to generate.
+//   EyedropperRange is the part of transformed space that maps to the eyedropper's sides.
+//   If it is nil, the whole range of the transformed coordinates is used, which changes with the output size and viewport.
 type CreateSymmetryPattern struct {
-	PatternViewport     ComplexNumberCorners `json:"pattern_viewport" yaml:"pattern_viewport"`
-	CoordinateThreshold ComplexNumberCorners `json:"coordinate_threshold" yaml:"coordinate_threshold"`
-	Eyedropper          *PixelCorners        `json:"eyedropper" yaml:"eyedropper"`
+	PatternViewport     ComplexNumberCorners        `json:"pattern_viewport" yaml:"pattern_viewport"`
+	CoordinateThreshold CoordinateThresholdSettings `json:"coordinate_threshold" yaml:"coordinate_threshold"`
+	Eyedropper          *EyedropperSettings         `json:"eyedropper" yaml:"eyedropper"`
+	EyedropperRange     *EyedropperRange            `json:"eyedropper_range,omitempty" yaml:"eyedropper_range,omitempty"`
+	Background          BackgroundSettings          `json:"background" yaml:"background"`
 
 	Formula formula.Arbitrary `json:"formula" yaml:"formula"`
 }
 
 // CreateWallpaperCommandMarshal can be marshaled and converted to a CreateSymmetryPattern
 type CreateWallpaperCommandMarshal struct {
-	PatternViewport     ComplexNumberCorners `json:"pattern_viewport" yaml:"pattern_viewport"`
-	CoordinateThreshold ComplexNumberCorners `json:"coordinate_threshold" yaml:"coordinate_threshold"`
-	Eyedropper          *PixelCorners        `json:"eyedropper" yaml:"eyedropper"`
+	PatternViewport     ComplexNumberCorners        `json:"pattern_viewport" yaml:"pattern_viewport"`
+	CoordinateThreshold CoordinateThresholdSettings `json:"coordinate_threshold" yaml:"coordinate_threshold"`
+	Eyedropper          *EyedropperSettings         `json:"eyedropper" yaml:"eyedropper"`
+	EyedropperRange     *EyedropperRange            `json:"eyedropper_range,omitempty" yaml:"eyedropper_range,omitempty"`
+	Background          BackgroundSettings          `json:"background" yaml:"background"`
 
 	Formula *formula.BuilderOptionMarshal `json:"formula" yaml:"formula"`
 }
 
 // NewCreateWallpaperCommandFromYAML reads the data and returns a CreateSymmetryPattern from it.
+//   If the data is invalid, the error is a *validation.Error listing every problem found.
 func NewCreateWallpaperCommandFromYAML(data []byte) (*CreateSymmetryPattern, error) {
-	return newCreateWallpaperCommandFromDatastream(data, yaml.Unmarshal)
+	return newCreateWallpaperCommandFromDatastream(data, validation.ParseYAML)
 }
 
 // NewCreateWallpaperCommandFromJSON reads the data and returns a CreateSymmetryPattern from it.
+//   If the data is invalid, the error is a *validation.Error listing every problem found.
 func NewCreateWallpaperCommandFromJSON(data []byte) (*CreateSymmetryPattern, error) {
-	return newCreateWallpaperCommandFromDatastream(data, json.Unmarshal)
+	return newCreateWallpaperCommandFromDatastream(data, validation.ParseJSON)
+}
+
+// NewCreateWallpaperCommandFromData reads the data, detects if it is JSON or YAML and returns a CreateSymmetryPattern from it.
+//   If the data is invalid, the error is a *validation.Error listing every problem found.
+func NewCreateWallpaperCommandFromData(data []byte) (*CreateSymmetryPattern, error) {
+	return newCreateWallpaperCommandFromDatastream(data, validation.Parse)
 }
 
 // newCreateWallpaperCommandFromDatastream consumes a given bytestream and tries to create a new object from it.
-func newCreateWallpaperCommandFromDatastream(data []byte, unmarshal utility.UnmarshalFunc) (*CreateSymmetryPattern, error) {
-	var unmarshalError error
+func newCreateWallpaperCommandFromDatastream(data []byte, parse validation.DocumentParser) (*CreateSymmetryPattern, error) {
+	document, parseError := parse(data)
+	if parseError != nil {
+		return nil, parseError
+	}
+
 	var commandToCreateMarshal CreateWallpaperCommandMarshal
-	unmarshalError = unmarshal(data, &commandToCreateMarshal)
+	problems := document.CheckFields(commandToCreateMarshal)
+	if len(problems) > 0 {
+		return nil, &validation.Error{Problems: document.Locate(problems)}
+	}
 
+	unmarshalError := document.Decode(&commandToCreateMarshal)
 	if unmarshalError != nil {
 		return nil, unmarshalError
 	}
 
+	problems = commandToCreateMarshal.Validate()
+	if len(problems) > 0 {
+		return nil, &validation.Error{Problems: document.Locate(problems)}
+	}
+
 	commandToCreate := &CreateSymmetryPattern{
 		PatternViewport:     commandToCreateMarshal.PatternViewport,
 		CoordinateThreshold: commandToCreateMarshal.CoordinateThreshold,
 		Eyedropper:          commandToCreateMarshal.Eyedropper,
+		EyedropperRange:     commandToCreateMarshal.EyedropperRange,
+		Background:          commandToCreateMarshal.Background,
 	}
 
 	if commandToCreateMarshal.Formula != nil {
-		commandToCreate.Formula, _ = formula.NewBuilder().WithMarshalOptions(*commandToCreateMarshal.Formula).Build()
+		var buildError error
+		commandToCreate.Formula, buildError = formula.NewBuilder().WithMarshalOptions(*commandToCreateMarshal.Formula).Build()
+		if buildError != nil {
+			return nil, buildError
+		}
 	}
 
 	return commandToCreate, nil
 }
+
+// NeedsSourceImage returns true if the pattern cannot be drawn without a source image.
+//   Domain coloring and gradient eyedroppers color the pattern without one, unless a background uses it.
+func (c CreateSymmetryPattern) NeedsSourceImage() bool {
+	if c.Eyedropper == nil || c.Eyedropper.SamplesSourceImage() {
+		return true
+	}
+	return c.Background.UsesSourceImage()
+}
+
+// Validate returns every problem found in the command, like an empty viewport or an unknown formula type.
+func (c CreateWallpaperCommandMarshal) Validate() []validation.Problem {
+	problems := []validation.Problem{}
+
+	if c.PatternViewport.XMin == c.PatternViewport.XMax {
+		problems = append(problems, validation.NewProblem("pattern_viewport.x_max", "x_min and x_max must be different"))
+	}
+	if c.PatternViewport.YMin == c.PatternViewport.YMax {
+		problems = append(problems, validation.NewProblem("pattern_viewport.y_max", "y_min and y_max must be different"))
+	}
+
+	problems = append(problems, c.CoordinateThreshold.Validate("coordinate_threshold")...)
+
+	eyedropperChoice := EyedropperChoice{Eyedropper: c.Eyedropper, EyedropperRange: c.EyedropperRange}
+	problems = append(problems, eyedropperChoice.Validate()...)
+
+	problems = append(problems, c.Background.Validate("background")...)
+
+	if c.Formula != nil {
+		problems = append(problems, c.Formula.Validate("formula")...)
+	}
+	return problems
+}
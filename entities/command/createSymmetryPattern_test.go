@@ -3,7 +3,12 @@ package command_test
 import (
 	"github.com/chadius/creatingsymmetry/entities/command"
 	"github.com/chadius/creatingsymmetry/entities/formula"
+	"github.com/chadius/creatingsymmetry/entities/imageoutput"
+	"github.com/chadius/creatingsymmetry/entities/validation"
 	. "gopkg.in/check.v1"
+	"image/color"
+	"io/ioutil"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -44,21 +49,17 @@ formula:
         imaginary: 2e-2
       power_n: 3
       power_m: 0
-      coefficient_pairs:
-        multiplier: 1
-        relationships:
+      coefficient_relationships:
         - -M-N
-        - "+M+NF"
+        - "+M+NF(N+M)"
     -
       multiplier:
         real: 1e-10
         imaginary: 0
       power_n: 1
       power_m: 1
-      coefficient_pairs:
-        multiplier: 1
-        relationships:
-        - -M-NF
+      coefficient_relationships:
+        - -M-NF(N+M)
 `)
 	wallpaperCommand, err := command.NewCreateWallpaperCommandFromYAML(yamlByteStream)
 	checker.Assert(err, IsNil)
@@ -91,10 +92,7 @@ func (suite *CreateWallpaperCommandSuite) TestCreateFromJSONWithBuilder(checker
 					},
 					"power_n": 3,
 					"power_m": 0,
-					"coefficient_pairs": {
-					  "multiplier": 1,
-					  "relationships": ["-M-N", "+M+NF"]
-					}
+					"coefficient_relationships": ["-M-N", "+M+NF(N+M)"]
 				},
 				{
 					"multiplier": {
@@ -103,10 +101,7 @@ func (suite *CreateWallpaperCommandSuite) TestCreateFromJSONWithBuilder(checker
 					},
 					"power_n": 1,
 					"power_m": 1,
-					"coefficient_pairs": {
-					  "multiplier": 1,
-					  "relationships": ["-M-NF"]
-					}
+					"coefficient_relationships": ["-M-NF(N+M)"]
 				}
 			]
 		}
@@ -173,3 +168,604 @@ formula:
 	}
 	checker.Assert(p2SymmetryFound, Equals, true)
 }
+
+func (suite *CreateWallpaperCommandSuite) TestCreateFromDataDetectsJSON(checker *C) {
+	jsonByteStream := []byte("{\n\t\"pattern_viewport\": {\n\t\t\"x_min\": -1,\n\t\t\"x_max\": 1,\n\t\t\"y_min\": -1,\n\t\t\"y_max\": 1\n\t},\n\t\"formula\": {\n\t\t\"type\": \"identity\"\n\t}\n}")
+	wallpaperCommand, err := command.NewCreateWallpaperCommandFromData(jsonByteStream)
+	checker.Assert(err, IsNil)
+
+	checker.Assert(wallpaperCommand.PatternViewport.XMin, Equals, -1.0)
+	checker.Assert(wallpaperCommand.PatternViewport.XMax, Equals, 1.0)
+	checker.Assert(reflect.TypeOf(wallpaperCommand.Formula).String(), Equals, "*formula.Identity")
+}
+
+func (suite *CreateWallpaperCommandSuite) TestCreateFromDataDetectsYAML(checker *C) {
+	yamlByteStream := []byte(`
+pattern_viewport:
+  x_min: -2
+  x_max: 2
+  y_min: -2
+  y_max: 2
+formula:
+  type: rosette
+  terms:
+    - power_n: 3
+`)
+	wallpaperCommand, err := command.NewCreateWallpaperCommandFromData(yamlByteStream)
+	checker.Assert(err, IsNil)
+
+	checker.Assert(wallpaperCommand.PatternViewport.XMin, Equals, -2.0)
+	checker.Assert(reflect.TypeOf(wallpaperCommand.Formula).String(), Equals, "*formula.Rosette")
+}
+
+func (suite *CreateWallpaperCommandSuite) TestUnknownFieldsAreReportedWithLineNumbers(checker *C) {
+	yamlByteStream := []byte(`pattern_viewport:
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+formula:
+  type: rosette
+  terms:
+    - power_n: 3
+      coefficient_pairs:
+        - -M-N
+`)
+	_, err := command.NewCreateWallpaperCommandFromYAML(yamlByteStream)
+	checker.Assert(err, FitsTypeOf, &validation.Error{})
+
+	problems := err.(*validation.Error).Problems
+	checker.Assert(problems, HasLen, 1)
+	checker.Assert(problems[0].Path, Equals, "formula.terms[0].coefficient_pairs")
+	checker.Assert(problems[0].Line, Equals, 10)
+	checker.Assert(problems[0].Message, Matches, `unknown field "coefficient_pairs".*`)
+}
+
+func (suite *CreateWallpaperCommandSuite) TestJSONPowersWithAFractionAreReportedWithLineNumbers(checker *C) {
+	jsonByteStream := []byte(`{
+	"pattern_viewport": {"x_min": -1, "x_max": 1, "y_min": -1, "y_max": 1},
+	"formula": {
+		"type": "rosette",
+		"terms": [
+			{"multiplier": {"real": 1, "imaginary": 0}, "power_n": 1.0, "power_m": 0}
+		]
+	}
+}`)
+	_, err := command.NewCreateWallpaperCommandFromData(jsonByteStream)
+	checker.Assert(err, FitsTypeOf, &validation.Error{})
+	checker.Assert(err, ErrorMatches, `found 1 problem:\nline 6: formula.terms\[0\].power_n: expected a whole number, found 1.0`)
+}
+
+func (suite *CreateWallpaperCommandSuite) TestAllProblemsAreReportedAtOnce(checker *C) {
+	jsonByteStream := []byte(`{
+	"pattern_viewport": {"x_min": 0, "x_max": 0, "y_min": -1, "y_max": 1},
+	"formula": {
+		"type": "rectangular",
+		"desired_symmetry": "p4",
+		"wave_packets": [
+			{
+				"terms": [
+					{"power_n": 1, "power_m": 0, "coefficient_relationships": ["+M+NF"]}
+				]
+			}
+		]
+	}
+}`)
+	_, err := command.NewCreateWallpaperCommandFromJSON(jsonByteStream)
+	checker.Assert(err, FitsTypeOf, &validation.Error{})
+
+	problems := err.(*validation.Error).Problems
+	checker.Assert(problems, HasLen, 4)
+	checker.Assert(problems[0].Path, Equals, "pattern_viewport.x_max")
+	checker.Assert(problems[0].Line, Equals, 2)
+	checker.Assert(problems[1].Path, Equals, "formula.lattice_height")
+	checker.Assert(problems[1].Line, Equals, 3)
+	checker.Assert(problems[1].Message, Matches, "rectangular lattice must specify a non zero lattice_height")
+	checker.Assert(problems[2].Path, Equals, "formula.desired_symmetry")
+	checker.Assert(problems[2].Line, Equals, 5)
+	checker.Assert(problems[3].Path, Equals, "formula.wave_packets[0].terms[0].coefficient_relationships[0]")
+	checker.Assert(problems[3].Line, Equals, 9)
+}
+
+func (suite *CreateWallpaperCommandSuite) TestUnknownFormulaTypeIsAnError(checker *C) {
+	yamlByteStream := []byte(`pattern_viewport:
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+formula:
+  type: rosete
+`)
+	_, err := command.NewCreateWallpaperCommandFromYAML(yamlByteStream)
+	checker.Assert(err, ErrorMatches, `(?s)found 1 problem:\nline 7: formula.type: unknown formula type "rosete".*`)
+}
+
+func (suite *CreateWallpaperCommandSuite) TestWrongValueTypeIsAnError(checker *C) {
+	yamlByteStream := []byte(`pattern_viewport:
+  x_min: -1
+  x_max: one
+  y_min: -1
+  y_max: 1
+`)
+	_, err := command.NewCreateWallpaperCommandFromYAML(yamlByteStream)
+	checker.Assert(err, ErrorMatches, `(?s)found 1 problem:\nline 3: pattern_viewport.x_max: expected a number, found "one"`)
+}
+
+func (suite *CreateWallpaperCommandSuite) TestEyedropperSampling(checker *C) {
+	wallpaperCommand, err := command.NewCreateWallpaperCommandFromYAML([]byte(`pattern_viewport:
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+eyedropper:
+  left: 0
+  right: 20
+  top: 0
+  bottom: 20
+  sampling: bicubic
+`))
+	checker.Assert(err, IsNil)
+	checker.Assert(wallpaperCommand.Eyedropper.Sampling, Equals, imageoutput.BicubicSampling)
+
+	_, err = command.NewCreateWallpaperCommandFromYAML([]byte(`pattern_viewport:
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+eyedropper:
+  left: 0
+  right: 20
+  top: 0
+  bottom: 20
+  sampling: linear
+`))
+	checker.Assert(err, ErrorMatches, `found 1 problem:\nline 11: eyedropper.sampling: unknown sampling "linear", expected one of: nearest, bilinear, bicubic`)
+}
+
+func (suite *CreateWallpaperCommandSuite) TestEyedropperEdgeMode(checker *C) {
+	wallpaperCommand, err := command.NewCreateWallpaperCommandFromYAML([]byte(`pattern_viewport:
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+eyedropper:
+  left: 0
+  right: 20
+  top: 0
+  bottom: 20
+  edge_mode: mirror
+  period_x: 2
+  period_y: 0.5
+`))
+	checker.Assert(err, IsNil)
+	checker.Assert(wallpaperCommand.Eyedropper.EdgeMode, Equals, imageoutput.MirrorEdgeMode)
+	checker.Assert(wallpaperCommand.Eyedropper.PeriodX, Equals, 2.0)
+	checker.Assert(wallpaperCommand.Eyedropper.PeriodY, Equals, 0.5)
+
+	_, err = command.NewCreateWallpaperCommandFromYAML([]byte(`pattern_viewport:
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+eyedropper:
+  left: 0
+  right: 20
+  top: 0
+  bottom: 20
+  edge_mode: repeat
+  period_x: 2
+`))
+	checker.Assert(err, ErrorMatches, `found 2 problems:
+line 6: eyedropper: period_x and period_y must be used together
+line 11: eyedropper.edge_mode: repeat edge mode needs period_x and period_y`)
+
+	_, err = command.NewCreateWallpaperCommandFromYAML([]byte(`pattern_viewport:
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+eyedropper:
+  left: 0
+  right: 20
+  top: 0
+  bottom: 20
+  edge_mode: wrap
+`))
+	checker.Assert(err, ErrorMatches, `found 1 problem:\nline 11: eyedropper.edge_mode: unknown edge mode "wrap", expected one of: clamp, repeat, mirror, transparent`)
+}
+
+func (suite *CreateWallpaperCommandSuite) TestPolarEyedropper(checker *C) {
+	wallpaperCommand, err := command.NewCreateWallpaperCommandFromYAML([]byte(`pattern_viewport:
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+eyedropper:
+  shape: polar
+  center_x: 100
+  center_y: 80
+  radius_x: 50
+  maximum_modulus: 2
+`))
+	checker.Assert(err, IsNil)
+	checker.Assert(wallpaperCommand.Eyedropper.IsPolar(), Equals, true)
+	radiusX, radiusY := wallpaperCommand.Eyedropper.PolarRadius()
+	checker.Assert(radiusX, Equals, 50.0)
+	checker.Assert(radiusY, Equals, 50.0)
+
+	_, err = command.NewCreateWallpaperCommandFromYAML([]byte(`pattern_viewport:
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+eyedropper:
+  shape: polar
+  right: 20
+  center_x: 100
+`))
+	checker.Assert(err, ErrorMatches, `found 2 problems:
+line 6: eyedropper.radius_x: polar eyedroppers need a positive radius_x
+line 8: eyedropper.right: right is only used by rectangular eyedroppers`)
+
+	_, err = command.NewCreateWallpaperCommandFromYAML([]byte(`pattern_viewport:
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+eyedropper:
+  shape: circle
+  radius_x: 20
+`))
+	checker.Assert(err, ErrorMatches, `found 2 problems:
+line 7: eyedropper.shape: unknown eyedropper shape "circle", expected one of: rectangular, polar, domain_coloring, gradient
+line 8: eyedropper.radius_x: radius_x is only used by polar eyedroppers, add shape: polar`)
+}
+
+func (suite *CreateWallpaperCommandSuite) TestEyedropperRange(checker *C) {
+	wallpaperCommand, err := command.NewCreateWallpaperCommandFromYAML([]byte(`pattern_viewport:
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+eyedropper_range:
+  x_min: -2
+  x_max: 2
+  y_min: -3
+  y_max: 3
+`))
+	checker.Assert(err, IsNil)
+	checker.Assert(*wallpaperCommand.EyedropperRange, Equals, command.EyedropperRange{XMin: -2, XMax: 2, YMin: -3, YMax: 3})
+
+	_, err = command.NewCreateWallpaperCommandFromYAML([]byte(`pattern_viewport:
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+eyedropper_range:
+  x_min: 2
+  x_max: -2
+  y_min: 3
+  y_max: 3
+`))
+	checker.Assert(err, ErrorMatches, `found 2 problems:
+line 8: eyedropper_range.x_max: x_max must be larger than x_min
+line 10: eyedropper_range.y_max: y_max must be larger than y_min`)
+
+	_, err = command.NewCreateWallpaperCommandFromYAML([]byte(`pattern_viewport:
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+eyedropper:
+  shape: polar
+  radius_x: 50
+eyedropper_range:
+  x_min: -2
+  x_max: 2
+  y_min: -3
+  y_max: 3
+`))
+	checker.Assert(err, ErrorMatches, `found 1 problem:
+line 9: eyedropper_range: polar eyedroppers only use upper_percentile, use maximum_modulus for a fixed range`)
+
+	_, err = command.NewCreateWallpaperCommandFromYAML([]byte(`pattern_viewport:
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+eyedropper:
+  left: 0
+  right: 20
+  top: 0
+  bottom: 20
+  period_x: 2
+  period_y: 2
+eyedropper_range:
+  x_min: -2
+  x_max: 2
+  y_min: -3
+  y_max: 3
+`))
+	checker.Assert(err, ErrorMatches, `found 1 problem:
+line 13: eyedropper_range: eyedropper_range is not used when the eyedropper has period_x and period_y`)
+}
+
+func (suite *CreateWallpaperCommandSuite) TestEyedropperRangeFromPercentiles(checker *C) {
+	wallpaperCommand, err := command.NewCreateWallpaperCommandFromYAML([]byte(`pattern_viewport:
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+eyedropper_range:
+  lower_percentile: 1
+  upper_percentile: 99
+`))
+	checker.Assert(err, IsNil)
+	checker.Assert(wallpaperCommand.EyedropperRange.UsesPercentiles(), Equals, true)
+	lowerPercentile, upperPercentile := wallpaperCommand.EyedropperRange.Percentiles()
+	checker.Assert(lowerPercentile, Equals, 1.0)
+	checker.Assert(upperPercentile, Equals, 99.0)
+
+	_, err = command.NewCreateWallpaperCommandFromYAML([]byte(`pattern_viewport:
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+eyedropper_range:
+  x_max: 2
+  lower_percentile: -1
+  upper_percentile: 101
+`))
+	checker.Assert(err, ErrorMatches, `found 3 problems:
+line 7: eyedropper_range.x_max: x_max is not used with percentiles
+line 8: eyedropper_range.lower_percentile: lower_percentile must be at least 0 and less than 100
+line 9: eyedropper_range.upper_percentile: upper_percentile must be more than 0 and at most 100`)
+
+	wallpaperCommand, err = command.NewCreateWallpaperCommandFromYAML([]byte(`pattern_viewport:
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+eyedropper:
+  shape: polar
+  radius_x: 50
+eyedropper_range:
+  upper_percentile: 95
+`))
+	checker.Assert(err, IsNil)
+	_, upperPercentile = wallpaperCommand.EyedropperRange.Percentiles()
+	checker.Assert(upperPercentile, Equals, 95.0)
+
+	_, err = command.NewCreateWallpaperCommandFromYAML([]byte(`pattern_viewport:
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+eyedropper:
+  shape: polar
+  radius_x: 50
+  maximum_modulus: 2
+eyedropper_range:
+  lower_percentile: 5
+  upper_percentile: 95
+`))
+	checker.Assert(err, ErrorMatches, `found 2 problems:
+line 11: eyedropper_range.lower_percentile: lower_percentile is not used by polar eyedroppers
+line 12: eyedropper_range.upper_percentile: upper_percentile is not used when the eyedropper has maximum_modulus`)
+}
+
+func (suite *CreateWallpaperCommandSuite) TestEyedropperMappingCurves(checker *C) {
+	wallpaperCommand, err := command.NewCreateWallpaperCommandFromYAML([]byte(`pattern_viewport:
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+eyedropper:
+  left: 0
+  right: 20
+  top: 0
+  bottom: 20
+  curve_x: signed_square_root
+  curve_y: histogram_equalized
+`))
+	checker.Assert(err, IsNil)
+	checker.Assert(wallpaperCommand.Eyedropper.CurveX, Equals, imageoutput.SignedSquareRootMappingCurve)
+	checker.Assert(wallpaperCommand.Eyedropper.CurveY, Equals, imageoutput.HistogramEqualizedMappingCurve)
+
+	_, err = command.NewCreateWallpaperCommandFromYAML([]byte(`pattern_viewport:
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+eyedropper:
+  left: 0
+  right: 20
+  top: 0
+  bottom: 20
+  period_x: 2
+  period_y: 2
+  curve_x: cubic
+  curve_y: sigmoid
+`))
+	checker.Assert(err, ErrorMatches, `found 2 problems:
+line 13: eyedropper.curve_x: unknown mapping curve "cubic", expected one of: linear, logarithmic, signed_square_root, sigmoid, histogram_equalized
+line 14: eyedropper.curve_y: curve_y is not used with period_x and period_y`)
+
+	_, err = command.NewCreateWallpaperCommandFromYAML([]byte(`pattern_viewport:
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+eyedropper:
+  shape: polar
+  radius_x: 50
+  curve_x: logarithmic
+`))
+	checker.Assert(err, ErrorMatches, `found 1 problem:\nline 9: eyedropper.curve_x: curve_x is only used by rectangular eyedroppers`)
+}
+
+func (suite *CreateWallpaperCommandSuite) TestDomainColoringEyedropper(checker *C) {
+	wallpaperCommand, err := command.NewCreateWallpaperCommandFromYAML([]byte(`pattern_viewport:
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+eyedropper:
+  shape: domain_coloring
+  modulus_contours: true
+  phase_contours: 12
+`))
+	checker.Assert(err, IsNil)
+	checker.Assert(wallpaperCommand.Eyedropper.IsDomainColoring(), Equals, true)
+	checker.Assert(wallpaperCommand.Eyedropper.ModulusContours, Equals, true)
+	checker.Assert(wallpaperCommand.Eyedropper.PhaseContours, Equals, 12)
+	checker.Assert(wallpaperCommand.NeedsSourceImage(), Equals, false)
+
+	_, err = command.NewCreateWallpaperCommandFromYAML([]byte(`pattern_viewport:
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+eyedropper:
+  shape: domain_coloring
+  right: 20
+  sampling: bilinear
+  phase_contours: -1
+eyedropper_range:
+  upper_percentile: 99
+`))
+	checker.Assert(err, ErrorMatches, `found 4 problems:
+line 8: eyedropper.right: right is not used by domain coloring eyedroppers
+line 9: eyedropper.sampling: sampling is not used by domain coloring eyedroppers
+line 10: eyedropper.phase_contours: phase_contours must be positive, leave it out for no phase contours
+line 11: eyedropper_range: eyedropper_range is not used by domain coloring eyedroppers`)
+
+	_, err = command.NewCreateWallpaperCommandFromYAML([]byte(`pattern_viewport:
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+eyedropper:
+  shape: polar
+  radius_x: 50
+  modulus_contours: true
+`))
+	checker.Assert(err, ErrorMatches, `found 1 problem:\nline 9: eyedropper.modulus_contours: modulus_contours is only used by domain coloring eyedroppers, add shape: domain_coloring`)
+}
+
+func (suite *CreateWallpaperCommandSuite) TestGradientEyedropper(checker *C) {
+	wallpaperCommand, err := command.NewCreateWallpaperCommandFromYAML([]byte(`pattern_viewport:
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+eyedropper:
+  shape: gradient
+  scalar: argument
+  stops:
+    - position: 0
+      color: "#000000"
+    - position: 1
+      color: "#ff000080"
+`))
+	checker.Assert(err, IsNil)
+	checker.Assert(wallpaperCommand.Eyedropper.IsGradient(), Equals, true)
+	checker.Assert(wallpaperCommand.Eyedropper.Scalar, Equals, imageoutput.ArgumentGradientScalar)
+	checker.Assert(wallpaperCommand.Eyedropper.Stops, HasLen, 2)
+	stopColor, err := wallpaperCommand.Eyedropper.Stops[1].StopColor()
+	checker.Assert(err, IsNil)
+	checker.Assert(stopColor, Equals, color.NRGBA{R: 255, G: 0, B: 0, A: 128})
+	checker.Assert(wallpaperCommand.NeedsSourceImage(), Equals, false)
+
+	_, err = command.NewCreateWallpaperCommandFromYAML([]byte(`pattern_viewport:
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+eyedropper:
+  shape: gradient
+  scalar: phase
+  scalar_min: 2
+  left: 10
+  stops:
+    - position: 0.5
+      color: "#000000"
+    - position: 0.25
+      color: black
+    - position: 2
+      color: "#ffffff"
+`))
+	checker.Assert(err, ErrorMatches, `found 6 problems:
+line 6: eyedropper.scalar_max: scalar_max must be larger than scalar_min
+line 8: eyedropper.scalar: unknown gradient scalar "phase", expected one of: modulus, argument, real, imaginary
+line 10: eyedropper.left: left is not used by gradient eyedroppers
+line 14: eyedropper.stops\[1\].position: stops must be in order of position
+line 15: eyedropper.stops\[1\].color: invalid color "black", expected #rrggbb or #rrggbbaa
+line 16: eyedropper.stops\[2\].position: position must be from 0 to 1`)
+
+	_, err = command.NewCreateWallpaperCommandFromYAML([]byte(`pattern_viewport:
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+eyedropper:
+  shape: gradient
+eyedropper_range:
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+`))
+	checker.Assert(err, ErrorMatches, `found 2 problems:
+line 6: eyedropper.stops: gradient eyedroppers need at least 2 stops
+line 8: eyedropper_range: eyedropper_range is not used by gradient eyedroppers, use scalar_min and scalar_max`)
+
+	_, err = command.NewCreateWallpaperCommandFromYAML([]byte(`pattern_viewport:
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+eyedropper:
+  shape: domain_coloring
+  scalar: modulus
+`))
+	checker.Assert(err, ErrorMatches, `found 1 problem:\nline 8: eyedropper.scalar: scalar is only used by gradient eyedroppers, add shape: gradient`)
+}
+
+func (suite *CreateWallpaperCommandSuite) TestNeedsSourceImage(checker *C) {
+	checker.Assert(command.CreateSymmetryPattern{}.NeedsSourceImage(), Equals, true)
+
+	domainColoring := command.CreateSymmetryPattern{
+		Eyedropper: &command.EyedropperSettings{Shape: command.DomainColoringEyedropperShape},
+	}
+	checker.Assert(domainColoring.NeedsSourceImage(), Equals, false)
+
+	domainColoring.Background.Unmapped = &command.BackgroundFill{Type: command.SourceBackgroundFill}
+	checker.Assert(domainColoring.NeedsSourceImage(), Equals, true)
+
+	gradient := command.CreateSymmetryPattern{
+		Eyedropper: &command.EyedropperSettings{Shape: command.GradientEyedropperShape},
+	}
+	checker.Assert(gradient.NeedsSourceImage(), Equals, false)
+}
+
+func (suite *CreateWallpaperCommandSuite) TestExampleFilesAreValid(checker *C) {
+	exampleFilenames, err := filepath.Glob(filepath.Join("..", "..", "example", "*", "*.yml"))
+	checker.Assert(err, IsNil)
+	checker.Assert(len(exampleFilenames) > 0, Equals, true)
+
+	for _, exampleFilename := range exampleFilenames {
+		data, err := ioutil.ReadFile(exampleFilename)
+		checker.Assert(err, IsNil)
+		_, err = command.NewCreateWallpaperCommandFromData(data)
+		checker.Assert(err, IsNil, Commentf("%s", exampleFilename))
+	}
+}
+
+func (suite *CreateWallpaperCommandSuite) TestPixelCornersStillBuildsAnEyedropper(checker *C) {
+	wallpaperCommand := command.CreateSymmetryPattern{
+		Eyedropper: &command.PixelCorners{LeftSide: 1, RightSide: 3, TopSide: 2, BottomSide: 4},
+	}
+	checker.Assert(wallpaperCommand.Eyedropper.RightSide, Equals, 3)
+	checker.Assert(wallpaperCommand.NeedsSourceImage(), Equals, true)
+}
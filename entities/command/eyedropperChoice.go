@@ -0,0 +1,60 @@
+package command
+
+import (
+	"github.com/chadius/creatingsymmetry/entities/validation"
+)
+
+// EyedropperChoice replaces the eyedropper and eyedropper range of a formula,
+//   so a pattern that was already transformed can be colored another way.
+//   It is read from a YAML or JSON document with the same eyedropper and eyedropper_range keys as the formula file.
+type EyedropperChoice struct {
+	Eyedropper      *EyedropperSettings `json:"eyedropper" yaml:"eyedropper"`
+	EyedropperRange *EyedropperRange    `json:"eyedropper_range,omitempty" yaml:"eyedropper_range,omitempty"`
+}
+
+// NewEyedropperChoiceFromData reads the data, detects if it is JSON or YAML and returns an EyedropperChoice from it.
+//   If the data is invalid, the error is a *validation.Error listing every problem found.
+func NewEyedropperChoiceFromData(data []byte) (*EyedropperChoice, error) {
+	document, parseError := validation.Parse(data)
+	if parseError != nil {
+		return nil, parseError
+	}
+
+	var choice EyedropperChoice
+	problems := document.CheckFields(choice)
+	if len(problems) > 0 {
+		return nil, &validation.Error{Problems: document.Locate(problems)}
+	}
+
+	unmarshalError := document.Decode(&choice)
+	if unmarshalError != nil {
+		return nil, unmarshalError
+	}
+
+	problems = choice.Validate()
+	if len(problems) > 0 {
+		return nil, &validation.Error{Problems: document.Locate(problems)}
+	}
+	return &choice, nil
+}
+
+// Validate returns every problem found in the eyedropper and its range.
+func (e EyedropperChoice) Validate() []validation.Problem {
+	problems := []validation.Problem{}
+	if e.Eyedropper != nil {
+		problems = append(problems, e.Eyedropper.Validate("eyedropper")...)
+	}
+	if e.EyedropperRange != nil {
+		problems = append(problems, e.EyedropperRange.Validate("eyedropper_range", e.Eyedropper)...)
+	}
+	return problems
+}
+
+// ApplyTo returns a copy of the pattern using this eyedropper and eyedropper range instead of its own.
+//   Everything else is shared with the pattern.
+func (e EyedropperChoice) ApplyTo(pattern *CreateSymmetryPattern) *CreateSymmetryPattern {
+	patternWithChoice := *pattern
+	patternWithChoice.Eyedropper = e.Eyedropper
+	patternWithChoice.EyedropperRange = e.EyedropperRange
+	return &patternWithChoice
+}
@@ -0,0 +1,74 @@
+package command_test
+
+import (
+	"github.com/chadius/creatingsymmetry/entities/command"
+	"github.com/chadius/creatingsymmetry/entities/imageoutput"
+	. "gopkg.in/check.v1"
+)
+
+type EyedropperChoiceSuite struct{}
+
+var _ = Suite(&EyedropperChoiceSuite{})
+
+func (suite *EyedropperChoiceSuite) TestReadsTheEyedropperAndItsRange(checker *C) {
+	choice, err := command.NewEyedropperChoiceFromData([]byte(`eyedropper:
+  shape: polar
+  radius_x: 50
+eyedropper_range:
+  upper_percentile: 95
+`))
+	checker.Assert(err, IsNil)
+	checker.Assert(choice.Eyedropper.IsPolar(), Equals, true)
+	checker.Assert(choice.EyedropperRange.UsesPercentiles(), Equals, true)
+
+	choice, err = command.NewEyedropperChoiceFromData([]byte(`{"eyedropper": {"shape": "gradient", "scalar": "real", "stops": [{"position": 0, "color": "#000000"}, {"position": 1, "color": "#ffffff"}]}}`))
+	checker.Assert(err, IsNil)
+	checker.Assert(choice.Eyedropper.IsGradient(), Equals, true)
+	checker.Assert(choice.Eyedropper.Scalar, Equals, imageoutput.RealGradientScalar)
+	checker.Assert(choice.EyedropperRange, IsNil)
+}
+
+func (suite *EyedropperChoiceSuite) TestReportsProblemsWithLineNumbers(checker *C) {
+	_, err := command.NewEyedropperChoiceFromData([]byte(`eyedropper:
+  shape: domain_coloring
+  right: 20
+formula:
+  type: rosette
+`))
+	checker.Assert(err, ErrorMatches, `found 1 problem:\nline 4: formula: unknown field "formula".*`)
+
+	_, err = command.NewEyedropperChoiceFromData([]byte(`eyedropper:
+  shape: domain_coloring
+  right: 20
+eyedropper_range:
+  upper_percentile: 99
+`))
+	checker.Assert(err, ErrorMatches, `found 2 problems:
+line 3: eyedropper.right: right is not used by domain coloring eyedroppers
+line 4: eyedropper_range: eyedropper_range is not used by domain coloring eyedroppers`)
+}
+
+func (suite *EyedropperChoiceSuite) TestReplacesThePatternsEyedropper(checker *C) {
+	pattern, err := command.NewCreateWallpaperCommandFromYAML([]byte(`pattern_viewport:
+  x_min: -1
+  x_max: 1
+  y_min: -1
+  y_max: 1
+eyedropper_range:
+  x_min: -2
+  x_max: 2
+  y_min: -2
+  y_max: 2
+`))
+	checker.Assert(err, IsNil)
+	choice, err := command.NewEyedropperChoiceFromData([]byte("eyedropper:\n  shape: domain_coloring\n"))
+	checker.Assert(err, IsNil)
+
+	patternWithChoice := choice.ApplyTo(pattern)
+	checker.Assert(patternWithChoice.Eyedropper.IsDomainColoring(), Equals, true)
+	checker.Assert(patternWithChoice.EyedropperRange, IsNil)
+	checker.Assert(patternWithChoice.NeedsSourceImage(), Equals, false)
+	checker.Assert(patternWithChoice.PatternViewport, Equals, pattern.PatternViewport)
+	checker.Assert(pattern.Eyedropper, IsNil)
+	checker.Assert(pattern.EyedropperRange, NotNil)
+}
@@ -0,0 +1,446 @@
+package command
+
+import (
+	"github.com/chadius/creatingsymmetry/entities/imageoutput"
+	"github.com/chadius/creatingsymmetry/entities/validation"
+	"image/color"
+	"strings"
+)
+
+// EyedropperShape chooses the shape of the region of the source image the eyedropper samples.
+//   Domain coloring and gradients do not sample the source image, they color each transformed coordinate by its value.
+type EyedropperShape string
+
+// Eyedropper shapes.
+const (
+	RectangularEyedropperShape    EyedropperShape = "rectangular"
+	PolarEyedropperShape          EyedropperShape = "polar"
+	DomainColoringEyedropperShape EyedropperShape = "domain_coloring"
+	GradientEyedropperShape       EyedropperShape = "gradient"
+)
+
+// EyedropperShapes returns every supported eyedropper shape.
+func EyedropperShapes() []EyedropperShape {
+	return []EyedropperShape{RectangularEyedropperShape, PolarEyedropperShape, DomainColoringEyedropperShape, GradientEyedropperShape}
+}
+
+// IsValid returns true if this is a supported eyedropper shape.
+func (s EyedropperShape) IsValid() bool {
+	for _, shape := range EyedropperShapes() {
+		if s == shape {
+			return true
+		}
+	}
+	return false
+}
+
+// EyedropperSettings note the region of the source image the eyedropper samples,
+//   and how colors are read between its pixels.
+//   Rectangular eyedroppers, the default shape, use the sides of the rectangle.
+//   PeriodX and PeriodY are the width and height in transformed space that span the rectangle once.
+//   If they are 0, the rectangle spans the range of the transformed coordinates instead.
+//   CurveX and CurveY spread the transformed coordinates across that range, linearly by default.
+//   Polar eyedroppers use the center and radius of an ellipse. RadiusY defaults to RadiusX, making a circle.
+//   MaximumModulus is the modulus that reaches the rim. If it is 0, the largest modulus is used instead.
+//   Domain coloring eyedroppers only use ModulusContours and PhaseContours, which shade bands of the pattern.
+//   Gradient eyedroppers only use Scalar, ScalarMin, ScalarMax and Stops. The scalar range maps onto stop positions 0 to 1.
+//   If ScalarMin and ScalarMax are 0, arguments use the full circle and other scalars use the range of the transformed coordinates.
+type EyedropperSettings struct {
+	Shape      EyedropperShape      `json:"shape,omitempty" yaml:"shape,omitempty"`
+	LeftSide   int                  `json:"left" yaml:"left"`
+	RightSide  int                  `json:"right" yaml:"right"`
+	TopSide    int                  `json:"top" yaml:"top"`
+	BottomSide int                  `json:"bottom" yaml:"bottom"`
+	Sampling   imageoutput.Sampling `json:"sampling,omitempty" yaml:"sampling,omitempty"`
+	EdgeMode   imageoutput.EdgeMode `json:"edge_mode,omitempty" yaml:"edge_mode,omitempty"`
+	PeriodX    float64              `json:"period_x,omitempty" yaml:"period_x,omitempty"`
+	PeriodY    float64              `json:"period_y,omitempty" yaml:"period_y,omitempty"`
+
+	CurveX imageoutput.MappingCurve `json:"curve_x,omitempty" yaml:"curve_x,omitempty"`
+	CurveY imageoutput.MappingCurve `json:"curve_y,omitempty" yaml:"curve_y,omitempty"`
+
+	CenterX        float64 `json:"center_x,omitempty" yaml:"center_x,omitempty"`
+	CenterY        float64 `json:"center_y,omitempty" yaml:"center_y,omitempty"`
+	RadiusX        float64 `json:"radius_x,omitempty" yaml:"radius_x,omitempty"`
+	RadiusY        float64 `json:"radius_y,omitempty" yaml:"radius_y,omitempty"`
+	MaximumModulus float64 `json:"maximum_modulus,omitempty" yaml:"maximum_modulus,omitempty"`
+
+	ModulusContours bool `json:"modulus_contours,omitempty" yaml:"modulus_contours,omitempty"`
+	PhaseContours   int  `json:"phase_contours,omitempty" yaml:"phase_contours,omitempty"`
+
+	Scalar    imageoutput.GradientScalar `json:"scalar,omitempty" yaml:"scalar,omitempty"`
+	ScalarMin float64                    `json:"scalar_min,omitempty" yaml:"scalar_min,omitempty"`
+	ScalarMax float64                    `json:"scalar_max,omitempty" yaml:"scalar_max,omitempty"`
+	Stops     []GradientStopSettings     `json:"stops,omitempty" yaml:"stops,omitempty"`
+}
+
+// PixelCorners is the old name of EyedropperSettings, from when the eyedropper only had its sides.
+//
+// Deprecated: use EyedropperSettings.
+type PixelCorners = EyedropperSettings
+
+// GradientStopSettings place a color along the gradient.
+//   Position goes from 0 at the start of the gradient to 1 at the end.
+//   Color is written as #rrggbb or #rrggbbaa.
+type GradientStopSettings struct {
+	Position float64 `json:"position" yaml:"position"`
+	Color    string  `json:"color" yaml:"color"`
+}
+
+// StopColor reads the color, written as #rrggbb or #rrggbbaa.
+func (g GradientStopSettings) StopColor() (color.NRGBA, error) {
+	return parseHexColor(g.Color)
+}
+
+// IsPolar returns true if the eyedropper samples an ellipse.
+func (e EyedropperSettings) IsPolar() bool {
+	return e.Shape == PolarEyedropperShape
+}
+
+// IsDomainColoring returns true if the eyedropper colors coordinates by their value instead of sampling the source image.
+func (e EyedropperSettings) IsDomainColoring() bool {
+	return e.Shape == DomainColoringEyedropperShape
+}
+
+// IsGradient returns true if the eyedropper colors coordinates using a gradient.
+func (e EyedropperSettings) IsGradient() bool {
+	return e.Shape == GradientEyedropperShape
+}
+
+// SamplesSourceImage returns true if the eyedropper reads its colors from the source image.
+func (e EyedropperSettings) SamplesSourceImage() bool {
+	return !e.IsDomainColoring() && !e.IsGradient()
+}
+
+// PolarRadius returns the horizontal and vertical radius of the ellipse.
+func (e EyedropperSettings) PolarRadius() (float64, float64) {
+	if e.RadiusY == 0 {
+		return e.RadiusX, e.RadiusX
+	}
+	return e.RadiusX, e.RadiusY
+}
+
+// Validate returns every problem found in the eyedropper settings.
+func (e EyedropperSettings) Validate(path string) []validation.Problem {
+	problems := []validation.Problem{}
+
+	if e.Shape != "" && !e.Shape.IsValid() {
+		shapeNames := []string{}
+		for _, shape := range EyedropperShapes() {
+			shapeNames = append(shapeNames, string(shape))
+		}
+		problems = append(problems, validation.NewProblemf(validation.JoinPath(path, "shape"), "unknown eyedropper shape %q, expected one of: %s", e.Shape, strings.Join(shapeNames, ", ")))
+	}
+
+	if e.IsDomainColoring() {
+		return append(problems, e.validateDomainColoring(path)...)
+	}
+	if e.IsGradient() {
+		return append(problems, e.validateGradient(path)...)
+	}
+
+	if e.Sampling != "" && !e.Sampling.IsValid() {
+		samplingNames := []string{}
+		for _, sampling := range imageoutput.SamplingMethods() {
+			samplingNames = append(samplingNames, string(sampling))
+		}
+		problems = append(problems, validation.NewProblemf(validation.JoinPath(path, "sampling"), "unknown sampling %q, expected one of: %s", e.Sampling, strings.Join(samplingNames, ", ")))
+	}
+
+	if e.IsPolar() {
+		return append(problems, e.validatePolar(path)...)
+	}
+	return append(problems, e.validateRectangular(path)...)
+}
+
+func (e EyedropperSettings) validateRectangular(path string) []validation.Problem {
+	problems := []validation.Problem{}
+
+	if e.EdgeMode != "" && !e.EdgeMode.IsValid() {
+		edgeModeNames := []string{}
+		for _, edgeMode := range imageoutput.EdgeModes() {
+			edgeModeNames = append(edgeModeNames, string(edgeMode))
+		}
+		problems = append(problems, validation.NewProblemf(validation.JoinPath(path, "edge_mode"), "unknown edge mode %q, expected one of: %s", e.EdgeMode, strings.Join(edgeModeNames, ", ")))
+	}
+
+	if e.PeriodX < 0 {
+		problems = append(problems, validation.NewProblem(validation.JoinPath(path, "period_x"), "period_x must be positive"))
+	}
+	if e.PeriodY < 0 {
+		problems = append(problems, validation.NewProblem(validation.JoinPath(path, "period_y"), "period_y must be positive"))
+	}
+	if (e.PeriodX == 0) != (e.PeriodY == 0) {
+		problems = append(problems, validation.NewProblem(path, "period_x and period_y must be used together"))
+	}
+	if e.EdgeMode.IsTiled() && (e.PeriodX == 0 || e.PeriodY == 0) {
+		problems = append(problems, validation.NewProblemf(validation.JoinPath(path, "edge_mode"), "%s edge mode needs period_x and period_y", e.EdgeMode))
+	}
+
+	curveFields := []struct {
+		field string
+		curve imageoutput.MappingCurve
+	}{
+		{field: "curve_x", curve: e.CurveX},
+		{field: "curve_y", curve: e.CurveY},
+	}
+	for _, curveField := range curveFields {
+		if curveField.curve == "" {
+			continue
+		}
+		if !curveField.curve.IsValid() {
+			curveNames := []string{}
+			for _, curve := range imageoutput.MappingCurves() {
+				curveNames = append(curveNames, string(curve))
+			}
+			problems = append(problems, validation.NewProblemf(validation.JoinPath(path, curveField.field), "unknown mapping curve %q, expected one of: %s", curveField.curve, strings.Join(curveNames, ", ")))
+			continue
+		}
+		if e.PeriodX != 0 && e.PeriodY != 0 {
+			problems = append(problems, validation.NewProblemf(validation.JoinPath(path, curveField.field), "%s is not used with period_x and period_y", curveField.field))
+		}
+	}
+
+	polarFields := []struct {
+		field string
+		isSet bool
+	}{
+		{field: "center_x", isSet: e.CenterX != 0},
+		{field: "center_y", isSet: e.CenterY != 0},
+		{field: "radius_x", isSet: e.RadiusX != 0},
+		{field: "radius_y", isSet: e.RadiusY != 0},
+		{field: "maximum_modulus", isSet: e.MaximumModulus != 0},
+	}
+	for _, polarField := range polarFields {
+		if polarField.isSet {
+			problems = append(problems, validation.NewProblemf(validation.JoinPath(path, polarField.field), "%s is only used by polar eyedroppers, add shape: polar", polarField.field))
+		}
+	}
+	return append(problems, e.validateUnusedShapeFields(path)...)
+}
+
+func (e EyedropperSettings) validatePolar(path string) []validation.Problem {
+	problems := []validation.Problem{}
+
+	if e.RadiusX <= 0 {
+		problems = append(problems, validation.NewProblem(validation.JoinPath(path, "radius_x"), "polar eyedroppers need a positive radius_x"))
+	}
+	if e.RadiusY < 0 {
+		problems = append(problems, validation.NewProblem(validation.JoinPath(path, "radius_y"), "radius_y must be positive, leave it out to use radius_x"))
+	}
+	if e.MaximumModulus < 0 {
+		problems = append(problems, validation.NewProblem(validation.JoinPath(path, "maximum_modulus"), "maximum_modulus must be positive, leave it out to use the largest modulus"))
+	}
+
+	rectangularFields := []struct {
+		field string
+		isSet bool
+	}{
+		{field: "left", isSet: e.LeftSide != 0},
+		{field: "right", isSet: e.RightSide != 0},
+		{field: "top", isSet: e.TopSide != 0},
+		{field: "bottom", isSet: e.BottomSide != 0},
+		{field: "edge_mode", isSet: e.EdgeMode != ""},
+		{field: "period_x", isSet: e.PeriodX != 0},
+		{field: "period_y", isSet: e.PeriodY != 0},
+		{field: "curve_x", isSet: e.CurveX != ""},
+		{field: "curve_y", isSet: e.CurveY != ""},
+	}
+	for _, rectangularField := range rectangularFields {
+		if rectangularField.isSet {
+			problems = append(problems, validation.NewProblemf(validation.JoinPath(path, rectangularField.field), "%s is only used by rectangular eyedroppers", rectangularField.field))
+		}
+	}
+	return append(problems, e.validateUnusedShapeFields(path)...)
+}
+
+// validateUnusedShapeFields reports fields that are only used by another shape of eyedropper,
+//   like contours set on an eyedropper that samples the source image.
+func (e EyedropperSettings) validateUnusedShapeFields(path string) []validation.Problem {
+	problems := []validation.Problem{}
+	shapeFields := []struct {
+		field     string
+		isSet     bool
+		shape     EyedropperShape
+		shapeName string
+	}{
+		{field: "modulus_contours", isSet: e.ModulusContours, shape: DomainColoringEyedropperShape, shapeName: "domain coloring"},
+		{field: "phase_contours", isSet: e.PhaseContours != 0, shape: DomainColoringEyedropperShape, shapeName: "domain coloring"},
+		{field: "scalar", isSet: e.Scalar != "", shape: GradientEyedropperShape, shapeName: "gradient"},
+		{field: "scalar_min", isSet: e.ScalarMin != 0, shape: GradientEyedropperShape, shapeName: "gradient"},
+		{field: "scalar_max", isSet: e.ScalarMax != 0, shape: GradientEyedropperShape, shapeName: "gradient"},
+		{field: "stops", isSet: len(e.Stops) > 0, shape: GradientEyedropperShape, shapeName: "gradient"},
+	}
+	for _, shapeField := range shapeFields {
+		if shapeField.isSet && e.Shape != shapeField.shape {
+			problems = append(problems, validation.NewProblemf(validation.JoinPath(path, shapeField.field), "%s is only used by %s eyedroppers, add shape: %s", shapeField.field, shapeField.shapeName, shapeField.shape))
+		}
+	}
+	return problems
+}
+
+// validateUnusedSourceImageFields reports fields that choose how the source image is sampled,
+//   for eyedroppers that do not sample it. shapeName describes the eyedropper in the message.
+func (e EyedropperSettings) validateUnusedSourceImageFields(path, shapeName string) []validation.Problem {
+	problems := []validation.Problem{}
+	sourceImageFields := []struct {
+		field string
+		isSet bool
+	}{
+		{field: "left", isSet: e.LeftSide != 0},
+		{field: "right", isSet: e.RightSide != 0},
+		{field: "top", isSet: e.TopSide != 0},
+		{field: "bottom", isSet: e.BottomSide != 0},
+		{field: "sampling", isSet: e.Sampling != ""},
+		{field: "edge_mode", isSet: e.EdgeMode != ""},
+		{field: "period_x", isSet: e.PeriodX != 0},
+		{field: "period_y", isSet: e.PeriodY != 0},
+		{field: "curve_x", isSet: e.CurveX != ""},
+		{field: "curve_y", isSet: e.CurveY != ""},
+		{field: "center_x", isSet: e.CenterX != 0},
+		{field: "center_y", isSet: e.CenterY != 0},
+		{field: "radius_x", isSet: e.RadiusX != 0},
+		{field: "radius_y", isSet: e.RadiusY != 0},
+		{field: "maximum_modulus", isSet: e.MaximumModulus != 0},
+	}
+	for _, sourceImageField := range sourceImageFields {
+		if sourceImageField.isSet {
+			problems = append(problems, validation.NewProblemf(validation.JoinPath(path, sourceImageField.field), "%s is not used by %s eyedroppers", sourceImageField.field, shapeName))
+		}
+	}
+	return append(problems, e.validateUnusedShapeFields(path)...)
+}
+
+func (e EyedropperSettings) validateDomainColoring(path string) []validation.Problem {
+	problems := []validation.Problem{}
+
+	if e.PhaseContours < 0 {
+		problems = append(problems, validation.NewProblem(validation.JoinPath(path, "phase_contours"), "phase_contours must be positive, leave it out for no phase contours"))
+	}
+	return append(problems, e.validateUnusedSourceImageFields(path, "domain coloring")...)
+}
+
+func (e EyedropperSettings) validateGradient(path string) []validation.Problem {
+	problems := []validation.Problem{}
+
+	if e.Scalar != "" && !e.Scalar.IsValid() {
+		scalarNames := []string{}
+		for _, scalar := range imageoutput.GradientScalars() {
+			scalarNames = append(scalarNames, string(scalar))
+		}
+		problems = append(problems, validation.NewProblemf(validation.JoinPath(path, "scalar"), "unknown gradient scalar %q, expected one of: %s", e.Scalar, strings.Join(scalarNames, ", ")))
+	}
+
+	if (e.ScalarMin != 0 || e.ScalarMax != 0) && e.ScalarMin >= e.ScalarMax {
+		problems = append(problems, validation.NewProblem(validation.JoinPath(path, "scalar_max"), "scalar_max must be larger than scalar_min"))
+	}
+
+	stopsPath := validation.JoinPath(path, "stops")
+	if len(e.Stops) < 2 {
+		problems = append(problems, validation.NewProblem(stopsPath, "gradient eyedroppers need at least 2 stops"))
+	}
+	for stopIndex, stop := range e.Stops {
+		stopPath := validation.IndexPath(stopsPath, stopIndex)
+		if stop.Position < 0 || stop.Position > 1 {
+			problems = append(problems, validation.NewProblem(validation.JoinPath(stopPath, "position"), "position must be from 0 to 1"))
+		} else if stopIndex > 0 && stop.Position < e.Stops[stopIndex-1].Position {
+			problems = append(problems, validation.NewProblem(validation.JoinPath(stopPath, "position"), "stops must be in order of position"))
+		}
+		if _, err := stop.StopColor(); err != nil {
+			problems = append(problems, validation.NewProblem(validation.JoinPath(stopPath, "color"), err.Error()))
+		}
+	}
+	return append(problems, e.validateUnusedSourceImageFields(path, "gradient")...)
+}
+
+// EyedropperRange chooses the part of transformed space that reaches the sides of a rectangular eyedropper.
+//   XMin, XMax, YMin and YMax are a fixed rectangle, so the colors do not depend on the output size or viewport.
+//   LowerPercentile and UpperPercentile use percentiles of the transformed coordinates instead,
+//   so a few coordinates near a pole do not stretch the range. An UpperPercentile of 0 means 100.
+//   Polar eyedroppers only use UpperPercentile, the percentile of the modulus that reaches the rim.
+type EyedropperRange struct {
+	XMin float64 `json:"x_min,omitempty" yaml:"x_min,omitempty"`
+	YMin float64 `json:"y_min,omitempty" yaml:"y_min,omitempty"`
+	XMax float64 `json:"x_max,omitempty" yaml:"x_max,omitempty"`
+	YMax float64 `json:"y_max,omitempty" yaml:"y_max,omitempty"`
+
+	LowerPercentile float64 `json:"lower_percentile,omitempty" yaml:"lower_percentile,omitempty"`
+	UpperPercentile float64 `json:"upper_percentile,omitempty" yaml:"upper_percentile,omitempty"`
+}
+
+// UsesPercentiles returns true if the range comes from percentiles instead of a fixed rectangle.
+func (r EyedropperRange) UsesPercentiles() bool {
+	return r.LowerPercentile != 0 || r.UpperPercentile != 0
+}
+
+// Percentiles returns the lower and upper percentiles of the range.
+func (r EyedropperRange) Percentiles() (float64, float64) {
+	return percentilesWithDefaults(r.LowerPercentile, r.UpperPercentile)
+}
+
+// Validate returns every problem found in the range, given the eyedropper it is used by.
+//   eyedropper is nil when the default eyedropper is used.
+func (r EyedropperRange) Validate(path string, eyedropper *EyedropperSettings) []validation.Problem {
+	if eyedropper != nil && eyedropper.IsDomainColoring() {
+		return []validation.Problem{
+			validation.NewProblem(path, "eyedropper_range is not used by domain coloring eyedroppers"),
+		}
+	}
+	if eyedropper != nil && eyedropper.IsGradient() {
+		return []validation.Problem{
+			validation.NewProblem(path, "eyedropper_range is not used by gradient eyedroppers, use scalar_min and scalar_max"),
+		}
+	}
+	if eyedropper != nil && !eyedropper.IsPolar() && eyedropper.PeriodX != 0 && eyedropper.PeriodY != 0 {
+		return []validation.Problem{
+			validation.NewProblem(path, "eyedropper_range is not used when the eyedropper has period_x and period_y"),
+		}
+	}
+
+	if r.UsesPercentiles() {
+		return r.validatePercentiles(path, eyedropper)
+	}
+
+	if eyedropper != nil && eyedropper.IsPolar() {
+		return []validation.Problem{
+			validation.NewProblem(path, "polar eyedroppers only use upper_percentile, use maximum_modulus for a fixed range"),
+		}
+	}
+
+	problems := []validation.Problem{}
+	if r.XMin >= r.XMax {
+		problems = append(problems, validation.NewProblem(validation.JoinPath(path, "x_max"), "x_max must be larger than x_min"))
+	}
+	if r.YMin >= r.YMax {
+		problems = append(problems, validation.NewProblem(validation.JoinPath(path, "y_max"), "y_max must be larger than y_min"))
+	}
+	return problems
+}
+
+func (r EyedropperRange) validatePercentiles(path string, eyedropper *EyedropperSettings) []validation.Problem {
+	problems := []validation.Problem{}
+	rectangleFields := []struct {
+		field string
+		isSet bool
+	}{
+		{field: "x_min", isSet: r.XMin != 0},
+		{field: "x_max", isSet: r.XMax != 0},
+		{field: "y_min", isSet: r.YMin != 0},
+		{field: "y_max", isSet: r.YMax != 0},
+	}
+	for _, rectangleField := range rectangleFields {
+		if rectangleField.isSet {
+			problems = append(problems, validation.NewProblemf(validation.JoinPath(path, rectangleField.field), "%s is not used with percentiles", rectangleField.field))
+		}
+	}
+
+	if eyedropper != nil && eyedropper.IsPolar() {
+		if r.LowerPercentile != 0 {
+			problems = append(problems, validation.NewProblem(validation.JoinPath(path, "lower_percentile"), "lower_percentile is not used by polar eyedroppers"))
+		}
+		if eyedropper.MaximumModulus != 0 {
+			problems = append(problems, validation.NewProblem(validation.JoinPath(path, "upper_percentile"), "upper_percentile is not used when the eyedropper has maximum_modulus"))
+		}
+	}
+	return append(problems, validatePercentiles(path, r.LowerPercentile, r.UpperPercentile)...)
+}
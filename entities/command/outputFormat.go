@@ -0,0 +1,92 @@
+package command
+
+import (
+	"image/jpeg"
+	"image/png"
+	"path/filepath"
+	"strings"
+)
+
+// OutputFormat names the image format used to encode the output.
+type OutputFormat string
+
+// Supported output formats.
+const (
+	PNGFormat  OutputFormat = "png"
+	JPEGFormat OutputFormat = "jpeg"
+	GIFFormat  OutputFormat = "gif"
+)
+
+// OutputFormats lists every supported output format.
+func OutputFormats() []OutputFormat {
+	return []OutputFormat{PNGFormat, JPEGFormat, GIFFormat}
+}
+
+// IsValid returns true if the format is supported.
+func (o OutputFormat) IsValid() bool {
+	for _, format := range OutputFormats() {
+		if o == format {
+			return true
+		}
+	}
+	return false
+}
+
+// OutputFormatForFilename guesses the format using the filename's extension.
+//   Returns false if the extension is not recognized.
+func OutputFormatForFilename(filename string) (OutputFormat, bool) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".png":
+		return PNGFormat, true
+	case ".jpg", ".jpeg":
+		return JPEGFormat, true
+	case ".gif":
+		return GIFFormat, true
+	}
+	return "", false
+}
+
+// PNGCompression names how hard the PNG encoder tries to shrink the file.
+type PNGCompression string
+
+// Supported PNG compression levels.
+const (
+	PNGDefaultCompression PNGCompression = "default"
+	PNGNoCompression      PNGCompression = "none"
+	PNGBestSpeed          PNGCompression = "best_speed"
+	PNGBestCompression    PNGCompression = "best_compression"
+)
+
+var pngCompressionLevels = map[PNGCompression]png.CompressionLevel{
+	PNGDefaultCompression: png.DefaultCompression,
+	PNGNoCompression:      png.NoCompression,
+	PNGBestSpeed:          png.BestSpeed,
+	PNGBestCompression:    png.BestCompression,
+}
+
+// PNGCompressions lists every supported PNG compression level.
+func PNGCompressions() []PNGCompression {
+	return []PNGCompression{PNGDefaultCompression, PNGNoCompression, PNGBestSpeed, PNGBestCompression}
+}
+
+// IsValid returns true if the compression level is supported.
+func (p PNGCompression) IsValid() bool {
+	_, found := pngCompressionLevels[p]
+	return found
+}
+
+// Level returns the level used by the png package.
+func (p PNGCompression) Level() png.CompressionLevel {
+	return pngCompressionLevels[p]
+}
+
+// Limits and defaults for the lossy formats.
+const (
+	MinimumJPEGQuality = 1
+	MaximumJPEGQuality = 100
+	DefaultJPEGQuality = jpeg.DefaultQuality
+
+	MinimumGIFColors = 2
+	MaximumGIFColors = 256
+	DefaultGIFColors = 256
+)
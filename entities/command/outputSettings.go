@@ -3,20 +3,51 @@ package command
 import (
 	"encoding/json"
 	"github.com/chadius/creatingsymmetry/entities/utility"
+	"github.com/chadius/creatingsymmetry/entities/validation"
 	"gopkg.in/yaml.v2"
+	"image/png"
+	"strings"
+)
+
+// Supersampling limits. Each pixel takes up to MaximumSupersampling by MaximumSupersampling samples.
+const (
+	MinimumSupersampling = 1
+	MaximumSupersampling = 16
 )
 
 // OutputSettingsBuilder is used to create output settings objects.
 type OutputSettingsBuilder struct {
-	outputWidth  int
-	outputHeight int
+	outputWidth    int
+	outputHeight   int
+	workerCount    int
+	outputFormat   OutputFormat
+	pngCompression PNGCompression
+	jpegQuality    int
+	gifColors      int
+
+	supersampling         int
+	adaptiveSupersampling bool
+	adaptiveThreshold     float64
+
+	streaming bool
 }
 
 // NewOutputSettingsBuilder returns a new object used to Build Formula objects.
 func NewOutputSettingsBuilder() *OutputSettingsBuilder {
 	return &OutputSettingsBuilder{
-		outputWidth:  0,
-		outputHeight: 0,
+		outputWidth:    0,
+		outputHeight:   0,
+		workerCount:    0,
+		outputFormat:   PNGFormat,
+		pngCompression: PNGDefaultCompression,
+		jpegQuality:    DefaultJPEGQuality,
+		gifColors:      DefaultGIFColors,
+
+		supersampling:         MinimumSupersampling,
+		adaptiveSupersampling: false,
+		adaptiveThreshold:     0,
+
+		streaming: false,
 	}
 }
 
@@ -38,10 +69,97 @@ func (b *OutputSettingsBuilder) OutputHeight(height int) *OutputSettingsBuilder
 	return b
 }
 
+// WorkerCount sets the number of workers used to render the image. 0 uses one worker per available CPU.
+func (b *OutputSettingsBuilder) WorkerCount(workerCount int) *OutputSettingsBuilder {
+	if workerCount < 0 {
+		return b
+	}
+	b.workerCount = workerCount
+	return b
+}
+
+// OutputFormat sets the format used to encode the image. Unknown formats are ignored.
+func (b *OutputSettingsBuilder) OutputFormat(format OutputFormat) *OutputSettingsBuilder {
+	if !format.IsValid() {
+		return b
+	}
+	b.outputFormat = format
+	return b
+}
+
+// PNGCompression sets how hard PNG images are compressed. Unknown levels are ignored.
+func (b *OutputSettingsBuilder) PNGCompression(compression PNGCompression) *OutputSettingsBuilder {
+	if !compression.IsValid() {
+		return b
+	}
+	b.pngCompression = compression
+	return b
+}
+
+// JPEGQuality sets the quality of JPEG images, from 1 to 100. Other values are ignored.
+func (b *OutputSettingsBuilder) JPEGQuality(quality int) *OutputSettingsBuilder {
+	if quality < MinimumJPEGQuality || quality > MaximumJPEGQuality {
+		return b
+	}
+	b.jpegQuality = quality
+	return b
+}
+
+// GIFColors sets the number of colors in GIF images, from 2 to 256. Other values are ignored.
+func (b *OutputSettingsBuilder) GIFColors(colors int) *OutputSettingsBuilder {
+	if colors < MinimumGIFColors || colors > MaximumGIFColors {
+		return b
+	}
+	b.gifColors = colors
+	return b
+}
+
+// Supersampling sets how many samples are taken along each side of a pixel, from 1 to 16. Other values are ignored.
+func (b *OutputSettingsBuilder) Supersampling(samplesPerSide int) *OutputSettingsBuilder {
+	if samplesPerSide < MinimumSupersampling || samplesPerSide > MaximumSupersampling {
+		return b
+	}
+	b.supersampling = samplesPerSide
+	return b
+}
+
+// AdaptiveSupersampling only supersamples pixels whose neighbors transform to very different coordinates.
+func (b *OutputSettingsBuilder) AdaptiveSupersampling(adaptive bool) *OutputSettingsBuilder {
+	b.adaptiveSupersampling = adaptive
+	return b
+}
+
+// AdaptiveThreshold sets the fraction of the transformed range that counts as very different.
+//   Values that are not positive are ignored.
+func (b *OutputSettingsBuilder) AdaptiveThreshold(threshold float64) *OutputSettingsBuilder {
+	if threshold <= 0 {
+		return b
+	}
+	b.adaptiveThreshold = threshold
+	return b
+}
+
+// Streaming renders and writes a few rows at a time, so very large images fit in memory.
+func (b *OutputSettingsBuilder) Streaming(streaming bool) *OutputSettingsBuilder {
+	b.streaming = streaming
+	return b
+}
+
 // OutputSettingsBuilderMarshal can be marshaled and converted to a OutputSettingsBuilder
 type OutputSettingsBuilderMarshal struct {
-	OutputWidth  int `json:"output_width" yaml:"output_width"`
-	OutputHeight int `json:"output_height" yaml:"output_height"`
+	OutputWidth    int            `json:"output_width" yaml:"output_width"`
+	OutputHeight   int            `json:"output_height" yaml:"output_height"`
+	WorkerCount    int            `json:"worker_count,omitempty" yaml:"worker_count,omitempty"`
+	OutputFormat   OutputFormat   `json:"output_format,omitempty" yaml:"output_format,omitempty"`
+	PNGCompression PNGCompression `json:"png_compression,omitempty" yaml:"png_compression,omitempty"`
+	JPEGQuality    int            `json:"jpeg_quality,omitempty" yaml:"jpeg_quality,omitempty"`
+	GIFColors      int            `json:"gif_colors,omitempty" yaml:"gif_colors,omitempty"`
+
+	Supersampling         int     `json:"supersampling,omitempty" yaml:"supersampling,omitempty"`
+	AdaptiveSupersampling bool    `json:"adaptive_supersampling,omitempty" yaml:"adaptive_supersampling,omitempty"`
+	AdaptiveThreshold     float64 `json:"adaptive_threshold,omitempty" yaml:"adaptive_threshold,omitempty"`
+
+	Streaming bool `json:"streaming,omitempty" yaml:"streaming,omitempty"`
 }
 
 // WithYAML consumes the yaml byte stream to fill settings.
@@ -56,6 +174,12 @@ func (b *OutputSettingsBuilder) WithJSON(byteStream []byte) *OutputSettingsBuild
 	return b.setOutputSettingsUsingMarshal(marshalSettings)
 }
 
+// WithData consumes the byte stream to fill settings, detecting if it is JSON or YAML.
+func (b *OutputSettingsBuilder) WithData(byteStream []byte) *OutputSettingsBuilder {
+	marshalSettings := newOutputSettingsBuilderMarshalFromByteStream(byteStream, utility.DetectUnmarshalFunc(byteStream))
+	return b.setOutputSettingsUsingMarshal(marshalSettings)
+}
+
 func newOutputSettingsBuilderMarshalFromByteStream(data []byte, unmarshal utility.UnmarshalFunc) *OutputSettingsBuilderMarshal {
 	var unmarshalError error
 	var marshalSettings OutputSettingsBuilderMarshal
@@ -68,23 +192,183 @@ func newOutputSettingsBuilderMarshalFromByteStream(data []byte, unmarshal utilit
 }
 
 func (b *OutputSettingsBuilder) setOutputSettingsUsingMarshal(marshalSettings *OutputSettingsBuilderMarshal) *OutputSettingsBuilder {
+	if marshalSettings == nil {
+		return b
+	}
 	b.OutputHeight(marshalSettings.OutputHeight)
 	b.OutputWidth(marshalSettings.OutputWidth)
+	b.WorkerCount(marshalSettings.WorkerCount)
+	b.OutputFormat(marshalSettings.OutputFormat)
+	b.PNGCompression(marshalSettings.PNGCompression)
+	b.JPEGQuality(marshalSettings.JPEGQuality)
+	b.GIFColors(marshalSettings.GIFColors)
+	b.Supersampling(marshalSettings.Supersampling)
+	b.AdaptiveSupersampling(marshalSettings.AdaptiveSupersampling)
+	b.AdaptiveThreshold(marshalSettings.AdaptiveThreshold)
+	b.Streaming(marshalSettings.Streaming)
 	return b
 }
 
+// Validate returns every problem found in the settings.
+func (m OutputSettingsBuilderMarshal) Validate() []validation.Problem {
+	problems := []validation.Problem{}
+	if m.OutputWidth <= 0 {
+		problems = append(problems, validation.NewProblem("output_width", "output_width must be a positive number of pixels"))
+	}
+	if m.OutputHeight <= 0 {
+		problems = append(problems, validation.NewProblem("output_height", "output_height must be a positive number of pixels"))
+	}
+	if m.WorkerCount < 0 {
+		problems = append(problems, validation.NewProblem("worker_count", "worker_count must not be negative, use 0 to use one worker per available CPU"))
+	}
+	problems = append(problems, m.validateSupersampling()...)
+	return append(problems, m.validateOutputFormat()...)
+}
+
+func (m OutputSettingsBuilderMarshal) validateSupersampling() []validation.Problem {
+	problems := []validation.Problem{}
+	if m.Supersampling != 0 && (m.Supersampling < MinimumSupersampling || m.Supersampling > MaximumSupersampling) {
+		problems = append(problems, validation.NewProblemf("supersampling", "supersampling must be between %d and %d", MinimumSupersampling, MaximumSupersampling))
+	}
+	if m.AdaptiveSupersampling && m.Supersampling < 2 {
+		problems = append(problems, validation.NewProblem("adaptive_supersampling", "adaptive_supersampling needs supersampling to be at least 2"))
+	}
+	if m.AdaptiveThreshold < 0 {
+		problems = append(problems, validation.NewProblem("adaptive_threshold", "adaptive_threshold must be a positive fraction of the transformed range"))
+	}
+	if m.AdaptiveThreshold != 0 && !m.AdaptiveSupersampling {
+		problems = append(problems, validation.NewProblem("adaptive_threshold", "adaptive_threshold is only used when adaptive_supersampling is true"))
+	}
+	if m.AdaptiveSupersampling && m.Streaming {
+		problems = append(problems, validation.NewProblem("adaptive_supersampling", "adaptive_supersampling cannot be used with streaming, it compares pixels across the whole image"))
+	}
+	return problems
+}
+
+func (m OutputSettingsBuilderMarshal) validateOutputFormat() []validation.Problem {
+	problems := []validation.Problem{}
+
+	outputFormat := m.OutputFormat
+	if outputFormat == "" {
+		outputFormat = PNGFormat
+	}
+	if !outputFormat.IsValid() {
+		formatNames := []string{}
+		for _, format := range OutputFormats() {
+			formatNames = append(formatNames, string(format))
+		}
+		problems = append(problems, validation.NewProblemf("output_format", "unknown output format %q, expected one of: %s", m.OutputFormat, strings.Join(formatNames, ", ")))
+	}
+
+	if m.PNGCompression != "" && !m.PNGCompression.IsValid() {
+		compressionNames := []string{}
+		for _, compression := range PNGCompressions() {
+			compressionNames = append(compressionNames, string(compression))
+		}
+		problems = append(problems, validation.NewProblemf("png_compression", "unknown png compression %q, expected one of: %s", m.PNGCompression, strings.Join(compressionNames, ", ")))
+	}
+	if m.JPEGQuality != 0 && (m.JPEGQuality < MinimumJPEGQuality || m.JPEGQuality > MaximumJPEGQuality) {
+		problems = append(problems, validation.NewProblemf("jpeg_quality", "jpeg_quality must be between %d and %d", MinimumJPEGQuality, MaximumJPEGQuality))
+	}
+	if m.GIFColors != 0 && (m.GIFColors < MinimumGIFColors || m.GIFColors > MaximumGIFColors) {
+		problems = append(problems, validation.NewProblemf("gif_colors", "gif_colors must be between %d and %d", MinimumGIFColors, MaximumGIFColors))
+	}
+
+	fieldFormats := []struct {
+		field  string
+		isSet  bool
+		format OutputFormat
+	}{
+		{field: "png_compression", isSet: m.PNGCompression != "", format: PNGFormat},
+		{field: "jpeg_quality", isSet: m.JPEGQuality != 0, format: JPEGFormat},
+		{field: "gif_colors", isSet: m.GIFColors != 0, format: GIFFormat},
+		{field: "streaming", isSet: m.Streaming, format: PNGFormat},
+	}
+	for _, fieldFormat := range fieldFormats {
+		if fieldFormat.isSet && outputFormat.IsValid() && outputFormat != fieldFormat.format {
+			problems = append(problems, validation.NewProblemf(fieldFormat.field, "%s is only used by %s output, not %s", fieldFormat.field, fieldFormat.format, outputFormat))
+		}
+	}
+	return problems
+}
+
+// NewOutputSettingsFromYAML reads the YAML data and returns OutputSettings from it.
+//   If the data is invalid, the error is a *validation.Error listing every problem found.
+func NewOutputSettingsFromYAML(data []byte) (*OutputSettings, error) {
+	return newOutputSettingsFromDatastream(data, validation.ParseYAML)
+}
+
+// NewOutputSettingsFromJSON reads the JSON data and returns OutputSettings from it.
+//   If the data is invalid, the error is a *validation.Error listing every problem found.
+func NewOutputSettingsFromJSON(data []byte) (*OutputSettings, error) {
+	return newOutputSettingsFromDatastream(data, validation.ParseJSON)
+}
+
+// NewOutputSettingsFromData reads the data, detects if it is JSON or YAML and returns OutputSettings from it.
+//   If the data is invalid, the error is a *validation.Error listing every problem found.
+func NewOutputSettingsFromData(data []byte) (*OutputSettings, error) {
+	return newOutputSettingsFromDatastream(data, validation.Parse)
+}
+
+func newOutputSettingsFromDatastream(data []byte, parse validation.DocumentParser) (*OutputSettings, error) {
+	document, parseError := parse(data)
+	if parseError != nil {
+		return nil, parseError
+	}
+
+	var marshalSettings OutputSettingsBuilderMarshal
+	problems := document.CheckFields(marshalSettings)
+	if len(problems) > 0 {
+		return nil, &validation.Error{Problems: document.Locate(problems)}
+	}
+
+	unmarshalError := document.Decode(&marshalSettings)
+	if unmarshalError != nil {
+		return nil, unmarshalError
+	}
+
+	problems = marshalSettings.Validate()
+	if len(problems) > 0 {
+		return nil, &validation.Error{Problems: document.Locate(problems)}
+	}
+
+	return NewOutputSettingsBuilder().setOutputSettingsUsingMarshal(&marshalSettings).Build(), nil
+}
+
 // Build creates OutputSettings using the builder settings.
 func (b *OutputSettingsBuilder) Build() *OutputSettings {
 	return &OutputSettings{
-		outputWidth:  b.outputWidth,
-		outputHeight: b.outputHeight,
+		outputWidth:    b.outputWidth,
+		outputHeight:   b.outputHeight,
+		workerCount:    b.workerCount,
+		outputFormat:   b.outputFormat,
+		pngCompression: b.pngCompression,
+		jpegQuality:    b.jpegQuality,
+		gifColors:      b.gifColors,
+
+		supersampling:         b.supersampling,
+		adaptiveSupersampling: b.adaptiveSupersampling,
+		adaptiveThreshold:     b.adaptiveThreshold,
+
+		streaming: b.streaming,
 	}
 }
 
 // OutputSettings stores how to render the transformation.
 type OutputSettings struct {
-	outputWidth  int
-	outputHeight int
+	outputWidth    int
+	outputHeight   int
+	workerCount    int
+	outputFormat   OutputFormat
+	pngCompression PNGCompression
+	jpegQuality    int
+	gifColors      int
+
+	supersampling         int
+	adaptiveSupersampling bool
+	adaptiveThreshold     float64
+
+	streaming bool
 }
 
 // OutputWidth gets the output outputWidth.
@@ -96,3 +380,48 @@ func (o *OutputSettings) OutputWidth() int {
 func (o *OutputSettings) OutputHeight() int {
 	return o.outputHeight
 }
+
+// WorkerCount is a getter. 0 means one worker per available CPU.
+func (o *OutputSettings) WorkerCount() int {
+	return o.workerCount
+}
+
+// OutputFormat is a getter.
+func (o *OutputSettings) OutputFormat() OutputFormat {
+	return o.outputFormat
+}
+
+// PNGCompressionLevel returns the compression level used to encode PNG images.
+func (o *OutputSettings) PNGCompressionLevel() png.CompressionLevel {
+	return o.pngCompression.Level()
+}
+
+// JPEGQuality is a getter.
+func (o *OutputSettings) JPEGQuality() int {
+	return o.jpegQuality
+}
+
+// GIFColors is a getter.
+func (o *OutputSettings) GIFColors() int {
+	return o.gifColors
+}
+
+// Supersampling is a getter. It is the number of samples taken along each side of a pixel.
+func (o *OutputSettings) Supersampling() int {
+	return o.supersampling
+}
+
+// AdaptiveSupersampling is a getter.
+func (o *OutputSettings) AdaptiveSupersampling() bool {
+	return o.adaptiveSupersampling
+}
+
+// AdaptiveThreshold is a getter. 0 means the transformer's default threshold.
+func (o *OutputSettings) AdaptiveThreshold() float64 {
+	return o.adaptiveThreshold
+}
+
+// Streaming is a getter. Streamed images are rendered and written a few rows at a time.
+func (o *OutputSettings) Streaming() bool {
+	return o.streaming
+}
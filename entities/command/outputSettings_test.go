@@ -3,6 +3,7 @@ package command_test
 import (
 	"github.com/chadius/creatingsymmetry/entities/command"
 	. "gopkg.in/check.v1"
+	"image/png"
 )
 
 type CreateOutputSettings struct {
@@ -17,6 +18,14 @@ func (suite *CreateOutputSettings) TestBuildOutputSettings(checker *C) {
 	checker.Assert(settings.OutputHeight(), Equals, 20)
 }
 
+func (suite *CreateOutputSettings) TestWorkerCountDefaultsToZero(checker *C) {
+	settings := command.NewOutputSettingsBuilder().Build()
+	checker.Assert(settings.WorkerCount(), Equals, 0)
+
+	settings = command.NewOutputSettingsBuilder().WorkerCount(4).WorkerCount(-2).Build()
+	checker.Assert(settings.WorkerCount(), Equals, 4)
+}
+
 func (suite *CreateOutputSettings) TestBuildOutputIgnoresInvalidOutputWidthHeight(checker *C) {
 	settings := command.NewOutputSettingsBuilder().OutputWidth(-100).OutputHeight(-20).Build()
 
@@ -45,3 +54,194 @@ func (suite *CreateOutputSettings) TestCreateFromJSONWithBuilder(checker *C) {
 	checker.Assert(settings.OutputWidth(), Equals, 150)
 	checker.Assert(settings.OutputHeight(), Equals, 40)
 }
+
+func (suite *CreateOutputSettings) TestCreateFromDataWithBuilder(checker *C) {
+	jsonByteStream := []byte("{\n\t\"output_width\": 150,\n\t\"output_height\": 40\n}")
+	settings := command.NewOutputSettingsBuilder().WithData(jsonByteStream).Build()
+
+	checker.Assert(settings.OutputWidth(), Equals, 150)
+	checker.Assert(settings.OutputHeight(), Equals, 40)
+}
+
+func (suite *CreateOutputSettings) TestCreateFromDataWithValidation(checker *C) {
+	settings, err := command.NewOutputSettingsFromData([]byte("output_width: 150\noutput_height: 40\nworker_count: 3\n"))
+	checker.Assert(err, IsNil)
+
+	checker.Assert(settings.OutputWidth(), Equals, 150)
+	checker.Assert(settings.OutputHeight(), Equals, 40)
+	checker.Assert(settings.WorkerCount(), Equals, 3)
+}
+
+func (suite *CreateOutputSettings) TestNegativeWorkerCountIsInvalid(checker *C) {
+	_, err := command.NewOutputSettingsFromYAML([]byte("output_width: 150\noutput_height: 40\nworker_count: -1\n"))
+	checker.Assert(err, ErrorMatches, "found 1 problem:\nline 3: worker_count: worker_count must not be negative.*")
+}
+
+func (suite *CreateOutputSettings) TestInvalidDataDoesNotPanicWithBuilder(checker *C) {
+	settings := command.NewOutputSettingsBuilder().WithJSON([]byte("{ not json")).Build()
+
+	checker.Assert(settings.OutputWidth(), Equals, 0)
+	checker.Assert(settings.OutputHeight(), Equals, 0)
+}
+
+func (suite *CreateOutputSettings) TestValidationReportsUnknownFieldsAndSizes(checker *C) {
+	_, err := command.NewOutputSettingsFromYAML([]byte("output_width: 150\noutput_hieght: 40\n"))
+	checker.Assert(err, ErrorMatches, `(?s)found 1 problem:\nline 2: output_hieght: unknown field "output_hieght".*`)
+
+	_, err = command.NewOutputSettingsFromJSON([]byte("{\n\"output_width\": 0,\n\"output_height\": -5\n}"))
+	checker.Assert(err, ErrorMatches, "found 2 problems:\nline 2: output_width: .*\nline 3: output_height: .*")
+}
+
+func (suite *CreateOutputSettings) TestJSONSizesMustBeWrittenAsWholeNumbers(checker *C) {
+	_, err := command.NewOutputSettingsFromData([]byte("{\n\"output_width\": 10.0,\n\"output_height\": 10\n}"))
+	checker.Assert(err, ErrorMatches, "found 1 problem:\nline 2: output_width: expected a whole number, found 10.0")
+
+	settings, err := command.NewOutputSettingsFromData([]byte("output_width: 10.0\noutput_height: 10\n"))
+	checker.Assert(err, IsNil)
+	checker.Assert(settings.OutputWidth(), Equals, 10)
+}
+
+func (suite *CreateOutputSettings) TestOutputFormatDefaultsToPNG(checker *C) {
+	settings := command.NewOutputSettingsBuilder().Build()
+
+	checker.Assert(settings.OutputFormat(), Equals, command.PNGFormat)
+	checker.Assert(settings.PNGCompressionLevel(), Equals, png.DefaultCompression)
+	checker.Assert(settings.JPEGQuality(), Equals, command.DefaultJPEGQuality)
+	checker.Assert(settings.GIFColors(), Equals, command.DefaultGIFColors)
+}
+
+func (suite *CreateOutputSettings) TestBuilderIgnoresInvalidFormatOptions(checker *C) {
+	settings := command.NewOutputSettingsBuilder().
+		OutputFormat("bmp").
+		PNGCompression("smallest").
+		JPEGQuality(101).
+		GIFColors(1).
+		Build()
+
+	checker.Assert(settings.OutputFormat(), Equals, command.PNGFormat)
+	checker.Assert(settings.PNGCompressionLevel(), Equals, png.DefaultCompression)
+	checker.Assert(settings.JPEGQuality(), Equals, command.DefaultJPEGQuality)
+	checker.Assert(settings.GIFColors(), Equals, command.DefaultGIFColors)
+}
+
+func (suite *CreateOutputSettings) TestReadFormatOptionsFromData(checker *C) {
+	settings, err := command.NewOutputSettingsFromYAML([]byte(`
+output_width: 10
+output_height: 10
+output_format: png
+png_compression: best_speed
+`))
+	checker.Assert(err, IsNil)
+	checker.Assert(settings.PNGCompressionLevel(), Equals, png.BestSpeed)
+
+	settings, err = command.NewOutputSettingsFromJSON([]byte(`{"output_width": 10, "output_height": 10, "output_format": "jpeg", "jpeg_quality": 40}`))
+	checker.Assert(err, IsNil)
+	checker.Assert(settings.OutputFormat(), Equals, command.JPEGFormat)
+	checker.Assert(settings.JPEGQuality(), Equals, 40)
+
+	settings, err = command.NewOutputSettingsFromJSON([]byte(`{"output_width": 10, "output_height": 10, "output_format": "gif", "gif_colors": 16}`))
+	checker.Assert(err, IsNil)
+	checker.Assert(settings.OutputFormat(), Equals, command.GIFFormat)
+	checker.Assert(settings.GIFColors(), Equals, 16)
+}
+
+func (suite *CreateOutputSettings) TestValidationRejectsFormatOptions(checker *C) {
+	_, err := command.NewOutputSettingsFromYAML([]byte(`output_width: 10
+output_height: 10
+output_format: webp
+`))
+	checker.Assert(err, ErrorMatches, `found 1 problem:\nline 3: output_format: unknown output format "webp", expected one of: png, jpeg, gif`)
+
+	_, err = command.NewOutputSettingsFromYAML([]byte(`output_width: 10
+output_height: 10
+jpeg_quality: 120
+gif_colors: 16
+`))
+	checker.Assert(err, ErrorMatches, `found 3 problems:
+line 3: jpeg_quality: jpeg_quality must be between 1 and 100
+line 3: jpeg_quality: jpeg_quality is only used by jpeg output, not png
+line 4: gif_colors: gif_colors is only used by gif output, not png`)
+}
+
+func (suite *CreateOutputSettings) TestSupersamplingDefaultsToOneSample(checker *C) {
+	settings := command.NewOutputSettingsBuilder().Supersampling(0).Supersampling(17).AdaptiveThreshold(-1).Build()
+
+	checker.Assert(settings.Supersampling(), Equals, 1)
+	checker.Assert(settings.AdaptiveSupersampling(), Equals, false)
+	checker.Assert(settings.AdaptiveThreshold(), Equals, 0.0)
+}
+
+func (suite *CreateOutputSettings) TestReadSupersamplingFromData(checker *C) {
+	settings, err := command.NewOutputSettingsFromYAML([]byte(`
+output_width: 10
+output_height: 10
+supersampling: 4
+adaptive_supersampling: true
+adaptive_threshold: 0.05
+`))
+	checker.Assert(err, IsNil)
+	checker.Assert(settings.Supersampling(), Equals, 4)
+	checker.Assert(settings.AdaptiveSupersampling(), Equals, true)
+	checker.Assert(settings.AdaptiveThreshold(), Equals, 0.05)
+}
+
+func (suite *CreateOutputSettings) TestValidationRejectsSupersamplingOptions(checker *C) {
+	_, err := command.NewOutputSettingsFromYAML([]byte(`output_width: 10
+output_height: 10
+supersampling: 20
+`))
+	checker.Assert(err, ErrorMatches, `found 1 problem:\nline 3: supersampling: supersampling must be between 1 and 16`)
+
+	_, err = command.NewOutputSettingsFromYAML([]byte(`output_width: 10
+output_height: 10
+adaptive_supersampling: true
+`))
+	checker.Assert(err, ErrorMatches, `found 1 problem:\nline 3: adaptive_supersampling: adaptive_supersampling needs supersampling to be at least 2`)
+
+	_, err = command.NewOutputSettingsFromYAML([]byte(`output_width: 10
+output_height: 10
+supersampling: 2
+adaptive_threshold: 0.1
+`))
+	checker.Assert(err, ErrorMatches, `found 1 problem:\nline 4: adaptive_threshold: adaptive_threshold is only used when adaptive_supersampling is true`)
+}
+
+func (suite *CreateOutputSettings) TestReadStreamingFromData(checker *C) {
+	settings, err := command.NewOutputSettingsFromYAML([]byte(`output_width: 10
+output_height: 10
+streaming: true
+`))
+	checker.Assert(err, IsNil)
+	checker.Assert(settings.Streaming(), Equals, true)
+	checker.Assert(command.NewOutputSettingsBuilder().Build().Streaming(), Equals, false)
+}
+
+func (suite *CreateOutputSettings) TestValidationRejectsStreamingOptions(checker *C) {
+	_, err := command.NewOutputSettingsFromYAML([]byte(`output_width: 10
+output_height: 10
+output_format: jpeg
+streaming: true
+`))
+	checker.Assert(err, ErrorMatches, `found 1 problem:\nline 4: streaming: streaming is only used by png output, not jpeg`)
+
+	_, err = command.NewOutputSettingsFromYAML([]byte(`output_width: 10
+output_height: 10
+supersampling: 2
+adaptive_supersampling: true
+streaming: true
+`))
+	checker.Assert(err, ErrorMatches, `found 1 problem:\nline 4: adaptive_supersampling: adaptive_supersampling cannot be used with streaming, it compares pixels across the whole image`)
+}
+
+func (suite *CreateOutputSettings) TestOutputFormatForFilename(checker *C) {
+	format, found := command.OutputFormatForFilename("out/preview.JPG")
+	checker.Assert(found, Equals, true)
+	checker.Assert(format, Equals, command.JPEGFormat)
+
+	format, found = command.OutputFormatForFilename("poster.gif")
+	checker.Assert(found, Equals, true)
+	checker.Assert(format, Equals, command.GIFFormat)
+
+	_, found = command.OutputFormatForFilename("poster.tiff")
+	checker.Assert(found, Equals, false)
+}
@@ -0,0 +1,38 @@
+package command
+
+import "github.com/chadius/creatingsymmetry/entities/validation"
+
+// Percentiles used by auto thresholds when neither percentile is given.
+//   Together they drop the 1% of coordinates farthest out on each side, which is usually where poles are.
+const (
+	DefaultLowerPercentile = 1.0
+	DefaultUpperPercentile = 99.0
+)
+
+// percentilesWithDefaults returns the lower and upper percentiles, using 100 for an upper percentile that was not given.
+func percentilesWithDefaults(lowerPercentile, upperPercentile float64) (float64, float64) {
+	if upperPercentile == 0 {
+		return lowerPercentile, 100
+	}
+	return lowerPercentile, upperPercentile
+}
+
+// validatePercentiles checks both percentiles are from 0 to 100, and the lower percentile is smaller.
+func validatePercentiles(path string, lowerPercentile, upperPercentile float64) []validation.Problem {
+	problems := []validation.Problem{}
+	if lowerPercentile < 0 || lowerPercentile >= 100 {
+		problems = append(problems, validation.NewProblem(validation.JoinPath(path, "lower_percentile"), "lower_percentile must be at least 0 and less than 100"))
+	}
+	if upperPercentile < 0 || upperPercentile > 100 {
+		problems = append(problems, validation.NewProblem(validation.JoinPath(path, "upper_percentile"), "upper_percentile must be more than 0 and at most 100"))
+	}
+	if len(problems) > 0 {
+		return problems
+	}
+
+	lowerPercentile, upperPercentile = percentilesWithDefaults(lowerPercentile, upperPercentile)
+	if lowerPercentile >= upperPercentile {
+		problems = append(problems, validation.NewProblem(validation.JoinPath(path, "upper_percentile"), "upper_percentile must be larger than lower_percentile"))
+	}
+	return problems
+}
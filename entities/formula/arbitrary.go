@@ -8,3 +8,11 @@ type Arbitrary interface {
 	LatticeVectors() []complex128
 	SymmetriesFound() []Symmetry
 }
+
+// RowCalculator formulas can transform evenly spaced coordinates faster than calling Calculate on each one.
+//   CalculateRow fills results with the transformed coordinates start, start + step, start + 2 * step, and so on.
+//   Each result is within RowErrorBound of Calculate, as a fraction of the sum of the magnitudes of the wave packet multipliers.
+//   The bound grows with the term powers and with how far the row is from the origin.
+type RowCalculator interface {
+	CalculateRow(start, step complex128, results []complex128)
+}
@@ -2,10 +2,46 @@ package formula
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/chadius/creatingsymmetry/entities/utility"
+	"github.com/chadius/creatingsymmetry/entities/validation"
 	"gopkg.in/yaml.v2"
+	"strings"
 )
 
+// formulaTypes lists every formula type the builder can make.
+var formulaTypes = []string{
+	"identity",
+	"rosette",
+	"frieze",
+	"rectangular",
+	"square",
+	"hexagonal",
+	"rhombic",
+	"generic",
+}
+
+// desiredSymmetriesByFormulaType lists the symmetries each lattice based formula can apply.
+var desiredSymmetriesByFormulaType = map[string][]Symmetry{
+	"rectangular": {P1, Pm, Pg, Pmm, Pmg, Pgg},
+	"square":      {P1, P4, P4m, P4g},
+	"hexagonal":   {P1, P3, P31m, P3m1, P6, P6m},
+	"rhombic":     {P1, Cm, Cmm},
+	"generic":     {P1, P2},
+}
+
+// fieldsUsedByFormulaType lists the optional fields each formula type reads.
+var fieldsUsedByFormulaType = map[string][]string{
+	"identity":    {},
+	"rosette":     {"terms"},
+	"frieze":      {"terms"},
+	"rectangular": {"lattice_height", "wave_packets", "desired_symmetry"},
+	"square":      {"wave_packets", "desired_symmetry"},
+	"hexagonal":   {"wave_packets", "desired_symmetry"},
+	"rhombic":     {"lattice_height", "wave_packets", "desired_symmetry"},
+	"generic":     {"lattice_width", "lattice_height", "wave_packets", "desired_symmetry"},
+}
+
 // Builder is used to create formula objects.
 type Builder struct {
 	formulaType       string
@@ -144,7 +180,10 @@ func (b *Builder) Build() (Arbitrary, error) {
 		}
 		return formula, err
 	}
-	return &Identity{}, nil
+	if b.formulaType == "identity" {
+		return &Identity{}, nil
+	}
+	return &Identity{}, fmt.Errorf("unknown formula type %q", b.formulaType)
 }
 
 // UsingYAMLData updates the builder, given data
@@ -210,6 +249,10 @@ func (b *Builder) WithMarshalOptions(marshaledOptions BuilderOptionMarshal) *Bui
 		b.Square()
 	}
 
+	if marshaledOptions.Type != "" && !isKnownFormulaType(marshaledOptions.Type) {
+		b.formulaType = marshaledOptions.Type
+	}
+
 	b.LatticeWidth(marshaledOptions.LatticeWidth).
 		LatticeHeight(marshaledOptions.LatticeHeight)
 
@@ -230,3 +273,108 @@ func (b *Builder) WithMarshalOptions(marshaledOptions BuilderOptionMarshal) *Bui
 
 	return b
 }
+
+// Validate returns every problem found in the options.
+//   path is where the options are found in the document, like "formula".
+func (b BuilderOptionMarshal) Validate(path string) []validation.Problem {
+	typePath := validation.JoinPath(path, "type")
+	if b.Type == "" {
+		return []validation.Problem{
+			validation.NewProblemf(typePath, "missing formula type, expected one of: %s", strings.Join(formulaTypes, ", ")),
+		}
+	}
+	if !isKnownFormulaType(b.Type) {
+		return []validation.Problem{
+			validation.NewProblemf(typePath, "unknown formula type %q, expected one of: %s", b.Type, strings.Join(formulaTypes, ", ")),
+		}
+	}
+
+	problems := b.validateUnusedFields(path)
+
+	if b.Type == "rosette" || b.Type == "frieze" {
+		if len(b.Terms) == 0 {
+			problems = append(problems, validation.NewProblemf(validation.JoinPath(path, "terms"), "%s formulas need at least one term", b.Type))
+		}
+		for index, term := range b.Terms {
+			problems = append(problems, term.Validate(validation.IndexPath(validation.JoinPath(path, "terms"), index))...)
+		}
+		return problems
+	}
+
+	if b.Type == "identity" {
+		return problems
+	}
+
+	if len(b.WavePackets) == 0 {
+		problems = append(problems, validation.NewProblemf(validation.JoinPath(path, "wave_packets"), "%s formulas need at least one wave packet", b.Type))
+	}
+	for index, wavePacket := range b.WavePackets {
+		problems = append(problems, wavePacket.Validate(validation.IndexPath(validation.JoinPath(path, "wave_packets"), index))...)
+	}
+
+	if (b.Type == "rectangular" || b.Type == "rhombic" || b.Type == "generic") && b.LatticeHeight == 0 {
+		problems = append(problems, validation.NewProblemf(validation.JoinPath(path, "lattice_height"), "%s lattice must specify a non zero lattice_height", b.Type))
+	}
+	if b.Type == "generic" && b.LatticeWidth == 0 {
+		problems = append(problems, validation.NewProblemf(validation.JoinPath(path, "lattice_width"), "%s lattice must specify a non zero lattice_width", b.Type))
+	}
+
+	return append(problems, b.validateDesiredSymmetry(path)...)
+}
+
+func (b BuilderOptionMarshal) validateUnusedFields(path string) []validation.Problem {
+	fieldIsSet := map[string]bool{
+		"terms":            len(b.Terms) > 0,
+		"wave_packets":     len(b.WavePackets) > 0,
+		"lattice_width":    b.LatticeWidth != 0,
+		"lattice_height":   b.LatticeHeight != 0,
+		"desired_symmetry": b.DesiredSymmetry != "",
+	}
+	for _, usedField := range fieldsUsedByFormulaType[b.Type] {
+		fieldIsSet[usedField] = false
+	}
+
+	problems := []validation.Problem{}
+	for _, field := range []string{"terms", "wave_packets", "lattice_width", "lattice_height", "desired_symmetry"} {
+		if fieldIsSet[field] {
+			problems = append(problems, validation.NewProblemf(validation.JoinPath(path, field), "%s is not used by %s formulas", field, b.Type))
+		}
+	}
+	return problems
+}
+
+func (b BuilderOptionMarshal) validateDesiredSymmetry(path string) []validation.Problem {
+	if b.DesiredSymmetry == "" {
+		return nil
+	}
+
+	allowedSymmetries := desiredSymmetriesByFormulaType[b.Type]
+	for _, symmetry := range allowedSymmetries {
+		if b.DesiredSymmetry == symmetry {
+			return nil
+		}
+	}
+
+	allowedSymmetryNames := []string{}
+	for _, symmetry := range allowedSymmetries {
+		allowedSymmetryNames = append(allowedSymmetryNames, string(symmetry))
+	}
+	return []validation.Problem{
+		validation.NewProblemf(
+			validation.JoinPath(path, "desired_symmetry"),
+			"%s lattice cannot apply %q, it can apply these desired symmetries: %s",
+			b.Type,
+			b.DesiredSymmetry,
+			strings.Join(allowedSymmetryNames, ", "),
+		),
+	}
+}
+
+func isKnownFormulaType(formulaType string) bool {
+	for _, knownType := range formulaTypes {
+		if formulaType == knownType {
+			return true
+		}
+	}
+	return false
+}
@@ -2,6 +2,7 @@ package formula_test
 
 import (
 	"github.com/chadius/creatingsymmetry/entities/formula"
+	"github.com/chadius/creatingsymmetry/entities/formula/coefficient"
 	"github.com/chadius/creatingsymmetry/entities/utility"
 	. "gopkg.in/check.v1"
 	"reflect"
@@ -460,3 +461,75 @@ func (suite *BuilderMakeFormulaUsingDataStream) TestMakeWallpaperSquareFormulaWi
 	checker.Assert(term.PowerN, Equals, 13)
 	checker.Assert(term.PowerM, Equals, -17)
 }
+
+func (suite *BuilderMakeFormulaUsingDataStream) TestUnknownFormulaTypeIsAnError(checker *C) {
+	newFormula, err := formula.NewBuilder().UsingYAMLData([]byte(`type: rosete`)).Build()
+	checker.Assert(err, ErrorMatches, `unknown formula type "rosete"`)
+	checker.Assert(reflect.TypeOf(newFormula).String(), Equals, "*formula.Identity")
+}
+
+type BuilderValidateOptions struct{}
+
+var _ = Suite(&BuilderValidateOptions{})
+
+func (suite *BuilderValidateOptions) TestValidOptionsHaveNoProblems(checker *C) {
+	options := formula.BuilderOptionMarshal{
+		Type:            "rhombic",
+		LatticeHeight:   0.5,
+		DesiredSymmetry: formula.Cm,
+		WavePackets: []formula.WavePacketMarshal{
+			{
+				Terms: []formula.TermMarshal{
+					{PowerN: 1, PowerM: -2, CoefficientRelationships: []coefficient.Relationship{coefficient.MinusMMinusN}},
+				},
+			},
+		},
+	}
+	checker.Assert(options.Validate("formula"), HasLen, 0)
+}
+
+func (suite *BuilderValidateOptions) TestMissingAndUnknownTypes(checker *C) {
+	problems := formula.BuilderOptionMarshal{}.Validate("formula")
+	checker.Assert(problems, HasLen, 1)
+	checker.Assert(problems[0].Path, Equals, "formula.type")
+	checker.Assert(problems[0].Message, Matches, "missing formula type.*")
+
+	problems = formula.BuilderOptionMarshal{Type: "rombic"}.Validate("formula")
+	checker.Assert(problems, HasLen, 1)
+	checker.Assert(problems[0].Message, Matches, `unknown formula type "rombic", expected one of: identity, rosette, .*`)
+}
+
+func (suite *BuilderValidateOptions) TestRosettesNeedTermsAndIgnoreLatticeFields(checker *C) {
+	problems := formula.BuilderOptionMarshal{
+		Type:          "rosette",
+		LatticeHeight: 2,
+	}.Validate("formula")
+	checker.Assert(problems, HasLen, 2)
+	checker.Assert(problems[0].Path, Equals, "formula.lattice_height")
+	checker.Assert(problems[0].Message, Equals, "lattice_height is not used by rosette formulas")
+	checker.Assert(problems[1].Path, Equals, "formula.terms")
+}
+
+func (suite *BuilderValidateOptions) TestLatticesNeedSizesAndAllowedSymmetries(checker *C) {
+	problems := formula.BuilderOptionMarshal{
+		Type:            "generic",
+		DesiredSymmetry: formula.P4,
+		WavePackets:     []formula.WavePacketMarshal{{}},
+	}.Validate("formula")
+	checker.Assert(problems, HasLen, 4)
+	checker.Assert(problems[0].Path, Equals, "formula.wave_packets[0].terms")
+	checker.Assert(problems[1].Path, Equals, "formula.lattice_height")
+	checker.Assert(problems[2].Path, Equals, "formula.lattice_width")
+	checker.Assert(problems[3].Path, Equals, "formula.desired_symmetry")
+	checker.Assert(problems[3].Message, Equals, `generic lattice cannot apply "p4", it can apply these desired symmetries: p1, p2`)
+}
+
+func (suite *BuilderValidateOptions) TestTermsRejectUnknownRelationships(checker *C) {
+	term := formula.TermMarshal{
+		CoefficientRelationships: []coefficient.Relationship{coefficient.PlusNPlusM, "+M+NF"},
+	}
+	problems := term.Validate("terms[0]")
+	checker.Assert(problems, HasLen, 1)
+	checker.Assert(problems[0].Path, Equals, "terms[0].coefficient_relationships[1]")
+	checker.Assert(problems[0].Message, Matches, `unknown coefficient relationship "\+M\+NF", expected one of: .*`)
+}
@@ -137,3 +137,35 @@ const (
 	PlusNMinusMNegateMultiplierIfOddPowerSum  Relationship = "+N-MF(N+M)"
 	MinusNPlusMNegateMultiplierIfOddPowerSum  Relationship = "-N+MF(N+M)"
 )
+
+// AllRelationships returns every Relationship a term can use.
+func AllRelationships() []Relationship {
+	return []Relationship{
+		PlusNPlusM,
+		PlusMPlusN,
+		MinusNMinusM,
+		MinusMMinusN,
+		PlusMPlusNNegateMultiplierIfOddPowerSum,
+		MinusMMinusNNegateMultiplierIfOddPowerSum,
+		PlusMMinusSumNAndM,
+		MinusSumNAndMPlusN,
+		PlusMMinusN,
+		MinusMPlusN,
+		PlusNMinusM,
+		PlusNMinusMNegateMultiplierIfOddPowerN,
+		MinusNPlusMNegateMultiplierIfOddPowerN,
+		MinusNPlusM,
+		PlusNMinusMNegateMultiplierIfOddPowerSum,
+		MinusNPlusMNegateMultiplierIfOddPowerSum,
+	}
+}
+
+// IsValid returns true if the relationship is one of the known relationships.
+func (r Relationship) IsValid() bool {
+	for _, knownRelationship := range AllRelationships() {
+		if r == knownRelationship {
+			return true
+		}
+	}
+	return false
+}
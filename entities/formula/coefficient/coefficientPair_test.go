@@ -262,3 +262,11 @@ func (suite *CoefficientPairFeatures) TestMinusNPlusMNegateMultiplierIfOddPowerS
 	checker.Assert(newSetsWithOddSumPower[0].PowerM, Equals, 2)
 	checker.Assert(newSetsWithOddSumPower[0].NegateMultiplier, Equals, true)
 }
+
+func (suite *CoefficientPairFeatures) TestKnownRelationshipsAreValid(checker *C) {
+	checker.Assert(coefficient.AllRelationships(), HasLen, 16)
+	for _, relationship := range coefficient.AllRelationships() {
+		checker.Assert(relationship.IsValid(), Equals, true)
+	}
+	checker.Assert(coefficient.Relationship("+M+NF").IsValid(), Equals, false)
+}
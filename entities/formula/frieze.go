@@ -1,19 +1,19 @@
 package formula
 
-import (
-	"github.com/chadius/creatingsymmetry/entities/formula/coefficient"
-	"math/cmplx"
-)
+import "math/cmplx"
 
 // Frieze formulas transform points into a horizontal repeating strip, like the frieze patterns on ceilings and columns.
+//   The terms' coefficient relationships are expanded when the formula is made, so Calculate only does arithmetic.
 type Frieze struct {
 	formulaLevelTerms []Term
+	expandedTerms     []expandedTerm
 }
 
 // NewFriezeFormula returns a new formula
 func NewFriezeFormula(formulaLevelTerms []Term) (*Frieze, error) {
 	return &Frieze{
 			formulaLevelTerms: formulaLevelTerms,
+			expandedTerms:     expandTerms(formulaLevelTerms),
 		},
 		nil
 }
@@ -26,34 +26,13 @@ func (f *Frieze) WavePackets() []WavePacket {
 // Calculate applies the Frieze formula to the complex number z.
 func (f *Frieze) Calculate(coordinate complex128) complex128 {
 	sumOfTermCalculations := complex(0, 0)
-	for _, term := range f.formulaLevelTerms {
-		termCalculation := f.calculateTerm(term, coordinate)
-		sumOfTermCalculations += termCalculation
+	for _, term := range f.expandedTerms {
+		sumOfTermCalculations += CalculateEulerTerm(coordinate, term.powerN, term.powerM, term.multiplier, term.ignoreComplexConjugate)
 	}
 
 	return sumOfTermCalculations
 }
 
-func (f *Frieze) calculateTerm(term Term, coordinate complex128) complex128 {
-	sum := complex(0.0, 0.0)
-
-	coefficientRelationships := []coefficient.Relationship{coefficient.PlusNPlusM}
-	coefficientRelationships = append(coefficientRelationships, term.CoefficientRelationships...)
-	coefficientSets := coefficient.Pairing{
-		PowerN: term.PowerN,
-		PowerM: term.PowerM,
-	}.GenerateCoefficientSets(coefficientRelationships)
-
-	for _, relationshipSet := range coefficientSets {
-		multiplier := term.Multiplier
-		if relationshipSet.NegateMultiplier == true {
-			multiplier *= -1
-		}
-		sum += CalculateEulerTerm(coordinate, relationshipSet.PowerN, relationshipSet.PowerM, multiplier, term.IgnoreComplexConjugate)
-	}
-	return sum
-}
-
 // CalculateEulerTerm calculates e^(i*n*z) * e^(-i*m*zConj)
 func CalculateEulerTerm(z complex128, power1, power2 int, scale complex128, ignoreComplexConjugate bool) complex128 {
 	eRaisedToTheNZi := cmplx.Exp(complex(0, 1) * z * complex(float64(power1), 0))
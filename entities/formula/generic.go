@@ -11,6 +11,7 @@ import (
 type Generic struct {
 	latticeVectors []complex128
 	wavePackets    []WavePacket
+	plan           *latticePlan
 }
 
 // NewGenericFormula returns a new formula object.
@@ -25,14 +26,16 @@ func NewGenericFormula(packets []WavePacket, latticeWidth, latticeHeight float64
 
 	newWavePackets := createNewWavePacketsBasedOnDesiredSymmetry(packets, desiredSymmetry)
 
-	return &Generic{
-			wavePackets: newWavePackets,
-			latticeVectors: []complex128{
-				complex(1, 0),
-				complex(latticeWidth, latticeHeight),
-			},
+	genericWallpaperFormula := &Generic{
+		wavePackets: newWavePackets,
+		latticeVectors: []complex128{
+			complex(1, 0),
+			complex(latticeWidth, latticeHeight),
 		},
-		nil
+	}
+	genericWallpaperFormula.plan = newLatticePlan(genericWallpaperFormula.latticeVectors, genericWallpaperFormula.wavePackets)
+
+	return genericWallpaperFormula, nil
 }
 
 // WavePackets returns the wave packets used.
@@ -42,7 +45,12 @@ func (r *Generic) WavePackets() []WavePacket {
 
 // Calculate transforms the coordinate using the Generic lattice's wave packets.
 func (r *Generic) Calculate(coordinate complex128) complex128 {
-	return CalculateCoordinateUsingWavePackets(coordinate, r.LatticeVectors(), r.WavePackets())
+	return r.plan.calculate(coordinate)
+}
+
+// CalculateRow transforms evenly spaced coordinates using the Generic lattice's wave packets.
+func (r *Generic) CalculateRow(start, step complex128, results []complex128) {
+	r.plan.calculateRow(start, step, results)
 }
 
 // FormulaLevelTerms returns an empty list, Generic formulas do not have base-level terms.
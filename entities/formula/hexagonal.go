@@ -10,6 +10,7 @@ import (
 type Hexagonal struct {
 	latticeVectors []complex128
 	wavePackets    []WavePacket
+	plan           *latticePlan
 }
 
 // NewHexagonalFormula returns a new formula object.
@@ -33,14 +34,16 @@ func NewHexagonalFormula(packets []WavePacket, desiredSymmetry Symmetry) (*Hexag
 		},
 		wavePacketsWithDesiredSymmetry)
 
-	return &Hexagonal{
-			wavePackets: packetsWithLockedCoefficients,
-			latticeVectors: []complex128{
-				complex(1, 0),
-				complex(-0.5, math.Sqrt(3.0)/2.0),
-			},
+	hexagonalWallpaperFormula := &Hexagonal{
+		wavePackets: packetsWithLockedCoefficients,
+		latticeVectors: []complex128{
+			complex(1, 0),
+			complex(-0.5, math.Sqrt(3.0)/2.0),
 		},
-		nil
+	}
+	hexagonalWallpaperFormula.plan = newLatticePlan(hexagonalWallpaperFormula.latticeVectors, hexagonalWallpaperFormula.wavePackets)
+
+	return hexagonalWallpaperFormula, nil
 }
 
 // WavePackets returns the wave packets used.
@@ -50,7 +53,12 @@ func (r *Hexagonal) WavePackets() []WavePacket {
 
 // Calculate transforms the coordinate using the Hexagonal lattice's wave packets.
 func (r *Hexagonal) Calculate(coordinate complex128) complex128 {
-	return CalculateCoordinateUsingWavePackets(coordinate, r.LatticeVectors(), r.WavePackets())
+	return r.plan.calculate(coordinate)
+}
+
+// CalculateRow transforms evenly spaced coordinates using the Hexagonal lattice's wave packets.
+func (r *Hexagonal) CalculateRow(start, step complex128, results []complex128) {
+	r.plan.calculateRow(start, step, results)
 }
 
 // FormulaLevelTerms returns an empty list, Hexagonal formulas do not have base-level terms.
@@ -0,0 +1,338 @@
+package formula
+
+import (
+	"github.com/chadius/creatingsymmetry/entities/formula/coefficient"
+	"math"
+	"math/cmplx"
+	"sort"
+)
+
+// expandedTerm is a rosette or frieze term after its coefficient relationships were applied.
+type expandedTerm struct {
+	powerN                 int
+	powerM                 int
+	multiplier             complex128
+	ignoreComplexConjugate bool
+}
+
+// expandTerms applies each term's coefficient relationships once, so they are not generated again for every coordinate.
+//   Expanded terms with the same powers are merged by adding their multipliers.
+//   Terms that ignore the complex conjugate do not use PowerM, so only their PowerN has to match.
+func expandTerms(terms []Term) []expandedTerm {
+	type termKey struct {
+		powerN                 int
+		powerM                 int
+		ignoreComplexConjugate bool
+	}
+	expandedTerms := []expandedTerm{}
+	indexByKey := map[termKey]int{}
+
+	for _, term := range terms {
+		coefficientRelationships := []coefficient.Relationship{coefficient.PlusNPlusM}
+		coefficientRelationships = append(coefficientRelationships, term.CoefficientRelationships...)
+		coefficientSets := coefficient.Pairing{
+			PowerN: term.PowerN,
+			PowerM: term.PowerM,
+		}.GenerateCoefficientSets(coefficientRelationships)
+
+		for _, relationshipSet := range coefficientSets {
+			multiplier := term.Multiplier
+			if relationshipSet.NegateMultiplier == true {
+				multiplier *= -1
+			}
+
+			key := termKey{
+				powerN:                 relationshipSet.PowerN,
+				powerM:                 relationshipSet.PowerM,
+				ignoreComplexConjugate: term.IgnoreComplexConjugate,
+			}
+			if term.IgnoreComplexConjugate {
+				key.powerM = 0
+			}
+
+			if index, alreadyExpanded := indexByKey[key]; alreadyExpanded {
+				expandedTerms[index].multiplier += multiplier
+				continue
+			}
+			indexByKey[key] = len(expandedTerms)
+			expandedTerms = append(expandedTerms, expandedTerm{
+				powerN:                 key.powerN,
+				powerM:                 key.powerM,
+				multiplier:             multiplier,
+				ignoreComplexConjugate: key.ignoreComplexConjugate,
+			})
+		}
+	}
+	return expandedTerms
+}
+
+// polarTerm is a rosette term written as multiplier * r^(n+m) * e^(i(n-m)θ), where z = r * e^(iθ).
+//   It refers to its powers by their index in the rosette plan, so terms with the same power share it.
+type polarTerm struct {
+	radiusPower     int
+	anglePower      int
+	radiusPowerSlot int
+	anglePowerSlot  int
+	multiplier      complex128
+}
+
+// rosettePlan calculates rosettes in polar form, so every power of the coordinate is calculated once per coordinate.
+type rosettePlan struct {
+	radiusPowers []int
+	anglePowers  []int
+	terms        []polarTerm
+}
+
+// maximumPowersOnTheStack is how many distinct powers can be calculated without allocating memory.
+const maximumPowersOnTheStack = 16
+
+// newRosettePlan turns each expanded term z^n * zConj^m into polar form.
+//   Terms that ignore the complex conjugate were expanded with m = 0, so they become r^n * e^(inθ).
+func newRosettePlan(expandedTerms []expandedTerm) *rosettePlan {
+	plan := &rosettePlan{
+		radiusPowers: []int{},
+		anglePowers:  []int{},
+		terms:        []polarTerm{},
+	}
+	for _, term := range expandedTerms {
+		plan.terms = append(plan.terms, polarTerm{
+			radiusPower: term.powerN + term.powerM,
+			anglePower:  term.powerN - term.powerM,
+			multiplier:  term.multiplier,
+		})
+		plan.radiusPowers = append(plan.radiusPowers, term.powerN+term.powerM)
+		plan.anglePowers = append(plan.anglePowers, term.powerN-term.powerM)
+	}
+
+	plan.radiusPowers = sortedDistinctPowers(plan.radiusPowers)
+	plan.anglePowers = sortedDistinctPowers(plan.anglePowers)
+	for index := range plan.terms {
+		plan.terms[index].radiusPowerSlot = sort.SearchInts(plan.radiusPowers, plan.terms[index].radiusPower)
+		plan.terms[index].anglePowerSlot = sort.SearchInts(plan.anglePowers, plan.terms[index].anglePower)
+	}
+	return plan
+}
+
+// sortedDistinctPowers sorts the powers and removes repeats.
+func sortedDistinctPowers(powers []int) []int {
+	sort.Ints(powers)
+	distinctPowers := []int{}
+	for index, power := range powers {
+		if index > 0 && power == powers[index-1] {
+			continue
+		}
+		distinctPowers = append(distinctPowers, power)
+	}
+	return distinctPowers
+}
+
+// calculate converts the coordinate to polar form once, then adds up every term.
+//   The origin is handled by calculateAtOrigin.
+func (p *rosettePlan) calculate(coordinate complex128) complex128 {
+	if coordinate == 0 {
+		return p.calculateAtOrigin()
+	}
+
+	radius := cmplx.Abs(coordinate)
+	direction := complex(real(coordinate)/radius, imag(coordinate)/radius)
+
+	var radiusBuffer, angleBuffer [maximumPowersOnTheStack]complex128
+	radiusValues := raiseToSortedPowers(complex(radius, 0), complex(1/radius, 0), p.radiusPowers, radiusBuffer[:0])
+	angleValues := raiseToSortedPowers(direction, cmplx.Conj(direction), p.anglePowers, angleBuffer[:0])
+
+	result := complex(0, 0)
+	for _, term := range p.terms {
+		result += radiusValues[term.radiusPowerSlot] * angleValues[term.anglePowerSlot] * term.multiplier
+	}
+	return result
+}
+
+// calculateAtOrigin decides what the rosette is at z = 0, where the angle θ does not exist.
+//   Terms with a 0 multiplier are ignored.
+//   Terms with n + m > 0 vanish, and terms with n = m = 0 are constant, so they add their multiplier.
+//   Terms with n + m < 0 are poles, so the result is Infinity.
+//   Otherwise terms with n + m = 0 keep their size but their direction depends on how the origin is approached,
+//   so the result is NaN.
+func (p *rosettePlan) calculateAtOrigin() complex128 {
+	result := complex(0, 0)
+	hasNoDirection := false
+	for _, term := range p.terms {
+		if term.multiplier == 0 || term.radiusPower > 0 {
+			continue
+		}
+		if term.radiusPower < 0 {
+			return cmplx.Inf()
+		}
+		if term.anglePower != 0 {
+			hasNoDirection = true
+			continue
+		}
+		result += term.multiplier
+	}
+
+	if hasNoDirection {
+		return cmplx.NaN()
+	}
+	return result
+}
+
+// raiseToSortedPowers appends base raised to each of the sorted powers to values.
+//   Powers are reached by multiplying the one before it, walking away from 0 in both directions,
+//   so powers shared by terms are only calculated once. Negative powers use the inverse of the base.
+func raiseToSortedPowers(base, inverse complex128, sortedPowers []int, values []complex128) []complex128 {
+	for range sortedPowers {
+		values = append(values, complex(0, 0))
+	}
+	firstNonNegativeIndex := sort.SearchInts(sortedPowers, 0)
+
+	value := complex(1, 0)
+	previousPower := 0
+	for index := firstNonNegativeIndex; index < len(sortedPowers); index++ {
+		value *= raiseToNonNegativePower(base, sortedPowers[index]-previousPower)
+		previousPower = sortedPowers[index]
+		values[index] = value
+	}
+
+	value = complex(1, 0)
+	previousPower = 0
+	for index := firstNonNegativeIndex - 1; index >= 0; index-- {
+		value *= raiseToNonNegativePower(inverse, previousPower-sortedPowers[index])
+		previousPower = sortedPowers[index]
+		values[index] = value
+	}
+	return values
+}
+
+// raiseToNonNegativePower multiplies the base by itself, squaring it to reach large powers quickly.
+func raiseToNonNegativePower(base complex128, power int) complex128 {
+	result := complex(1, 0)
+	for power > 0 {
+		if power%2 == 1 {
+			result *= base
+		}
+		base *= base
+		power /= 2
+	}
+	return result
+}
+
+// rowStepsBetweenExactPhases is how many times a term's phase is stepped along a row before it is calculated exactly again.
+const rowStepsBetweenExactPhases = 64
+
+// machineEpsilon is the gap between 1 and the next float64.
+const machineEpsilon = 2.220446049250313e-16
+
+// rowErrorEpsilons is how many machine epsilons RowErrorBound allows for each power and lattice cell.
+//   Measured errors stay below about 6.
+const rowErrorEpsilons = 64
+
+// RowErrorBound returns the most CalculateRow can differ from Calculate,
+//   as a fraction of the sum of the magnitudes of the wave packet multipliers.
+//   powerSum is the largest |n| + |m| of any term, and latticeCells is the largest lattice coordinate along the row,
+//   about how many lattice cells the row is from the origin.
+//   Calculate rounds each lattice coordinate by a machine epsilon of its size, and a term's phase multiplies that by its powers,
+//   so Calculate is only this accurate itself. CalculateRow reaches each phase in a different order, so it rounds differently.
+//   Stepping a phase along the row adds a few epsilons each step, until it is calculated again every rowStepsBetweenExactPhases steps.
+func RowErrorBound(powerSum int, latticeCells float64) float64 {
+	return rowErrorEpsilons * machineEpsilon * float64(1+powerSum) * (1 + latticeCells)
+}
+
+// latticeTerm is one wave term of a lattice formula, with its share of the wave packet's multiplier.
+type latticeTerm struct {
+	powerN     int
+	powerM     int
+	multiplier complex128
+}
+
+// latticePlan transforms coordinates with wave packets, doing the work that does not depend on the coordinate once.
+//   Lattice coordinates use the inverse of the matrix made from the lattice vectors.
+//   Each wave packet's multiplier is divided evenly among its terms, and terms with the same powers are merged.
+type latticePlan struct {
+	latticeXFromX float64
+	latticeXFromY float64
+	latticeYFromX float64
+	latticeYFromY float64
+	terms         []latticeTerm
+}
+
+// newLatticePlan solves the lattice vectors and flattens the wave packets.
+func newLatticePlan(latticeVectors []complex128, wavePackets []WavePacket) *latticePlan {
+	vector1 := latticeVectors[0]
+	vector2 := latticeVectors[1]
+	determinant := real(vector1)*imag(vector2) - real(vector2)*imag(vector1)
+
+	plan := &latticePlan{
+		latticeXFromX: imag(vector2) / determinant,
+		latticeXFromY: -real(vector2) / determinant,
+		latticeYFromX: -imag(vector1) / determinant,
+		latticeYFromY: real(vector1) / determinant,
+		terms:         []latticeTerm{},
+	}
+
+	type powers struct {
+		powerN int
+		powerM int
+	}
+	indexByPowers := map[powers]int{}
+	for _, wavePacket := range wavePackets {
+		multiplierPerTerm := wavePacket.Multiplier() / complex(float64(len(wavePacket.Terms())), 0)
+		for _, term := range wavePacket.Terms() {
+			key := powers{powerN: term.PowerN, powerM: term.PowerM}
+			if index, alreadyAdded := indexByPowers[key]; alreadyAdded {
+				plan.terms[index].multiplier += multiplierPerTerm
+				continue
+			}
+			indexByPowers[key] = len(plan.terms)
+			plan.terms = append(plan.terms, latticeTerm{
+				powerN:     term.PowerN,
+				powerM:     term.PowerM,
+				multiplier: multiplierPerTerm,
+			})
+		}
+	}
+	return plan
+}
+
+// calculate gives the same result as CalculateCoordinateUsingWavePackets, using only arithmetic.
+func (p *latticePlan) calculate(coordinate complex128) complex128 {
+	latticeX := p.latticeXFromX*real(coordinate) + p.latticeXFromY*imag(coordinate)
+	latticeY := p.latticeYFromX*real(coordinate) + p.latticeYFromY*imag(coordinate)
+
+	result := complex(0, 0)
+	for _, term := range p.terms {
+		sine, cosine := math.Sincos(2.0 * math.Pi * (float64(term.powerN)*latticeX + float64(term.powerM)*latticeY))
+		result += complex(cosine, sine) * term.multiplier
+	}
+	return result
+}
+
+// calculateRow fills the results by stepping each term's phase along the row instead of calculating it for every coordinate.
+//   Moving one step along the row always turns a term's phase by the same angle, so each term is multiplied by a fixed rotation.
+//   Every rowStepsBetweenExactPhases steps the phase is calculated exactly again, so rounding errors cannot build up.
+func (p *latticePlan) calculateRow(start, step complex128, results []complex128) {
+	for index := range results {
+		results[index] = complex(0, 0)
+	}
+
+	startX := p.latticeXFromX*real(start) + p.latticeXFromY*imag(start)
+	startY := p.latticeYFromX*real(start) + p.latticeYFromY*imag(start)
+	stepX := p.latticeXFromX*real(step) + p.latticeXFromY*imag(step)
+	stepY := p.latticeYFromX*real(step) + p.latticeYFromY*imag(step)
+
+	for _, term := range p.terms {
+		startPhase := 2.0 * math.Pi * (float64(term.powerN)*startX + float64(term.powerM)*startY)
+		phasePerStep := 2.0 * math.Pi * (float64(term.powerN)*stepX + float64(term.powerM)*stepY)
+		rotationSine, rotationCosine := math.Sincos(phasePerStep)
+		rotation := complex(rotationCosine, rotationSine)
+
+		var contribution complex128
+		for index := range results {
+			if index%rowStepsBetweenExactPhases == 0 {
+				sine, cosine := math.Sincos(startPhase + float64(index)*phasePerStep)
+				contribution = complex(cosine, sine) * term.multiplier
+			}
+			results[index] += contribution
+			contribution *= rotation
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package formula_test
+
+import (
+	"fmt"
+	"github.com/chadius/creatingsymmetry/entities/command"
+	"github.com/chadius/creatingsymmetry/entities/formula"
+	"github.com/chadius/creatingsymmetry/entities/formula/coefficient"
+	"github.com/chadius/creatingsymmetry/entities/utility"
+	. "gopkg.in/check.v1"
+	"io/ioutil"
+	"math"
+	"math/cmplx"
+	"path/filepath"
+	"testing"
+)
+
+type PlanTest struct{}
+
+var _ = Suite(&PlanTest{})
+
+// sampleCoordinates spreads coordinates over a few lattice cells, away from the origin.
+func sampleCoordinates() []complex128 {
+	coordinates := []complex128{}
+	for x := -2.0; x <= 2.0; x += 0.35 {
+		for y := -2.0; y <= 2.0; y += 0.45 {
+			coordinates = append(coordinates, complex(x+0.05, y+0.05))
+		}
+	}
+	return coordinates
+}
+
+// calculateTermsPerCoordinate applies the terms the way they were applied before they were expanded ahead of time.
+func calculateTermsPerCoordinate(coordinate complex128, terms []formula.Term, calculateTerm func(complex128, int, int, complex128, bool) complex128) complex128 {
+	sum := complex(0, 0)
+	for _, term := range terms {
+		coefficientSets := coefficient.Pairing{
+			PowerN: term.PowerN,
+			PowerM: term.PowerM,
+		}.GenerateCoefficientSets(append([]coefficient.Relationship{coefficient.PlusNPlusM}, term.CoefficientRelationships...))
+		for _, relationshipSet := range coefficientSets {
+			multiplier := term.Multiplier
+			if relationshipSet.NegateMultiplier {
+				multiplier *= -1
+			}
+			sum += calculateTerm(coordinate, relationshipSet.PowerN, relationshipSet.PowerM, multiplier, term.IgnoreComplexConjugate)
+		}
+	}
+	return sum
+}
+
+// loadExampleFormulas builds the formula of every example file matching the pattern.
+func loadExampleFormulas(pattern string) (map[string]formula.Arbitrary, error) {
+	filenames, err := filepath.Glob(filepath.Join("..", "..", "example", pattern))
+	if err != nil {
+		return nil, err
+	}
+
+	formulaByFilename := map[string]formula.Arbitrary{}
+	for _, filename := range filenames {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		wallpaperCommand, err := command.NewCreateWallpaperCommandFromData(data)
+		if err != nil {
+			return nil, err
+		}
+		formulaByFilename[filepath.Base(filename)] = wallpaperCommand.Formula
+	}
+	return formulaByFilename, nil
+}
+
+func (suite *PlanTest) TestLatticesMatchCalculatingEachWavePacket(checker *C) {
+	lattices, err := loadExampleFormulas(filepath.Join("lattices", "*.yml"))
+	checker.Assert(err, IsNil)
+	checker.Assert(len(lattices) > 0, Equals, true)
+
+	for filename, lattice := range lattices {
+		for _, coordinate := range sampleCoordinates() {
+			expected := formula.CalculateCoordinateUsingWavePackets(coordinate, lattice.LatticeVectors(), lattice.WavePackets())
+			actual := lattice.Calculate(coordinate)
+			checker.Assert(real(actual), utility.NumericallyCloseEnough{}, real(expected), 1e-9, Commentf("%s at %v", filename, coordinate))
+			checker.Assert(imag(actual), utility.NumericallyCloseEnough{}, imag(expected), 1e-9, Commentf("%s at %v", filename, coordinate))
+		}
+	}
+}
+
+func (suite *PlanTest) TestExampleRosettesAndFriezesMatchExpandingEachTerm(checker *C) {
+	rosettes, err := loadExampleFormulas(filepath.Join("rosettes", "*.yml"))
+	checker.Assert(err, IsNil)
+	friezes, err := loadExampleFormulas(filepath.Join("friezes", "*.yml"))
+	checker.Assert(err, IsNil)
+
+	for filename, exampleFormula := range friezes {
+		rosettes[filename] = exampleFormula
+	}
+	for filename, exampleFormula := range rosettes {
+		calculateTerm := formula.CalculateExponentTerm
+		if _, isFrieze := exampleFormula.(*formula.Frieze); isFrieze {
+			calculateTerm = formula.CalculateEulerTerm
+		}
+		for _, coordinate := range sampleCoordinates() {
+			expected := calculateTermsPerCoordinate(coordinate, exampleFormula.FormulaLevelTerms(), calculateTerm)
+			actual := exampleFormula.Calculate(coordinate)
+			checker.Assert(real(actual), utility.NumericallyCloseEnough{}, real(expected), 1e-6, Commentf("%s at %v", filename, coordinate))
+			checker.Assert(imag(actual), utility.NumericallyCloseEnough{}, imag(expected), 1e-6, Commentf("%s at %v", filename, coordinate))
+		}
+	}
+}
+
+func (suite *PlanTest) TestDuplicateRosetteTermsAreMerged(checker *C) {
+	rosetteFormula, _ := formula.NewBuilder().Rosette().
+		AddTerm(formula.NewTermBuilder().Multiplier(complex(2, 0)).PowerN(3).PowerM(1).Build()).
+		AddTerm(formula.NewTermBuilder().Multiplier(complex(0, 1)).PowerN(3).PowerM(1).Build()).
+		Build()
+	mergedFormula, _ := formula.NewBuilder().Rosette().
+		AddTerm(formula.NewTermBuilder().Multiplier(complex(2, 1)).PowerN(3).PowerM(1).Build()).
+		Build()
+
+	for _, coordinate := range sampleCoordinates() {
+		expected := mergedFormula.Calculate(coordinate)
+		actual := rosetteFormula.Calculate(coordinate)
+		checker.Assert(real(actual), utility.NumericallyCloseEnough{}, real(expected), 1e-9)
+		checker.Assert(imag(actual), utility.NumericallyCloseEnough{}, imag(expected), 1e-9)
+	}
+}
+
+func (suite *PlanTest) TestTermsThatCancelOutStillProduceAResult(checker *C) {
+	rosetteFormula, _ := formula.NewBuilder().Rosette().
+		AddTerm(formula.NewTermBuilder().Multiplier(complex(1, 0)).PowerN(2).PowerM(-1).Build()).
+		AddTerm(formula.NewTermBuilder().Multiplier(complex(-1, 0)).PowerN(2).PowerM(-1).Build()).
+		Build()
+
+	transformedPoint := rosetteFormula.Calculate(complex(1, 1))
+	checker.Assert(real(transformedPoint), utility.NumericallyCloseEnough{}, 0, 1e-9)
+	checker.Assert(imag(transformedPoint), utility.NumericallyCloseEnough{}, 0, 1e-9)
+}
+
+// sumOfMultiplierMagnitudes adds the magnitude of every wave packet's multiplier, the scale of the row error bound.
+func sumOfMultiplierMagnitudes(lattice formula.Arbitrary) float64 {
+	sum := 0.0
+	for _, wavePacket := range lattice.WavePackets() {
+		sum += cmplx.Abs(wavePacket.Multiplier())
+	}
+	return sum
+}
+
+// largestPowerSum returns the largest |n| + |m| of any wave term in the lattice.
+func largestPowerSum(lattice formula.Arbitrary) int {
+	largest := 0
+	for _, wavePacket := range lattice.WavePackets() {
+		for _, term := range wavePacket.Terms() {
+			powerSum := int(math.Abs(float64(term.PowerN)) + math.Abs(float64(term.PowerM)))
+			if powerSum > largest {
+				largest = powerSum
+			}
+		}
+	}
+	return largest
+}
+
+// latticeCells returns the largest lattice coordinate of the coordinates, measured in lattice vectors.
+func latticeCells(lattice formula.Arbitrary, coordinates ...complex128) float64 {
+	vector1, vector2 := lattice.LatticeVectors()[0], lattice.LatticeVectors()[1]
+	determinant := real(vector1)*imag(vector2) - real(vector2)*imag(vector1)
+	largest := 0.0
+	for _, coordinate := range coordinates {
+		latticeX := (imag(vector2)*real(coordinate) - real(vector2)*imag(coordinate)) / determinant
+		latticeY := (real(vector1)*imag(coordinate) - imag(vector1)*real(coordinate)) / determinant
+		largest = math.Max(largest, math.Max(math.Abs(latticeX), math.Abs(latticeY)))
+	}
+	return largest
+}
+
+// checkRowStaysWithinTheErrorBound compares each result of CalculateRow with Calculate.
+func checkRowStaysWithinTheErrorBound(checker *C, name string, lattice formula.Arbitrary, start, step complex128, count int) {
+	rowCalculator, canCalculateRows := lattice.(formula.RowCalculator)
+	checker.Assert(canCalculateRows, Equals, true, Commentf("%s", name))
+
+	results := make([]complex128, count)
+	rowCalculator.CalculateRow(start, step, results)
+	end := start + complex(float64(count-1), 0)*step
+	bound := formula.RowErrorBound(largestPowerSum(lattice), latticeCells(lattice, start, end))
+	tolerance := bound * sumOfMultiplierMagnitudes(lattice)
+	for index, result := range results {
+		expected := lattice.Calculate(start + complex(float64(index), 0)*step)
+		checker.Assert(cmplx.Abs(result-expected) <= tolerance, Equals, true, Commentf("%s at step %d", name, index))
+	}
+}
+
+func (suite *PlanTest) TestLatticeRowsStayWithinTheErrorBound(checker *C) {
+	lattices, err := loadExampleFormulas(filepath.Join("lattices", "*.yml"))
+	checker.Assert(err, IsNil)
+
+	for filename, lattice := range lattices {
+		checkRowStaysWithinTheErrorBound(checker, filename, lattice, complex(-3.1, 1.7), complex(0.013, 0.002), 500)
+	}
+}
+
+func (suite *PlanTest) TestHighPowerRowsFarFromTheOriginStayWithinTheErrorBound(checker *C) {
+	builders := map[string]func() *formula.Builder{
+		"square":      func() *formula.Builder { return formula.NewBuilder().Square() },
+		"hexagonal":   func() *formula.Builder { return formula.NewBuilder().Hexagonal() },
+		"rectangular": func() *formula.Builder { return formula.NewBuilder().Rectangular().LatticeHeight(0.3) },
+	}
+	powers := [][2]int{{20, 0}, {13, -9}, {40, 7}, {-60, 60}}
+	for latticeName, newBuilder := range builders {
+		for _, power := range powers {
+			lattice, err := newBuilder().
+				AddWavePacket(
+					formula.NewWavePacketBuilder().
+						Multiplier(complex(1, 0.3)).
+						AddTerm(formula.NewTermBuilder().PowerN(power[0]).PowerM(power[1]).Build()).
+						Build(),
+				).
+				Build()
+			checker.Assert(err, IsNil)
+
+			for _, distance := range []float64{300, 1000, 5000} {
+				name := fmt.Sprintf("%s lattice with powers %v, %v cells from the origin", latticeName, power, distance)
+				start := complex(distance*0.93+0.123, -distance*0.37+0.071)
+				checkRowStaysWithinTheErrorBound(checker, name, lattice, start, complex(0.00071, 0), 2000)
+				checkRowStaysWithinTheErrorBound(checker, name, lattice, start, complex(-0.0013, 0.0009), 2000)
+			}
+		}
+	}
+}
+
+// benchmarkExampleFormulas compares each example formula against calculating it without the plan.
+func benchmarkExampleFormulas(b *testing.B, pattern string, calculateWithoutPlan func(formula.Arbitrary, complex128) complex128) {
+	exampleFormulas, err := loadExampleFormulas(pattern)
+	if err != nil {
+		b.Fatal(err)
+	}
+	coordinates := sampleCoordinates()
+
+	for filename, exampleFormula := range exampleFormulas {
+		exampleFormula := exampleFormula
+		b.Run(filename+"/compiled", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, coordinate := range coordinates {
+					exampleFormula.Calculate(coordinate)
+				}
+			}
+		})
+		b.Run(filename+"/per_coordinate", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, coordinate := range coordinates {
+					calculateWithoutPlan(exampleFormula, coordinate)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkExampleLattices(b *testing.B) {
+	benchmarkExampleFormulas(b, filepath.Join("lattices", "*.yml"), func(lattice formula.Arbitrary, coordinate complex128) complex128 {
+		return formula.CalculateCoordinateUsingWavePackets(coordinate, lattice.LatticeVectors(), lattice.WavePackets())
+	})
+}
+
+func BenchmarkExampleRosettes(b *testing.B) {
+	benchmarkExampleFormulas(b, filepath.Join("rosettes", "*.yml"), func(rosette formula.Arbitrary, coordinate complex128) complex128 {
+		if _, isFrieze := rosette.(*formula.Frieze); isFrieze {
+			return calculateTermsPerCoordinate(coordinate, rosette.FormulaLevelTerms(), formula.CalculateEulerTerm)
+		}
+		return calculateTermsPerCoordinate(coordinate, rosette.FormulaLevelTerms(), formula.CalculateExponentTerm)
+	})
+}
+
+func BenchmarkExampleLatticeRows(b *testing.B) {
+	lattices, err := loadExampleFormulas(filepath.Join("lattices", "*.yml"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	start := complex(-2, 0.5)
+	step := complex(4.0/1000.0, 0)
+	results := make([]complex128, 1000)
+
+	for filename, lattice := range lattices {
+		lattice := lattice
+		b.Run(filename+"/row", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				lattice.(formula.RowCalculator).CalculateRow(start, step, results)
+			}
+		})
+		b.Run(filename+"/per_coordinate", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for index := range results {
+					results[index] = lattice.Calculate(start + complex(float64(index), 0)*step)
+				}
+			}
+		})
+	}
+}
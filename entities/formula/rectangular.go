@@ -9,6 +9,7 @@ import (
 type Rectangular struct {
 	latticeVectors []complex128
 	wavePackets    []WavePacket
+	plan           *latticePlan
 }
 
 // NewRectangularFormula returns a new formula object.
@@ -29,14 +30,16 @@ func NewRectangularFormula(packets []WavePacket, latticeHeight float64, desiredS
 
 	wavePacketsWithDesiredSymmetry := createNewWavePacketsBasedOnDesiredSymmetry(packets, desiredSymmetry)
 
-	return &Rectangular{
-			wavePackets: wavePacketsWithDesiredSymmetry,
-			latticeVectors: []complex128{
-				complex(1, 0),
-				complex(0, latticeHeight),
-			},
+	rectangularWallpaperFormula := &Rectangular{
+		wavePackets: wavePacketsWithDesiredSymmetry,
+		latticeVectors: []complex128{
+			complex(1, 0),
+			complex(0, latticeHeight),
 		},
-		nil
+	}
+	rectangularWallpaperFormula.plan = newLatticePlan(rectangularWallpaperFormula.latticeVectors, rectangularWallpaperFormula.wavePackets)
+
+	return rectangularWallpaperFormula, nil
 }
 
 // WavePackets returns the wave packets used.
@@ -46,7 +49,12 @@ func (r *Rectangular) WavePackets() []WavePacket {
 
 // Calculate transforms the coordinate using the Rectangular lattice's wave packets.
 func (r *Rectangular) Calculate(coordinate complex128) complex128 {
-	return CalculateCoordinateUsingWavePackets(coordinate, r.LatticeVectors(), r.WavePackets())
+	return r.plan.calculate(coordinate)
+}
+
+// CalculateRow transforms evenly spaced coordinates using the Rectangular lattice's wave packets.
+func (r *Rectangular) CalculateRow(start, step complex128, results []complex128) {
+	r.plan.calculateRow(start, step, results)
 }
 
 // FormulaLevelTerms returns an empty list, Rectangular formulas do not have base-level terms.
@@ -9,6 +9,7 @@ import (
 type Rhombic struct {
 	latticeVectors []complex128
 	wavePackets    []WavePacket
+	plan           *latticePlan
 }
 
 // NewRhombicFormula returns a new formula object.
@@ -31,14 +32,16 @@ func NewRhombicFormula(packets []WavePacket, latticeHeight float64, desiredSymme
 		},
 		wavePacketsWithDesiredSymmetry)
 
-	return &Rhombic{
-			wavePackets: packetsWithLockedCoefficients,
-			latticeVectors: []complex128{
-				complex(0.5, latticeHeight),
-				complex(0.5, latticeHeight*-1),
-			},
+	rhombicWallpaperFormula := &Rhombic{
+		wavePackets: packetsWithLockedCoefficients,
+		latticeVectors: []complex128{
+			complex(0.5, latticeHeight),
+			complex(0.5, latticeHeight*-1),
 		},
-		nil
+	}
+	rhombicWallpaperFormula.plan = newLatticePlan(rhombicWallpaperFormula.latticeVectors, rhombicWallpaperFormula.wavePackets)
+
+	return rhombicWallpaperFormula, nil
 }
 
 // WavePackets returns the wave packets used.
@@ -48,7 +51,12 @@ func (r *Rhombic) WavePackets() []WavePacket {
 
 // Calculate transforms the coordinate using the Rhombic lattice's wave packets.
 func (r *Rhombic) Calculate(coordinate complex128) complex128 {
-	return CalculateCoordinateUsingWavePackets(coordinate, r.LatticeVectors(), r.WavePackets())
+	return r.plan.calculate(coordinate)
+}
+
+// CalculateRow transforms evenly spaced coordinates using the Rhombic lattice's wave packets.
+func (r *Rhombic) CalculateRow(start, step complex128, results []complex128) {
+	r.plan.calculateRow(start, step, results)
 }
 
 // FormulaLevelTerms returns an empty list, Rhombic formulas do not have base-level terms.
@@ -1,19 +1,20 @@
 package formula
 
-import (
-	"github.com/chadius/creatingsymmetry/entities/formula/coefficient"
-	"math/cmplx"
-)
+import "math/cmplx"
 
 // Rosette formulas transform points around a central origin, similar to a rosette surrounding a center.
+//   The terms' coefficient relationships are expanded when the formula is made, so Calculate only does arithmetic.
+//   Each coordinate is converted to polar form once, and every term reuses its powers.
 type Rosette struct {
 	formulaLevelTerms []Term
+	plan              *rosettePlan
 }
 
 // NewRosetteFormula returns a new formula
 func NewRosetteFormula(formulaLevelTerms []Term) (*Rosette, error) {
 	return &Rosette{
 			formulaLevelTerms: formulaLevelTerms,
+			plan:              newRosettePlan(expandTerms(formulaLevelTerms)),
 		},
 		nil
 }
@@ -24,34 +25,10 @@ func (r *Rosette) WavePackets() []WavePacket {
 }
 
 // Calculate applies the Rosette formula to the complex number z.
+//   At z = 0 the result is Infinity if any term has a negative power,
+//   and NaN if a term's direction depends on how z reaches 0.
 func (r *Rosette) Calculate(coordinate complex128) complex128 {
-	sumOfTermCalculations := complex(0, 0)
-	for _, term := range r.formulaLevelTerms {
-		termCalculation := r.calculateTerm(term, coordinate)
-		sumOfTermCalculations += termCalculation
-	}
-
-	return sumOfTermCalculations
-}
-
-func (r *Rosette) calculateTerm(term Term, coordinate complex128) complex128 {
-	sum := complex(0.0, 0.0)
-
-	coefficientRelationships := []coefficient.Relationship{coefficient.PlusNPlusM}
-	coefficientRelationships = append(coefficientRelationships, term.CoefficientRelationships...)
-	coefficientSets := coefficient.Pairing{
-		PowerN: term.PowerN,
-		PowerM: term.PowerM,
-	}.GenerateCoefficientSets(coefficientRelationships)
-
-	for _, relationshipSet := range coefficientSets {
-		multiplier := term.Multiplier
-		if relationshipSet.NegateMultiplier == true {
-			multiplier *= -1
-		}
-		sum += CalculateExponentTerm(coordinate, relationshipSet.PowerN, relationshipSet.PowerM, multiplier, term.IgnoreComplexConjugate)
-	}
-	return sum
+	return r.plan.calculate(coordinate)
 }
 
 // CalculateExponentTerm calculates (z^power * zConj^conjugatePower)
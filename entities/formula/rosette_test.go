@@ -5,6 +5,7 @@ import (
 	"github.com/chadius/creatingsymmetry/entities/formula/coefficient"
 	"github.com/chadius/creatingsymmetry/entities/utility"
 	. "gopkg.in/check.v1"
+	"math/cmplx"
 )
 
 type RosetteTest struct{}
@@ -26,3 +27,79 @@ func (suite *RosetteTest) TestRosetteFormulaTransforms(checker *C) {
 	checker.Assert(real(transformedPoint), utility.NumericallyCloseEnough{}, 12, 1e-6)
 	checker.Assert(imag(transformedPoint), utility.NumericallyCloseEnough{}, 0, 1e-6)
 }
+
+func (suite *RosetteTest) TestPolarFormMatchesMultiplyingEachPower(checker *C) {
+	terms := []*formula.Term{
+		formula.NewTermBuilder().Multiplier(complex(1, -2)).PowerN(7).PowerM(-3).Build(),
+		formula.NewTermBuilder().Multiplier(complex(0.5, 0)).PowerN(-5).PowerM(2).Build(),
+		formula.NewTermBuilder().Multiplier(complex(0, 3)).PowerN(4).PowerM(4).Build(),
+		formula.NewTermBuilder().Multiplier(complex(-1, 1)).PowerN(-2).PowerM(0).IgnoreComplexConjugate().Build(),
+	}
+	builder := formula.NewBuilder().Rosette()
+	for _, term := range terms {
+		builder.AddTerm(term)
+	}
+	rosetteFormula, _ := builder.Build()
+
+	for _, coordinate := range []complex128{complex(0.3, -0.8), complex(-1.5, 0.25), complex(0, 2), complex(-0.01, 0)} {
+		expected := complex(0, 0)
+		for _, term := range terms {
+			expected += formula.CalculateExponentTerm(coordinate, term.PowerN, term.PowerM, term.Multiplier, term.IgnoreComplexConjugate)
+		}
+		transformedPoint := rosetteFormula.Calculate(coordinate)
+		tolerance := 1e-9 * cmplx.Abs(expected)
+		checker.Assert(real(transformedPoint), utility.NumericallyCloseEnough{}, real(expected), tolerance, Commentf("%v", coordinate))
+		checker.Assert(imag(transformedPoint), utility.NumericallyCloseEnough{}, imag(expected), tolerance, Commentf("%v", coordinate))
+	}
+}
+
+func (suite *RosetteTest) TestPositivePowersVanishAtTheOrigin(checker *C) {
+	rosetteFormula, _ := formula.NewBuilder().Rosette().
+		AddTerm(formula.NewTermBuilder().Multiplier(complex(2, 0)).PowerN(2).PowerM(-1).Build()).
+		AddTerm(formula.NewTermBuilder().Multiplier(complex(0, 5)).PowerN(0).PowerM(0).Build()).
+		Build()
+
+	transformedPoint := rosetteFormula.Calculate(complex(0, 0))
+	checker.Assert(real(transformedPoint), utility.NumericallyCloseEnough{}, 0, 1e-9)
+	checker.Assert(imag(transformedPoint), utility.NumericallyCloseEnough{}, 5, 1e-9)
+}
+
+func (suite *RosetteTest) TestNegativePowersAreInfiniteAtTheOrigin(checker *C) {
+	rosetteFormula, _ := formula.NewBuilder().Rosette().
+		AddTerm(formula.NewTermBuilder().Multiplier(complex(1, 0)).PowerN(1).PowerM(-1).Build()).
+		AddTerm(formula.NewTermBuilder().Multiplier(complex(1, 0)).PowerN(-3).PowerM(0).Build()).
+		Build()
+
+	checker.Assert(cmplx.IsInf(rosetteFormula.Calculate(complex(0, 0))), Equals, true)
+}
+
+func (suite *RosetteTest) TestTermsWithoutADirectionAreNaNAtTheOrigin(checker *C) {
+	rosetteFormula, _ := formula.NewBuilder().Rosette().
+		AddTerm(formula.NewTermBuilder().Multiplier(complex(1, 0)).PowerN(2).PowerM(-2).Build()).
+		AddTerm(formula.NewTermBuilder().Multiplier(complex(1, 0)).PowerN(1).PowerM(1).Build()).
+		Build()
+
+	checker.Assert(cmplx.IsNaN(rosetteFormula.Calculate(complex(0, 0))), Equals, true)
+}
+
+func (suite *RosetteTest) TestTermsThatCancelOutAreIgnoredAtTheOrigin(checker *C) {
+	rosetteFormula, _ := formula.NewBuilder().Rosette().
+		AddTerm(formula.NewTermBuilder().Multiplier(complex(1, 0)).PowerN(-1).PowerM(0).Build()).
+		AddTerm(formula.NewTermBuilder().Multiplier(complex(-1, 0)).PowerN(-1).PowerM(0).Build()).
+		AddTerm(formula.NewTermBuilder().Multiplier(complex(3, 0)).PowerN(1).PowerM(0).Build()).
+		Build()
+
+	transformedPoint := rosetteFormula.Calculate(complex(0, 0))
+	checker.Assert(real(transformedPoint), utility.NumericallyCloseEnough{}, 0, 1e-9)
+	checker.Assert(imag(transformedPoint), utility.NumericallyCloseEnough{}, 0, 1e-9)
+}
+
+func (suite *RosetteTest) TestTinyCoordinatesStayFinite(checker *C) {
+	rosetteFormula, _ := formula.NewBuilder().Rosette().
+		AddTerm(formula.NewTermBuilder().Multiplier(complex(1, 0)).PowerN(3).PowerM(-1).Build()).
+		Build()
+
+	transformedPoint := rosetteFormula.Calculate(complex(1e-200, 1e-200))
+	checker.Assert(cmplx.IsNaN(transformedPoint), Equals, false)
+	checker.Assert(cmplx.IsInf(transformedPoint), Equals, false)
+}
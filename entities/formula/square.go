@@ -9,6 +9,7 @@ import (
 type Square struct {
 	latticeVectors []complex128
 	wavePackets    []WavePacket
+	plan           *latticePlan
 }
 
 // NewSquareFormula returns a new formula object.
@@ -39,6 +40,8 @@ func NewSquareFormula(packets []WavePacket, desiredSymmetry Symmetry) (*Square,
 		},
 	}
 
+	squareWallpaperFormula.plan = newLatticePlan(squareWallpaperFormula.latticeVectors, squareWallpaperFormula.wavePackets)
+
 	return squareWallpaperFormula, nil
 }
 
@@ -49,7 +52,12 @@ func (r *Square) WavePackets() []WavePacket {
 
 // Calculate transforms the coordinate using the Square lattice's wave packets.
 func (r *Square) Calculate(coordinate complex128) complex128 {
-	return CalculateCoordinateUsingWavePackets(coordinate, r.LatticeVectors(), r.WavePackets())
+	return r.plan.calculate(coordinate)
+}
+
+// CalculateRow transforms evenly spaced coordinates using the Square lattice's wave packets.
+func (r *Square) CalculateRow(start, step complex128, results []complex128) {
+	r.plan.calculateRow(start, step, results)
 }
 
 // FormulaLevelTerms returns an empty list, Square formulas do not have base-level terms.
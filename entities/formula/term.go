@@ -4,9 +4,11 @@ import (
 	"encoding/json"
 	"github.com/chadius/creatingsymmetry/entities/formula/coefficient"
 	"github.com/chadius/creatingsymmetry/entities/utility"
+	"github.com/chadius/creatingsymmetry/entities/validation"
 	"gopkg.in/yaml.v2"
 	"math"
 	"math/cmplx"
+	"strings"
 )
 
 // Term objects help shape the calculation of every formula.
@@ -160,3 +162,25 @@ func (t *TermBuilder) WithMarshalOptions(marshaledOptions TermMarshal) *TermBuil
 
 	return t
 }
+
+// Validate returns every problem found in the term options.
+func (t TermMarshal) Validate(path string) []validation.Problem {
+	problems := []validation.Problem{}
+	for index, relationship := range t.CoefficientRelationships {
+		if relationship.IsValid() {
+			continue
+		}
+
+		knownRelationships := []string{}
+		for _, knownRelationship := range coefficient.AllRelationships() {
+			knownRelationships = append(knownRelationships, string(knownRelationship))
+		}
+		problems = append(problems, validation.NewProblemf(
+			validation.IndexPath(validation.JoinPath(path, "coefficient_relationships"), index),
+			"unknown coefficient relationship %q, expected one of: %s",
+			relationship,
+			strings.Join(knownRelationships, ", "),
+		))
+	}
+	return problems
+}
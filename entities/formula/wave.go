@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"github.com/chadius/creatingsymmetry/entities/formula/coefficient"
 	"github.com/chadius/creatingsymmetry/entities/utility"
+	"github.com/chadius/creatingsymmetry/entities/validation"
 	"gopkg.in/yaml.v2"
 )
 
@@ -306,3 +307,17 @@ func (w *WavePacketBuilder) WithMarshalOptions(marshaledOptions WavePacketMarsha
 	}
 	return w
 }
+
+// Validate returns every problem found in the wave packet options.
+func (w WavePacketMarshal) Validate(path string) []validation.Problem {
+	termsPath := validation.JoinPath(path, "terms")
+	if len(w.Terms) == 0 {
+		return []validation.Problem{validation.NewProblem(termsPath, "wave packets need at least one term")}
+	}
+
+	problems := []validation.Problem{}
+	for index, term := range w.Terms {
+		problems = append(problems, term.Validate(validation.IndexPath(termsPath, index))...)
+	}
+	return problems
+}
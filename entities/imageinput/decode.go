@@ -0,0 +1,92 @@
+package imageinput
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+)
+
+// Supported source image formats, as named by the image package.
+const (
+	pngFormatName  = "png"
+	jpegFormatName = "jpeg"
+	gifFormatName  = "gif"
+)
+
+// UnsupportedFormatError is returned when the source image is not a PNG, JPEG or GIF.
+type UnsupportedFormatError struct {
+	// DetectedFormat names the format if it was recognized, like WebP. It is empty otherwise.
+	DetectedFormat string
+}
+
+// Error explains which formats are supported.
+func (u *UnsupportedFormatError) Error() string {
+	if u.DetectedFormat != "" {
+		return fmt.Sprintf("source image is a %s image, which is not supported, use a PNG, JPEG or GIF instead", u.DetectedFormat)
+	}
+	return "source image format is not recognized, use a PNG, JPEG or GIF instead"
+}
+
+// Decode reads a PNG, JPEG or GIF image.
+//   JPEG images are rotated and flipped using their EXIF orientation, so they appear the way a photo viewer shows them.
+//   GIF images only use the first frame.
+func Decode(input io.Reader) (image.Image, error) {
+	data, err := ioutil.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+
+	switch detectFormat(data) {
+	case pngFormatName:
+		return png.Decode(bytes.NewReader(data))
+	case gifFormatName:
+		return gif.Decode(bytes.NewReader(data))
+	case jpegFormatName:
+		sourceImage, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return applyOrientation(sourceImage, readJPEGOrientation(data)), nil
+	}
+	return nil, &UnsupportedFormatError{DetectedFormat: detectUnsupportedFormat(data)}
+}
+
+// detectFormat returns the name of a supported format using the first few bytes of the data.
+//   Returns an empty string if the format is not supported.
+func detectFormat(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return pngFormatName
+	case bytes.HasPrefix(data, []byte("\xff\xd8")):
+		return jpegFormatName
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		return gifFormatName
+	}
+	return ""
+}
+
+// detectUnsupportedFormat names common image formats that cannot be decoded, so the error can explain the problem.
+func detectUnsupportedFormat(data []byte) string {
+	switch {
+	case len(data) >= 12 && bytes.Equal(data[0:4], []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WEBP")):
+		return "WebP"
+	case bytes.HasPrefix(data, []byte("BM")):
+		return "BMP"
+	case bytes.HasPrefix(data, []byte("II*\x00")), bytes.HasPrefix(data, []byte("MM\x00*")):
+		return "TIFF"
+	case len(data) >= 12 && bytes.Equal(data[4:8], []byte("ftyp")):
+		brand := string(data[8:12])
+		if brand == "heic" || brand == "heix" || brand == "mif1" {
+			return "HEIC"
+		}
+		if brand == "avif" {
+			return "AVIF"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,99 @@
+package imageinput_test
+
+import (
+	"bytes"
+	"github.com/chadius/creatingsymmetry/entities/imageinput"
+	. "gopkg.in/check.v1"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type DecodeTests struct {
+	sourceImage *image.NRGBA
+}
+
+var _ = Suite(&DecodeTests{})
+
+var (
+	red  = color.NRGBA{R: 255, A: 255}
+	blue = color.NRGBA{B: 255, A: 255}
+)
+
+// SetUpTest makes a 16x8 image, red on the left half and blue on the right half.
+func (suite *DecodeTests) SetUpTest(checker *C) {
+	suite.sourceImage = image.NewNRGBA(image.Rect(0, 0, 16, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 16; x++ {
+			if x < 8 {
+				suite.sourceImage.SetNRGBA(x, y, red)
+			} else {
+				suite.sourceImage.SetNRGBA(x, y, blue)
+			}
+		}
+	}
+}
+
+func assertColorIsClose(checker *C, actual color.Color, expected color.NRGBA) {
+	actualColor := color.NRGBAModel.Convert(actual).(color.NRGBA)
+	for _, channels := range [][2]uint8{
+		{actualColor.R, expected.R},
+		{actualColor.G, expected.G},
+		{actualColor.B, expected.B},
+	} {
+		difference := int(channels[0]) - int(channels[1])
+		checker.Assert(difference > -32 && difference < 32, Equals, true, Commentf("%v is not close to %v", actualColor, expected))
+	}
+}
+
+func (suite *DecodeTests) TestDecodesPNG(checker *C) {
+	var data bytes.Buffer
+	png.Encode(&data, suite.sourceImage)
+
+	decodedImage, err := imageinput.Decode(&data)
+	checker.Assert(err, IsNil)
+	checker.Assert(decodedImage.Bounds(), Equals, image.Rect(0, 0, 16, 8))
+	assertColorIsClose(checker, decodedImage.At(0, 0), red)
+}
+
+func (suite *DecodeTests) TestDecodesFirstGIFFrame(checker *C) {
+	firstFrame := image.NewPaletted(image.Rect(0, 0, 2, 2), palette.Plan9)
+	firstFrame.Set(0, 0, red)
+	secondFrame := image.NewPaletted(image.Rect(0, 0, 2, 2), palette.Plan9)
+	secondFrame.Set(0, 0, blue)
+	var data bytes.Buffer
+	gif.EncodeAll(&data, &gif.GIF{
+		Image: []*image.Paletted{firstFrame, secondFrame},
+		Delay: []int{0, 0},
+	})
+
+	decodedImage, err := imageinput.Decode(&data)
+	checker.Assert(err, IsNil)
+	assertColorIsClose(checker, decodedImage.At(0, 0), red)
+}
+
+func (suite *DecodeTests) TestDecodesJPEGWithoutEXIF(checker *C) {
+	var data bytes.Buffer
+	jpeg.Encode(&data, suite.sourceImage, &jpeg.Options{Quality: 100})
+
+	decodedImage, err := imageinput.Decode(&data)
+	checker.Assert(err, IsNil)
+	checker.Assert(decodedImage.Bounds(), Equals, image.Rect(0, 0, 16, 8))
+	assertColorIsClose(checker, decodedImage.At(0, 0), red)
+	assertColorIsClose(checker, decodedImage.At(15, 0), blue)
+}
+
+func (suite *DecodeTests) TestUnsupportedFormatsHaveAClearError(checker *C) {
+	_, err := imageinput.Decode(bytes.NewReader([]byte("RIFF\x00\x00\x00\x00WEBPVP8 ")))
+	checker.Assert(err, FitsTypeOf, &imageinput.UnsupportedFormatError{})
+	checker.Assert(err, ErrorMatches, "source image is a WebP image, which is not supported, use a PNG, JPEG or GIF instead")
+
+	_, err = imageinput.Decode(bytes.NewReader([]byte("this is not an image")))
+	checker.Assert(err, ErrorMatches, "source image format is not recognized, use a PNG, JPEG or GIF instead")
+}
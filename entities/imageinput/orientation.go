@@ -0,0 +1,148 @@
+package imageinput
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+)
+
+// EXIF orientation values. Each one describes how to turn the stored pixels so they appear upright.
+const (
+	orientationNormal          = 1
+	orientationFlipHorizontal  = 2
+	orientationRotate180       = 3
+	orientationFlipVertical    = 4
+	orientationTranspose       = 5
+	orientationRotateClockwise = 6
+	orientationTransverse      = 7
+	orientationRotateCounter   = 8
+)
+
+const (
+	jpegMarkerPrefix       = 0xFF
+	jpegStartOfImage       = 0xD8
+	jpegStartOfScan        = 0xDA
+	jpegApplicationSegment = 0xE1
+	exifOrientationTag     = 0x0112
+	exifShortType          = 3
+)
+
+// readJPEGOrientation looks for the EXIF orientation in the JPEG's APP1 segment.
+//   Returns orientationNormal if there is no orientation or the EXIF data cannot be read.
+func readJPEGOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != jpegMarkerPrefix || data[1] != jpegStartOfImage {
+		return orientationNormal
+	}
+
+	offset := 2
+	for offset+4 <= len(data) {
+		if data[offset] != jpegMarkerPrefix {
+			return orientationNormal
+		}
+		marker := data[offset+1]
+		if marker == jpegStartOfScan {
+			return orientationNormal
+		}
+
+		segmentLength := int(binary.BigEndian.Uint16(data[offset+2 : offset+4]))
+		segmentEnd := offset + 2 + segmentLength
+		if segmentLength < 2 || segmentEnd > len(data) {
+			return orientationNormal
+		}
+
+		segment := data[offset+4 : segmentEnd]
+		if marker == jpegApplicationSegment && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return readEXIFOrientation(segment[6:])
+		}
+		offset = segmentEnd
+	}
+	return orientationNormal
+}
+
+// readEXIFOrientation reads the orientation tag from the first image file directory of the TIFF structured EXIF data.
+func readEXIFOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return orientationNormal
+	}
+
+	var byteOrder binary.ByteOrder
+	switch string(tiff[0:2]) {
+	case "II":
+		byteOrder = binary.LittleEndian
+	case "MM":
+		byteOrder = binary.BigEndian
+	default:
+		return orientationNormal
+	}
+
+	directoryOffset := int(byteOrder.Uint32(tiff[4:8]))
+	if directoryOffset < 8 || directoryOffset+2 > len(tiff) {
+		return orientationNormal
+	}
+
+	entryCount := int(byteOrder.Uint16(tiff[directoryOffset : directoryOffset+2]))
+	for entryIndex := 0; entryIndex < entryCount; entryIndex++ {
+		entryOffset := directoryOffset + 2 + entryIndex*12
+		if entryOffset+12 > len(tiff) {
+			return orientationNormal
+		}
+		entry := tiff[entryOffset : entryOffset+12]
+		if byteOrder.Uint16(entry[0:2]) != exifOrientationTag {
+			continue
+		}
+		if byteOrder.Uint16(entry[2:4]) != exifShortType {
+			return orientationNormal
+		}
+
+		orientation := int(byteOrder.Uint16(entry[8:10]))
+		if orientation < orientationNormal || orientation > orientationRotateCounter {
+			return orientationNormal
+		}
+		return orientation
+	}
+	return orientationNormal
+}
+
+// applyOrientation returns a copy of the image turned upright.
+//   Images with the normal orientation are returned unchanged.
+func applyOrientation(sourceImage image.Image, orientation int) image.Image {
+	if orientation == orientationNormal {
+		return sourceImage
+	}
+
+	bounds := sourceImage.Bounds()
+	source := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(source, source.Bounds(), sourceImage, bounds.Min, draw.Src)
+	width, height := bounds.Dx(), bounds.Dy()
+
+	outputWidth, outputHeight := width, height
+	if orientation >= orientationTranspose {
+		outputWidth, outputHeight = height, width
+	}
+	output := image.NewNRGBA(image.Rect(0, 0, outputWidth, outputHeight))
+
+	for outputY := 0; outputY < outputHeight; outputY++ {
+		for outputX := 0; outputX < outputWidth; outputX++ {
+			sourceX, sourceY := outputX, outputY
+			switch orientation {
+			case orientationFlipHorizontal:
+				sourceX = width - 1 - outputX
+			case orientationRotate180:
+				sourceX, sourceY = width-1-outputX, height-1-outputY
+			case orientationFlipVertical:
+				sourceY = height - 1 - outputY
+			case orientationTranspose:
+				sourceX, sourceY = outputY, outputX
+			case orientationRotateClockwise:
+				sourceX, sourceY = outputY, height-1-outputX
+			case orientationTransverse:
+				sourceX, sourceY = width-1-outputY, height-1-outputX
+			case orientationRotateCounter:
+				sourceX, sourceY = width-1-outputY, outputX
+			}
+			output.SetNRGBA(outputX, outputY, source.NRGBAAt(sourceX, sourceY))
+		}
+	}
+	return output
+}
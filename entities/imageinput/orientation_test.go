@@ -0,0 +1,127 @@
+package imageinput_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/chadius/creatingsymmetry/entities/imageinput"
+	. "gopkg.in/check.v1"
+	"image"
+	"image/color"
+	"image/jpeg"
+)
+
+type OrientationTests struct {
+	sourceImage *image.NRGBA
+}
+
+var _ = Suite(&OrientationTests{})
+
+// SetUpTest makes a 16x8 image with a red top left quarter, a blue top right quarter and a white bottom half.
+func (suite *OrientationTests) SetUpTest(checker *C) {
+	suite.sourceImage = image.NewNRGBA(image.Rect(0, 0, 16, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 16; x++ {
+			switch {
+			case y >= 4:
+				suite.sourceImage.SetNRGBA(x, y, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+			case x < 8:
+				suite.sourceImage.SetNRGBA(x, y, red)
+			default:
+				suite.sourceImage.SetNRGBA(x, y, blue)
+			}
+		}
+	}
+}
+
+// jpegWithOrientation encodes the image and adds an EXIF segment with the orientation after the start of image marker.
+func jpegWithOrientation(sourceImage image.Image, orientation uint16, byteOrder binary.ByteOrder) []byte {
+	var encoded bytes.Buffer
+	jpeg.Encode(&encoded, sourceImage, &jpeg.Options{Quality: 100})
+
+	var tiff bytes.Buffer
+	if byteOrder == binary.LittleEndian {
+		tiff.WriteString("II")
+	} else {
+		tiff.WriteString("MM")
+	}
+	binary.Write(&tiff, byteOrder, uint16(42))
+	binary.Write(&tiff, byteOrder, uint32(8))
+	binary.Write(&tiff, byteOrder, uint16(1))
+	binary.Write(&tiff, byteOrder, uint16(0x0112))
+	binary.Write(&tiff, byteOrder, uint16(3))
+	binary.Write(&tiff, byteOrder, uint32(1))
+	binary.Write(&tiff, byteOrder, orientation)
+	binary.Write(&tiff, byteOrder, uint16(0))
+	binary.Write(&tiff, byteOrder, uint32(0))
+
+	segment := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	var data bytes.Buffer
+	data.Write(encoded.Bytes()[:2])
+	data.Write([]byte{0xFF, 0xE1})
+	binary.Write(&data, binary.BigEndian, uint16(len(segment)+2))
+	data.Write(segment)
+	data.Write(encoded.Bytes()[2:])
+	return data.Bytes()
+}
+
+func (suite *OrientationTests) decodeWithOrientation(checker *C, orientation uint16, byteOrder binary.ByteOrder) image.Image {
+	decodedImage, err := imageinput.Decode(bytes.NewReader(jpegWithOrientation(suite.sourceImage, orientation, byteOrder)))
+	checker.Assert(err, IsNil)
+	return decodedImage
+}
+
+func (suite *OrientationTests) TestNormalOrientationIsUnchanged(checker *C) {
+	decodedImage := suite.decodeWithOrientation(checker, 1, binary.BigEndian)
+
+	checker.Assert(decodedImage.Bounds(), Equals, image.Rect(0, 0, 16, 8))
+	assertColorIsClose(checker, decodedImage.At(2, 1), red)
+	assertColorIsClose(checker, decodedImage.At(13, 1), blue)
+}
+
+func (suite *OrientationTests) TestRotate180(checker *C) {
+	decodedImage := suite.decodeWithOrientation(checker, 3, binary.LittleEndian)
+
+	checker.Assert(decodedImage.Bounds(), Equals, image.Rect(0, 0, 16, 8))
+	assertColorIsClose(checker, decodedImage.At(13, 6), red)
+	assertColorIsClose(checker, decodedImage.At(2, 6), blue)
+}
+
+func (suite *OrientationTests) TestRotateClockwise(checker *C) {
+	decodedImage := suite.decodeWithOrientation(checker, 6, binary.BigEndian)
+
+	checker.Assert(decodedImage.Bounds(), Equals, image.Rect(0, 0, 8, 16))
+	assertColorIsClose(checker, decodedImage.At(6, 2), red)
+	assertColorIsClose(checker, decodedImage.At(6, 13), blue)
+}
+
+func (suite *OrientationTests) TestRotateCounterClockwise(checker *C) {
+	decodedImage := suite.decodeWithOrientation(checker, 8, binary.LittleEndian)
+
+	checker.Assert(decodedImage.Bounds(), Equals, image.Rect(0, 0, 8, 16))
+	assertColorIsClose(checker, decodedImage.At(1, 13), red)
+	assertColorIsClose(checker, decodedImage.At(1, 2), blue)
+}
+
+func (suite *OrientationTests) TestMirroredOrientations(checker *C) {
+	flippedHorizontally := suite.decodeWithOrientation(checker, 2, binary.BigEndian)
+	assertColorIsClose(checker, flippedHorizontally.At(2, 1), blue)
+
+	flippedVertically := suite.decodeWithOrientation(checker, 4, binary.BigEndian)
+	assertColorIsClose(checker, flippedVertically.At(2, 6), red)
+
+	transposed := suite.decodeWithOrientation(checker, 5, binary.BigEndian)
+	checker.Assert(transposed.Bounds(), Equals, image.Rect(0, 0, 8, 16))
+	assertColorIsClose(checker, transposed.At(1, 2), red)
+	assertColorIsClose(checker, transposed.At(1, 13), blue)
+
+	transversed := suite.decodeWithOrientation(checker, 7, binary.BigEndian)
+	checker.Assert(transversed.Bounds(), Equals, image.Rect(0, 0, 8, 16))
+	assertColorIsClose(checker, transversed.At(6, 13), red)
+	assertColorIsClose(checker, transversed.At(6, 2), blue)
+}
+
+func (suite *OrientationTests) TestInvalidOrientationIsIgnored(checker *C) {
+	decodedImage := suite.decodeWithOrientation(checker, 42, binary.BigEndian)
+
+	checker.Assert(decodedImage.Bounds(), Equals, image.Rect(0, 0, 16, 8))
+}
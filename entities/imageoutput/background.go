@@ -0,0 +1,105 @@
+package imageoutput
+
+import (
+	"image"
+	"image/color"
+)
+
+// Background chooses the color drawn behind coordinates that were filtered out or could not be mapped.
+type Background interface {
+	ColorAt(coordinate *MappedCoordinate) color.NRGBA
+}
+
+// SolidColorBackground fills with a single color.
+type SolidColorBackground struct {
+	color color.NRGBA
+}
+
+// NewSolidColorBackground fills with the given color.
+func NewSolidColorBackground(fillColor color.NRGBA) *SolidColorBackground {
+	return &SolidColorBackground{color: fillColor}
+}
+
+// ColorAt returns the fill color.
+func (s *SolidColorBackground) ColorAt(coordinate *MappedCoordinate) color.NRGBA {
+	return s.color
+}
+
+// ImageBackground stretches an image over the output image.
+//   Using the source image shows it untransformed behind the pattern.
+type ImageBackground struct {
+	backgroundImage image.Image
+	outputWidth     int
+	outputHeight    int
+}
+
+// NewImageBackground stretches the image over an output image that is outputWidth by outputHeight pixels.
+func NewImageBackground(backgroundImage image.Image, outputWidth, outputHeight int) *ImageBackground {
+	return &ImageBackground{
+		backgroundImage: backgroundImage,
+		outputWidth:     outputWidth,
+		outputHeight:    outputHeight,
+	}
+}
+
+// ColorAt blends the background image's pixels where the coordinate will be drawn.
+func (i *ImageBackground) ColorAt(coordinate *MappedCoordinate) color.NRGBA {
+	bounds := i.backgroundImage.Bounds()
+	grid := &pixelGrid{
+		sourceImage: i.backgroundImage,
+		left:        bounds.Min.X,
+		right:       bounds.Max.X,
+		top:         bounds.Min.Y,
+		bottom:      bounds.Max.Y,
+		edgeMode:    ClampEdgeMode,
+	}
+	return grid.sample(
+		BilinearSampling,
+		float64(bounds.Min.X)+coordinate.InputImageSampleX()/float64(i.outputWidth)*float64(bounds.Dx()),
+		float64(bounds.Min.Y)+coordinate.InputImageSampleY()/float64(i.outputHeight)*float64(bounds.Dy()),
+	)
+}
+
+// FillBackground draws the backgrounds behind the colors the eyedropper chose for each coordinate.
+//   Coordinates at Infinity or NaN use the unmapped background.
+//   Coordinates the threshold did not fully keep show the filtered background, in proportion to how much was removed.
+//   A nil background leaves the colors transparent.
+func FillBackground(collection *CoordinateCollection, colors *[]color.Color, filtered, unmapped Background) {
+	if colors == nil {
+		return
+	}
+	for coordinateIndex, coordinate := range *collection.Coordinates() {
+		if coordinateIndex >= len(*colors) {
+			return
+		}
+
+		if !coordinate.CanBeCompared() {
+			if unmapped != nil {
+				(*colors)[coordinateIndex] = unmapped.ColorAt(coordinate)
+			}
+			continue
+		}
+
+		if filtered == nil || coordinate.FilterWeight() >= 1 {
+			continue
+		}
+		(*colors)[coordinateIndex] = blendBehind(
+			(*colors)[coordinateIndex],
+			filtered.ColorAt(coordinate),
+			1-coordinate.FilterWeight(),
+		)
+	}
+}
+
+// blendBehind adds the background, scaled by its weight, to the foreground color.
+//   The foreground was already faded by the threshold, so the weights add up to the original color.
+func blendBehind(foreground color.Color, background color.NRGBA, backgroundWeight float64) color.NRGBA {
+	r, g, b, a := foreground.RGBA()
+	blended := premultipliedColor{r: float64(r), g: float64(g), b: float64(b), a: float64(a)}
+	backgroundR, backgroundG, backgroundB, backgroundA := background.RGBA()
+	blended = blended.add(
+		premultipliedColor{r: float64(backgroundR), g: float64(backgroundG), b: float64(backgroundB), a: float64(backgroundA)},
+		backgroundWeight,
+	)
+	return blended.toNRGBA()
+}
@@ -0,0 +1,86 @@
+package imageoutput_test
+
+import (
+	"github.com/chadius/creatingsymmetry/entities/imageoutput"
+	. "gopkg.in/check.v1"
+	"image"
+	"image/color"
+	"math"
+)
+
+type BackgroundTests struct {
+	coordinates []*imageoutput.MappedCoordinate
+	colors      []color.Color
+}
+
+var _ = Suite(&BackgroundTests{})
+
+// SetUpTest creates a kept coordinate, a filtered coordinate, a half kept coordinate and an unmapped coordinate.
+//   Their colors are what the eyedropper would choose.
+func (suite *BackgroundTests) SetUpTest(checker *C) {
+	suite.coordinates = []*imageoutput.MappedCoordinate{
+		imageoutput.NewMappedCoordinateUsingInputImageSample(0, 0, 0.5, 0.5),
+		imageoutput.NewMappedCoordinateUsingInputImageSample(1, 0, 0.5, 0.5),
+		imageoutput.NewMappedCoordinateUsingInputImageSample(0, 1, 0.5, 0.5),
+		imageoutput.NewMappedCoordinateUsingInputImageSample(1, 1, 0.5, 0.5),
+	}
+	suite.coordinates[0].MarkAsSatisfyingFilter()
+	suite.coordinates[2].MarkFilterWeight(0.5)
+	suite.coordinates[3].UpdateTransformedCoordinates(math.Inf(1), 0)
+
+	suite.colors = []color.Color{
+		color.NRGBA{R: 255, A: 255},
+		color.NRGBA{},
+		color.NRGBA{R: 255, A: 128},
+		color.NRGBA{},
+	}
+}
+
+func (suite *BackgroundTests) fill(filtered, unmapped imageoutput.Background) []color.NRGBA {
+	collection := imageoutput.CoordinateCollectionBuilder().WithCoordinates(&suite.coordinates).Build()
+	imageoutput.FillBackground(collection, &suite.colors, filtered, unmapped)
+
+	filledColors := []color.NRGBA{}
+	for _, filledColor := range suite.colors {
+		filledColors = append(filledColors, color.NRGBAModel.Convert(filledColor).(color.NRGBA))
+	}
+	return filledColors
+}
+
+func (suite *BackgroundTests) TestNoBackgroundsLeaveColorsTransparent(checker *C) {
+	checker.Assert(suite.fill(nil, nil), DeepEquals, []color.NRGBA{
+		{R: 255, A: 255},
+		{},
+		{R: 255, A: 128},
+		{},
+	})
+}
+
+func (suite *BackgroundTests) TestFilteredAndUnmappedBackgroundsAreSeparate(checker *C) {
+	white := imageoutput.NewSolidColorBackground(color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	blue := imageoutput.NewSolidColorBackground(color.NRGBA{B: 255, A: 255})
+
+	filledColors := suite.fill(white, blue)
+	checker.Assert(filledColors[0], Equals, color.NRGBA{R: 255, A: 255})
+	checker.Assert(filledColors[1], Equals, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	checker.Assert(filledColors[2], Equals, color.NRGBA{R: 255, G: 128, B: 128, A: 255})
+	checker.Assert(filledColors[3], Equals, color.NRGBA{B: 255, A: 255})
+
+	suite.SetUpTest(checker)
+	filledColors = suite.fill(nil, blue)
+	checker.Assert(filledColors[1], Equals, color.NRGBA{})
+	checker.Assert(filledColors[3], Equals, color.NRGBA{B: 255, A: 255})
+}
+
+func (suite *BackgroundTests) TestImageBackgroundIsStretchedOverTheOutput(checker *C) {
+	backgroundImage := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			backgroundImage.Set(x, y, color.NRGBA{G: uint8(x * 60), B: uint8(y * 60), A: 255})
+		}
+	}
+	background := imageoutput.NewImageBackground(backgroundImage, 2, 2)
+
+	checker.Assert(background.ColorAt(imageoutput.NewMappedCoordinateUsingInputImageSample(0, 0, 0.25, 0.25)), Equals, color.NRGBA{A: 255})
+	checker.Assert(background.ColorAt(imageoutput.NewMappedCoordinateUsingInputImageSample(1, 1, 0.75, 0.75)), Equals, color.NRGBA{G: 180, B: 180, A: 255})
+}
@@ -0,0 +1,108 @@
+package imageoutput
+
+import "math"
+
+// CompositeCoordinateThreshold combines other thresholds, like keeping coordinates that are inside a ring
+//   but not inside a sector.
+//   Feathered children are combined by weight: unions keep the largest weight and intersections keep the smallest.
+//   Coordinates at Infinity or NaN are never kept, even when the children are inverted.
+type CompositeCoordinateThreshold struct {
+	children []CoordinateThreshold
+	combine  func(weightByChild []float64) float64
+}
+
+// NewUnionCoordinateThreshold keeps coordinates kept by any of the children.
+func NewUnionCoordinateThreshold(children ...CoordinateThreshold) *CompositeCoordinateThreshold {
+	return &CompositeCoordinateThreshold{
+		children: children,
+		combine: func(weightByChild []float64) float64 {
+			weight := 0.0
+			for _, childWeight := range weightByChild {
+				weight = math.Max(weight, childWeight)
+			}
+			return weight
+		},
+	}
+}
+
+// NewIntersectionCoordinateThreshold keeps coordinates kept by every child.
+func NewIntersectionCoordinateThreshold(children ...CoordinateThreshold) *CompositeCoordinateThreshold {
+	return &CompositeCoordinateThreshold{
+		children: children,
+		combine: func(weightByChild []float64) float64 {
+			if len(weightByChild) == 0 {
+				return 0
+			}
+			weight := 1.0
+			for _, childWeight := range weightByChild {
+				weight = math.Min(weight, childWeight)
+			}
+			return weight
+		},
+	}
+}
+
+// NewDifferenceCoordinateThreshold keeps coordinates kept by the first threshold
+//   that are not kept by any of the removed thresholds.
+func NewDifferenceCoordinateThreshold(first CoordinateThreshold, removed ...CoordinateThreshold) *CompositeCoordinateThreshold {
+	return &CompositeCoordinateThreshold{
+		children: append([]CoordinateThreshold{first}, removed...),
+		combine: func(weightByChild []float64) float64 {
+			weight := weightByChild[0]
+			for _, removedWeight := range weightByChild[1:] {
+				weight = math.Min(weight, 1-removedWeight)
+			}
+			return weight
+		},
+	}
+}
+
+// NewInvertedCoordinateThreshold keeps coordinates the child does not keep.
+func NewInvertedCoordinateThreshold(child CoordinateThreshold) *CompositeCoordinateThreshold {
+	return &CompositeCoordinateThreshold{
+		children: []CoordinateThreshold{child},
+		combine: func(weightByChild []float64) float64 {
+			return 1 - weightByChild[0]
+		},
+	}
+}
+
+// FilterAndMarkMappedCoordinateCollection asks each child how much of each coordinate it keeps,
+//   then marks the coordinates with the combined weight.
+func (c *CompositeCoordinateThreshold) FilterAndMarkMappedCoordinateCollection(collection *CoordinateCollection) {
+	coordinates := *collection.Coordinates()
+	weightsByChild := make([][]float64, len(c.children))
+	for childIndex, child := range c.children {
+		weightsByChild[childIndex] = filterWeightsFrom(child, coordinates)
+	}
+
+	weightByChild := make([]float64, len(c.children))
+	for coordinateIndex, coordinateToFilter := range coordinates {
+		if !coordinateToFilter.CanBeCompared() {
+			continue
+		}
+		for childIndex := range c.children {
+			weightByChild[childIndex] = weightsByChild[childIndex][coordinateIndex]
+		}
+		coordinateToFilter.MarkFilterWeight(c.combine(weightByChild))
+	}
+}
+
+// filterWeightsFrom runs the threshold on copies of the coordinates, so the originals are not marked.
+//   The copies keep every location, so thresholds can look at where the coordinate will be drawn.
+//   Returns the filter weight the threshold gave each coordinate.
+func filterWeightsFrom(threshold CoordinateThreshold, coordinates []*MappedCoordinate) []float64 {
+	copies := make([]*MappedCoordinate, len(coordinates))
+	for coordinateIndex, coordinate := range coordinates {
+		unmarkedCopy := *coordinate
+		unmarkedCopy.filterWeight = 0
+		copies[coordinateIndex] = &unmarkedCopy
+	}
+	threshold.FilterAndMarkMappedCoordinateCollection(CoordinateCollectionBuilder().WithCoordinates(&copies).Build())
+
+	weights := make([]float64, len(coordinates))
+	for coordinateIndex, coordinate := range copies {
+		weights[coordinateIndex] = coordinate.FilterWeight()
+	}
+	return weights
+}
@@ -0,0 +1,77 @@
+package imageoutput_test
+
+import (
+	"github.com/chadius/creatingsymmetry/entities/imageoutput"
+	. "gopkg.in/check.v1"
+	"math"
+)
+
+type CompositeCoordinateThresholdTests struct {
+	ring      imageoutput.CoordinateThreshold
+	rightHalf imageoutput.CoordinateThreshold
+}
+
+var _ = Suite(&CompositeCoordinateThresholdTests{})
+
+func (suite *CompositeCoordinateThresholdTests) SetUpTest(checker *C) {
+	suite.ring = imageoutput.NewAnnulusCoordinateThreshold(0, 0, 2, 5)
+	suite.rightHalf = imageoutput.CoordinateFilterBuilder().
+		WithMinimumX(0).
+		WithMaximumX(10).
+		WithMinimumY(-10).
+		WithMaximumY(10).
+		Build()
+}
+
+func (suite *CompositeCoordinateThresholdTests) TestUnionKeepsCoordinatesKeptByAnyChild(checker *C) {
+	threshold := imageoutput.NewUnionCoordinateThreshold(suite.ring, suite.rightHalf)
+
+	checker.Assert(
+		satisfiedPoints(threshold, -3, 1, 3, -1, complex(math.NaN(), 0)),
+		DeepEquals,
+		[]bool{true, true, true, false, false},
+	)
+}
+
+func (suite *CompositeCoordinateThresholdTests) TestIntersectionKeepsCoordinatesKeptByEveryChild(checker *C) {
+	threshold := imageoutput.NewIntersectionCoordinateThreshold(suite.ring, suite.rightHalf)
+
+	checker.Assert(
+		satisfiedPoints(threshold, -3, 1, 3, -1),
+		DeepEquals,
+		[]bool{false, false, true, false},
+	)
+}
+
+func (suite *CompositeCoordinateThresholdTests) TestDifferenceRemovesCoordinatesKeptByTheOtherChildren(checker *C) {
+	threshold := imageoutput.NewDifferenceCoordinateThreshold(suite.ring, suite.rightHalf)
+
+	checker.Assert(
+		satisfiedPoints(threshold, -3, 1, 3, 3i, -1),
+		DeepEquals,
+		[]bool{true, false, false, false, false},
+	)
+}
+
+func (suite *CompositeCoordinateThresholdTests) TestInvertKeepsComparableCoordinatesTheChildRejects(checker *C) {
+	threshold := imageoutput.NewInvertedCoordinateThreshold(suite.ring)
+
+	checker.Assert(
+		satisfiedPoints(threshold, 1, 3, 6, complex(math.Inf(1), 0)),
+		DeepEquals,
+		[]bool{true, false, true, false},
+	)
+}
+
+func (suite *CompositeCoordinateThresholdTests) TestCompositesCanBeNested(checker *C) {
+	threshold := imageoutput.NewUnionCoordinateThreshold(
+		imageoutput.NewDifferenceCoordinateThreshold(suite.ring, suite.rightHalf),
+		imageoutput.NewDiscCoordinateThreshold(0, 0, 0.5),
+	)
+
+	checker.Assert(
+		satisfiedPoints(threshold, -3, 3, 0.25, 1),
+		DeepEquals,
+		[]bool{true, false, true, false},
+	)
+}
@@ -1,6 +1,9 @@
 package imageoutput
 
-import "math"
+import (
+	"math"
+	"sort"
+)
 
 // CoordinateCollection holds an array of coordinates as they turn into symmetry patterns.
 type CoordinateCollection struct {
@@ -67,3 +70,77 @@ func (c *CoordinateCollection) MaximumTransformedY() float64 {
 	}
 	return maximumY
 }
+
+// MaximumTransformedModulus returns the greatest distance from the origin to a transformed coordinate in the collection.
+func (c *CoordinateCollection) MaximumTransformedModulus() float64 {
+	foundCandidate := false
+	maximumModulus := math.NaN()
+	for _, coordinate := range *c.coordinates {
+		coordinateIsValid := coordinate.CanBeCompared() && coordinate.SatisfiesFilter()
+		modulus := math.Hypot(coordinate.TransformedX(), coordinate.TransformedY())
+		if coordinateIsValid && (!foundCandidate || modulus > maximumModulus) {
+			maximumModulus = modulus
+			foundCandidate = true
+		}
+	}
+	return maximumModulus
+}
+
+// TransformedXPercentiles returns the TransformedX coordinates that the lower and upper percent of the collection are below.
+//   Percents go from 0 to 100, so 0 and 100 return the minimum and maximum.
+//   A few coordinates near a pole can stretch the minimum and maximum very far,
+//   but they barely move percentiles like the 1st and the 99th.
+func (c *CoordinateCollection) TransformedXPercentiles(lowerPercent, upperPercent float64) (float64, float64) {
+	sortedX := sortedValues(*c.coordinates, true, func(coordinate *MappedCoordinate) float64 {
+		return coordinate.TransformedX()
+	})
+	return percentileOfSortedValues(sortedX, lowerPercent), percentileOfSortedValues(sortedX, upperPercent)
+}
+
+// TransformedYPercentiles returns the TransformedY coordinates that the lower and upper percent of the collection are below.
+func (c *CoordinateCollection) TransformedYPercentiles(lowerPercent, upperPercent float64) (float64, float64) {
+	sortedY := sortedValues(*c.coordinates, true, func(coordinate *MappedCoordinate) float64 {
+		return coordinate.TransformedY()
+	})
+	return percentileOfSortedValues(sortedY, lowerPercent), percentileOfSortedValues(sortedY, upperPercent)
+}
+
+// TransformedModulusPercentile returns the distance from the origin that the given percent of the collection is within.
+func (c *CoordinateCollection) TransformedModulusPercentile(percent float64) float64 {
+	sortedModulus := sortedValues(*c.coordinates, true, func(coordinate *MappedCoordinate) float64 {
+		return math.Hypot(coordinate.TransformedX(), coordinate.TransformedY())
+	})
+	return percentileOfSortedValues(sortedModulus, percent)
+}
+
+// sortedValues returns the value of each comparable coordinate, from smallest to largest.
+//   If onlyFiltered is true, coordinates that did not satisfy the filter are left out.
+func sortedValues(coordinates []*MappedCoordinate, onlyFiltered bool, value func(coordinate *MappedCoordinate) float64) []float64 {
+	values := []float64{}
+	for _, coordinate := range coordinates {
+		if !coordinate.CanBeCompared() {
+			continue
+		}
+		if onlyFiltered && !coordinate.SatisfiesFilter() {
+			continue
+		}
+		values = append(values, value(coordinate))
+	}
+	sort.Float64s(values)
+	return values
+}
+
+// percentileOfSortedValues returns the value that the percent of the sorted values are below,
+//   interpolating between the closest values. Returns NaN if there are no values.
+func percentileOfSortedValues(sorted []float64, percent float64) float64 {
+	if len(sorted) == 0 {
+		return math.NaN()
+	}
+	rank := math.Max(0, math.Min(100, percent)) / 100 * float64(len(sorted)-1)
+	lowerIndex := int(math.Floor(rank))
+	if lowerIndex >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	fraction := rank - float64(lowerIndex)
+	return sorted[lowerIndex] + fraction*(sorted[lowerIndex+1]-sorted[lowerIndex])
+}
@@ -2,6 +2,7 @@ package imageoutput_test
 
 import (
 	"github.com/chadius/creatingsymmetry/entities/imageoutput"
+	"github.com/chadius/creatingsymmetry/entities/utility"
 	. "gopkg.in/check.v1"
 	"math"
 )
@@ -122,3 +123,59 @@ func (suite *CoordinateCollectionTests) TestReturnsNaNMinimumIfAllCoordinatesAre
 	checker.Assert(math.IsNaN(collection.MinimumTransformedX()), Equals, true)
 	checker.Assert(math.IsNaN(collection.MinimumTransformedY()), Equals, true)
 }
+
+func (suite *CoordinateCollectionTests) TestReturnsMaximumModulusRespectingSatisfiedFilter(checker *C) {
+	coordinates := []*imageoutput.MappedCoordinate{
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(-3, 4),
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(1, 0),
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(100, 0),
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(math.Inf(1), 0),
+	}
+	collection := imageoutput.CoordinateCollectionBuilder().WithCoordinates(&coordinates).Build()
+	(*collection.Coordinates())[0].MarkAsSatisfyingFilter()
+	(*collection.Coordinates())[1].MarkAsSatisfyingFilter()
+	(*collection.Coordinates())[3].MarkAsSatisfyingFilter()
+	checker.Assert(collection.MaximumTransformedModulus(), Equals, float64(5))
+}
+
+func (suite *CoordinateCollectionTests) TestReturnsPercentilesOfFilteredCoordinates(checker *C) {
+	coordinates := []*imageoutput.MappedCoordinate{
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(0, 0),
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(3, -30),
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(1, -10),
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(1000, 20),
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(2, -20),
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(-500, 0),
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(math.NaN(), 0),
+	}
+	collection := imageoutput.CoordinateCollectionBuilder().WithCoordinates(&coordinates).Build()
+	for _, coordinate := range coordinates[:5] {
+		coordinate.MarkAsSatisfyingFilter()
+	}
+	coordinates[6].MarkAsSatisfyingFilter()
+
+	lowerX, upperX := collection.TransformedXPercentiles(25, 75)
+	checker.Assert(lowerX, Equals, 1.0)
+	checker.Assert(upperX, Equals, 3.0)
+
+	lowerX, upperX = collection.TransformedXPercentiles(0, 100)
+	checker.Assert(lowerX, Equals, 0.0)
+	checker.Assert(upperX, Equals, 1000.0)
+
+	lowerY, upperY := collection.TransformedYPercentiles(10, 50)
+	checker.Assert(lowerY, utility.NumericallyCloseEnough{}, -26.0, 1e-6)
+	checker.Assert(upperY, Equals, -10.0)
+
+	checker.Assert(collection.TransformedModulusPercentile(50), utility.NumericallyCloseEnough{}, math.Hypot(2, -20), 1e-6)
+}
+
+func (suite *CoordinateCollectionTests) TestPercentilesOfEmptyCollectionsAreNaN(checker *C) {
+	coordinates := []*imageoutput.MappedCoordinate{
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(1, 1),
+	}
+	collection := imageoutput.CoordinateCollectionBuilder().WithCoordinates(&coordinates).Build()
+
+	lowerX, _ := collection.TransformedXPercentiles(1, 99)
+	checker.Assert(math.IsNaN(lowerX), Equals, true)
+	checker.Assert(math.IsNaN(collection.TransformedModulusPercentile(50)), Equals, true)
+}
@@ -1,5 +1,7 @@
 package imageoutput
 
+import "math"
+
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
 
 //counterfeiter:generate . CoordinateThreshold
@@ -80,3 +82,14 @@ func (c *RectangularCoordinateThreshold) FilterAndMarkMappedCoordinateCollection
 		c.filterAndMarkMappedCoordinate(coordinateToFilter)
 	}
 }
+
+// SignedDistance returns the distance from the point to the rectangle's nearest side.
+//   It is negative inside the rectangle.
+func (c *RectangularCoordinateThreshold) SignedDistance(x, y float64) float64 {
+	distancePastSideX := math.Max(c.minimumX-x, x-c.maximumX)
+	distancePastSideY := math.Max(c.minimumY-y, y-c.maximumY)
+	if distancePastSideX <= 0 && distancePastSideY <= 0 {
+		return math.Max(distancePastSideX, distancePastSideY)
+	}
+	return math.Hypot(math.Max(distancePastSideX, 0), math.Max(distancePastSideY, 0))
+}
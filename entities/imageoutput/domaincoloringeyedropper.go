@@ -0,0 +1,99 @@
+package imageoutput
+
+import (
+	"image/color"
+	"math"
+)
+
+// contourShadeDepth is how much darker the bottom of each contour band is than its top.
+const contourShadeDepth = 0.25
+
+// DomainColoringEyedropper colors each coordinate using the transformed complex value itself, instead of a source image.
+//   The argument picks the hue: red along the positive real axis, then yellow, green, cyan, blue and magenta counterclockwise.
+//   The modulus picks the lightness: zeros are black, a modulus of 1 is fully saturated and large values fade to white.
+//   Contours shade bands where the modulus doubles, or where the argument crosses evenly spaced angles,
+//   so the structure of the formula can be read without choosing a source image.
+type DomainColoringEyedropper struct {
+	modulusContours bool
+	phaseContours   int
+}
+
+// ModulusContours returns true if a band is shaded each time the modulus doubles.
+func (e *DomainColoringEyedropper) ModulusContours() bool {
+	return e.modulusContours
+}
+
+// PhaseContours returns how many bands the argument is shaded into around the circle. 0 means there are no bands.
+func (e *DomainColoringEyedropper) PhaseContours() int {
+	return e.phaseContours
+}
+
+// ConvertCoordinatesToColors colors each coordinate by its transformed value.
+//   Coordinates that did not satisfy the filter or are at infinity turn transparent.
+func (e *DomainColoringEyedropper) ConvertCoordinatesToColors(collection *CoordinateCollection) *[]color.Color {
+	convertedColors := []color.Color{}
+	for _, coordinate := range *collection.Coordinates() {
+		if !coordinate.SatisfiesFilter() || !coordinate.CanBeCompared() {
+			convertedColors = append(convertedColors, color.NRGBA{})
+			continue
+		}
+		convertedColors = append(convertedColors, fadeByFilterWeight(e.colorAt(coordinate.TransformedX(), coordinate.TransformedY()), coordinate))
+	}
+	return &convertedColors
+}
+
+// colorAt returns the color of the complex value x + yi.
+func (e *DomainColoringEyedropper) colorAt(x, y float64) color.NRGBA {
+	modulus := math.Hypot(x, y)
+	argument := math.Atan2(y, x)
+	if argument < 0 {
+		argument += 2 * math.Pi
+	}
+
+	lightness := 2 / math.Pi * math.Atan(modulus)
+	if e.modulusContours && modulus > 0 {
+		_, bandFraction := math.Modf(math.Log2(modulus))
+		if bandFraction < 0 {
+			bandFraction++
+		}
+		lightness *= 1 - contourShadeDepth*(1-bandFraction)
+	}
+	if e.phaseContours > 0 {
+		_, bandFraction := math.Modf(argument / (2 * math.Pi) * float64(e.phaseContours))
+		lightness *= 1 - contourShadeDepth*(1-bandFraction)
+	}
+	return colorFromHueAndLightness(argument/(2*math.Pi), lightness)
+}
+
+// colorFromHueAndLightness converts a fully saturated hue and lightness to an opaque color.
+//   Hue and lightness go from 0 to 1. A lightness of 0.5 is the pure hue.
+func colorFromHueAndLightness(hue, lightness float64) color.NRGBA {
+	chroma := 1 - math.Abs(2*lightness-1)
+	channel := func(offset float64) uint8 {
+		position := math.Mod(hue*6+offset, 6)
+		ramp := math.Max(0, math.Min(1, math.Abs(position-3)-1))
+		value := lightness + chroma*(ramp-0.5)
+		return uint8(math.Round(255 * math.Max(0, math.Min(1, value))))
+	}
+	return color.NRGBA{
+		R: channel(0),
+		G: channel(4),
+		B: channel(2),
+		A: 255,
+	}
+}
+
+// CanStream returns true, each coordinate's color only depends on the coordinate.
+func (e *DomainColoringEyedropper) CanStream() bool {
+	return true
+}
+
+// NeedsStatistics returns false, domain coloring does not use the range of the coordinates.
+func (e *DomainColoringEyedropper) NeedsStatistics() bool {
+	return false
+}
+
+// WithStatistics returns the same eyedropper.
+func (e *DomainColoringEyedropper) WithStatistics(statistics *TransformedStatistics) Eyedropper {
+	return e
+}
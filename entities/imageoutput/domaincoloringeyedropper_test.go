@@ -0,0 +1,87 @@
+package imageoutput_test
+
+import (
+	"github.com/chadius/creatingsymmetry/entities/imageoutput"
+	. "gopkg.in/check.v1"
+	"image/color"
+	"math"
+)
+
+type DomainColoringEyedropperTests struct {
+}
+
+var _ = Suite(&DomainColoringEyedropperTests{})
+
+// domainColors colors each transformed coordinate, after marking it as satisfying the filter.
+func domainColors(eyedropper *imageoutput.DomainColoringEyedropper, transformedCoordinates ...complex128) []color.Color {
+	coordinates := []*imageoutput.MappedCoordinate{}
+	for _, transformedCoordinate := range transformedCoordinates {
+		coordinate := imageoutput.NewMappedCoordinateUsingTransformedCoordinates(real(transformedCoordinate), imag(transformedCoordinate))
+		coordinate.MarkAsSatisfyingFilter()
+		coordinates = append(coordinates, coordinate)
+	}
+	collection := imageoutput.CoordinateCollectionBuilder().WithCoordinates(&coordinates).Build()
+	return *eyedropper.ConvertCoordinatesToColors(collection)
+}
+
+func (suite *DomainColoringEyedropperTests) TestBuilderIgnoresNegativePhaseContours(checker *C) {
+	eyedropper := imageoutput.DomainColoringEyedropperBuilder().WithModulusContours(true).WithPhaseContours(-3).Build()
+	checker.Assert(eyedropper.ModulusContours(), Equals, true)
+	checker.Assert(eyedropper.PhaseContours(), Equals, 0)
+}
+
+func (suite *DomainColoringEyedropperTests) TestArgumentPicksTheHue(checker *C) {
+	colors := domainColors(imageoutput.DomainColoringEyedropperBuilder().Build(), 1, 1i, -1, -1i)
+	checker.Assert(colors[0], Equals, color.NRGBA{R: 255, G: 0, B: 0, A: 255})
+	checker.Assert(colors[1], Equals, color.NRGBA{R: 128, G: 255, B: 0, A: 255})
+	checker.Assert(colors[2], Equals, color.NRGBA{R: 0, G: 255, B: 255, A: 255})
+	checker.Assert(colors[3], Equals, color.NRGBA{R: 128, G: 0, B: 255, A: 255})
+}
+
+func (suite *DomainColoringEyedropperTests) TestModulusPicksTheLightness(checker *C) {
+	colors := domainColors(imageoutput.DomainColoringEyedropperBuilder().Build(), 0, 1e9)
+	checker.Assert(colors[0], Equals, color.NRGBA{R: 0, G: 0, B: 0, A: 255})
+	checker.Assert(colors[1], Equals, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+}
+
+func (suite *DomainColoringEyedropperTests) TestModulusContoursDarkenEachBand(checker *C) {
+	eyedropper := imageoutput.DomainColoringEyedropperBuilder().WithModulusContours(true).Build()
+	colors := domainColors(eyedropper, 1, 1.99, 2)
+
+	brightness := func(shadedColor color.Color) uint32 {
+		r, g, b, _ := shadedColor.RGBA()
+		return r + g + b
+	}
+	startOfBand := brightness(colors[0])
+	endOfBand := brightness(colors[1])
+	startOfNextBand := brightness(colors[2])
+	checker.Assert(endOfBand > startOfBand, Equals, true)
+	checker.Assert(startOfNextBand < endOfBand, Equals, true)
+}
+
+func (suite *DomainColoringEyedropperTests) TestPhaseContoursDarkenEachBand(checker *C) {
+	eyedropper := imageoutput.DomainColoringEyedropperBuilder().WithPhaseContours(4).Build()
+	colors := domainColors(eyedropper, 1, complex(math.Cos(1.5), math.Sin(1.5)), 1i)
+
+	_, startOfBand, _, _ := colors[0].RGBA()
+	_, endOfBand, _, _ := colors[1].RGBA()
+	_, startOfNextBand, _, _ := colors[2].RGBA()
+	checker.Assert(startOfBand < endOfBand, Equals, true)
+	checker.Assert(startOfNextBand < endOfBand, Equals, true)
+}
+
+func (suite *DomainColoringEyedropperTests) TestFilteredAndInfiniteCoordinatesAreTransparent(checker *C) {
+	coordinates := []*imageoutput.MappedCoordinate{
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(1, 0),
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(math.Inf(1), 0),
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(1, 0),
+	}
+	coordinates[1].MarkAsSatisfyingFilter()
+	coordinates[2].MarkFilterWeight(0.5)
+	collection := imageoutput.CoordinateCollectionBuilder().WithCoordinates(&coordinates).Build()
+
+	colors := *imageoutput.DomainColoringEyedropperBuilder().Build().ConvertCoordinatesToColors(collection)
+	checker.Assert(colors[0], Equals, color.NRGBA{})
+	checker.Assert(colors[1], Equals, color.NRGBA{})
+	checker.Assert(colors[2], Equals, color.NRGBA{R: 255, G: 0, B: 0, A: 128})
+}
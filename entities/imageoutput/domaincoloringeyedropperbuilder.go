@@ -0,0 +1,40 @@
+package imageoutput
+
+// DomainColoringEyedropperBuilderOptions stores the options used to build a domain coloring eyedropper.
+type DomainColoringEyedropperBuilderOptions struct {
+	modulusContours bool
+	phaseContours   int
+}
+
+// DomainColoringEyedropperBuilder creates a DomainColoringEyedropperBuilderOptions with default values.
+//   Can be chained with other class functions. Call Build() to create the
+//   final object.
+func DomainColoringEyedropperBuilder() *DomainColoringEyedropperBuilderOptions {
+	return &DomainColoringEyedropperBuilderOptions{
+		modulusContours: false,
+		phaseContours:   0,
+	}
+}
+
+// WithModulusContours shades a band each time the modulus doubles.
+func (e *DomainColoringEyedropperBuilderOptions) WithModulusContours(modulusContours bool) *DomainColoringEyedropperBuilderOptions {
+	e.modulusContours = modulusContours
+	return e
+}
+
+// WithPhaseContours shades the argument into this many bands around the circle. Negative counts are ignored.
+func (e *DomainColoringEyedropperBuilderOptions) WithPhaseContours(count int) *DomainColoringEyedropperBuilderOptions {
+	if count < 0 {
+		return e
+	}
+	e.phaseContours = count
+	return e
+}
+
+// Build uses the builder options to create a domain coloring eyedropper.
+func (e *DomainColoringEyedropperBuilderOptions) Build() *DomainColoringEyedropper {
+	return &DomainColoringEyedropper{
+		modulusContours: e.modulusContours,
+		phaseContours:   e.phaseContours,
+	}
+}
@@ -0,0 +1,66 @@
+package imageoutput
+
+// EdgeMode chooses which color the eyedropper uses past the sides of its rectangle.
+type EdgeMode string
+
+// Edge modes.
+const (
+	// ClampEdgeMode repeats the closest pixel on the side of the rectangle.
+	ClampEdgeMode EdgeMode = "clamp"
+	// RepeatEdgeMode tiles the rectangle, so the left side follows the right side.
+	RepeatEdgeMode EdgeMode = "repeat"
+	// MirrorEdgeMode tiles the rectangle, flipping every other tile so the sides line up.
+	MirrorEdgeMode EdgeMode = "mirror"
+	// TransparentEdgeMode turns everything outside of the rectangle transparent.
+	TransparentEdgeMode EdgeMode = "transparent"
+)
+
+// EdgeModes returns every supported edge mode.
+func EdgeModes() []EdgeMode {
+	return []EdgeMode{ClampEdgeMode, RepeatEdgeMode, MirrorEdgeMode, TransparentEdgeMode}
+}
+
+// IsValid returns true if this is a supported edge mode.
+func (e EdgeMode) IsValid() bool {
+	for _, mode := range EdgeModes() {
+		if e == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTiled returns true if the edge mode repeats the rectangle. Tiled edge modes need a period.
+func (e EdgeMode) IsTiled() bool {
+	return e == RepeatEdgeMode || e == MirrorEdgeMode
+}
+
+// resolvePixel returns the pixel inside the range from minimum up to (but not including) maximum to read instead.
+//   Returns false if the pixel should be transparent.
+func (e EdgeMode) resolvePixel(pixel, minimum, maximum int) (int, bool) {
+	size := maximum - minimum
+	if size <= 0 {
+		return minimum, e != TransparentEdgeMode
+	}
+	if pixel >= minimum && pixel < maximum {
+		return pixel, true
+	}
+
+	switch e {
+	case TransparentEdgeMode:
+		return pixel, false
+	case RepeatEdgeMode:
+		return minimum + positiveModulo(pixel-minimum, size), true
+	case MirrorEdgeMode:
+		offset := positiveModulo(pixel-minimum, 2*size)
+		if offset >= size {
+			offset = 2*size - 1 - offset
+		}
+		return minimum + offset, true
+	}
+	return clampPixel(pixel, minimum, maximum), true
+}
+
+func positiveModulo(value, divisor int) int {
+	return ((value % divisor) + divisor) % divisor
+}
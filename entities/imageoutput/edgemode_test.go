@@ -0,0 +1,98 @@
+package imageoutput_test
+
+import (
+	"github.com/chadius/creatingsymmetry/entities/imageoutput"
+	. "gopkg.in/check.v1"
+	"image"
+	"image/color"
+)
+
+type EdgeModeTests struct {
+	sourceImage *image.NRGBA
+	pixelColors []color.NRGBA
+}
+
+var _ = Suite(&EdgeModeTests{})
+
+func (suite *EdgeModeTests) SetUpTest(checker *C) {
+	suite.pixelColors = []color.NRGBA{
+		{R: 10, G: 0, B: 0, A: 255},
+		{R: 20, G: 0, B: 0, A: 255},
+		{R: 30, G: 0, B: 0, A: 255},
+		{R: 40, G: 0, B: 0, A: 255},
+	}
+	suite.sourceImage = image.NewNRGBA(image.Rect(0, 0, 4, 1))
+	for pixelX, pixelColor := range suite.pixelColors {
+		suite.sourceImage.SetNRGBA(pixelX, 0, pixelColor)
+	}
+}
+
+// sampleAt converts transformed X coordinates into colors using an eyedropper with a period of 4,
+//   so each whole number in transformed space moves one pixel.
+func (suite *EdgeModeTests) sampleAt(edgeMode imageoutput.EdgeMode, transformedX ...float64) []color.Color {
+	coordinates := []*imageoutput.MappedCoordinate{}
+	for _, x := range transformedX {
+		coordinate := imageoutput.NewMappedCoordinateUsingTransformedCoordinates(x, 0.5)
+		coordinate.MarkAsSatisfyingFilter()
+		coordinates = append(coordinates, coordinate)
+	}
+	collection := imageoutput.CoordinateCollectionBuilder().WithCoordinates(&coordinates).Build()
+
+	eyedropper := imageoutput.EyedropperBuilder().
+		WithLeftSide(0).
+		WithRightSide(4).
+		WithTopSide(0).
+		WithBottomSide(1).
+		WithImage(suite.sourceImage).
+		WithEdgeMode(edgeMode).
+		WithPeriod(4, 1).
+		Build()
+	return *eyedropper.ConvertCoordinatesToColors(collection)
+}
+
+func (suite *EdgeModeTests) TestBuilderDefaults(checker *C) {
+	eyedropper := imageoutput.EyedropperBuilder().WithEdgeMode("wrap").WithPeriod(2, 0).Build()
+
+	checker.Assert(eyedropper.EdgeMode(), Equals, imageoutput.ClampEdgeMode)
+	checker.Assert(eyedropper.HasPeriod(), Equals, false)
+}
+
+func (suite *EdgeModeTests) TestPeriodMapsFromTheOrigin(checker *C) {
+	colors := suite.sampleAt(imageoutput.ClampEdgeMode, 1.5, 2.5)
+
+	checker.Assert(colors[0], Equals, suite.pixelColors[1])
+	checker.Assert(colors[1], Equals, suite.pixelColors[2])
+}
+
+func (suite *EdgeModeTests) TestClampUsesTheClosestPixel(checker *C) {
+	colors := suite.sampleAt(imageoutput.ClampEdgeMode, -0.5, 4.5, 100)
+
+	checker.Assert(colors[0], Equals, suite.pixelColors[0])
+	checker.Assert(colors[1], Equals, suite.pixelColors[3])
+	checker.Assert(colors[2], Equals, suite.pixelColors[3])
+}
+
+func (suite *EdgeModeTests) TestRepeatTilesTheEyedropper(checker *C) {
+	colors := suite.sampleAt(imageoutput.RepeatEdgeMode, -0.5, 4.5, 9.5)
+
+	checker.Assert(colors[0], Equals, suite.pixelColors[3])
+	checker.Assert(colors[1], Equals, suite.pixelColors[0])
+	checker.Assert(colors[2], Equals, suite.pixelColors[1])
+}
+
+func (suite *EdgeModeTests) TestMirrorFlipsEveryOtherTile(checker *C) {
+	colors := suite.sampleAt(imageoutput.MirrorEdgeMode, -0.5, 4.5, 7.5, 8.5)
+
+	checker.Assert(colors[0], Equals, suite.pixelColors[0])
+	checker.Assert(colors[1], Equals, suite.pixelColors[3])
+	checker.Assert(colors[2], Equals, suite.pixelColors[0])
+	checker.Assert(colors[3], Equals, suite.pixelColors[0])
+}
+
+func (suite *EdgeModeTests) TestTransparentHidesEverythingOutside(checker *C) {
+	colors := suite.sampleAt(imageoutput.TransparentEdgeMode, -0.5, 3.5, 4.5)
+
+	checker.Assert(colors[0], Equals, color.NRGBA{})
+	checker.Assert(colors[1], Equals, suite.pixelColors[3])
+	checker.Assert(colors[2], Equals, color.NRGBA{})
+}
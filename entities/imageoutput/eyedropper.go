@@ -21,6 +21,22 @@ type RectangularEyedropper struct {
 	topBoundary    int
 	bottomBoundary int
 	sourceImage    image.Image
+	sampling       Sampling
+	edgeMode       EdgeMode
+	periodX        float64
+	periodY        float64
+
+	hasTransformedRange bool
+	transformedMinimumX float64
+	transformedMaximumX float64
+	transformedMinimumY float64
+	transformedMaximumY float64
+
+	lowerPercentile float64
+	upperPercentile float64
+
+	curveX MappingCurve
+	curveY MappingCurve
 }
 
 // LeftSide returns the left side of the boundary.
@@ -48,45 +64,104 @@ func (e *RectangularEyedropper) Image() image.Image {
 	return e.sourceImage
 }
 
+// Sampling returns how colors are read between the source image's pixels.
+func (e *RectangularEyedropper) Sampling() Sampling {
+	return e.sampling
+}
+
+// EdgeMode returns how colors are chosen past the sides of the eyedropper.
+func (e *RectangularEyedropper) EdgeMode() EdgeMode {
+	return e.edgeMode
+}
+
+// Period returns the width and height in transformed space that spans the eyedropper once.
+//   Both are 0 if the eyedropper spans the range of the transformed coordinates instead.
+func (e *RectangularEyedropper) Period() (float64, float64) {
+	return e.periodX, e.periodY
+}
+
+// HasPeriod returns true if the eyedropper uses a fixed period.
+func (e *RectangularEyedropper) HasPeriod() bool {
+	return e.periodX > 0 && e.periodY > 0
+}
+
+// TransformedRange returns the minimum X, maximum X, minimum Y and maximum Y in transformed space
+//   that map to the eyedropper's sides. All are 0 if the eyedropper uses the range of each collection instead.
+func (e *RectangularEyedropper) TransformedRange() (float64, float64, float64, float64) {
+	return e.transformedMinimumX, e.transformedMaximumX, e.transformedMinimumY, e.transformedMaximumY
+}
+
+// HasTransformedRange returns true if the eyedropper uses a fixed range in transformed space.
+func (e *RectangularEyedropper) HasTransformedRange() bool {
+	return e.hasTransformedRange
+}
+
+// PercentileRange returns the lower and upper percentiles of each collection that map to the eyedropper's sides.
+//   They are 0 and 100 if the eyedropper uses the minimum and maximum of each collection.
+func (e *RectangularEyedropper) PercentileRange() (float64, float64) {
+	return e.lowerPercentile, e.upperPercentile
+}
+
+// MappingCurves returns the curves that spread transformed X and Y coordinates across the eyedropper.
+func (e *RectangularEyedropper) MappingCurves() (MappingCurve, MappingCurve) {
+	return e.curveX, e.curveY
+}
+
 // ConvertCoordinatesToColors uses the collection of coordinates, maps it to the eyedropper range,
 //   and samples the color in the source image at that location.
 //   if the coordinate is mapped outside the source image, it will turn transparent.
+//   Bilinear and bicubic sampling blend nearby pixels. The edge mode chooses the pixels used past the eyedropper's sides.
 func (e *RectangularEyedropper) ConvertCoordinatesToColors(collection *CoordinateCollection) *[]color.Color {
 	var convertedColors []color.Color
 	convertedColors = []color.Color{}
 	e.mapCoordinatesToEyedropperBoundary(collection)
+	grid := &pixelGrid{
+		sourceImage: e.Image(),
+		left:        e.LeftSide(),
+		right:       e.RightSide(),
+		top:         e.TopSide(),
+		bottom:      e.BottomSide(),
+		edgeMode:    e.EdgeMode(),
+	}
 
 	for _, coordinate := range *collection.Coordinates() {
-		var newColor color.NRGBA
-		if coordinate.HasMappedCoordinate() {
-			mappedCoordinateX, mappedCoordinateY := coordinate.MappedCoordinate()
-			sourceColorR, sourceColorG, sourceColorB, sourceColorA := (e.Image()).At(int(mappedCoordinateX), int(mappedCoordinateY)).RGBA()
-			newColor = color.NRGBA{
-				R: uint8(sourceColorR >> 8),
-				G: uint8(sourceColorG >> 8),
-				B: uint8(sourceColorB >> 8),
-				A: uint8(sourceColorA >> 8),
-			}
-		} else {
-			newColor = color.NRGBA{
-				R: uint8(0 >> 8),
-				G: uint8(0 >> 8),
-				B: uint8(0 >> 8),
-				A: uint8(0 >> 8),
-			}
+		if !coordinate.HasMappedCoordinate() {
+			convertedColors = append(convertedColors, color.NRGBA{})
+			continue
 		}
-		convertedColors = append(convertedColors, newColor)
+
+		mappedCoordinateX, mappedCoordinateY := coordinate.MappedCoordinate()
+		convertedColors = append(convertedColors, fadeByFilterWeight(grid.sample(e.Sampling(), mappedCoordinateX, mappedCoordinateY), coordinate))
 	}
 	return &convertedColors
 }
 
 // mapCoordinatesToEyedropperBoundary maps each coordinate from its minimum and maximum to the eyedropper's boundary.
+//   If the eyedropper has a fixed transformed range, that range is used instead of the collection's,
+//   so the colors do not depend on the output size or viewport.
+//   Otherwise the range goes from the lower to the upper percentile of the collection.
+//   Each axis uses its mapping curve to spread the coordinates inside the range across the boundary.
+//   Coordinates outside the range are drawn on the nearest side.
+//   If the eyedropper has a period, each period of transformed space maps to the boundary instead,
+//   starting from the origin. Coordinates can then map past the boundary.
 //   Only coordinates that satisfied their filter will be updated.
 func (e *RectangularEyedropper) mapCoordinatesToEyedropperBoundary(collection *CoordinateCollection) {
-	collectionMinimumX := collection.MinimumTransformedX()
-	collectionMaximumX := collection.MaximumTransformedX()
-	collectionMinimumY := collection.MinimumTransformedY()
-	collectionMaximumY := collection.MaximumTransformedY()
+	collectionMinimumX, collectionMaximumX, collectionMinimumY, collectionMaximumY := e.TransformedRange()
+	if !e.HasTransformedRange() {
+		collectionMinimumX, collectionMaximumX, collectionMinimumY, collectionMaximumY = e.collectionRange(collection)
+	}
+
+	curveX, curveY := e.MappingCurves()
+	fractionAcrossX := curveX.newAxisMapping(collectionMinimumX, collectionMaximumX, func() []float64 {
+		return sortedValues(*collection.Coordinates(), true, func(coordinate *MappedCoordinate) float64 {
+			return coordinate.TransformedX()
+		})
+	})
+	fractionAcrossY := curveY.newAxisMapping(collectionMinimumY, collectionMaximumY, func() []float64 {
+		return sortedValues(*collection.Coordinates(), true, func(coordinate *MappedCoordinate) float64 {
+			return coordinate.TransformedY()
+		})
+	})
 
 	for _, coordinate := range *collection.Coordinates() {
 		if !coordinate.SatisfiesFilter() {
@@ -96,18 +171,26 @@ func (e *RectangularEyedropper) mapCoordinatesToEyedropperBoundary(collection *C
 			continue
 		}
 
+		if e.HasPeriod() {
+			coordinate.StoreMappedCoordinate(
+				float64(e.LeftSide())+coordinate.TransformedX()/e.periodX*float64(e.RightSide()-e.LeftSide()),
+				float64(e.TopSide())+coordinate.TransformedY()/e.periodY*float64(e.BottomSide()-e.TopSide()),
+			)
+			continue
+		}
+
 		eyedropperX := mathutility.ScaleValueBetweenTwoRanges(
-			coordinate.TransformedX(),
-			collectionMinimumX,
-			collectionMaximumX,
+			fractionAcrossX(coordinate.TransformedX()),
+			0,
+			1,
 			float64(e.LeftSide()),
 			float64(e.RightSide()),
 		)
 
 		eyedropperY := mathutility.ScaleValueBetweenTwoRanges(
-			coordinate.TransformedY(),
-			collectionMinimumY,
-			collectionMaximumY,
+			fractionAcrossY(coordinate.TransformedY()),
+			0,
+			1,
 			float64(e.TopSide()),
 			float64(e.BottomSide()),
 		)
@@ -115,3 +198,46 @@ func (e *RectangularEyedropper) mapCoordinatesToEyedropperBoundary(collection *C
 		coordinate.StoreMappedCoordinate(eyedropperX, eyedropperY)
 	}
 }
+
+// collectionRange returns the minimum X, maximum X, minimum Y and maximum Y of the collection,
+//   using the percentile range if it is narrower than the whole collection.
+func (e *RectangularEyedropper) collectionRange(collection *CoordinateCollection) (float64, float64, float64, float64) {
+	lowerPercentile, upperPercentile := e.PercentileRange()
+	if lowerPercentile <= 0 && upperPercentile >= 100 {
+		return collection.MinimumTransformedX(), collection.MaximumTransformedX(), collection.MinimumTransformedY(), collection.MaximumTransformedY()
+	}
+
+	minimumX, maximumX := collection.TransformedXPercentiles(lowerPercentile, upperPercentile)
+	minimumY, maximumY := collection.TransformedYPercentiles(lowerPercentile, upperPercentile)
+	return minimumX, maximumX, minimumY, maximumY
+}
+
+// CanStream returns false if the colors depend on every coordinate at once.
+//   Percentile ranges and histogram equalization sort every coordinate, unless a period is used instead.
+func (e *RectangularEyedropper) CanStream() bool {
+	if e.HasPeriod() {
+		return true
+	}
+	lowerPercentile, upperPercentile := e.PercentileRange()
+	if !e.HasTransformedRange() && (lowerPercentile > 0 || upperPercentile < 100) {
+		return false
+	}
+	return e.curveX != HistogramEqualizedMappingCurve && e.curveY != HistogramEqualizedMappingCurve
+}
+
+// NeedsStatistics returns true if the eyedropper stretches across the range of the coordinates.
+func (e *RectangularEyedropper) NeedsStatistics() bool {
+	return !e.HasPeriod() && !e.HasTransformedRange()
+}
+
+// WithStatistics returns a copy of the eyedropper that uses the measured range as its fixed transformed range.
+func (e *RectangularEyedropper) WithStatistics(statistics *TransformedStatistics) Eyedropper {
+	if !e.NeedsStatistics() || !statistics.Found() {
+		return e
+	}
+	eyedropperWithRange := *e
+	eyedropperWithRange.hasTransformedRange = true
+	eyedropperWithRange.transformedMinimumX, eyedropperWithRange.transformedMaximumX = statistics.TransformedXRange()
+	eyedropperWithRange.transformedMinimumY, eyedropperWithRange.transformedMaximumY = statistics.TransformedYRange()
+	return &eyedropperWithRange
+}
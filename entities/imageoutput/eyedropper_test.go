@@ -156,3 +156,97 @@ func (suite *RectangularEyedropperTests) TestEyedropperDoesNotMapInvalidCoordina
 	assertPixelHasNoAlpha(5)
 	assertPixelHasNoAlpha(6)
 }
+
+func (suite *RectangularEyedropperTests) TestBuilderIgnoresEmptyTransformedRanges(checker *C) {
+	eyedropper := imageoutput.EyedropperBuilder().WithTransformedRange(1, 1, 0, 2).Build()
+	checker.Assert(eyedropper.HasTransformedRange(), Equals, false)
+
+	eyedropper = imageoutput.EyedropperBuilder().WithTransformedRange(0, 2, 3, -3).Build()
+	checker.Assert(eyedropper.HasTransformedRange(), Equals, false)
+
+	eyedropper = imageoutput.EyedropperBuilder().WithTransformedRange(-1, 2, -3, 4).Build()
+	checker.Assert(eyedropper.HasTransformedRange(), Equals, true)
+	minimumX, maximumX, minimumY, maximumY := eyedropper.TransformedRange()
+	checker.Assert([]float64{minimumX, maximumX, minimumY, maximumY}, DeepEquals, []float64{-1, 2, -3, 4})
+}
+
+func (suite *RectangularEyedropperTests) TestTransformedRangeDoesNotDependOnTheCollection(checker *C) {
+	sourceImage := generate2x2ImageWithRedGreenBlueBlackPixels()
+	eyedropper := imageoutput.EyedropperBuilder().
+		WithLeftSide(0).
+		WithRightSide(2).
+		WithTopSide(0).
+		WithBottomSide(2).
+		WithTransformedRange(0, 4, 0, 4).
+		WithImage(sourceImage).
+		Build()
+
+	colorsAt := func(transformedCoordinates ...complex128) []color.Color {
+		coordinates := []*imageoutput.MappedCoordinate{}
+		for _, transformedCoordinate := range transformedCoordinates {
+			coordinate := imageoutput.NewMappedCoordinateUsingTransformedCoordinates(real(transformedCoordinate), imag(transformedCoordinate))
+			coordinate.MarkAsSatisfyingFilter()
+			coordinates = append(coordinates, coordinate)
+		}
+		collection := imageoutput.CoordinateCollectionBuilder().WithCoordinates(&coordinates).Build()
+		return *eyedropper.ConvertCoordinatesToColors(collection)
+	}
+
+	green := color.NRGBA{R: 0, G: 255, B: 0, A: 255}
+	blue := color.NRGBA{R: 0, G: 0, B: 255, A: 255}
+
+	smallerCollection := colorsAt(complex(3, 1), complex(1, 3))
+	checker.Assert(smallerCollection[0], Equals, green)
+	checker.Assert(smallerCollection[1], Equals, blue)
+
+	largerCollection := colorsAt(complex(-10, -10), complex(3, 1), complex(1, 3), complex(10, 10))
+	checker.Assert(largerCollection[1], Equals, green)
+	checker.Assert(largerCollection[2], Equals, blue)
+}
+
+func (suite *RectangularEyedropperTests) TestBuilderIgnoresInvalidPercentileRanges(checker *C) {
+	for _, percentiles := range [][]float64{{50, 50}, {-1, 99}, {1, 101}} {
+		lowerPercentile, upperPercentile := imageoutput.EyedropperBuilder().WithPercentileRange(percentiles[0], percentiles[1]).Build().PercentileRange()
+		checker.Assert(lowerPercentile, Equals, 0.0)
+		checker.Assert(upperPercentile, Equals, 100.0)
+	}
+
+	lowerPercentile, upperPercentile := imageoutput.EyedropperBuilder().WithPercentileRange(1, 99).Build().PercentileRange()
+	checker.Assert(lowerPercentile, Equals, 1.0)
+	checker.Assert(upperPercentile, Equals, 99.0)
+}
+
+func (suite *RectangularEyedropperTests) TestPercentileRangeIgnoresOutliers(checker *C) {
+	sourceImage := generate2x2ImageWithRedGreenBlueBlackPixels()
+	eyedropperBuilder := func() *imageoutput.EyedropperBuilderOptions {
+		return imageoutput.EyedropperBuilder().
+			WithLeftSide(0).
+			WithRightSide(2).
+			WithTopSide(0).
+			WithBottomSide(2).
+			WithImage(sourceImage)
+	}
+
+	colorsAt := func(eyedropper *imageoutput.RectangularEyedropper) []color.Color {
+		coordinates := []*imageoutput.MappedCoordinate{}
+		for _, transformed := range []float64{0, 1, 2, 3, 1000} {
+			coordinate := imageoutput.NewMappedCoordinateUsingTransformedCoordinates(transformed, transformed)
+			coordinate.MarkAsSatisfyingFilter()
+			coordinates = append(coordinates, coordinate)
+		}
+		collection := imageoutput.CoordinateCollectionBuilder().WithCoordinates(&coordinates).Build()
+		return *eyedropper.ConvertCoordinatesToColors(collection)
+	}
+
+	red := color.NRGBA{R: 255, G: 0, B: 0, A: 255}
+	black := color.NRGBA{R: 0, G: 0, B: 0, A: 255}
+
+	extremaColors := colorsAt(eyedropperBuilder().Build())
+	checker.Assert(extremaColors[2], Equals, red)
+
+	percentileColors := colorsAt(eyedropperBuilder().WithPercentileRange(0, 75).Build())
+	checker.Assert(percentileColors[0], Equals, red)
+	checker.Assert(percentileColors[1], Equals, red)
+	checker.Assert(percentileColors[2], Equals, black)
+	checker.Assert(percentileColors[4], Equals, black)
+}
@@ -9,6 +9,22 @@ type EyedropperBuilderOptions struct {
 	bottomSide  int
 	topSide     int
 	sourceImage image.Image
+	sampling    Sampling
+	edgeMode    EdgeMode
+	periodX     float64
+	periodY     float64
+
+	hasTransformedRange bool
+	transformedMinimumX float64
+	transformedMaximumX float64
+	transformedMinimumY float64
+	transformedMaximumY float64
+
+	lowerPercentile float64
+	upperPercentile float64
+
+	curveX MappingCurve
+	curveY MappingCurve
 }
 
 // EyedropperBuilder creates a EyedropperBuilderOptions with default values.
@@ -21,6 +37,18 @@ func EyedropperBuilder() *EyedropperBuilderOptions {
 		bottomSide:  0,
 		topSide:     0,
 		sourceImage: nil,
+		sampling:    NearestSampling,
+		edgeMode:    ClampEdgeMode,
+		periodX:     0,
+		periodY:     0,
+
+		hasTransformedRange: false,
+
+		lowerPercentile: 0,
+		upperPercentile: 100,
+
+		curveX: LinearMappingCurve,
+		curveY: LinearMappingCurve,
 	}
 }
 
@@ -54,6 +82,74 @@ func (e *EyedropperBuilderOptions) WithImage(sourceImage image.Image) *Eyedroppe
 	return e
 }
 
+// WithSampling sets how colors are read between pixels. Unknown sampling methods are ignored.
+func (e *EyedropperBuilderOptions) WithSampling(sampling Sampling) *EyedropperBuilderOptions {
+	if !sampling.IsValid() {
+		return e
+	}
+	e.sampling = sampling
+	return e
+}
+
+// WithEdgeMode sets how colors are chosen past the sides. Unknown edge modes are ignored.
+func (e *EyedropperBuilderOptions) WithEdgeMode(edgeMode EdgeMode) *EyedropperBuilderOptions {
+	if !edgeMode.IsValid() {
+		return e
+	}
+	e.edgeMode = edgeMode
+	return e
+}
+
+// WithPeriod sets the width and height in transformed space that spans the eyedropper once.
+//   Ignored unless both are positive.
+func (e *EyedropperBuilderOptions) WithPeriod(periodX, periodY float64) *EyedropperBuilderOptions {
+	if periodX <= 0 || periodY <= 0 {
+		return e
+	}
+	e.periodX = periodX
+	e.periodY = periodY
+	return e
+}
+
+// WithTransformedRange sets the rectangle in transformed space that maps to the eyedropper's sides.
+//   Ignored unless each minimum is smaller than its maximum.
+func (e *EyedropperBuilderOptions) WithTransformedRange(minimumX, maximumX, minimumY, maximumY float64) *EyedropperBuilderOptions {
+	if minimumX >= maximumX || minimumY >= maximumY {
+		return e
+	}
+	e.hasTransformedRange = true
+	e.transformedMinimumX = minimumX
+	e.transformedMaximumX = maximumX
+	e.transformedMinimumY = minimumY
+	e.transformedMaximumY = maximumY
+	return e
+}
+
+// WithPercentileRange maps the lower and upper percentiles of each collection to the eyedropper's sides,
+//   instead of the minimum and maximum. Coordinates outside the percentiles are drawn on the nearest side,
+//   or on the rim of polar eyedroppers.
+//   Ignored unless 0 <= lowerPercentile < upperPercentile <= 100.
+func (e *EyedropperBuilderOptions) WithPercentileRange(lowerPercentile, upperPercentile float64) *EyedropperBuilderOptions {
+	if lowerPercentile < 0 || upperPercentile > 100 || lowerPercentile >= upperPercentile {
+		return e
+	}
+	e.lowerPercentile = lowerPercentile
+	e.upperPercentile = upperPercentile
+	return e
+}
+
+// WithMappingCurves sets how transformed X and Y coordinates are spread across the eyedropper.
+//   Unknown curves are ignored, so each axis can be set on its own.
+func (e *EyedropperBuilderOptions) WithMappingCurves(curveX, curveY MappingCurve) *EyedropperBuilderOptions {
+	if curveX.IsValid() {
+		e.curveX = curveX
+	}
+	if curveY.IsValid() {
+		e.curveY = curveY
+	}
+	return e
+}
+
 // Build uses the builder options to create a power.
 func (e *EyedropperBuilderOptions) Build() *RectangularEyedropper {
 	return &RectangularEyedropper{
@@ -62,5 +158,21 @@ func (e *EyedropperBuilderOptions) Build() *RectangularEyedropper {
 		topBoundary:    e.topSide,
 		bottomBoundary: e.bottomSide,
 		sourceImage:    e.sourceImage,
+		sampling:       e.sampling,
+		edgeMode:       e.edgeMode,
+		periodX:        e.periodX,
+		periodY:        e.periodY,
+
+		hasTransformedRange: e.hasTransformedRange,
+		transformedMinimumX: e.transformedMinimumX,
+		transformedMaximumX: e.transformedMaximumX,
+		transformedMinimumY: e.transformedMinimumY,
+		transformedMaximumY: e.transformedMaximumY,
+
+		lowerPercentile: e.lowerPercentile,
+		upperPercentile: e.upperPercentile,
+
+		curveX: e.curveX,
+		curveY: e.curveY,
 	}
 }
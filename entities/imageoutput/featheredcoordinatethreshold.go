@@ -0,0 +1,62 @@
+package imageoutput
+
+import (
+	"image/color"
+	"math"
+)
+
+// ShapeCoordinateThreshold is a CoordinateThreshold that can measure how far a coordinate is from its edge.
+type ShapeCoordinateThreshold interface {
+	CoordinateThreshold
+	// SignedDistance returns the distance from the point to the edge of the shape.
+	//   It is negative inside the shape and positive outside.
+	SignedDistance(x, y float64) float64
+}
+
+// FeatheredCoordinateThreshold softens the edge of a shape, so the kept region fades out instead of stopping at a jagged border.
+type FeatheredCoordinateThreshold struct {
+	shape ShapeCoordinateThreshold
+	width float64
+}
+
+// NewFeatheredCoordinateThreshold fades the shape's edge over the width, measured in transformed space.
+//   The fade is centered on the edge: coordinates on the edge are half kept,
+//   coordinates half the width inside are fully kept and coordinates half the width outside are removed.
+func NewFeatheredCoordinateThreshold(shape ShapeCoordinateThreshold, width float64) *FeatheredCoordinateThreshold {
+	return &FeatheredCoordinateThreshold{
+		shape: shape,
+		width: width,
+	}
+}
+
+// Width returns the distance the edge fades over.
+func (c *FeatheredCoordinateThreshold) Width() float64 {
+	return c.width
+}
+
+// FilterAndMarkMappedCoordinateCollection marks each comparable coordinate with how much of it is kept.
+//   Without a width, this is the same as the shape's hard edge.
+func (c *FeatheredCoordinateThreshold) FilterAndMarkMappedCoordinateCollection(collection *CoordinateCollection) {
+	if c.width <= 0 {
+		c.shape.FilterAndMarkMappedCoordinateCollection(collection)
+		return
+	}
+
+	for _, coordinateToFilter := range *collection.Coordinates() {
+		if !coordinateToFilter.CanBeCompared() {
+			continue
+		}
+		distance := c.shape.SignedDistance(coordinateToFilter.TransformedX(), coordinateToFilter.TransformedY())
+		coordinateToFilter.MarkFilterWeight(0.5 - distance/c.width)
+	}
+}
+
+// fadeByFilterWeight multiplies the color's alpha by the coordinate's filter weight.
+func fadeByFilterWeight(sampledColor color.NRGBA, coordinate *MappedCoordinate) color.NRGBA {
+	weight := coordinate.FilterWeight()
+	if weight >= 1 {
+		return sampledColor
+	}
+	sampledColor.A = uint8(math.Round(float64(sampledColor.A) * weight))
+	return sampledColor
+}
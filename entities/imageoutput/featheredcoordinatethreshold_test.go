@@ -0,0 +1,113 @@
+package imageoutput_test
+
+import (
+	"github.com/chadius/creatingsymmetry/entities/imageoutput"
+	"github.com/chadius/creatingsymmetry/entities/utility"
+	. "gopkg.in/check.v1"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+type FeatheredCoordinateThresholdTests struct{}
+
+var _ = Suite(&FeatheredCoordinateThresholdTests{})
+
+// filterWeights filters the points and returns the weight given to each one.
+func filterWeights(threshold imageoutput.CoordinateThreshold, points ...complex128) []float64 {
+	coordinates := []*imageoutput.MappedCoordinate{}
+	for _, point := range points {
+		coordinates = append(coordinates, imageoutput.NewMappedCoordinateUsingTransformedCoordinates(real(point), imag(point)))
+	}
+	threshold.FilterAndMarkMappedCoordinateCollection(imageoutput.CoordinateCollectionBuilder().WithCoordinates(&coordinates).Build())
+
+	weights := []float64{}
+	for _, coordinate := range coordinates {
+		weights = append(weights, coordinate.FilterWeight())
+	}
+	return weights
+}
+
+func (suite *FeatheredCoordinateThresholdTests) TestMarkFilterWeightIsClamped(checker *C) {
+	coordinate := imageoutput.NewMappedCoordinateUsingTransformedCoordinates(0, 0)
+	checker.Assert(coordinate.SatisfiesFilter(), Equals, false)
+
+	coordinate.MarkFilterWeight(0.25)
+	checker.Assert(coordinate.FilterWeight(), Equals, 0.25)
+	checker.Assert(coordinate.SatisfiesFilter(), Equals, true)
+
+	coordinate.MarkFilterWeight(3)
+	checker.Assert(coordinate.FilterWeight(), Equals, 1.0)
+
+	coordinate.MarkFilterWeight(math.NaN())
+	checker.Assert(coordinate.FilterWeight(), Equals, 0.0)
+	checker.Assert(coordinate.SatisfiesFilter(), Equals, false)
+}
+
+func (suite *FeatheredCoordinateThresholdTests) TestShapesMeasureSignedDistance(checker *C) {
+	rectangle := imageoutput.CoordinateFilterBuilder().WithMinimumX(-1).WithMaximumX(1).WithMinimumY(-2).WithMaximumY(2).Build().(imageoutput.ShapeCoordinateThreshold)
+	checker.Assert(rectangle.SignedDistance(0, 0), utility.NumericallyCloseEnough{}, -1.0, 1e-9)
+	checker.Assert(rectangle.SignedDistance(4, 6), utility.NumericallyCloseEnough{}, 5.0, 1e-9)
+
+	disc := imageoutput.NewDiscCoordinateThreshold(1, 0, 2)
+	checker.Assert(disc.SignedDistance(1, 0), utility.NumericallyCloseEnough{}, -2.0, 1e-9)
+	checker.Assert(disc.SignedDistance(4, 0), utility.NumericallyCloseEnough{}, 1.0, 1e-9)
+
+	annulus := imageoutput.NewAnnulusCoordinateThreshold(0, 0, 2, 4)
+	checker.Assert(annulus.SignedDistance(0, 0), utility.NumericallyCloseEnough{}, 2.0, 1e-9)
+	checker.Assert(annulus.SignedDistance(0, 3), utility.NumericallyCloseEnough{}, -1.0, 1e-9)
+
+	quarter := imageoutput.NewSectorCoordinateThreshold(0, 0, 0, math.Pi/2, 0, 10)
+	checker.Assert(quarter.SignedDistance(3, 1), utility.NumericallyCloseEnough{}, -1.0, 1e-9)
+	checker.Assert(quarter.SignedDistance(3, -1), utility.NumericallyCloseEnough{}, 1.0, 1e-9)
+	checker.Assert(quarter.SignedDistance(0, 12), utility.NumericallyCloseEnough{}, 2.0, 1e-9)
+
+	square := imageoutput.NewPolygonCoordinateThreshold([]complex128{0, 4, complex(4, 4), 4i})
+	checker.Assert(square.SignedDistance(1, 2), utility.NumericallyCloseEnough{}, -1.0, 1e-9)
+	checker.Assert(square.SignedDistance(7, 8), utility.NumericallyCloseEnough{}, 5.0, 1e-9)
+}
+
+func (suite *FeatheredCoordinateThresholdTests) TestFeatherFadesAcrossTheEdge(checker *C) {
+	threshold := imageoutput.NewFeatheredCoordinateThreshold(imageoutput.NewDiscCoordinateThreshold(0, 0, 2), 1)
+
+	weights := filterWeights(threshold, 0, 1.5, 1.75, 2, 2.25, 2.5, complex(math.Inf(1), 0))
+	expectedWeights := []float64{1, 1, 0.75, 0.5, 0.25, 0, 0}
+	for pointIndex, expectedWeight := range expectedWeights {
+		checker.Assert(weights[pointIndex], utility.NumericallyCloseEnough{}, expectedWeight, 1e-9, Commentf("point %d", pointIndex))
+	}
+}
+
+func (suite *FeatheredCoordinateThresholdTests) TestNoWidthKeepsTheHardEdge(checker *C) {
+	threshold := imageoutput.NewFeatheredCoordinateThreshold(imageoutput.NewDiscCoordinateThreshold(0, 0, 2), 0)
+
+	checker.Assert(filterWeights(threshold, 0, 2, 2.1), DeepEquals, []float64{1, 1, 0})
+}
+
+func (suite *FeatheredCoordinateThresholdTests) TestCombinationsUseTheChildWeights(checker *C) {
+	feathered := imageoutput.NewFeatheredCoordinateThreshold(imageoutput.NewDiscCoordinateThreshold(0, 0, 2), 1)
+	rightHalf := imageoutput.CoordinateFilterBuilder().WithMinimumX(0).WithMaximumX(10).WithMinimumY(-10).WithMaximumY(10).Build()
+
+	checker.Assert(filterWeights(imageoutput.NewUnionCoordinateThreshold(feathered, rightHalf), -2.25, 2.25), DeepEquals, []float64{0.25, 1})
+	checker.Assert(filterWeights(imageoutput.NewIntersectionCoordinateThreshold(feathered, rightHalf), -2.25, 2.25), DeepEquals, []float64{0, 0.25})
+	checker.Assert(filterWeights(imageoutput.NewDifferenceCoordinateThreshold(rightHalf, feathered), 1.75, 2.25), DeepEquals, []float64{0.25, 0.75})
+	checker.Assert(filterWeights(imageoutput.NewInvertedCoordinateThreshold(feathered), 2.25), DeepEquals, []float64{0.75})
+}
+
+func (suite *FeatheredCoordinateThresholdTests) TestEyedropperFadesPartiallyKeptCoordinates(checker *C) {
+	sourceImage := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	draw.Draw(sourceImage, sourceImage.Bounds(), image.NewUniform(color.NRGBA{R: 255, A: 255}), image.Point{}, draw.Src)
+
+	coordinates := []*imageoutput.MappedCoordinate{
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(0, 0),
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(1, 1),
+	}
+	coordinates[0].MarkAsSatisfyingFilter()
+	coordinates[1].MarkFilterWeight(0.5)
+	collection := imageoutput.CoordinateCollectionBuilder().WithCoordinates(&coordinates).Build()
+
+	eyedropper := imageoutput.EyedropperBuilder().WithLeftSide(0).WithRightSide(2).WithTopSide(0).WithBottomSide(2).WithImage(sourceImage).Build()
+	convertedColors := *eyedropper.ConvertCoordinatesToColors(collection)
+	checker.Assert(color.NRGBAModel.Convert(convertedColors[0]), Equals, color.NRGBA{R: 255, A: 255})
+	checker.Assert(color.NRGBAModel.Convert(convertedColors[1]), Equals, color.NRGBA{R: 255, A: 128})
+}
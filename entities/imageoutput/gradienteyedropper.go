@@ -0,0 +1,233 @@
+package imageoutput
+
+import (
+	"image/color"
+	"math"
+	"sort"
+)
+
+// GradientScalar chooses the number taken from each transformed coordinate to pick its color along the gradient.
+type GradientScalar string
+
+// Gradient scalars. Arguments are in degrees, counterclockwise from the positive real axis, from 0 to 360.
+const (
+	ModulusGradientScalar   GradientScalar = "modulus"
+	ArgumentGradientScalar  GradientScalar = "argument"
+	RealGradientScalar      GradientScalar = "real"
+	ImaginaryGradientScalar GradientScalar = "imaginary"
+)
+
+// GradientScalars returns every supported gradient scalar.
+func GradientScalars() []GradientScalar {
+	return []GradientScalar{
+		ModulusGradientScalar,
+		ArgumentGradientScalar,
+		RealGradientScalar,
+		ImaginaryGradientScalar,
+	}
+}
+
+// IsValid returns true if this is a supported gradient scalar.
+func (s GradientScalar) IsValid() bool {
+	for _, scalar := range GradientScalars() {
+		if s == scalar {
+			return true
+		}
+	}
+	return false
+}
+
+// valueOf returns the scalar of the coordinate's transformed value.
+func (s GradientScalar) valueOf(coordinate *MappedCoordinate) float64 {
+	switch s {
+	case ArgumentGradientScalar:
+		argument := math.Atan2(coordinate.TransformedY(), coordinate.TransformedX()) * 180 / math.Pi
+		if argument < 0 {
+			argument += 360
+		}
+		return argument
+	case RealGradientScalar:
+		return coordinate.TransformedX()
+	case ImaginaryGradientScalar:
+		return coordinate.TransformedY()
+	}
+	return math.Hypot(coordinate.TransformedX(), coordinate.TransformedY())
+}
+
+// GradientStop is a color at a position along the gradient, from 0 at the start to 1 at the end.
+type GradientStop struct {
+	position float64
+	color    color.NRGBA
+}
+
+// NewGradientStop places the color at the position along the gradient.
+func NewGradientStop(position float64, stopColor color.NRGBA) GradientStop {
+	return GradientStop{
+		position: position,
+		color:    stopColor,
+	}
+}
+
+// Position returns how far along the gradient the stop is, from 0 to 1.
+func (g GradientStop) Position() float64 {
+	return g.position
+}
+
+// Color returns the color at the stop.
+func (g GradientStop) Color() color.NRGBA {
+	return g.color
+}
+
+// GradientEyedropper colors each coordinate by taking a scalar from its transformed value,
+//   like the modulus or the argument, and reading the color at that point along a gradient.
+//   The scalar range maps onto positions 0 to 1. Colors between stops are blended,
+//   and positions before the first stop or after the last stop use that stop's color.
+type GradientEyedropper struct {
+	scalar         GradientScalar
+	stops          []GradientStop
+	hasScalarRange bool
+	scalarMinimum  float64
+	scalarMaximum  float64
+}
+
+// Scalar returns the number taken from each transformed coordinate.
+func (e *GradientEyedropper) Scalar() GradientScalar {
+	return e.scalar
+}
+
+// Stops returns the gradient's stops, ordered by position.
+func (e *GradientEyedropper) Stops() []GradientStop {
+	return e.stops
+}
+
+// HasScalarRange returns true if the scalar range is fixed instead of coming from the collection.
+func (e *GradientEyedropper) HasScalarRange() bool {
+	return e.hasScalarRange
+}
+
+// ScalarRange returns the scalars that map to the start and end of the gradient, if they are fixed.
+func (e *GradientEyedropper) ScalarRange() (float64, float64) {
+	return e.scalarMinimum, e.scalarMaximum
+}
+
+// ConvertCoordinatesToColors reads the color along the gradient for each coordinate.
+//   Coordinates that did not satisfy the filter or are at infinity turn transparent.
+func (e *GradientEyedropper) ConvertCoordinatesToColors(collection *CoordinateCollection) *[]color.Color {
+	scalarMinimum, scalarMaximum := e.collectionScalarRange(collection)
+
+	convertedColors := []color.Color{}
+	for _, coordinate := range *collection.Coordinates() {
+		if !coordinate.SatisfiesFilter() || !coordinate.CanBeCompared() {
+			convertedColors = append(convertedColors, color.NRGBA{})
+			continue
+		}
+
+		position := 0.0
+		if scalarMaximum > scalarMinimum {
+			position = (e.scalar.valueOf(coordinate) - scalarMinimum) / (scalarMaximum - scalarMinimum)
+		}
+		convertedColors = append(convertedColors, fadeByFilterWeight(e.colorAt(position), coordinate))
+	}
+	return &convertedColors
+}
+
+// collectionScalarRange returns the fixed scalar range, if there is one.
+//   Arguments always go around the full circle, from 0 to 360 degrees.
+//   Otherwise the range spans the scalars of the coordinates that satisfied the filter.
+func (e *GradientEyedropper) collectionScalarRange(collection *CoordinateCollection) (float64, float64) {
+	if e.HasScalarRange() {
+		return e.ScalarRange()
+	}
+	if e.scalar == ArgumentGradientScalar {
+		return 0, 360
+	}
+
+	foundScalar := false
+	scalarMinimum, scalarMaximum := 0.0, 0.0
+	for _, coordinate := range *collection.Coordinates() {
+		if !coordinate.SatisfiesFilter() || !coordinate.CanBeCompared() {
+			continue
+		}
+		scalar := e.scalar.valueOf(coordinate)
+		if !foundScalar {
+			scalarMinimum, scalarMaximum = scalar, scalar
+			foundScalar = true
+			continue
+		}
+		scalarMinimum = math.Min(scalarMinimum, scalar)
+		scalarMaximum = math.Max(scalarMaximum, scalar)
+	}
+	return scalarMinimum, scalarMaximum
+}
+
+// colorAt blends the stops on either side of the position.
+//   Positions before the first stop or after the last stop use its color.
+func (e *GradientEyedropper) colorAt(position float64) color.NRGBA {
+	if len(e.stops) == 0 {
+		return color.NRGBA{}
+	}
+
+	nextStopIndex := sort.Search(len(e.stops), func(index int) bool {
+		return e.stops[index].position > position
+	})
+	if nextStopIndex == 0 {
+		return e.stops[0].color
+	}
+	if nextStopIndex == len(e.stops) {
+		return e.stops[len(e.stops)-1].color
+	}
+
+	previousStop := e.stops[nextStopIndex-1]
+	nextStop := e.stops[nextStopIndex]
+	fractionToNext := (position - previousStop.position) / (nextStop.position - previousStop.position)
+
+	var blended premultipliedColor
+	for _, weightedStop := range []struct {
+		stop   GradientStop
+		weight float64
+	}{
+		{stop: previousStop, weight: 1 - fractionToNext},
+		{stop: nextStop, weight: fractionToNext},
+	} {
+		r, g, b, a := weightedStop.stop.color.RGBA()
+		blended = blended.add(
+			premultipliedColor{r: float64(r), g: float64(g), b: float64(b), a: float64(a)},
+			weightedStop.weight,
+		)
+	}
+	return blended.toNRGBA()
+}
+
+// CanStream returns true, gradients only need the range of the scalar.
+func (e *GradientEyedropper) CanStream() bool {
+	return true
+}
+
+// NeedsStatistics returns true if the gradient spans the range of the scalar instead of a fixed range.
+func (e *GradientEyedropper) NeedsStatistics() bool {
+	return !e.HasScalarRange() && e.scalar != ArgumentGradientScalar
+}
+
+// WithStatistics returns a copy of the eyedropper that uses the measured range of the scalar as its fixed range.
+func (e *GradientEyedropper) WithStatistics(statistics *TransformedStatistics) Eyedropper {
+	if !e.NeedsStatistics() {
+		return e
+	}
+
+	scalarMinimum, scalarMaximum := statistics.ModulusRange()
+	switch e.scalar {
+	case RealGradientScalar:
+		scalarMinimum, scalarMaximum = statistics.TransformedXRange()
+	case ImaginaryGradientScalar:
+		scalarMinimum, scalarMaximum = statistics.TransformedYRange()
+	}
+	if !(scalarMinimum < scalarMaximum) {
+		return e
+	}
+
+	eyedropperWithRange := *e
+	eyedropperWithRange.hasScalarRange = true
+	eyedropperWithRange.scalarMinimum = scalarMinimum
+	eyedropperWithRange.scalarMaximum = scalarMaximum
+	return &eyedropperWithRange
+}
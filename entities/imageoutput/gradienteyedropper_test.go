@@ -0,0 +1,165 @@
+package imageoutput_test
+
+import (
+	"github.com/chadius/creatingsymmetry/entities/imageoutput"
+	. "gopkg.in/check.v1"
+	"image/color"
+	"math"
+)
+
+type GradientEyedropperTests struct {
+	black color.NRGBA
+	red   color.NRGBA
+	white color.NRGBA
+}
+
+var _ = Suite(&GradientEyedropperTests{})
+
+func (suite *GradientEyedropperTests) SetUpTest(checker *C) {
+	suite.black = color.NRGBA{R: 0, G: 0, B: 0, A: 255}
+	suite.red = color.NRGBA{R: 255, G: 0, B: 0, A: 255}
+	suite.white = color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+}
+
+// gradientColors colors each transformed coordinate, after marking it as satisfying the filter.
+func gradientColors(eyedropper *imageoutput.GradientEyedropper, transformedCoordinates ...complex128) []color.Color {
+	coordinates := []*imageoutput.MappedCoordinate{}
+	for _, transformedCoordinate := range transformedCoordinates {
+		coordinate := imageoutput.NewMappedCoordinateUsingTransformedCoordinates(real(transformedCoordinate), imag(transformedCoordinate))
+		coordinate.MarkAsSatisfyingFilter()
+		coordinates = append(coordinates, coordinate)
+	}
+	collection := imageoutput.CoordinateCollectionBuilder().WithCoordinates(&coordinates).Build()
+	return *eyedropper.ConvertCoordinatesToColors(collection)
+}
+
+func (suite *GradientEyedropperTests) TestBuilderDefaultsAndIgnoresInvalidOptions(checker *C) {
+	eyedropper := imageoutput.GradientEyedropperBuilder().
+		WithScalar("phase").
+		WithScalarRange(2, 2).
+		Build()
+	checker.Assert(eyedropper.Scalar(), Equals, imageoutput.ModulusGradientScalar)
+	checker.Assert(eyedropper.HasScalarRange(), Equals, false)
+	checker.Assert(eyedropper.Stops(), HasLen, 0)
+}
+
+func (suite *GradientEyedropperTests) TestBuilderOrdersStopsByPosition(checker *C) {
+	eyedropper := imageoutput.GradientEyedropperBuilder().
+		WithStops(
+			imageoutput.NewGradientStop(1, suite.white),
+			imageoutput.NewGradientStop(0, suite.black),
+			imageoutput.NewGradientStop(0.5, suite.red),
+		).
+		Build()
+	stops := eyedropper.Stops()
+	checker.Assert(stops, HasLen, 3)
+	checker.Assert(stops[0].Color(), Equals, suite.black)
+	checker.Assert(stops[1].Position(), Equals, 0.5)
+	checker.Assert(stops[2].Color(), Equals, suite.white)
+}
+
+func (suite *GradientEyedropperTests) TestModulusIsStretchedAcrossTheCollection(checker *C) {
+	eyedropper := imageoutput.GradientEyedropperBuilder().
+		WithStops(
+			imageoutput.NewGradientStop(0, suite.black),
+			imageoutput.NewGradientStop(1, suite.white),
+		).
+		Build()
+
+	colors := gradientColors(eyedropper, 1, 2i, -3)
+	checker.Assert(colors[0], Equals, suite.black)
+	checker.Assert(colors[1], Equals, color.NRGBA{R: 128, G: 128, B: 128, A: 255})
+	checker.Assert(colors[2], Equals, suite.white)
+}
+
+func (suite *GradientEyedropperTests) TestScalarRangeClampsToTheEnds(checker *C) {
+	eyedropper := imageoutput.GradientEyedropperBuilder().
+		WithScalar(imageoutput.RealGradientScalar).
+		WithScalarRange(0, 10).
+		WithStops(
+			imageoutput.NewGradientStop(0.25, suite.black),
+			imageoutput.NewGradientStop(0.75, suite.white),
+		).
+		Build()
+
+	colors := gradientColors(eyedropper, -5, 2, 5, 8, 20)
+	checker.Assert(colors[0], Equals, suite.black)
+	checker.Assert(colors[1], Equals, suite.black)
+	checker.Assert(colors[2], Equals, color.NRGBA{R: 128, G: 128, B: 128, A: 255})
+	checker.Assert(colors[3], Equals, suite.white)
+	checker.Assert(colors[4], Equals, suite.white)
+}
+
+func (suite *GradientEyedropperTests) TestArgumentGoesAroundTheCircle(checker *C) {
+	eyedropper := imageoutput.GradientEyedropperBuilder().
+		WithScalar(imageoutput.ArgumentGradientScalar).
+		WithStops(
+			imageoutput.NewGradientStop(0, suite.black),
+			imageoutput.NewGradientStop(0.5, suite.red),
+			imageoutput.NewGradientStop(1, suite.black),
+		).
+		Build()
+
+	colors := gradientColors(eyedropper, 1, -1, 1i, -1i)
+	checker.Assert(colors[0], Equals, suite.black)
+	checker.Assert(colors[1], Equals, suite.red)
+	checker.Assert(colors[2], Equals, color.NRGBA{R: 128, G: 0, B: 0, A: 255})
+	checker.Assert(colors[3], Equals, color.NRGBA{R: 128, G: 0, B: 0, A: 255})
+}
+
+func (suite *GradientEyedropperTests) TestStopsAtTheSamePositionMakeAHardEdge(checker *C) {
+	eyedropper := imageoutput.GradientEyedropperBuilder().
+		WithScalar(imageoutput.ImaginaryGradientScalar).
+		WithScalarRange(0, 1).
+		WithStops(
+			imageoutput.NewGradientStop(0, suite.black),
+			imageoutput.NewGradientStop(0.5, suite.black),
+			imageoutput.NewGradientStop(0.5, suite.white),
+			imageoutput.NewGradientStop(1, suite.white),
+		).
+		Build()
+
+	colors := gradientColors(eyedropper, 0.49i, 0.5i, 0.51i)
+	checker.Assert(colors[0], Equals, suite.black)
+	checker.Assert(colors[1], Equals, suite.white)
+	checker.Assert(colors[2], Equals, suite.white)
+}
+
+func (suite *GradientEyedropperTests) TestBlendsTransparentStopsWithoutDarkening(checker *C) {
+	eyedropper := imageoutput.GradientEyedropperBuilder().
+		WithScalarRange(0, 1).
+		WithStops(
+			imageoutput.NewGradientStop(0, color.NRGBA{}),
+			imageoutput.NewGradientStop(1, suite.red),
+		).
+		Build()
+
+	colors := gradientColors(eyedropper, 0.5)
+	checker.Assert(colors[0], Equals, color.NRGBA{R: 255, G: 0, B: 0, A: 128})
+}
+
+func (suite *GradientEyedropperTests) TestHonorsTheFilterMarks(checker *C) {
+	coordinates := []*imageoutput.MappedCoordinate{
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(100, 0),
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(math.NaN(), 0),
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(1, 0),
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(2, 0),
+	}
+	coordinates[1].MarkAsSatisfyingFilter()
+	coordinates[2].MarkAsSatisfyingFilter()
+	coordinates[3].MarkFilterWeight(0.5)
+	collection := imageoutput.CoordinateCollectionBuilder().WithCoordinates(&coordinates).Build()
+
+	eyedropper := imageoutput.GradientEyedropperBuilder().
+		WithStops(
+			imageoutput.NewGradientStop(0, suite.black),
+			imageoutput.NewGradientStop(1, suite.white),
+		).
+		Build()
+	colors := *eyedropper.ConvertCoordinatesToColors(collection)
+
+	checker.Assert(colors[0], Equals, color.NRGBA{})
+	checker.Assert(colors[1], Equals, color.NRGBA{})
+	checker.Assert(colors[2], Equals, suite.black)
+	checker.Assert(colors[3], Equals, color.NRGBA{R: 255, G: 255, B: 255, A: 128})
+}
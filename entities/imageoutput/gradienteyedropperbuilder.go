@@ -0,0 +1,67 @@
+package imageoutput
+
+import "sort"
+
+// GradientEyedropperBuilderOptions stores the options used to build a gradient eyedropper.
+type GradientEyedropperBuilderOptions struct {
+	scalar         GradientScalar
+	stops          []GradientStop
+	hasScalarRange bool
+	scalarMinimum  float64
+	scalarMaximum  float64
+}
+
+// GradientEyedropperBuilder creates a GradientEyedropperBuilderOptions with default values.
+//   Can be chained with other class functions. Call Build() to create the
+//   final object.
+func GradientEyedropperBuilder() *GradientEyedropperBuilderOptions {
+	return &GradientEyedropperBuilderOptions{
+		scalar:         ModulusGradientScalar,
+		stops:          []GradientStop{},
+		hasScalarRange: false,
+		scalarMinimum:  0,
+		scalarMaximum:  0,
+	}
+}
+
+// WithScalar sets the number taken from each transformed coordinate. Unknown scalars are ignored.
+func (e *GradientEyedropperBuilderOptions) WithScalar(scalar GradientScalar) *GradientEyedropperBuilderOptions {
+	if !scalar.IsValid() {
+		return e
+	}
+	e.scalar = scalar
+	return e
+}
+
+// WithStops sets the gradient's stops. They are ordered by position, keeping stops at the same position in order
+//   so the color can change sharply there.
+func (e *GradientEyedropperBuilderOptions) WithStops(stops ...GradientStop) *GradientEyedropperBuilderOptions {
+	e.stops = append([]GradientStop{}, stops...)
+	sort.SliceStable(e.stops, func(i, j int) bool {
+		return e.stops[i].position < e.stops[j].position
+	})
+	return e
+}
+
+// WithScalarRange sets the scalars that map to the start and end of the gradient,
+//   so the colors do not depend on the output size or viewport. Ignored unless the minimum is less than the maximum.
+func (e *GradientEyedropperBuilderOptions) WithScalarRange(minimum, maximum float64) *GradientEyedropperBuilderOptions {
+	if !(minimum < maximum) {
+		return e
+	}
+	e.hasScalarRange = true
+	e.scalarMinimum = minimum
+	e.scalarMaximum = maximum
+	return e
+}
+
+// Build uses the builder options to create a gradient eyedropper.
+func (e *GradientEyedropperBuilderOptions) Build() *GradientEyedropper {
+	return &GradientEyedropper{
+		scalar:         e.scalar,
+		stops:          e.stops,
+		hasScalarRange: e.hasScalarRange,
+		scalarMinimum:  e.scalarMinimum,
+		scalarMaximum:  e.scalarMaximum,
+	}
+}
@@ -7,13 +7,16 @@ type MappedCoordinate struct {
 	inputImageX int
 	inputImageY int
 
+	inputImageSampleOffsetX float64
+	inputImageSampleOffsetY float64
+
 	patternViewportX float64
 	patternViewportY float64
 
 	transformedX float64
 	transformedY float64
 
-	satisfiedFilter      bool
+	filterWeight         float64
 	hasMappedCoordinates bool
 
 	mappedCoordinateX float64
@@ -31,6 +34,17 @@ func NewMappedCoordinateUsingInputImageCoordinates(inputImageX, inputImageY int)
 	}
 }
 
+// NewMappedCoordinateUsingInputImageSample returns a new mapped coordinate inside the pixel at inputImageX and inputImageY.
+//   The offsets are between 0 and 1 and note where the sample is taken inside the pixel, 0.5 is the center.
+func NewMappedCoordinateUsingInputImageSample(inputImageX, inputImageY int, sampleOffsetX, sampleOffsetY float64) *MappedCoordinate {
+	return &MappedCoordinate{
+		inputImageX:             inputImageX,
+		inputImageY:             inputImageY,
+		inputImageSampleOffsetX: sampleOffsetX,
+		inputImageSampleOffsetY: sampleOffsetY,
+	}
+}
+
 // NewMappedCoordinateUsingOutputImageCoordinates returns a new mapped coordinate at the given outputImageX and outputImageY location.
 func NewMappedCoordinateUsingOutputImageCoordinates(outputImageX, outputImageY int) *MappedCoordinate {
 	return &MappedCoordinate{
@@ -42,9 +56,9 @@ func NewMappedCoordinateUsingOutputImageCoordinates(outputImageX, outputImageY i
 // NewMappedCoordinateUsingTransformedCoordinates returns a new mapped coordinate at the given transformedX and transformedY location.
 func NewMappedCoordinateUsingTransformedCoordinates(transformedX, transformedY float64) *MappedCoordinate {
 	return &MappedCoordinate{
-		transformedX:    transformedX,
-		transformedY:    transformedY,
-		satisfiedFilter: false,
+		transformedX: transformedX,
+		transformedY: transformedY,
+		filterWeight: 0,
 	}
 }
 
@@ -68,6 +82,16 @@ func (m *MappedCoordinate) InputImageY() int {
 	return m.inputImageY
 }
 
+// InputImageSampleX returns where the sample was taken along the x axis, including the offset inside the pixel.
+func (m *MappedCoordinate) InputImageSampleX() float64 {
+	return float64(m.inputImageX) + m.inputImageSampleOffsetX
+}
+
+// InputImageSampleY returns where the sample was taken along the y axis, including the offset inside the pixel.
+func (m *MappedCoordinate) InputImageSampleY() float64 {
+	return float64(m.inputImageY) + m.inputImageSampleOffsetY
+}
+
 // TransformedX returns the TransformedX coordinate.
 func (m *MappedCoordinate) TransformedX() float64 {
 	return m.transformedX
@@ -109,14 +133,29 @@ func (m *MappedCoordinate) CanBeCompared() bool {
 		math.IsNaN(m.TransformedY()))
 }
 
-// MarkAsSatisfyingFilter marks this coordinate as satisfying the filter.
+// MarkAsSatisfyingFilter marks this coordinate as fully satisfying the filter.
 func (m *MappedCoordinate) MarkAsSatisfyingFilter() {
-	m.satisfiedFilter = true
+	m.filterWeight = 1
 }
 
-// SatisfiesFilter returns the filtered status.
+// MarkFilterWeight records how much this coordinate satisfies the filter, from 0 to 1.
+//   Coordinates near the edge of a feathered threshold are partially kept.
+func (m *MappedCoordinate) MarkFilterWeight(weight float64) {
+	if math.IsNaN(weight) {
+		weight = 0
+	}
+	m.filterWeight = math.Max(0, math.Min(1, weight))
+}
+
+// SatisfiesFilter returns true if any part of the coordinate satisfies the filter.
 func (m *MappedCoordinate) SatisfiesFilter() bool {
-	return m.satisfiedFilter
+	return m.filterWeight > 0
+}
+
+// FilterWeight returns how much this coordinate satisfies the filter, from 0 to 1.
+//   The coordinate's color is faded by this amount.
+func (m *MappedCoordinate) FilterWeight() float64 {
+	return m.filterWeight
 }
 
 // HasMappedCoordinate returns true if this coordinate stored another mapped coordinate
@@ -82,3 +82,11 @@ func (suite *MappedCoordinateTest) TestStoreMappedCoordinate(checker *C) {
 	checker.Assert(x, Equals, 2e0)
 	checker.Assert(y, Equals, -3e-3)
 }
+
+func (suite *MappedCoordinateTest) TestCreateMappedCoordinateUsingInputImageSample(checker *C) {
+	coordinate := imageoutput.NewMappedCoordinateUsingInputImageSample(20, 3, 0.25, 0.75)
+	checker.Assert(coordinate.InputImageX(), Equals, 20)
+	checker.Assert(coordinate.InputImageY(), Equals, 3)
+	checker.Assert(coordinate.InputImageSampleX(), Equals, 20.25)
+	checker.Assert(coordinate.InputImageSampleY(), Equals, 3.75)
+}
@@ -0,0 +1,136 @@
+package imageoutput
+
+import (
+	"math"
+	"sort"
+)
+
+// MappingCurve chooses how transformed coordinates are spread across the eyedropper along one axis.
+//   Formulas often cluster their coordinates near the origin, so a linear mapping only uses the middle of the source image.
+type MappingCurve string
+
+// Mapping curves. Every curve keeps the order of the coordinates, so the minimum still maps to the first side.
+const (
+	LinearMappingCurve             MappingCurve = "linear"
+	LogarithmicMappingCurve        MappingCurve = "logarithmic"
+	SignedSquareRootMappingCurve   MappingCurve = "signed_square_root"
+	SigmoidMappingCurve            MappingCurve = "sigmoid"
+	HistogramEqualizedMappingCurve MappingCurve = "histogram_equalized"
+)
+
+// sigmoidStepsPerRange is how many sigmoid scales fit across the range.
+//   The ends of the range reach about 2% and 98% of the way up the curve before they are stretched to the sides.
+const sigmoidStepsPerRange = 8
+
+// MappingCurves returns every supported mapping curve.
+func MappingCurves() []MappingCurve {
+	return []MappingCurve{
+		LinearMappingCurve,
+		LogarithmicMappingCurve,
+		SignedSquareRootMappingCurve,
+		SigmoidMappingCurve,
+		HistogramEqualizedMappingCurve,
+	}
+}
+
+// IsValid returns true if this is a supported mapping curve.
+func (c MappingCurve) IsValid() bool {
+	for _, curve := range MappingCurves() {
+		if c == curve {
+			return true
+		}
+	}
+	return false
+}
+
+// newAxisMapping returns a function that turns a value between the minimum and maximum into a fraction from 0 to 1.
+//   Values past the minimum and maximum are clamped.
+//   Histogram equalization calls sortedValues once, to get every value along the axis from smallest to largest.
+func (c MappingCurve) newAxisMapping(minimum, maximum float64, sortedValues func() []float64) func(value float64) float64 {
+	if !(minimum < maximum) {
+		return func(value float64) float64 {
+			return 0
+		}
+	}
+
+	switch c {
+	case LogarithmicMappingCurve:
+		return warpedAxisMapping(minimum, maximum, func(value float64) float64 {
+			return math.Copysign(math.Log1p(math.Abs(value)), value)
+		})
+	case SignedSquareRootMappingCurve:
+		return warpedAxisMapping(minimum, maximum, func(value float64) float64 {
+			return math.Copysign(math.Sqrt(math.Abs(value)), value)
+		})
+	case SigmoidMappingCurve:
+		center := (minimum + maximum) / 2
+		scale := (maximum - minimum) / sigmoidStepsPerRange
+		return warpedAxisMapping(minimum, maximum, func(value float64) float64 {
+			return 1 / (1 + math.Exp(-(value-center)/scale))
+		})
+	case HistogramEqualizedMappingCurve:
+		return histogramEqualizedAxisMapping(minimum, maximum, sortedValues())
+	}
+	return warpedAxisMapping(minimum, maximum, func(value float64) float64 {
+		return value
+	})
+}
+
+// warpedAxisMapping applies the warp, then scales the result so the minimum is 0 and the maximum is 1.
+//   The warp must always increase.
+func warpedAxisMapping(minimum, maximum float64, warp func(value float64) float64) func(value float64) float64 {
+	warpedMinimum := warp(minimum)
+	warpedMaximum := warp(maximum)
+	return func(value float64) float64 {
+		if value <= minimum {
+			return 0
+		}
+		if value >= maximum {
+			return 1
+		}
+		return (warp(value) - warpedMinimum) / (warpedMaximum - warpedMinimum)
+	}
+}
+
+// histogramEqualizedAxisMapping uses the rank of each value among the sorted values between the minimum and maximum,
+//   so every part of the eyedropper is used by the same number of coordinates.
+//   Falls back to a linear mapping if there are fewer than 2 values to rank.
+func histogramEqualizedAxisMapping(minimum, maximum float64, sortedValues []float64) func(value float64) float64 {
+	firstInside := sort.SearchFloat64s(sortedValues, minimum)
+	afterLastInside := sort.Search(len(sortedValues), func(index int) bool {
+		return sortedValues[index] > maximum
+	})
+	valuesInside := sortedValues[firstInside:afterLastInside]
+	if len(valuesInside) < 2 {
+		return LinearMappingCurve.newAxisMapping(minimum, maximum, nil)
+	}
+
+	return func(value float64) float64 {
+		return rankFractionOfSortedValues(valuesInside, value)
+	}
+}
+
+// rankFractionOfSortedValues returns how far through the sorted values the value is, from 0 to 1.
+//   It is the opposite of percentileOfSortedValues: values between two sorted values are interpolated,
+//   and values repeated in the list use the middle of their ranks.
+func rankFractionOfSortedValues(sorted []float64, value float64) float64 {
+	lastIndex := len(sorted) - 1
+	if value < sorted[0] {
+		return 0
+	}
+	if value > sorted[lastIndex] {
+		return 1
+	}
+
+	first := sort.SearchFloat64s(sorted, value)
+	if sorted[first] == value {
+		afterLast := sort.Search(len(sorted), func(index int) bool {
+			return sorted[index] > value
+		})
+		return float64(first+afterLast-1) / 2 / float64(lastIndex)
+	}
+
+	previous := first - 1
+	fractionToNext := (value - sorted[previous]) / (sorted[first] - sorted[previous])
+	return (float64(previous) + fractionToNext) / float64(lastIndex)
+}
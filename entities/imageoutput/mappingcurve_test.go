@@ -0,0 +1,106 @@
+package imageoutput_test
+
+import (
+	"github.com/chadius/creatingsymmetry/entities/imageoutput"
+	"github.com/chadius/creatingsymmetry/entities/utility"
+	. "gopkg.in/check.v1"
+)
+
+type MappingCurveTests struct {
+}
+
+var _ = Suite(&MappingCurveTests{})
+
+func (suite *MappingCurveTests) TestBuilderUsesLinearCurvesByDefault(checker *C) {
+	curveX, curveY := imageoutput.EyedropperBuilder().Build().MappingCurves()
+	checker.Assert(curveX, Equals, imageoutput.LinearMappingCurve)
+	checker.Assert(curveY, Equals, imageoutput.LinearMappingCurve)
+}
+
+func (suite *MappingCurveTests) TestBuilderIgnoresUnknownCurvesOnEachAxis(checker *C) {
+	curveX, curveY := imageoutput.EyedropperBuilder().
+		WithMappingCurves("cubic", imageoutput.SigmoidMappingCurve).
+		Build().
+		MappingCurves()
+	checker.Assert(curveX, Equals, imageoutput.LinearMappingCurve)
+	checker.Assert(curveY, Equals, imageoutput.SigmoidMappingCurve)
+}
+
+// mappedXAlongCurve maps the transformed X coordinates across an eyedropper from 0 to 200,
+//   and returns where each one was drawn.
+func mappedXAlongCurve(curve imageoutput.MappingCurve, transformedXs ...float64) []float64 {
+	coordinates := []*imageoutput.MappedCoordinate{}
+	for _, transformedX := range transformedXs {
+		coordinate := imageoutput.NewMappedCoordinateUsingTransformedCoordinates(transformedX, 0)
+		coordinate.MarkAsSatisfyingFilter()
+		coordinates = append(coordinates, coordinate)
+	}
+	collection := imageoutput.CoordinateCollectionBuilder().WithCoordinates(&coordinates).Build()
+
+	eyedropper := imageoutput.EyedropperBuilder().
+		WithLeftSide(0).
+		WithRightSide(200).
+		WithTopSide(0).
+		WithBottomSide(200).
+		WithMappingCurves(curve, imageoutput.LinearMappingCurve).
+		WithImage(generate2x2ImageWithRedGreenBlueBlackPixels()).
+		Build()
+	eyedropper.ConvertCoordinatesToColors(collection)
+
+	mappedXs := []float64{}
+	for _, coordinate := range coordinates {
+		mappedX, _ := coordinate.MappedCoordinate()
+		mappedXs = append(mappedXs, mappedX)
+	}
+	return mappedXs
+}
+
+func (suite *MappingCurveTests) TestEveryCurveKeepsTheOrderAndReachesBothSides(checker *C) {
+	for _, curve := range imageoutput.MappingCurves() {
+		mappedXs := mappedXAlongCurve(curve, -100, -1, 0, 1, 100)
+		checker.Assert(mappedXs[0], utility.NumericallyCloseEnough{}, 0.0, 1e-6, Commentf("%s", curve))
+		checker.Assert(mappedXs[4], utility.NumericallyCloseEnough{}, 200.0, 1e-6, Commentf("%s", curve))
+		for index := 1; index < len(mappedXs); index++ {
+			checker.Assert(mappedXs[index] > mappedXs[index-1], Equals, true, Commentf("%s", curve))
+		}
+	}
+}
+
+func (suite *MappingCurveTests) TestLinearCurveSpreadsEvenly(checker *C) {
+	mappedXs := mappedXAlongCurve(imageoutput.LinearMappingCurve, -100, -1, 0, 1, 100)
+	checker.Assert(mappedXs[1], utility.NumericallyCloseEnough{}, 99.0, 1e-6)
+	checker.Assert(mappedXs[3], utility.NumericallyCloseEnough{}, 101.0, 1e-6)
+}
+
+func (suite *MappingCurveTests) TestSignedSquareRootSpreadsValuesNearZero(checker *C) {
+	mappedXs := mappedXAlongCurve(imageoutput.SignedSquareRootMappingCurve, -100, -1, 0, 1, 100)
+	checker.Assert(mappedXs[1], utility.NumericallyCloseEnough{}, 90.0, 1e-6)
+	checker.Assert(mappedXs[2], utility.NumericallyCloseEnough{}, 100.0, 1e-6)
+	checker.Assert(mappedXs[3], utility.NumericallyCloseEnough{}, 110.0, 1e-6)
+}
+
+func (suite *MappingCurveTests) TestLogarithmicSpreadsValuesNearZero(checker *C) {
+	mappedXs := mappedXAlongCurve(imageoutput.LogarithmicMappingCurve, -100, -1, 0, 1, 100)
+	checker.Assert(mappedXs[1], utility.NumericallyCloseEnough{}, 84.98, 1e-2)
+	checker.Assert(mappedXs[2], utility.NumericallyCloseEnough{}, 100.0, 1e-6)
+	checker.Assert(mappedXs[3], utility.NumericallyCloseEnough{}, 115.02, 1e-2)
+}
+
+func (suite *MappingCurveTests) TestSigmoidIsSymmetricAroundTheCenter(checker *C) {
+	mappedXs := mappedXAlongCurve(imageoutput.SigmoidMappingCurve, -100, -50, 0, 50, 100)
+	checker.Assert(mappedXs[2], utility.NumericallyCloseEnough{}, 100.0, 1e-6)
+	checker.Assert(mappedXs[1]+mappedXs[3], utility.NumericallyCloseEnough{}, 200.0, 1e-6)
+	checker.Assert(mappedXs[3] > 150, Equals, true)
+}
+
+func (suite *MappingCurveTests) TestHistogramEqualizationSpreadsClusteredValuesEvenly(checker *C) {
+	mappedXs := mappedXAlongCurve(imageoutput.HistogramEqualizedMappingCurve, -100, -0.2, -0.1, 0.1, 100)
+	checker.Assert(mappedXs, DeepEquals, []float64{0, 50, 100, 150, 200})
+}
+
+func (suite *MappingCurveTests) TestHistogramEqualizationUsesTheMiddleRankForRepeatedValues(checker *C) {
+	mappedXs := mappedXAlongCurve(imageoutput.HistogramEqualizedMappingCurve, 0, 0, 0, 5, 10)
+	checker.Assert(mappedXs[0], utility.NumericallyCloseEnough{}, 50.0, 1e-6)
+	checker.Assert(mappedXs[3], utility.NumericallyCloseEnough{}, 150.0, 1e-6)
+	checker.Assert(mappedXs[4], utility.NumericallyCloseEnough{}, 200.0, 1e-6)
+}
@@ -0,0 +1,87 @@
+package imageoutput
+
+import (
+	"image"
+	"image/color"
+)
+
+// MaskCoordinateThreshold uses a grayscale image to choose how much of each coordinate is kept.
+//   White keeps the coordinate, black removes it and grays partially keep it. Transparent mask pixels remove coordinates.
+//   The mask covers either a rectangle in transformed space or the entire output image.
+type MaskCoordinateThreshold struct {
+	mask         image.Image
+	outputSpace  bool
+	minimumX     float64
+	maximumX     float64
+	minimumY     float64
+	maximumY     float64
+	outputWidth  int
+	outputHeight int
+}
+
+// NewTransformedSpaceMaskCoordinateThreshold stretches the mask over a rectangle in transformed space.
+//   Like the eyedropper, the top of the mask is at the minimum Y. Coordinates outside of the rectangle are removed.
+func NewTransformedSpaceMaskCoordinateThreshold(mask image.Image, minimumX, maximumX, minimumY, maximumY float64) *MaskCoordinateThreshold {
+	return &MaskCoordinateThreshold{
+		mask:     mask,
+		minimumX: minimumX,
+		maximumX: maximumX,
+		minimumY: minimumY,
+		maximumY: maximumY,
+	}
+}
+
+// NewOutputSpaceMaskCoordinateThreshold stretches the mask over the output image, which is outputWidth by outputHeight pixels.
+//   Each coordinate uses the mask where it will be drawn, no matter where the formula moved it.
+func NewOutputSpaceMaskCoordinateThreshold(mask image.Image, outputWidth, outputHeight int) *MaskCoordinateThreshold {
+	return &MaskCoordinateThreshold{
+		mask:         mask,
+		outputSpace:  true,
+		outputWidth:  outputWidth,
+		outputHeight: outputHeight,
+	}
+}
+
+// FilterAndMarkMappedCoordinateCollection marks each comparable coordinate using the mask's gray value at its location.
+func (c *MaskCoordinateThreshold) FilterAndMarkMappedCoordinateCollection(collection *CoordinateCollection) {
+	bounds := c.mask.Bounds()
+	grid := &pixelGrid{
+		sourceImage: c.mask,
+		left:        bounds.Min.X,
+		right:       bounds.Max.X,
+		top:         bounds.Min.Y,
+		bottom:      bounds.Max.Y,
+		edgeMode:    ClampEdgeMode,
+	}
+
+	for _, coordinateToFilter := range *collection.Coordinates() {
+		if !coordinateToFilter.CanBeCompared() {
+			continue
+		}
+		fractionX, fractionY := c.maskFraction(coordinateToFilter)
+		if fractionX < 0 || fractionX > 1 || fractionY < 0 || fractionY > 1 {
+			continue
+		}
+		maskColor := grid.sample(
+			BilinearSampling,
+			float64(bounds.Min.X)+fractionX*float64(bounds.Dx()),
+			float64(bounds.Min.Y)+fractionY*float64(bounds.Dy()),
+		)
+		coordinateToFilter.MarkFilterWeight(maskWeight(maskColor))
+	}
+}
+
+// maskFraction returns how far across and down the mask the coordinate is, from 0 to 1.
+func (c *MaskCoordinateThreshold) maskFraction(coordinate *MappedCoordinate) (float64, float64) {
+	if c.outputSpace {
+		return coordinate.InputImageSampleX() / float64(c.outputWidth), coordinate.InputImageSampleY() / float64(c.outputHeight)
+	}
+	return (coordinate.TransformedX() - c.minimumX) / (c.maximumX - c.minimumX),
+		(coordinate.TransformedY() - c.minimumY) / (c.maximumY - c.minimumY)
+}
+
+// maskWeight returns the brightness of the mask color, from 0 to 1, multiplied by its alpha.
+func maskWeight(maskColor color.NRGBA) float64 {
+	brightness := (0.299*float64(maskColor.R) + 0.587*float64(maskColor.G) + 0.114*float64(maskColor.B)) / 0xff
+	return brightness * float64(maskColor.A) / 0xff
+}
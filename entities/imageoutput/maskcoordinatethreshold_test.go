@@ -0,0 +1,69 @@
+package imageoutput_test
+
+import (
+	"github.com/chadius/creatingsymmetry/entities/imageoutput"
+	"github.com/chadius/creatingsymmetry/entities/utility"
+	. "gopkg.in/check.v1"
+	"image"
+	"image/color"
+	"math"
+)
+
+type MaskCoordinateThresholdTests struct {
+	mask *image.NRGBA
+}
+
+var _ = Suite(&MaskCoordinateThresholdTests{})
+
+// SetUpTest creates a 2x2 mask with a white top left pixel, a gray bottom left pixel and black pixels on the right.
+func (suite *MaskCoordinateThresholdTests) SetUpTest(checker *C) {
+	suite.mask = image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	suite.mask.Set(0, 0, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	suite.mask.Set(1, 0, color.NRGBA{A: 255})
+	suite.mask.Set(0, 1, color.NRGBA{R: 51, G: 51, B: 51, A: 255})
+	suite.mask.Set(1, 1, color.NRGBA{A: 255})
+}
+
+func (suite *MaskCoordinateThresholdTests) TestTransformedSpaceMaskCoversTheRectangle(checker *C) {
+	threshold := imageoutput.NewTransformedSpaceMaskCoordinateThreshold(suite.mask, 0, 2, 0, 2)
+
+	weights := filterWeights(threshold, complex(0.5, 0.5), complex(1.5, 0.5), complex(0.5, 1.5), complex(1, 0.5), complex(3, 1), complex(math.NaN(), 1))
+	expectedWeights := []float64{1, 0, 0.2, 0.5, 0, 0}
+	for pointIndex, expectedWeight := range expectedWeights {
+		checker.Assert(weights[pointIndex], utility.NumericallyCloseEnough{}, expectedWeight, 1e-2, Commentf("point %d", pointIndex))
+	}
+}
+
+func (suite *MaskCoordinateThresholdTests) TestOutputSpaceMaskUsesTheOutputPixel(checker *C) {
+	threshold := imageoutput.NewOutputSpaceMaskCoordinateThreshold(suite.mask, 4, 4)
+
+	coordinates := []*imageoutput.MappedCoordinate{
+		imageoutput.NewMappedCoordinateUsingInputImageSample(0, 0, 0.5, 0.5),
+		imageoutput.NewMappedCoordinateUsingInputImageSample(3, 0, 0.5, 0.5),
+		imageoutput.NewMappedCoordinateUsingInputImageSample(0, 3, 0.5, 0.5),
+	}
+	for _, coordinate := range coordinates {
+		coordinate.UpdateTransformedCoordinates(100, -100)
+	}
+	threshold.FilterAndMarkMappedCoordinateCollection(imageoutput.CoordinateCollectionBuilder().WithCoordinates(&coordinates).Build())
+
+	checker.Assert(coordinates[0].FilterWeight(), utility.NumericallyCloseEnough{}, 1.0, 1e-2)
+	checker.Assert(coordinates[1].FilterWeight(), utility.NumericallyCloseEnough{}, 0.0, 1e-2)
+	checker.Assert(coordinates[2].FilterWeight(), utility.NumericallyCloseEnough{}, 0.2, 1e-2)
+}
+
+func (suite *MaskCoordinateThresholdTests) TestOutputSpaceMasksWorkInsideCombinations(checker *C) {
+	threshold := imageoutput.NewIntersectionCoordinateThreshold(
+		imageoutput.NewOutputSpaceMaskCoordinateThreshold(suite.mask, 2, 2),
+		imageoutput.NewDiscCoordinateThreshold(0, 0, 10),
+	)
+
+	coordinates := []*imageoutput.MappedCoordinate{
+		imageoutput.NewMappedCoordinateUsingInputImageSample(0, 0, 0.5, 0.5),
+		imageoutput.NewMappedCoordinateUsingInputImageSample(1, 0, 0.5, 0.5),
+	}
+	threshold.FilterAndMarkMappedCoordinateCollection(imageoutput.CoordinateCollectionBuilder().WithCoordinates(&coordinates).Build())
+
+	checker.Assert(coordinates[0].FilterWeight(), Equals, 1.0)
+	checker.Assert(coordinates[1].FilterWeight(), Equals, 0.0)
+}
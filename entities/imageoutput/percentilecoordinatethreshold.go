@@ -0,0 +1,57 @@
+package imageoutput
+
+import "math"
+
+// PercentileCoordinateThreshold keeps coordinates inside a rectangle chosen from the collection itself.
+//   The rectangle spans the lower to the upper percentile of the transformed X and Y coordinates,
+//   so the few coordinates near a pole are removed without choosing the rectangle by hand.
+type PercentileCoordinateThreshold struct {
+	lowerPercentile float64
+	upperPercentile float64
+}
+
+// NewPercentileCoordinateThreshold keeps coordinates between the lower and upper percentiles, from 0 to 100.
+func NewPercentileCoordinateThreshold(lowerPercentile, upperPercentile float64) *PercentileCoordinateThreshold {
+	return &PercentileCoordinateThreshold{
+		lowerPercentile: lowerPercentile,
+		upperPercentile: upperPercentile,
+	}
+}
+
+// Percentiles returns the lower and upper percentiles of the rectangle.
+func (c *PercentileCoordinateThreshold) Percentiles() (float64, float64) {
+	return c.lowerPercentile, c.upperPercentile
+}
+
+// FilterAndMarkMappedCoordinateCollection suggests a rectangle for the collection and keeps the coordinates inside it.
+func (c *PercentileCoordinateThreshold) FilterAndMarkMappedCoordinateCollection(collection *CoordinateCollection) {
+	rectangle := SuggestRectangularCoordinateThreshold(collection, c.lowerPercentile, c.upperPercentile)
+	if rectangle == nil {
+		return
+	}
+	rectangle.FilterAndMarkMappedCoordinateCollection(collection)
+}
+
+// SuggestRectangularCoordinateThreshold returns the rectangle between the lower and upper percentiles
+//   of every comparable coordinate in the collection, from 0 to 100.
+//   Filter marks are ignored, so the collection does not need to be filtered first.
+//   Returns nil if none of the coordinates can be compared.
+func SuggestRectangularCoordinateThreshold(collection *CoordinateCollection, lowerPercentile, upperPercentile float64) *RectangularCoordinateThreshold {
+	sortedX := sortedValues(*collection.Coordinates(), false, func(coordinate *MappedCoordinate) float64 {
+		return coordinate.TransformedX()
+	})
+	sortedY := sortedValues(*collection.Coordinates(), false, func(coordinate *MappedCoordinate) float64 {
+		return coordinate.TransformedY()
+	})
+
+	minimumX := percentileOfSortedValues(sortedX, lowerPercentile)
+	if math.IsNaN(minimumX) {
+		return nil
+	}
+	return &RectangularCoordinateThreshold{
+		minimumX: minimumX,
+		maximumX: percentileOfSortedValues(sortedX, upperPercentile),
+		minimumY: percentileOfSortedValues(sortedY, lowerPercentile),
+		maximumY: percentileOfSortedValues(sortedY, upperPercentile),
+	}
+}
@@ -0,0 +1,48 @@
+package imageoutput_test
+
+import (
+	"github.com/chadius/creatingsymmetry/entities/imageoutput"
+	. "gopkg.in/check.v1"
+	"math"
+)
+
+type PercentileCoordinateThresholdTests struct{}
+
+var _ = Suite(&PercentileCoordinateThresholdTests{})
+
+func (suite *PercentileCoordinateThresholdTests) TestSuggestsTheRectangleBetweenThePercentiles(checker *C) {
+	coordinates := []*imageoutput.MappedCoordinate{
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(0, 40),
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(1, 30),
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(2, 20),
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(3, 10),
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(1000, 0),
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(math.Inf(1), 0),
+	}
+	collection := imageoutput.CoordinateCollectionBuilder().WithCoordinates(&coordinates).Build()
+
+	rectangle := imageoutput.SuggestRectangularCoordinateThreshold(collection, 25, 75)
+	checker.Assert(rectangle.MinimumX(), Equals, 1.0)
+	checker.Assert(rectangle.MaximumX(), Equals, 3.0)
+	checker.Assert(rectangle.MinimumY(), Equals, 10.0)
+	checker.Assert(rectangle.MaximumY(), Equals, 30.0)
+}
+
+func (suite *PercentileCoordinateThresholdTests) TestCannotSuggestWithoutComparableCoordinates(checker *C) {
+	coordinates := []*imageoutput.MappedCoordinate{
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(math.NaN(), 0),
+	}
+	collection := imageoutput.CoordinateCollectionBuilder().WithCoordinates(&coordinates).Build()
+
+	checker.Assert(imageoutput.SuggestRectangularCoordinateThreshold(collection, 1, 99), IsNil)
+}
+
+func (suite *PercentileCoordinateThresholdTests) TestRemovesCoordinatesOutsideThePercentiles(checker *C) {
+	threshold := imageoutput.NewPercentileCoordinateThreshold(0, 75)
+
+	checker.Assert(
+		satisfiedPoints(threshold, 0, 1, 2, 3, 1000, complex(math.NaN(), 0)),
+		DeepEquals,
+		[]bool{true, true, true, true, false, false},
+	)
+}
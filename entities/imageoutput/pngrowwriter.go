@@ -0,0 +1,244 @@
+package imageoutput
+
+import (
+	"bufio"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image/png"
+	"io"
+)
+
+// pngSignature starts every PNG file.
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+// PNG row filters, each row starts with the one used.
+const (
+	pngFilterNone    = 0
+	pngFilterSub     = 1
+	pngFilterUp      = 2
+	pngFilterAverage = 3
+	pngFilterPaeth   = 4
+)
+
+// pngBytesPerPixel is the size of an 8 bit RGBA pixel.
+const pngBytesPerPixel = 4
+
+// pngChunkSize is the most compressed data written in one IDAT chunk.
+const pngChunkSize = 1 << 16
+
+// PNGRowWriter encodes an 8 bit RGBA PNG image one row at a time, from top to bottom,
+//   so the whole image never has to be in memory. Only the previous row is kept, to filter the next one.
+//   Like image/png, each row uses the filter that is likely to compress best.
+type PNGRowWriter struct {
+	output        io.Writer
+	width         int
+	height        int
+	rowsWritten   int
+	useFilters    bool
+	compressed    *zlib.Writer
+	chunks        *bufio.Writer
+	previousRow   []uint8
+	filteredRows  [5][]uint8
+	writeError    error
+	alreadyClosed bool
+}
+
+// NewPNGRowWriter writes the PNG header for a width by height image and gets ready to write its rows.
+func NewPNGRowWriter(output io.Writer, width, height int, compressionLevel png.CompressionLevel) (*PNGRowWriter, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("cannot write a %d by %d PNG image", width, height)
+	}
+
+	writer := &PNGRowWriter{
+		output:      output,
+		width:       width,
+		height:      height,
+		useFilters:  compressionLevel != png.NoCompression,
+		previousRow: make([]uint8, width*pngBytesPerPixel),
+	}
+	for filter := range writer.filteredRows {
+		writer.filteredRows[filter] = make([]uint8, 1+width*pngBytesPerPixel)
+		writer.filteredRows[filter][0] = uint8(filter)
+	}
+
+	if _, err := io.WriteString(output, pngSignature); err != nil {
+		return nil, err
+	}
+	header := make([]uint8, 13)
+	binary.BigEndian.PutUint32(header[0:4], uint32(width))
+	binary.BigEndian.PutUint32(header[4:8], uint32(height))
+	header[8] = 8
+	header[9] = 6
+	if err := writer.writeChunk("IHDR", header); err != nil {
+		return nil, err
+	}
+
+	writer.chunks = bufio.NewWriterSize(pngDataChunkWriter{writer: writer}, pngChunkSize)
+	compressed, err := zlib.NewWriterLevel(writer.chunks, zlibLevel(compressionLevel))
+	if err != nil {
+		return nil, err
+	}
+	writer.compressed = compressed
+	return writer, nil
+}
+
+// zlibLevel converts the PNG compression level to a zlib compression level.
+func zlibLevel(compressionLevel png.CompressionLevel) int {
+	switch compressionLevel {
+	case png.NoCompression:
+		return zlib.NoCompression
+	case png.BestSpeed:
+		return zlib.BestSpeed
+	case png.BestCompression:
+		return zlib.BestCompression
+	}
+	return zlib.DefaultCompression
+}
+
+// WriteRow filters and compresses the next row.
+//   pixels holds 4 bytes for each pixel: red, green, blue and alpha, not premultiplied, like image.NRGBA.
+func (w *PNGRowWriter) WriteRow(pixels []uint8) error {
+	if w.writeError != nil {
+		return w.writeError
+	}
+	if len(pixels) != w.width*pngBytesPerPixel {
+		return fmt.Errorf("row has %d bytes, expected %d for %d pixels", len(pixels), w.width*pngBytesPerPixel, w.width)
+	}
+	if w.rowsWritten >= w.height {
+		return fmt.Errorf("image only has %d rows", w.height)
+	}
+
+	_, w.writeError = w.compressed.Write(w.filterRow(pixels))
+	copy(w.previousRow, pixels)
+	w.rowsWritten++
+	return w.writeError
+}
+
+// Close finishes the compressed data and writes the end of the image.
+//   Returns an error if fewer rows were written than the height of the image.
+func (w *PNGRowWriter) Close() error {
+	if w.alreadyClosed {
+		return w.writeError
+	}
+	w.alreadyClosed = true
+	if w.writeError != nil {
+		return w.writeError
+	}
+	if w.rowsWritten < w.height {
+		w.writeError = fmt.Errorf("only %d of %d rows were written", w.rowsWritten, w.height)
+		return w.writeError
+	}
+
+	if w.writeError = w.compressed.Close(); w.writeError != nil {
+		return w.writeError
+	}
+	if w.writeError = w.chunks.Flush(); w.writeError != nil {
+		return w.writeError
+	}
+	w.writeError = w.writeChunk("IEND", nil)
+	return w.writeError
+}
+
+// filterRow returns the row with the filter byte in front.
+//   Without compression the row is not filtered.
+//   Otherwise every filter is tried and the one with the smallest sum of absolute differences is used, like image/png.
+func (w *PNGRowWriter) filterRow(pixels []uint8) []uint8 {
+	noFilter := w.filteredRows[pngFilterNone]
+	copy(noFilter[1:], pixels)
+	if !w.useFilters {
+		return noFilter
+	}
+
+	previous := w.previousRow
+	sub := w.filteredRows[pngFilterSub][1:]
+	up := w.filteredRows[pngFilterUp][1:]
+	average := w.filteredRows[pngFilterAverage][1:]
+	paeth := w.filteredRows[pngFilterPaeth][1:]
+	for index, value := range pixels {
+		var left, upperLeft uint8
+		if index >= pngBytesPerPixel {
+			left = pixels[index-pngBytesPerPixel]
+			upperLeft = previous[index-pngBytesPerPixel]
+		}
+		above := previous[index]
+
+		sub[index] = value - left
+		up[index] = value - above
+		average[index] = value - uint8((int(left)+int(above))/2)
+		paeth[index] = value - paethPredictor(left, above, upperLeft)
+	}
+
+	bestFilter := pngFilterNone
+	bestSum := -1
+	for filter, filteredRow := range w.filteredRows {
+		sum := 0
+		for _, value := range filteredRow[1:] {
+			sum += absoluteSignedByte(value)
+		}
+		if bestSum < 0 || sum < bestSum {
+			bestFilter = filter
+			bestSum = sum
+		}
+	}
+	return w.filteredRows[bestFilter]
+}
+
+// paethPredictor picks whichever of the left, above and upper left bytes is closest to left + above - upper left.
+func paethPredictor(left, above, upperLeft uint8) uint8 {
+	estimate := int(left) + int(above) - int(upperLeft)
+	distanceToLeft := absoluteInt(estimate - int(left))
+	distanceToAbove := absoluteInt(estimate - int(above))
+	distanceToUpperLeft := absoluteInt(estimate - int(upperLeft))
+	if distanceToLeft <= distanceToAbove && distanceToLeft <= distanceToUpperLeft {
+		return left
+	}
+	if distanceToAbove <= distanceToUpperLeft {
+		return above
+	}
+	return upperLeft
+}
+
+func absoluteInt(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
+
+// absoluteSignedByte treats the byte as a signed difference, so 255 is as small as 1.
+func absoluteSignedByte(value uint8) int {
+	return absoluteInt(int(int8(value)))
+}
+
+// writeChunk writes the chunk's length, type, data and checksum.
+func (w *PNGRowWriter) writeChunk(chunkType string, data []uint8) error {
+	header := make([]uint8, 8)
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(data)))
+	copy(header[4:8], chunkType)
+	checksum := crc32.NewIEEE()
+	checksum.Write(header[4:8])
+	checksum.Write(data)
+	footer := make([]uint8, 4)
+	binary.BigEndian.PutUint32(footer, checksum.Sum32())
+
+	for _, part := range [][]uint8{header, data, footer} {
+		if _, err := w.output.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pngDataChunkWriter writes everything it receives as one IDAT chunk.
+type pngDataChunkWriter struct {
+	writer *PNGRowWriter
+}
+
+func (p pngDataChunkWriter) Write(data []uint8) (int, error) {
+	if err := p.writer.writeChunk("IDAT", data); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
@@ -0,0 +1,79 @@
+package imageoutput_test
+
+import (
+	"bytes"
+	"github.com/chadius/creatingsymmetry/entities/imageoutput"
+	. "gopkg.in/check.v1"
+	"image"
+	"image/png"
+)
+
+type PNGRowWriterTests struct {
+	sourceImage *image.NRGBA
+}
+
+var _ = Suite(&PNGRowWriterTests{})
+
+func (suite *PNGRowWriterTests) SetUpTest(checker *C) {
+	suite.sourceImage = image.NewNRGBA(image.Rect(0, 0, 37, 23))
+	for y := 0; y < 23; y++ {
+		for x := 0; x < 37; x++ {
+			offset := suite.sourceImage.PixOffset(x, y)
+			suite.sourceImage.Pix[offset] = uint8(x * 7)
+			suite.sourceImage.Pix[offset+1] = uint8(y * 11)
+			suite.sourceImage.Pix[offset+2] = uint8((x * y) ^ 0x5a)
+			suite.sourceImage.Pix[offset+3] = uint8(255 - x - y)
+		}
+	}
+}
+
+func (suite *PNGRowWriterTests) writeRows(checker *C, compressionLevel png.CompressionLevel) *bytes.Buffer {
+	output := &bytes.Buffer{}
+	writer, err := imageoutput.NewPNGRowWriter(output, 37, 23, compressionLevel)
+	checker.Assert(err, IsNil)
+	for y := 0; y < 23; y++ {
+		checker.Assert(writer.WriteRow(suite.sourceImage.Pix[y*suite.sourceImage.Stride:(y+1)*suite.sourceImage.Stride]), IsNil)
+	}
+	checker.Assert(writer.Close(), IsNil)
+	return output
+}
+
+func (suite *PNGRowWriterTests) TestDecodesToTheSamePixels(checker *C) {
+	compressionLevels := []png.CompressionLevel{
+		png.DefaultCompression,
+		png.NoCompression,
+		png.BestSpeed,
+		png.BestCompression,
+	}
+	for _, compressionLevel := range compressionLevels {
+		decodedImage, err := png.Decode(suite.writeRows(checker, compressionLevel))
+		checker.Assert(err, IsNil)
+		checker.Assert(decodedImage.Bounds(), Equals, suite.sourceImage.Bounds())
+		decodedNRGBA, isNRGBA := decodedImage.(*image.NRGBA)
+		checker.Assert(isNRGBA, Equals, true)
+		checker.Assert(decodedNRGBA.Pix, DeepEquals, suite.sourceImage.Pix, Commentf("compression level %d", compressionLevel))
+	}
+}
+
+func (suite *PNGRowWriterTests) TestRejectsEmptyImages(checker *C) {
+	_, err := imageoutput.NewPNGRowWriter(&bytes.Buffer{}, 0, 10, png.DefaultCompression)
+	checker.Assert(err, ErrorMatches, "cannot write a 0 by 10 PNG image")
+}
+
+func (suite *PNGRowWriterTests) TestRejectsRowsOfTheWrongLength(checker *C) {
+	writer, _ := imageoutput.NewPNGRowWriter(&bytes.Buffer{}, 37, 23, png.DefaultCompression)
+	err := writer.WriteRow(make([]uint8, 36*4))
+	checker.Assert(err, ErrorMatches, "row has 144 bytes, expected 148 for 37 pixels")
+}
+
+func (suite *PNGRowWriterTests) TestRejectsTooManyRows(checker *C) {
+	writer, _ := imageoutput.NewPNGRowWriter(&bytes.Buffer{}, 2, 1, png.DefaultCompression)
+	checker.Assert(writer.WriteRow(make([]uint8, 8)), IsNil)
+	checker.Assert(writer.WriteRow(make([]uint8, 8)), ErrorMatches, "image only has 1 rows")
+}
+
+func (suite *PNGRowWriterTests) TestCloseRejectsMissingRows(checker *C) {
+	writer, _ := imageoutput.NewPNGRowWriter(&bytes.Buffer{}, 2, 3, png.DefaultCompression)
+	checker.Assert(writer.WriteRow(make([]uint8, 8)), IsNil)
+	checker.Assert(writer.Close(), ErrorMatches, "only 1 of 3 rows were written")
+}
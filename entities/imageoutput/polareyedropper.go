@@ -0,0 +1,139 @@
+package imageoutput
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// PolarEyedropper samples transformed coordinates against a circular or elliptical portion of the source image.
+//   The argument of the transformed coordinate picks the angle around the center,
+//   and its modulus picks how far from the center to sample.
+//   Angles increase counterclockwise, starting to the right of the center.
+type PolarEyedropper struct {
+	centerX        float64
+	centerY        float64
+	radiusX        float64
+	radiusY        float64
+	maximumModulus float64
+	sourceImage    image.Image
+	sampling       Sampling
+
+	modulusPercentile float64
+}
+
+// Center returns the center of the ellipse in the source image.
+func (e *PolarEyedropper) Center() (float64, float64) {
+	return e.centerX, e.centerY
+}
+
+// Radius returns the horizontal and vertical radius of the ellipse, in pixels.
+func (e *PolarEyedropper) Radius() (float64, float64) {
+	return e.radiusX, e.radiusY
+}
+
+// MaximumModulus returns the modulus that maps to the rim of the ellipse.
+//   0 means the modulus percentile of the collection is used instead.
+func (e *PolarEyedropper) MaximumModulus() float64 {
+	return e.maximumModulus
+}
+
+// ModulusPercentile returns the percentile of the collection's modulus used when there is no maximum modulus.
+//   100 uses the largest modulus.
+func (e *PolarEyedropper) ModulusPercentile() float64 {
+	return e.modulusPercentile
+}
+
+// Image returns the source image
+func (e *PolarEyedropper) Image() image.Image {
+	return e.sourceImage
+}
+
+// Sampling returns how colors are read between the source image's pixels.
+func (e *PolarEyedropper) Sampling() Sampling {
+	return e.sampling
+}
+
+// ConvertCoordinatesToColors maps each coordinate to a point inside the ellipse
+//   and samples the color in the source image at that location.
+//   Coordinates with a larger modulus than the maximum are drawn on the rim.
+//   Coordinates that did not satisfy the filter or are at infinity turn transparent.
+func (e *PolarEyedropper) ConvertCoordinatesToColors(collection *CoordinateCollection) *[]color.Color {
+	convertedColors := []color.Color{}
+	e.mapCoordinatesToEllipse(collection)
+
+	bounds := e.Image().Bounds()
+	grid := &pixelGrid{
+		sourceImage: e.Image(),
+		left:        bounds.Min.X,
+		right:       bounds.Max.X,
+		top:         bounds.Min.Y,
+		bottom:      bounds.Max.Y,
+		edgeMode:    ClampEdgeMode,
+	}
+
+	for _, coordinate := range *collection.Coordinates() {
+		if !coordinate.HasMappedCoordinate() {
+			convertedColors = append(convertedColors, color.NRGBA{})
+			continue
+		}
+
+		mappedCoordinateX, mappedCoordinateY := coordinate.MappedCoordinate()
+		convertedColors = append(convertedColors, fadeByFilterWeight(grid.sample(e.Sampling(), mappedCoordinateX, mappedCoordinateY), coordinate))
+	}
+	return &convertedColors
+}
+
+// mapCoordinatesToEllipse stores the point in the source image for each coordinate.
+//   Only coordinates that satisfied their filter will be updated.
+func (e *PolarEyedropper) mapCoordinatesToEllipse(collection *CoordinateCollection) {
+	maximumModulus := e.MaximumModulus()
+	if maximumModulus <= 0 && e.ModulusPercentile() < 100 {
+		maximumModulus = collection.TransformedModulusPercentile(e.ModulusPercentile())
+	}
+	if maximumModulus <= 0 {
+		maximumModulus = collection.MaximumTransformedModulus()
+	}
+
+	for _, coordinate := range *collection.Coordinates() {
+		if !coordinate.SatisfiesFilter() {
+			continue
+		}
+		if !coordinate.CanBeCompared() {
+			continue
+		}
+
+		radiusFraction := 0.0
+		if maximumModulus > 0 {
+			modulus := math.Hypot(coordinate.TransformedX(), coordinate.TransformedY())
+			radiusFraction = math.Min(1, modulus/maximumModulus)
+		}
+		argument := math.Atan2(coordinate.TransformedY(), coordinate.TransformedX())
+
+		coordinate.StoreMappedCoordinate(
+			e.centerX+radiusFraction*e.radiusX*math.Cos(argument),
+			e.centerY-radiusFraction*e.radiusY*math.Sin(argument),
+		)
+	}
+}
+
+// CanStream returns false if the maximum modulus is a percentile, which needs every coordinate at once.
+func (e *PolarEyedropper) CanStream() bool {
+	return e.MaximumModulus() > 0 || e.ModulusPercentile() >= 100
+}
+
+// NeedsStatistics returns true if the rim is the farthest coordinate from the origin.
+func (e *PolarEyedropper) NeedsStatistics() bool {
+	return e.MaximumModulus() <= 0
+}
+
+// WithStatistics returns a copy of the eyedropper that uses the measured maximum modulus.
+func (e *PolarEyedropper) WithStatistics(statistics *TransformedStatistics) Eyedropper {
+	_, maximumModulus := statistics.ModulusRange()
+	if !e.NeedsStatistics() || !(maximumModulus > 0) {
+		return e
+	}
+	eyedropperWithModulus := *e
+	eyedropperWithModulus.maximumModulus = maximumModulus
+	return &eyedropperWithModulus
+}
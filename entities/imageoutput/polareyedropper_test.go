@@ -0,0 +1,163 @@
+package imageoutput_test
+
+import (
+	"github.com/chadius/creatingsymmetry/entities/imageoutput"
+	"github.com/chadius/creatingsymmetry/entities/utility"
+	. "gopkg.in/check.v1"
+	"image"
+	"image/color"
+	"math"
+)
+
+type PolarEyedropperTests struct {
+	sourceImage *image.NRGBA
+}
+
+var _ = Suite(&PolarEyedropperTests{})
+
+var (
+	polarCenterColor = color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+	polarRightColor  = color.NRGBA{R: 255, G: 0, B: 0, A: 255}
+	polarTopColor    = color.NRGBA{R: 0, G: 255, B: 0, A: 255}
+	polarLeftColor   = color.NRGBA{R: 0, G: 0, B: 255, A: 255}
+	polarBottomColor = color.NRGBA{R: 0, G: 0, B: 0, A: 255}
+)
+
+// SetUpTest creates a 5x5 image with a white center and different colors at each edge.
+func (suite *PolarEyedropperTests) SetUpTest(checker *C) {
+	suite.sourceImage = image.NewNRGBA(image.Rect(0, 0, 5, 5))
+	suite.sourceImage.SetNRGBA(2, 2, polarCenterColor)
+	suite.sourceImage.SetNRGBA(4, 2, polarRightColor)
+	suite.sourceImage.SetNRGBA(2, 0, polarTopColor)
+	suite.sourceImage.SetNRGBA(0, 2, polarLeftColor)
+	suite.sourceImage.SetNRGBA(2, 4, polarBottomColor)
+}
+
+func (suite *PolarEyedropperTests) polarEyedropper() *imageoutput.PolarEyedropper {
+	return imageoutput.PolarEyedropperBuilder().
+		WithCenter(2.5, 2.5).
+		WithRadius(2, 2).
+		WithImage(suite.sourceImage).
+		Build()
+}
+
+func satisfyingCoordinates(points ...complex128) *imageoutput.CoordinateCollection {
+	coordinates := []*imageoutput.MappedCoordinate{}
+	for _, point := range points {
+		coordinate := imageoutput.NewMappedCoordinateUsingTransformedCoordinates(real(point), imag(point))
+		coordinate.MarkAsSatisfyingFilter()
+		coordinates = append(coordinates, coordinate)
+	}
+	return imageoutput.CoordinateCollectionBuilder().WithCoordinates(&coordinates).Build()
+}
+
+func (suite *PolarEyedropperTests) TestBuilderSetsEllipse(checker *C) {
+	eyedropper := imageoutput.PolarEyedropperBuilder().
+		WithCenter(10, 20).
+		WithRadius(5, 3).
+		WithMaximumModulus(2).
+		WithSampling(imageoutput.BilinearSampling).
+		Build()
+
+	centerX, centerY := eyedropper.Center()
+	checker.Assert(centerX, Equals, 10.0)
+	checker.Assert(centerY, Equals, 20.0)
+	radiusX, radiusY := eyedropper.Radius()
+	checker.Assert(radiusX, Equals, 5.0)
+	checker.Assert(radiusY, Equals, 3.0)
+	checker.Assert(eyedropper.MaximumModulus(), Equals, 2.0)
+	checker.Assert(eyedropper.Sampling(), Equals, imageoutput.BilinearSampling)
+}
+
+func (suite *PolarEyedropperTests) TestBuilderIgnoresInvalidValues(checker *C) {
+	eyedropper := imageoutput.PolarEyedropperBuilder().
+		WithRadius(5, -1).
+		WithMaximumModulus(-2).
+		Build()
+
+	radiusX, _ := eyedropper.Radius()
+	checker.Assert(radiusX, Equals, 0.0)
+	checker.Assert(eyedropper.MaximumModulus(), Equals, 0.0)
+}
+
+func (suite *PolarEyedropperTests) TestArgumentPicksTheAngle(checker *C) {
+	collection := satisfyingCoordinates(1, 1i, -1, -1i, 0)
+	colors := *suite.polarEyedropper().ConvertCoordinatesToColors(collection)
+
+	checker.Assert(colors[0], Equals, polarRightColor)
+	checker.Assert(colors[1], Equals, polarTopColor)
+	checker.Assert(colors[2], Equals, polarLeftColor)
+	checker.Assert(colors[3], Equals, polarBottomColor)
+	checker.Assert(colors[4], Equals, polarCenterColor)
+}
+
+func (suite *PolarEyedropperTests) TestModulusPicksTheRadius(checker *C) {
+	collection := satisfyingCoordinates(4, 2, complex(0, -3))
+	suite.polarEyedropper().ConvertCoordinatesToColors(collection)
+
+	coordinates := *collection.Coordinates()
+	mappedX, mappedY := coordinates[0].MappedCoordinate()
+	checker.Assert(mappedX, utility.NumericallyCloseEnough{}, 4.5, 1e-6)
+	checker.Assert(mappedY, utility.NumericallyCloseEnough{}, 2.5, 1e-6)
+
+	mappedX, mappedY = coordinates[1].MappedCoordinate()
+	checker.Assert(mappedX, utility.NumericallyCloseEnough{}, 3.5, 1e-6)
+	checker.Assert(mappedY, utility.NumericallyCloseEnough{}, 2.5, 1e-6)
+
+	mappedX, mappedY = coordinates[2].MappedCoordinate()
+	checker.Assert(mappedX, utility.NumericallyCloseEnough{}, 2.5, 1e-6)
+	checker.Assert(mappedY, utility.NumericallyCloseEnough{}, 4.0, 1e-6)
+}
+
+func (suite *PolarEyedropperTests) TestMaximumModulusReachesTheRim(checker *C) {
+	eyedropper := imageoutput.PolarEyedropperBuilder().
+		WithCenter(2.5, 2.5).
+		WithRadius(2, 1).
+		WithMaximumModulus(2).
+		WithImage(suite.sourceImage).
+		Build()
+	collection := satisfyingCoordinates(1i, 10)
+	eyedropper.ConvertCoordinatesToColors(collection)
+
+	coordinates := *collection.Coordinates()
+	mappedX, mappedY := coordinates[0].MappedCoordinate()
+	checker.Assert(mappedX, utility.NumericallyCloseEnough{}, 2.5, 1e-6)
+	checker.Assert(mappedY, utility.NumericallyCloseEnough{}, 2.0, 1e-6)
+
+	mappedX, mappedY = coordinates[1].MappedCoordinate()
+	checker.Assert(mappedX, utility.NumericallyCloseEnough{}, 4.5, 1e-6)
+	checker.Assert(mappedY, utility.NumericallyCloseEnough{}, 2.5, 1e-6)
+}
+
+func (suite *PolarEyedropperTests) TestInvalidCoordinatesAreTransparent(checker *C) {
+	coordinates := []*imageoutput.MappedCoordinate{
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(1, 0),
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(math.Inf(1), 0),
+	}
+	coordinates[1].MarkAsSatisfyingFilter()
+	collection := imageoutput.CoordinateCollectionBuilder().WithCoordinates(&coordinates).Build()
+	colors := *suite.polarEyedropper().ConvertCoordinatesToColors(collection)
+
+	checker.Assert(colors[0], Equals, color.NRGBA{})
+	checker.Assert(colors[1], Equals, color.NRGBA{})
+}
+
+func (suite *PolarEyedropperTests) TestModulusPercentileReachesTheRim(checker *C) {
+	checker.Assert(imageoutput.PolarEyedropperBuilder().WithModulusPercentile(0).Build().ModulusPercentile(), Equals, 100.0)
+	checker.Assert(imageoutput.PolarEyedropperBuilder().WithModulusPercentile(101).Build().ModulusPercentile(), Equals, 100.0)
+
+	eyedropper := imageoutput.PolarEyedropperBuilder().
+		WithCenter(2.5, 2.5).
+		WithRadius(2, 2).
+		WithModulusPercentile(50).
+		WithImage(suite.sourceImage).
+		Build()
+	collection := satisfyingCoordinates(1, 2, 3, 1000)
+	eyedropper.ConvertCoordinatesToColors(collection)
+
+	coordinates := *collection.Coordinates()
+	mappedX, _ := coordinates[0].MappedCoordinate()
+	checker.Assert(mappedX, utility.NumericallyCloseEnough{}, 2.5+2*1/2.5, 1e-6)
+	mappedX, _ = coordinates[2].MappedCoordinate()
+	checker.Assert(mappedX, utility.NumericallyCloseEnough{}, 4.5, 1e-6)
+}
@@ -0,0 +1,100 @@
+package imageoutput
+
+import "image"
+
+// PolarEyedropperBuilderOptions stores the options used to build a polar eyedropper.
+type PolarEyedropperBuilderOptions struct {
+	centerX        float64
+	centerY        float64
+	radiusX        float64
+	radiusY        float64
+	maximumModulus float64
+	sourceImage    image.Image
+	sampling       Sampling
+
+	modulusPercentile float64
+}
+
+// PolarEyedropperBuilder creates a PolarEyedropperBuilderOptions with default values.
+//   Can be chained with other class functions. Call Build() to create the
+//   final object.
+func PolarEyedropperBuilder() *PolarEyedropperBuilderOptions {
+	return &PolarEyedropperBuilderOptions{
+		centerX:        0,
+		centerY:        0,
+		radiusX:        0,
+		radiusY:        0,
+		maximumModulus: 0,
+		sourceImage:    nil,
+		sampling:       NearestSampling,
+
+		modulusPercentile: 100,
+	}
+}
+
+// WithCenter sets the center of the ellipse in the source image.
+func (e *PolarEyedropperBuilderOptions) WithCenter(x, y float64) *PolarEyedropperBuilderOptions {
+	e.centerX = x
+	e.centerY = y
+	return e
+}
+
+// WithRadius sets the horizontal and vertical radius of the ellipse.
+//   Use the same radius for a circle. Ignored unless both are positive.
+func (e *PolarEyedropperBuilderOptions) WithRadius(radiusX, radiusY float64) *PolarEyedropperBuilderOptions {
+	if radiusX <= 0 || radiusY <= 0 {
+		return e
+	}
+	e.radiusX = radiusX
+	e.radiusY = radiusY
+	return e
+}
+
+// WithMaximumModulus sets the modulus that maps to the rim of the ellipse. Values that are not positive are ignored.
+func (e *PolarEyedropperBuilderOptions) WithMaximumModulus(maximumModulus float64) *PolarEyedropperBuilderOptions {
+	if maximumModulus <= 0 {
+		return e
+	}
+	e.maximumModulus = maximumModulus
+	return e
+}
+
+// WithModulusPercentile uses the modulus that the given percent of each collection is within as the maximum modulus,
+//   instead of the largest modulus. Ignored unless it is more than 0 and at most 100.
+func (e *PolarEyedropperBuilderOptions) WithModulusPercentile(percent float64) *PolarEyedropperBuilderOptions {
+	if percent <= 0 || percent > 100 {
+		return e
+	}
+	e.modulusPercentile = percent
+	return e
+}
+
+// WithImage sets the source image.
+func (e *PolarEyedropperBuilderOptions) WithImage(sourceImage image.Image) *PolarEyedropperBuilderOptions {
+	e.sourceImage = sourceImage
+	return e
+}
+
+// WithSampling sets how colors are read between pixels. Unknown sampling methods are ignored.
+func (e *PolarEyedropperBuilderOptions) WithSampling(sampling Sampling) *PolarEyedropperBuilderOptions {
+	if !sampling.IsValid() {
+		return e
+	}
+	e.sampling = sampling
+	return e
+}
+
+// Build uses the builder options to create a polar eyedropper.
+func (e *PolarEyedropperBuilderOptions) Build() *PolarEyedropper {
+	return &PolarEyedropper{
+		centerX:        e.centerX,
+		centerY:        e.centerY,
+		radiusX:        e.radiusX,
+		radiusY:        e.radiusY,
+		maximumModulus: e.maximumModulus,
+		sourceImage:    e.sourceImage,
+		sampling:       e.sampling,
+
+		modulusPercentile: e.modulusPercentile,
+	}
+}
@@ -0,0 +1,162 @@
+package imageoutput
+
+import (
+	"image"
+	"image/color"
+	"sort"
+)
+
+// MedianCutQuantizer picks a palette for an image by splitting its colors into boxes.
+//   The box with the widest range of colors is split in half at its median until the palette is full.
+//   Each box adds its average color to the palette.
+//   If the image has transparent pixels, one palette entry is kept for them.
+type MedianCutQuantizer struct{}
+
+// weightedColor is one opaque color and how many pixels use it.
+type weightedColor struct {
+	channels [3]uint8
+	count    int
+}
+
+// colorBox holds similar colors that will share one palette entry.
+type colorBox struct {
+	colors []weightedColor
+}
+
+// Quantize appends up to cap(palette) - len(palette) colors to the palette and returns it.
+func (q *MedianCutQuantizer) Quantize(palette color.Palette, sourceImage image.Image) color.Palette {
+	availableColors := cap(palette) - len(palette)
+	if availableColors <= 0 {
+		return palette
+	}
+
+	opaqueColors, hasTransparentPixels := countColors(sourceImage)
+	if hasTransparentPixels {
+		palette = append(palette, color.NRGBA{})
+		availableColors--
+	}
+	if len(opaqueColors) == 0 || availableColors <= 0 {
+		return palette
+	}
+
+	boxes := []*colorBox{{colors: opaqueColors}}
+	for len(boxes) < availableColors {
+		boxIndex, channel := widestBox(boxes)
+		if boxIndex < 0 {
+			break
+		}
+		firstHalf, secondHalf := boxes[boxIndex].split(channel)
+		boxes[boxIndex] = firstHalf
+		boxes = append(boxes, secondHalf)
+	}
+
+	for _, box := range boxes {
+		palette = append(palette, box.average())
+	}
+	return palette
+}
+
+// countColors returns every opaque color in the image with its pixel count, in a repeatable order.
+//   Pixels that are less than half opaque count as transparent.
+func countColors(sourceImage image.Image) ([]weightedColor, bool) {
+	countByColor := map[[3]uint8]int{}
+	hasTransparentPixels := false
+	bounds := sourceImage.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			pixel := color.NRGBAModel.Convert(sourceImage.At(x, y)).(color.NRGBA)
+			if pixel.A < 128 {
+				hasTransparentPixels = true
+				continue
+			}
+			countByColor[[3]uint8{pixel.R, pixel.G, pixel.B}]++
+		}
+	}
+
+	colors := []weightedColor{}
+	for channels, count := range countByColor {
+		colors = append(colors, weightedColor{channels: channels, count: count})
+	}
+	sort.Slice(colors, func(i, j int) bool {
+		return colorKey(colors[i].channels) < colorKey(colors[j].channels)
+	})
+	return colors, hasTransparentPixels
+}
+
+func colorKey(channels [3]uint8) int {
+	return int(channels[0])<<16 | int(channels[1])<<8 | int(channels[2])
+}
+
+// widestBox returns the box with the widest channel range and that channel.
+//   Returns -1 if no box can be split.
+func widestBox(boxes []*colorBox) (int, int) {
+	widestIndex := -1
+	widestChannel := 0
+	widestRange := 0
+	for boxIndex, box := range boxes {
+		if len(box.colors) < 2 {
+			continue
+		}
+		for channel := 0; channel < 3; channel++ {
+			channelRange := box.channelRange(channel)
+			if channelRange > widestRange {
+				widestIndex = boxIndex
+				widestChannel = channel
+				widestRange = channelRange
+			}
+		}
+	}
+	return widestIndex, widestChannel
+}
+
+func (b *colorBox) channelRange(channel int) int {
+	minimum, maximum := 255, 0
+	for _, boxColor := range b.colors {
+		value := int(boxColor.channels[channel])
+		if value < minimum {
+			minimum = value
+		}
+		if value > maximum {
+			maximum = value
+		}
+	}
+	return maximum - minimum
+}
+
+// split sorts the colors along the channel and divides them where half of the pixels are on each side.
+func (b *colorBox) split(channel int) (*colorBox, *colorBox) {
+	sort.SliceStable(b.colors, func(i, j int) bool {
+		return b.colors[i].channels[channel] < b.colors[j].channels[channel]
+	})
+
+	totalCount := 0
+	for _, boxColor := range b.colors {
+		totalCount += boxColor.count
+	}
+
+	splitIndex := 1
+	runningCount := b.colors[0].count
+	for splitIndex < len(b.colors)-1 && runningCount*2 < totalCount {
+		runningCount += b.colors[splitIndex].count
+		splitIndex++
+	}
+	return &colorBox{colors: b.colors[:splitIndex]}, &colorBox{colors: b.colors[splitIndex:]}
+}
+
+// average returns the pixel weighted average color of the box.
+func (b *colorBox) average() color.Color {
+	var sums [3]int
+	totalCount := 0
+	for _, boxColor := range b.colors {
+		for channel := 0; channel < 3; channel++ {
+			sums[channel] += int(boxColor.channels[channel]) * boxColor.count
+		}
+		totalCount += boxColor.count
+	}
+	return color.NRGBA{
+		R: uint8((sums[0] + totalCount/2) / totalCount),
+		G: uint8((sums[1] + totalCount/2) / totalCount),
+		B: uint8((sums[2] + totalCount/2) / totalCount),
+		A: 255,
+	}
+}
@@ -0,0 +1,76 @@
+package imageoutput_test
+
+import (
+	"github.com/chadius/creatingsymmetry/entities/imageoutput"
+	. "gopkg.in/check.v1"
+	"image"
+	"image/color"
+)
+
+type MedianCutQuantizerTests struct {
+}
+
+var _ = Suite(&MedianCutQuantizerTests{})
+
+func imageWithColors(colors ...color.Color) image.Image {
+	colorImage := image.NewNRGBA(image.Rect(0, 0, len(colors), 1))
+	for x, pixelColor := range colors {
+		colorImage.Set(x, 0, pixelColor)
+	}
+	return colorImage
+}
+
+func (suite *MedianCutQuantizerTests) TestKeepsColorsWhenThereIsRoom(checker *C) {
+	red := color.NRGBA{R: 255, A: 255}
+	blue := color.NRGBA{B: 255, A: 255}
+	quantizer := imageoutput.MedianCutQuantizer{}
+
+	palette := quantizer.Quantize(make(color.Palette, 0, 16), imageWithColors(red, blue, red))
+
+	checker.Assert(palette, HasLen, 2)
+	checker.Assert(palette, DeepEquals, color.Palette{blue, red})
+}
+
+func (suite *MedianCutQuantizerTests) TestAveragesColorsWhenPaletteIsFull(checker *C) {
+	quantizer := imageoutput.MedianCutQuantizer{}
+
+	palette := quantizer.Quantize(
+		make(color.Palette, 0, 2),
+		imageWithColors(
+			color.NRGBA{R: 0, A: 255},
+			color.NRGBA{R: 10, A: 255},
+			color.NRGBA{R: 240, A: 255},
+			color.NRGBA{R: 250, A: 255},
+		),
+	)
+
+	checker.Assert(palette, DeepEquals, color.Palette{
+		color.NRGBA{R: 5, A: 255},
+		color.NRGBA{R: 245, A: 255},
+	})
+}
+
+func (suite *MedianCutQuantizerTests) TestReservesAnEntryForTransparentPixels(checker *C) {
+	quantizer := imageoutput.MedianCutQuantizer{}
+
+	palette := quantizer.Quantize(
+		make(color.Palette, 0, 2),
+		imageWithColors(
+			color.NRGBA{},
+			color.NRGBA{G: 100, A: 255},
+			color.NRGBA{G: 200, A: 255},
+		),
+	)
+
+	checker.Assert(palette, DeepEquals, color.Palette{
+		color.NRGBA{},
+		color.NRGBA{G: 150, A: 255},
+	})
+}
+
+func (suite *MedianCutQuantizerTests) TestDoesNotGrowAFullPalette(checker *C) {
+	quantizer := imageoutput.MedianCutQuantizer{}
+	palette := color.Palette{color.Black}
+
+	checker.Assert(quantizer.Quantize(palette[:1:1], imageWithColors(color.White)), HasLen, 1)
+}
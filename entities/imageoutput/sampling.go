@@ -0,0 +1,173 @@
+package imageoutput
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// Sampling chooses how the eyedropper reads a color between the source image's pixels.
+type Sampling string
+
+// Sampling methods, from fastest to smoothest.
+const (
+	NearestSampling  Sampling = "nearest"
+	BilinearSampling Sampling = "bilinear"
+	BicubicSampling  Sampling = "bicubic"
+)
+
+// SamplingMethods returns every supported sampling method.
+func SamplingMethods() []Sampling {
+	return []Sampling{NearestSampling, BilinearSampling, BicubicSampling}
+}
+
+// IsValid returns true if this is a supported sampling method.
+func (s Sampling) IsValid() bool {
+	for _, method := range SamplingMethods() {
+		if s == method {
+			return true
+		}
+	}
+	return false
+}
+
+// premultipliedColor stores a color with each channel multiplied by its alpha, from 0 to 0xffff.
+//   Blending premultiplied colors keeps transparent pixels from darkening their neighbors.
+type premultipliedColor struct {
+	r, g, b, a float64
+}
+
+// pixelGrid reads source pixels inside the sides of the eyedropper.
+//   The edge mode chooses the pixel to read when a pixel is past the sides.
+type pixelGrid struct {
+	sourceImage image.Image
+	left        int
+	right       int
+	top         int
+	bottom      int
+	edgeMode    EdgeMode
+}
+
+func (g *pixelGrid) at(x, y int) premultipliedColor {
+	x, insideX := g.edgeMode.resolvePixel(x, g.left, g.right)
+	y, insideY := g.edgeMode.resolvePixel(y, g.top, g.bottom)
+	if !insideX || !insideY {
+		return premultipliedColor{}
+	}
+	r, gr, b, a := g.sourceImage.At(x, y).RGBA()
+	return premultipliedColor{r: float64(r), g: float64(gr), b: float64(b), a: float64(a)}
+}
+
+// clampPixel keeps the pixel between the minimum and the pixel before the maximum.
+func clampPixel(pixel, minimum, maximum int) int {
+	if pixel >= maximum {
+		pixel = maximum - 1
+	}
+	if pixel < minimum {
+		pixel = minimum
+	}
+	return pixel
+}
+
+// sample reads the color at the location using the sampling method.
+func (g *pixelGrid) sample(sampling Sampling, x, y float64) color.NRGBA {
+	switch sampling {
+	case BilinearSampling:
+		return g.sampleBilinear(x, y)
+	case BicubicSampling:
+		return g.sampleBicubic(x, y)
+	}
+	return g.sampleNearest(x, y)
+}
+
+// sampleNearest reads the pixel that contains the location.
+func (g *pixelGrid) sampleNearest(x, y float64) color.NRGBA {
+	return g.at(int(math.Floor(x)), int(math.Floor(y))).toNRGBA()
+}
+
+// sampleBilinear blends the 4 pixels around the location.
+//   Pixel centers are halfway between whole numbers, so 0.5 is the center of the first pixel.
+func (g *pixelGrid) sampleBilinear(x, y float64) color.NRGBA {
+	pixelX, fractionX := splitSamplePosition(x)
+	pixelY, fractionY := splitSamplePosition(y)
+
+	var blended premultipliedColor
+	for offsetY := 0; offsetY < 2; offsetY++ {
+		weightY := 1 - fractionY
+		if offsetY == 1 {
+			weightY = fractionY
+		}
+		for offsetX := 0; offsetX < 2; offsetX++ {
+			weightX := 1 - fractionX
+			if offsetX == 1 {
+				weightX = fractionX
+			}
+			blended = blended.add(g.at(pixelX+offsetX, pixelY+offsetY), weightX*weightY)
+		}
+	}
+	return blended.toNRGBA()
+}
+
+// sampleBicubic blends the 16 pixels around the location using a Catmull-Rom spline.
+//   It is sharper than bilinear sampling but may slightly overshoot next to hard edges.
+func (g *pixelGrid) sampleBicubic(x, y float64) color.NRGBA {
+	pixelX, fractionX := splitSamplePosition(x)
+	pixelY, fractionY := splitSamplePosition(y)
+
+	var blended premultipliedColor
+	for offsetY := -1; offsetY <= 2; offsetY++ {
+		weightY := catmullRomWeight(float64(offsetY) - fractionY)
+		for offsetX := -1; offsetX <= 2; offsetX++ {
+			weightX := catmullRomWeight(float64(offsetX) - fractionX)
+			blended = blended.add(g.at(pixelX+offsetX, pixelY+offsetY), weightX*weightY)
+		}
+	}
+	return blended.toNRGBA()
+}
+
+// splitSamplePosition returns the pixel whose center is at or before the position,
+//   and how far the position is toward the next pixel's center.
+func splitSamplePosition(position float64) (int, float64) {
+	centered := position - 0.5
+	pixel := math.Floor(centered)
+	return int(pixel), centered - pixel
+}
+
+// catmullRomWeight returns how much a pixel contributes when it is the given distance from the sample.
+func catmullRomWeight(distance float64) float64 {
+	distance = math.Abs(distance)
+	if distance < 1 {
+		return 1.5*distance*distance*distance - 2.5*distance*distance + 1
+	}
+	if distance < 2 {
+		return -0.5*distance*distance*distance + 2.5*distance*distance - 4*distance + 2
+	}
+	return 0
+}
+
+func (p premultipliedColor) add(other premultipliedColor, weight float64) premultipliedColor {
+	return premultipliedColor{
+		r: p.r + other.r*weight,
+		g: p.g + other.g*weight,
+		b: p.b + other.b*weight,
+		a: p.a + other.a*weight,
+	}
+}
+
+// toNRGBA clamps the channels and removes the alpha from each color channel.
+func (p premultipliedColor) toNRGBA() color.NRGBA {
+	alpha := math.Max(0, math.Min(0xffff, p.a))
+	if alpha == 0 {
+		return color.NRGBA{}
+	}
+	unpremultiply := func(channel float64) uint8 {
+		channel = math.Max(0, math.Min(alpha, channel))
+		return uint8(math.Round(channel / alpha * 0xff))
+	}
+	return color.NRGBA{
+		R: unpremultiply(p.r),
+		G: unpremultiply(p.g),
+		B: unpremultiply(p.b),
+		A: uint8(math.Round(alpha / 0xffff * 0xff)),
+	}
+}
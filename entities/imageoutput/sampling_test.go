@@ -0,0 +1,92 @@
+package imageoutput_test
+
+import (
+	"github.com/chadius/creatingsymmetry/entities/imageoutput"
+	. "gopkg.in/check.v1"
+	"image"
+	"image/color"
+)
+
+type SamplingTests struct {
+	sourceImage *image.NRGBA
+}
+
+var _ = Suite(&SamplingTests{})
+
+func (suite *SamplingTests) SetUpTest(checker *C) {
+	suite.sourceImage = image.NewNRGBA(image.Rect(0, 0, 4, 1))
+	suite.sourceImage.SetNRGBA(0, 0, color.NRGBA{R: 0, G: 0, B: 0, A: 255})
+	suite.sourceImage.SetNRGBA(1, 0, color.NRGBA{R: 0, G: 0, B: 0, A: 255})
+	suite.sourceImage.SetNRGBA(2, 0, color.NRGBA{R: 200, G: 100, B: 0, A: 255})
+	suite.sourceImage.SetNRGBA(3, 0, color.NRGBA{R: 200, G: 100, B: 0, A: 0})
+}
+
+// sampleAt converts eyedropper positions into colors.
+//   The transformed coordinates range from 0 to 4 so they match the source image's pixels.
+func (suite *SamplingTests) sampleAt(sampling imageoutput.Sampling, positionsX ...float64) []color.Color {
+	coordinates := []*imageoutput.MappedCoordinate{
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(0, 0),
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(4, 1),
+	}
+	for _, positionX := range positionsX {
+		coordinates = append(coordinates, imageoutput.NewMappedCoordinateUsingTransformedCoordinates(positionX, 0.5))
+	}
+	for _, coordinate := range coordinates {
+		coordinate.MarkAsSatisfyingFilter()
+	}
+	collection := imageoutput.CoordinateCollectionBuilder().WithCoordinates(&coordinates).Build()
+
+	eyedropper := imageoutput.EyedropperBuilder().
+		WithLeftSide(0).
+		WithRightSide(4).
+		WithTopSide(0).
+		WithBottomSide(1).
+		WithImage(suite.sourceImage).
+		WithSampling(sampling).
+		Build()
+	return (*eyedropper.ConvertCoordinatesToColors(collection))[2:]
+}
+
+func (suite *SamplingTests) TestEyedropperDefaultsToNearestSampling(checker *C) {
+	eyedropper := imageoutput.EyedropperBuilder().WithSampling("smooth").Build()
+	checker.Assert(eyedropper.Sampling(), Equals, imageoutput.NearestSampling)
+}
+
+func (suite *SamplingTests) TestNearestSamplingReadsOnePixel(checker *C) {
+	colors := suite.sampleAt(imageoutput.NearestSampling, 1.9, 2.1)
+	checker.Assert(colors[0], Equals, color.NRGBA{R: 0, G: 0, B: 0, A: 255})
+	checker.Assert(colors[1], Equals, color.NRGBA{R: 200, G: 100, B: 0, A: 255})
+}
+
+func (suite *SamplingTests) TestBilinearSamplingBlendsNeighboringPixels(checker *C) {
+	colors := suite.sampleAt(imageoutput.BilinearSampling, 1.5, 2.0, 2.5)
+	checker.Assert(colors[0], Equals, color.NRGBA{R: 0, G: 0, B: 0, A: 255})
+	checker.Assert(colors[1], Equals, color.NRGBA{R: 100, G: 50, B: 0, A: 255})
+	checker.Assert(colors[2], Equals, color.NRGBA{R: 200, G: 100, B: 0, A: 255})
+}
+
+func (suite *SamplingTests) TestBilinearSamplingDoesNotDarkenTransparentEdges(checker *C) {
+	colors := suite.sampleAt(imageoutput.BilinearSampling, 3.0)
+	checker.Assert(colors[0], Equals, color.NRGBA{R: 200, G: 100, B: 0, A: 128})
+}
+
+func (suite *SamplingTests) TestBilinearSamplingRepeatsEdgePixels(checker *C) {
+	colors := suite.sampleAt(imageoutput.BilinearSampling, 0.1)
+	checker.Assert(colors[0], Equals, color.NRGBA{R: 0, G: 0, B: 0, A: 255})
+}
+
+func (suite *SamplingTests) TestBicubicSamplingMatchesPixelCenters(checker *C) {
+	colors := suite.sampleAt(imageoutput.BicubicSampling, 0.5, 2.5)
+	checker.Assert(colors[0], Equals, color.NRGBA{R: 0, G: 0, B: 0, A: 255})
+	checker.Assert(colors[1], Equals, color.NRGBA{R: 200, G: 100, B: 0, A: 255})
+}
+
+func (suite *SamplingTests) TestBicubicSamplingIsSmoothBetweenPixels(checker *C) {
+	colors := suite.sampleAt(imageoutput.BicubicSampling, 1.75, 2.0, 2.25)
+	red := []uint8{}
+	for _, sampledColor := range colors {
+		red = append(red, sampledColor.(color.NRGBA).R)
+	}
+	checker.Assert(red[0] < red[1], Equals, true)
+	checker.Assert(red[1] < red[2], Equals, true)
+}
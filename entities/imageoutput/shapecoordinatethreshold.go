@@ -0,0 +1,234 @@
+package imageoutput
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// markCoordinatesInside marks each comparable coordinate as satisfying the filter if it is inside the shape.
+func markCoordinatesInside(collection *CoordinateCollection, isInside func(x, y float64) bool) {
+	for _, coordinateToFilter := range *collection.Coordinates() {
+		if !coordinateToFilter.CanBeCompared() {
+			continue
+		}
+		if isInside(coordinateToFilter.TransformedX(), coordinateToFilter.TransformedY()) {
+			coordinateToFilter.MarkAsSatisfyingFilter()
+		}
+	}
+}
+
+// DiscCoordinateThreshold keeps coordinates inside a circle.
+type DiscCoordinateThreshold struct {
+	centerX float64
+	centerY float64
+	radius  float64
+}
+
+// NewDiscCoordinateThreshold keeps coordinates that are no farther than the radius from the center.
+func NewDiscCoordinateThreshold(centerX, centerY, radius float64) *DiscCoordinateThreshold {
+	return &DiscCoordinateThreshold{
+		centerX: centerX,
+		centerY: centerY,
+		radius:  radius,
+	}
+}
+
+// FilterAndMarkMappedCoordinateCollection marks coordinates inside the circle.
+func (c *DiscCoordinateThreshold) FilterAndMarkMappedCoordinateCollection(collection *CoordinateCollection) {
+	markCoordinatesInside(collection, func(x, y float64) bool {
+		return math.Hypot(x-c.centerX, y-c.centerY) <= c.radius
+	})
+}
+
+// SignedDistance returns the distance from the point to the circle. It is negative inside the circle.
+func (c *DiscCoordinateThreshold) SignedDistance(x, y float64) float64 {
+	return math.Hypot(x-c.centerX, y-c.centerY) - c.radius
+}
+
+// AnnulusCoordinateThreshold keeps coordinates inside a ring.
+type AnnulusCoordinateThreshold struct {
+	centerX     float64
+	centerY     float64
+	innerRadius float64
+	outerRadius float64
+}
+
+// NewAnnulusCoordinateThreshold keeps coordinates whose distance from the center is between the inner and outer radius.
+func NewAnnulusCoordinateThreshold(centerX, centerY, innerRadius, outerRadius float64) *AnnulusCoordinateThreshold {
+	return &AnnulusCoordinateThreshold{
+		centerX:     centerX,
+		centerY:     centerY,
+		innerRadius: innerRadius,
+		outerRadius: outerRadius,
+	}
+}
+
+// FilterAndMarkMappedCoordinateCollection marks coordinates inside the ring.
+func (c *AnnulusCoordinateThreshold) FilterAndMarkMappedCoordinateCollection(collection *CoordinateCollection) {
+	markCoordinatesInside(collection, func(x, y float64) bool {
+		distance := math.Hypot(x-c.centerX, y-c.centerY)
+		return distance >= c.innerRadius && distance <= c.outerRadius
+	})
+}
+
+// SignedDistance returns the distance from the point to the nearest edge of the ring. It is negative inside the ring.
+func (c *AnnulusCoordinateThreshold) SignedDistance(x, y float64) float64 {
+	distance := math.Hypot(x-c.centerX, y-c.centerY)
+	return math.Max(c.innerRadius-distance, distance-c.outerRadius)
+}
+
+// SectorCoordinateThreshold keeps coordinates inside a wedge around a center.
+type SectorCoordinateThreshold struct {
+	centerX     float64
+	centerY     float64
+	startAngle  float64
+	sweepAngle  float64
+	innerRadius float64
+	outerRadius float64
+}
+
+// NewSectorCoordinateThreshold keeps coordinates whose angle around the center is between the start and end angle.
+//   Angles are in radians, counterclockwise from the positive X axis.
+//   The wedge runs counterclockwise from the start angle to the end angle, so it can cross the positive X axis.
+//   If the end angle is a full turn after the start angle, every angle is kept.
+//   Coordinates closer than the inner radius are removed. An outer radius of 0 means the wedge has no outer limit.
+func NewSectorCoordinateThreshold(centerX, centerY, startAngle, endAngle, innerRadius, outerRadius float64) *SectorCoordinateThreshold {
+	sweepAngle := math.Mod(endAngle-startAngle, 2*math.Pi)
+	if sweepAngle < 0 {
+		sweepAngle += 2 * math.Pi
+	}
+	if sweepAngle == 0 && endAngle != startAngle {
+		sweepAngle = 2 * math.Pi
+	}
+
+	return &SectorCoordinateThreshold{
+		centerX:     centerX,
+		centerY:     centerY,
+		startAngle:  startAngle,
+		sweepAngle:  sweepAngle,
+		innerRadius: innerRadius,
+		outerRadius: outerRadius,
+	}
+}
+
+// FilterAndMarkMappedCoordinateCollection marks coordinates inside the wedge.
+func (c *SectorCoordinateThreshold) FilterAndMarkMappedCoordinateCollection(collection *CoordinateCollection) {
+	markCoordinatesInside(collection, func(x, y float64) bool {
+		distance := math.Hypot(x-c.centerX, y-c.centerY)
+		if distance < c.innerRadius {
+			return false
+		}
+		if c.outerRadius > 0 && distance > c.outerRadius {
+			return false
+		}
+		return c.isInsideSweep(x, y)
+	})
+}
+
+// isInsideSweep returns true if the point's angle around the center is between the start and end angle.
+func (c *SectorCoordinateThreshold) isInsideSweep(x, y float64) bool {
+	angleAfterStart := math.Mod(math.Atan2(y-c.centerY, x-c.centerX)-c.startAngle, 2*math.Pi)
+	if angleAfterStart < 0 {
+		angleAfterStart += 2 * math.Pi
+	}
+	return angleAfterStart <= c.sweepAngle
+}
+
+// SignedDistance returns the distance from the point to the nearest edge of the wedge. It is negative inside the wedge.
+//   Near the corners this is the larger of the distance to the arcs and the distance to the straight sides,
+//   which is close enough to soften the edge.
+func (c *SectorCoordinateThreshold) SignedDistance(x, y float64) float64 {
+	distance := math.Hypot(x-c.centerX, y-c.centerY)
+	signedDistance := c.innerRadius - distance
+	if c.outerRadius > 0 {
+		signedDistance = math.Max(signedDistance, distance-c.outerRadius)
+	}
+	if c.sweepAngle >= 2*math.Pi {
+		return signedDistance
+	}
+
+	distanceToSides := math.Min(
+		c.distanceToSide(x, y, c.startAngle),
+		c.distanceToSide(x, y, c.startAngle+c.sweepAngle),
+	)
+	if c.isInsideSweep(x, y) {
+		return math.Max(signedDistance, -distanceToSides)
+	}
+	return math.Max(signedDistance, distanceToSides)
+}
+
+// distanceToSide returns the distance from the point to the ray leaving the center at the angle.
+func (c *SectorCoordinateThreshold) distanceToSide(x, y, angle float64) float64 {
+	offsetX, offsetY := x-c.centerX, y-c.centerY
+	distanceAlongSide := offsetX*math.Cos(angle) + offsetY*math.Sin(angle)
+	if distanceAlongSide <= 0 {
+		return math.Hypot(offsetX, offsetY)
+	}
+	return math.Abs(offsetX*math.Sin(angle) - offsetY*math.Cos(angle))
+}
+
+// PolygonCoordinateThreshold keeps coordinates inside a polygon.
+type PolygonCoordinateThreshold struct {
+	vertices []complex128
+}
+
+// NewPolygonCoordinateThreshold keeps coordinates inside the polygon made by connecting the vertices in order.
+//   The last vertex connects back to the first.
+//   If the polygon crosses itself, areas that are surrounded an odd number of times are inside.
+func NewPolygonCoordinateThreshold(vertices []complex128) *PolygonCoordinateThreshold {
+	return &PolygonCoordinateThreshold{
+		vertices: append([]complex128{}, vertices...),
+	}
+}
+
+// FilterAndMarkMappedCoordinateCollection marks coordinates inside the polygon.
+func (c *PolygonCoordinateThreshold) FilterAndMarkMappedCoordinateCollection(collection *CoordinateCollection) {
+	markCoordinatesInside(collection, c.contains)
+}
+
+// SignedDistance returns the distance from the point to the polygon's nearest edge. It is negative inside the polygon.
+func (c *PolygonCoordinateThreshold) SignedDistance(x, y float64) float64 {
+	distance := math.Inf(1)
+	previousVertex := len(c.vertices) - 1
+	for vertexIndex := range c.vertices {
+		distance = math.Min(distance, distanceToSegment(complex(x, y), c.vertices[previousVertex], c.vertices[vertexIndex]))
+		previousVertex = vertexIndex
+	}
+	if c.contains(x, y) {
+		return -distance
+	}
+	return distance
+}
+
+// distanceToSegment returns the distance from the point to the closest point on the segment between start and end.
+func distanceToSegment(point, start, end complex128) float64 {
+	segment := end - start
+	segmentLengthSquared := real(segment)*real(segment) + imag(segment)*imag(segment)
+	if segmentLengthSquared == 0 {
+		return cmplx.Abs(point - start)
+	}
+	fractionAlongSegment := (real(point-start)*real(segment) + imag(point-start)*imag(segment)) / segmentLengthSquared
+	fractionAlongSegment = math.Max(0, math.Min(1, fractionAlongSegment))
+	return cmplx.Abs(point - (start + complex(fractionAlongSegment, 0)*segment))
+}
+
+// contains casts a ray to the right of the point and counts how many edges it crosses.
+//   An odd number of crossings means the point is inside.
+func (c *PolygonCoordinateThreshold) contains(x, y float64) bool {
+	isInside := false
+	previousVertex := len(c.vertices) - 1
+	for vertexIndex := range c.vertices {
+		currentX, currentY := real(c.vertices[vertexIndex]), imag(c.vertices[vertexIndex])
+		previousX, previousY := real(c.vertices[previousVertex]), imag(c.vertices[previousVertex])
+		previousVertex = vertexIndex
+
+		if (currentY > y) == (previousY > y) {
+			continue
+		}
+		crossingX := currentX + (y-currentY)/(previousY-currentY)*(previousX-currentX)
+		if x < crossingX {
+			isInside = !isInside
+		}
+	}
+	return isInside
+}
@@ -0,0 +1,87 @@
+package imageoutput_test
+
+import (
+	"github.com/chadius/creatingsymmetry/entities/imageoutput"
+	. "gopkg.in/check.v1"
+	"math"
+)
+
+type ShapeCoordinateThresholdTests struct{}
+
+var _ = Suite(&ShapeCoordinateThresholdTests{})
+
+// satisfiedPoints filters the points and returns whether each one satisfied the threshold.
+func satisfiedPoints(threshold imageoutput.CoordinateThreshold, points ...complex128) []bool {
+	coordinates := []*imageoutput.MappedCoordinate{}
+	for _, point := range points {
+		coordinates = append(coordinates, imageoutput.NewMappedCoordinateUsingTransformedCoordinates(real(point), imag(point)))
+	}
+	collection := imageoutput.CoordinateCollectionBuilder().WithCoordinates(&coordinates).Build()
+	threshold.FilterAndMarkMappedCoordinateCollection(collection)
+
+	satisfied := []bool{}
+	for _, coordinate := range coordinates {
+		satisfied = append(satisfied, coordinate.SatisfiesFilter())
+	}
+	return satisfied
+}
+
+func (suite *ShapeCoordinateThresholdTests) TestDiscKeepsCoordinatesInsideTheCircle(checker *C) {
+	threshold := imageoutput.NewDiscCoordinateThreshold(1, 1, 2)
+
+	checker.Assert(
+		satisfiedPoints(threshold, complex(1, 1), complex(3, 1), complex(2.5, 2.5), complex(math.Inf(1), 1)),
+		DeepEquals,
+		[]bool{true, true, false, false},
+	)
+}
+
+func (suite *ShapeCoordinateThresholdTests) TestAnnulusKeepsCoordinatesInsideTheRing(checker *C) {
+	threshold := imageoutput.NewAnnulusCoordinateThreshold(0, 0, 1, 2)
+
+	checker.Assert(
+		satisfiedPoints(threshold, 0, 0.5i, 1, complex(-1, -1), -2i, 3),
+		DeepEquals,
+		[]bool{false, false, true, true, true, false},
+	)
+}
+
+func (suite *ShapeCoordinateThresholdTests) TestSectorKeepsCoordinatesInsideTheWedge(checker *C) {
+	threshold := imageoutput.NewSectorCoordinateThreshold(0, 0, 0, math.Pi/2, 0, 0)
+
+	checker.Assert(
+		satisfiedPoints(threshold, complex(1, 1), complex(100, 1), complex(-1, 1), complex(1, -1), 0),
+		DeepEquals,
+		[]bool{true, true, false, false, true},
+	)
+}
+
+func (suite *ShapeCoordinateThresholdTests) TestSectorCanCrossThePositiveXAxis(checker *C) {
+	threshold := imageoutput.NewSectorCoordinateThreshold(0, 0, -math.Pi/4, math.Pi/4, 0, 0)
+
+	checker.Assert(
+		satisfiedPoints(threshold, complex(1, 0.5), complex(1, -0.5), complex(-1, 0), complex(0, 1)),
+		DeepEquals,
+		[]bool{true, true, false, false},
+	)
+}
+
+func (suite *ShapeCoordinateThresholdTests) TestSectorUsesRadiusLimits(checker *C) {
+	threshold := imageoutput.NewSectorCoordinateThreshold(1, 0, 0, 2*math.Pi, 1, 2)
+
+	checker.Assert(
+		satisfiedPoints(threshold, complex(1.5, 0), complex(2.5, 0), complex(-0.5, 0), complex(4, 0)),
+		DeepEquals,
+		[]bool{false, true, true, false},
+	)
+}
+
+func (suite *ShapeCoordinateThresholdTests) TestPolygonKeepsCoordinatesInsideThePolygon(checker *C) {
+	threshold := imageoutput.NewPolygonCoordinateThreshold([]complex128{0, 4, complex(4, 4), complex(2, 1), complex(0, 4)})
+
+	checker.Assert(
+		satisfiedPoints(threshold, complex(1, 0.5), complex(3, 0.5), complex(2, 3), complex(0.5, 2), complex(5, 1)),
+		DeepEquals,
+		[]bool{true, true, false, true, false},
+	)
+}
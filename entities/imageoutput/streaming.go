@@ -0,0 +1,103 @@
+package imageoutput
+
+import "math"
+
+// StreamingEyedropper eyedroppers can color a large image a few rows at a time, instead of every coordinate at once.
+//   CanStream is false if the colors depend on every coordinate at once, like percentiles and histogram equalization.
+//   NeedsStatistics is true if the colors depend on the range of every coordinate,
+//   then WithStatistics returns an eyedropper that colors each collection with the range measured across the whole image.
+type StreamingEyedropper interface {
+	Eyedropper
+	CanStream() bool
+	NeedsStatistics() bool
+	WithStatistics(statistics *TransformedStatistics) Eyedropper
+}
+
+// TransformedStatistics measures the range of the transformed coordinates across several collections,
+//   so a few rows at a time can be colored the same as coloring the whole image at once.
+//   Like the collection's minimum and maximum, only comparable coordinates that satisfied the filter are measured.
+type TransformedStatistics struct {
+	found          bool
+	minimumX       float64
+	maximumX       float64
+	minimumY       float64
+	maximumY       float64
+	minimumModulus float64
+	maximumModulus float64
+}
+
+// NewTransformedStatistics returns statistics that have not measured any coordinates.
+func NewTransformedStatistics() *TransformedStatistics {
+	return &TransformedStatistics{
+		found:          false,
+		minimumX:       math.NaN(),
+		maximumX:       math.NaN(),
+		minimumY:       math.NaN(),
+		maximumY:       math.NaN(),
+		minimumModulus: math.NaN(),
+		maximumModulus: math.NaN(),
+	}
+}
+
+// Add measures the coordinates in the collection.
+func (s *TransformedStatistics) Add(collection *CoordinateCollection) {
+	for _, coordinate := range *collection.Coordinates() {
+		if !coordinate.CanBeCompared() || !coordinate.SatisfiesFilter() {
+			continue
+		}
+
+		x := coordinate.TransformedX()
+		y := coordinate.TransformedY()
+		modulus := math.Hypot(x, y)
+		if !s.found {
+			s.found = true
+			s.minimumX, s.maximumX = x, x
+			s.minimumY, s.maximumY = y, y
+			s.minimumModulus, s.maximumModulus = modulus, modulus
+			continue
+		}
+
+		s.minimumX = math.Min(s.minimumX, x)
+		s.maximumX = math.Max(s.maximumX, x)
+		s.minimumY = math.Min(s.minimumY, y)
+		s.maximumY = math.Max(s.maximumY, y)
+		s.minimumModulus = math.Min(s.minimumModulus, modulus)
+		s.maximumModulus = math.Max(s.maximumModulus, modulus)
+	}
+}
+
+// Found returns true if at least one coordinate was measured.
+func (s *TransformedStatistics) Found() bool {
+	return s.found
+}
+
+// TransformedXRange returns the minimum and maximum TransformedX coordinate. Both are NaN if nothing was measured.
+func (s *TransformedStatistics) TransformedXRange() (float64, float64) {
+	return s.minimumX, s.maximumX
+}
+
+// TransformedYRange returns the minimum and maximum TransformedY coordinate. Both are NaN if nothing was measured.
+func (s *TransformedStatistics) TransformedYRange() (float64, float64) {
+	return s.minimumY, s.maximumY
+}
+
+// ModulusRange returns the minimum and maximum distance from the origin. Both are NaN if nothing was measured.
+func (s *TransformedStatistics) ModulusRange() (float64, float64) {
+	return s.minimumModulus, s.maximumModulus
+}
+
+// CoordinateThresholdCanStream returns false if the threshold needs every coordinate at once,
+//   like an auto threshold's percentiles, so it cannot filter a few rows at a time.
+func CoordinateThresholdCanStream(threshold CoordinateThreshold) bool {
+	switch typedThreshold := threshold.(type) {
+	case *PercentileCoordinateThreshold:
+		return false
+	case *CompositeCoordinateThreshold:
+		for _, child := range typedThreshold.children {
+			if !CoordinateThresholdCanStream(child) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,191 @@
+package imageoutput_test
+
+import (
+	"github.com/chadius/creatingsymmetry/entities/imageoutput"
+	. "gopkg.in/check.v1"
+	"image"
+	"image/color"
+	"math"
+)
+
+type TransformedStatisticsTests struct {
+	coordinates []*imageoutput.MappedCoordinate
+}
+
+var _ = Suite(&TransformedStatisticsTests{})
+
+func (suite *TransformedStatisticsTests) SetUpTest(checker *C) {
+	suite.coordinates = []*imageoutput.MappedCoordinate{
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(-3, 4),
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(1, 0),
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(0.5, -2),
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(100, 0),
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(math.Inf(1), 0),
+		imageoutput.NewMappedCoordinateUsingTransformedCoordinates(2, 1.5),
+	}
+	for _, index := range []int{0, 1, 2, 4, 5} {
+		suite.coordinates[index].MarkAsSatisfyingFilter()
+	}
+}
+
+func (suite *TransformedStatisticsTests) TestNothingMeasuredIsNaN(checker *C) {
+	statistics := imageoutput.NewTransformedStatistics()
+	checker.Assert(statistics.Found(), Equals, false)
+	minimumX, maximumX := statistics.TransformedXRange()
+	checker.Assert(math.IsNaN(minimumX), Equals, true)
+	checker.Assert(math.IsNaN(maximumX), Equals, true)
+	_, maximumModulus := statistics.ModulusRange()
+	checker.Assert(math.IsNaN(maximumModulus), Equals, true)
+}
+
+func (suite *TransformedStatisticsTests) TestSeveralCollectionsMatchTheWholeCollection(checker *C) {
+	firstHalf := suite.coordinates[:3]
+	secondHalf := suite.coordinates[3:]
+	statistics := imageoutput.NewTransformedStatistics()
+	statistics.Add(imageoutput.CoordinateCollectionBuilder().WithCoordinates(&firstHalf).Build())
+	statistics.Add(imageoutput.CoordinateCollectionBuilder().WithCoordinates(&secondHalf).Build())
+
+	whole := imageoutput.CoordinateCollectionBuilder().WithCoordinates(&suite.coordinates).Build()
+	checker.Assert(statistics.Found(), Equals, true)
+	minimumX, maximumX := statistics.TransformedXRange()
+	checker.Assert(minimumX, Equals, whole.MinimumTransformedX())
+	checker.Assert(maximumX, Equals, whole.MaximumTransformedX())
+	minimumY, maximumY := statistics.TransformedYRange()
+	checker.Assert(minimumY, Equals, whole.MinimumTransformedY())
+	checker.Assert(maximumY, Equals, whole.MaximumTransformedY())
+	minimumModulus, maximumModulus := statistics.ModulusRange()
+	checker.Assert(minimumModulus, Equals, float64(1))
+	checker.Assert(maximumModulus, Equals, whole.MaximumTransformedModulus())
+}
+
+type StreamingEyedropperTests struct {
+	sourceImage *image.NRGBA
+	coordinates []*imageoutput.MappedCoordinate
+}
+
+var _ = Suite(&StreamingEyedropperTests{})
+
+func (suite *StreamingEyedropperTests) SetUpTest(checker *C) {
+	suite.sourceImage = image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for index := range suite.sourceImage.Pix {
+		suite.sourceImage.Pix[index] = uint8(index * 37)
+	}
+
+	suite.coordinates = []*imageoutput.MappedCoordinate{}
+	for index := 0; index < 12; index++ {
+		coordinate := imageoutput.NewMappedCoordinateUsingTransformedCoordinates(
+			math.Cos(float64(index))*float64(index)/3,
+			math.Sin(float64(index))*float64(index)/4,
+		)
+		if index != 7 {
+			coordinate.MarkAsSatisfyingFilter()
+		}
+		suite.coordinates = append(suite.coordinates, coordinate)
+	}
+}
+
+// colorInParts colors the coordinates a few at a time, using the statistics measured across all of them.
+func (suite *StreamingEyedropperTests) colorInParts(eyedropper imageoutput.StreamingEyedropper) []color.Color {
+	collections := []*imageoutput.CoordinateCollection{}
+	for start := 0; start < len(suite.coordinates); start += 5 {
+		end := start + 5
+		if end > len(suite.coordinates) {
+			end = len(suite.coordinates)
+		}
+		part := suite.coordinates[start:end]
+		collections = append(collections, imageoutput.CoordinateCollectionBuilder().WithCoordinates(&part).Build())
+	}
+
+	statistics := imageoutput.NewTransformedStatistics()
+	for _, collection := range collections {
+		statistics.Add(collection)
+	}
+
+	colors := []color.Color{}
+	streamingEyedropper := eyedropper.WithStatistics(statistics)
+	for _, collection := range collections {
+		colors = append(colors, *streamingEyedropper.ConvertCoordinatesToColors(collection)...)
+	}
+	return colors
+}
+
+func (suite *StreamingEyedropperTests) TestColoringInPartsMatchesColoringTheWholeCollection(checker *C) {
+	eyedroppers := map[string]imageoutput.StreamingEyedropper{
+		"rectangular": imageoutput.EyedropperBuilder().
+			WithRightSide(4).
+			WithBottomSide(4).
+			WithSampling(imageoutput.BilinearSampling).
+			WithImage(suite.sourceImage).
+			Build(),
+		"polar": imageoutput.PolarEyedropperBuilder().
+			WithCenter(2, 2).
+			WithRadius(2, 2).
+			WithImage(suite.sourceImage).
+			Build(),
+		"gradient modulus": imageoutput.GradientEyedropperBuilder().
+			WithScalar(imageoutput.ModulusGradientScalar).
+			Build(),
+		"gradient imaginary": imageoutput.GradientEyedropperBuilder().
+			WithScalar(imageoutput.ImaginaryGradientScalar).
+			Build(),
+		"domain coloring": imageoutput.DomainColoringEyedropperBuilder().Build(),
+	}
+
+	for name, eyedropper := range eyedroppers {
+		checker.Assert(eyedropper.CanStream(), Equals, true, Commentf("%s eyedropper", name))
+		whole := imageoutput.CoordinateCollectionBuilder().WithCoordinates(&suite.coordinates).Build()
+		expectedColors := *eyedropper.ConvertCoordinatesToColors(whole)
+		actualColors := suite.colorInParts(eyedropper)
+		checker.Assert(actualColors, HasLen, len(expectedColors))
+		for index := range expectedColors {
+			checker.Assert(actualColors[index], DeepEquals, expectedColors[index], Commentf("%s eyedropper, coordinate %d", name, index))
+		}
+	}
+}
+
+func (suite *StreamingEyedropperTests) TestOnlyEyedroppersWithoutAFixedRangeNeedStatistics(checker *C) {
+	checker.Assert(imageoutput.EyedropperBuilder().WithImage(suite.sourceImage).Build().NeedsStatistics(), Equals, true)
+	checker.Assert(imageoutput.EyedropperBuilder().WithPeriod(1, 1).WithImage(suite.sourceImage).Build().NeedsStatistics(), Equals, false)
+	checker.Assert(imageoutput.EyedropperBuilder().WithTransformedRange(-1, 1, -1, 1).WithImage(suite.sourceImage).Build().NeedsStatistics(), Equals, false)
+	checker.Assert(imageoutput.PolarEyedropperBuilder().WithImage(suite.sourceImage).Build().NeedsStatistics(), Equals, true)
+	checker.Assert(imageoutput.PolarEyedropperBuilder().WithMaximumModulus(2).WithImage(suite.sourceImage).Build().NeedsStatistics(), Equals, false)
+	checker.Assert(imageoutput.GradientEyedropperBuilder().WithScalar(imageoutput.RealGradientScalar).Build().NeedsStatistics(), Equals, true)
+	checker.Assert(imageoutput.GradientEyedropperBuilder().WithScalar(imageoutput.ArgumentGradientScalar).Build().NeedsStatistics(), Equals, false)
+	checker.Assert(imageoutput.GradientEyedropperBuilder().WithScalarRange(0, 1).Build().NeedsStatistics(), Equals, false)
+	checker.Assert(imageoutput.DomainColoringEyedropperBuilder().Build().NeedsStatistics(), Equals, false)
+}
+
+func (suite *StreamingEyedropperTests) TestPercentilesAndHistogramsCannotStream(checker *C) {
+	checker.Assert(imageoutput.EyedropperBuilder().WithPercentileRange(5, 95).WithImage(suite.sourceImage).Build().CanStream(), Equals, false)
+	checker.Assert(
+		imageoutput.EyedropperBuilder().
+			WithMappingCurves(imageoutput.HistogramEqualizedMappingCurve, imageoutput.LinearMappingCurve).
+			WithImage(suite.sourceImage).
+			Build().
+			CanStream(),
+		Equals,
+		false,
+	)
+	checker.Assert(imageoutput.PolarEyedropperBuilder().WithModulusPercentile(90).WithImage(suite.sourceImage).Build().CanStream(), Equals, false)
+	checker.Assert(
+		imageoutput.PolarEyedropperBuilder().WithModulusPercentile(90).WithMaximumModulus(2).WithImage(suite.sourceImage).Build().CanStream(),
+		Equals,
+		true,
+	)
+}
+
+func (suite *StreamingEyedropperTests) TestAutoThresholdsCannotStream(checker *C) {
+	disc := imageoutput.NewDiscCoordinateThreshold(0, 0, 1)
+	checker.Assert(imageoutput.CoordinateThresholdCanStream(disc), Equals, true)
+	checker.Assert(imageoutput.CoordinateThresholdCanStream(imageoutput.NewPercentileCoordinateThreshold(1, 99)), Equals, false)
+	checker.Assert(
+		imageoutput.CoordinateThresholdCanStream(
+			imageoutput.NewIntersectionCoordinateThreshold(
+				disc,
+				imageoutput.NewUnionCoordinateThreshold(disc, imageoutput.NewPercentileCoordinateThreshold(1, 99)),
+			),
+		),
+		Equals,
+		false,
+	)
+}
@@ -0,0 +1,37 @@
+package transformer
+
+import (
+	"github.com/chadius/creatingsymmetry/entities/imageoutput"
+)
+
+// CoordinateMap holds every sample of the output image after it was scaled to the viewport,
+//   transformed by the formula and marked by the coordinate threshold.
+//   None of those stages use the source image, so one map can be colored with many source images and eyedroppers.
+//   Each coloring works on its own copy of the samples, so several goroutines can color a map at the same time.
+type CoordinateMap struct {
+	plan       *samplePlan
+	collection *imageoutput.CoordinateCollection
+}
+
+// Width returns the width of the output image in pixels.
+func (c *CoordinateMap) Width() int {
+	return c.plan.width
+}
+
+// Height returns the height of the output image in pixels.
+func (c *CoordinateMap) Height() int {
+	return c.plan.height
+}
+
+// coordinatesToColor copies every sample for one coloring.
+//   Eyedroppers store where each coordinate lands in the source image, so they must not share the map's samples.
+func (c *CoordinateMap) coordinatesToColor() *imageoutput.CoordinateCollection {
+	samples := *c.collection.Coordinates()
+	copies := make([]imageoutput.MappedCoordinate, len(samples))
+	coordinates := make([]*imageoutput.MappedCoordinate, len(samples))
+	for sampleIndex, sample := range samples {
+		copies[sampleIndex] = *sample
+		coordinates[sampleIndex] = &copies[sampleIndex]
+	}
+	return imageoutput.CoordinateCollectionBuilder().WithCoordinates(&coordinates).Build()
+}
@@ -0,0 +1,158 @@
+package transformer_test
+
+import (
+	"context"
+	"github.com/chadius/creatingsymmetry/entities/command"
+	"github.com/chadius/creatingsymmetry/entities/formula"
+	"github.com/chadius/creatingsymmetry/entities/imageoutput"
+	"github.com/chadius/creatingsymmetry/entities/imageoutput/imageoutputfakes"
+	transformerEntity "github.com/chadius/creatingsymmetry/entities/transformer"
+	. "gopkg.in/check.v1"
+	"image"
+	"image/color"
+	"sync"
+)
+
+type CoordinateMapTests struct {
+	settings *transformerEntity.Settings
+}
+
+var _ = Suite(&CoordinateMapTests{})
+
+func (suite *CoordinateMapTests) SetUpTest(checker *C) {
+	rosetteFormula, _ := formula.NewBuilder().
+		Rosette().
+		AddTerm(
+			formula.NewTermBuilder().
+				Multiplier(complex(1, -0.5)).
+				PowerN(3).
+				PowerM(1).
+				Build(),
+		).
+		Build()
+
+	suite.settings = &transformerEntity.Settings{
+		PatternViewportXMin: -1,
+		PatternViewportXMax: 1,
+		PatternViewportYMin: -1,
+		PatternViewportYMax: 1,
+		Formula:             &command.CreateSymmetryPattern{Formula: rosetteFormula},
+		CoordinateThreshold: imageoutput.NewDiscCoordinateThreshold(0, 0, 1.5),
+		OutputWidth:         13,
+		OutputHeight:        9,
+		Supersampling:       2,
+		FilteredBackground:  imageoutput.NewSolidColorBackground(color.NRGBA{R: 10, G: 200, B: 30, A: 255}),
+	}
+}
+
+// coordinateMapSourceImage returns a small image whose pixels depend on the seed, so different images color differently.
+func coordinateMapSourceImage(seed int) *image.NRGBA {
+	sourceImage := image.NewNRGBA(image.Rect(0, 0, 3, 3))
+	for index := range sourceImage.Pix {
+		sourceImage.Pix[index] = uint8(index*seed*29 + 255*((index+1)%4/3))
+	}
+	return sourceImage
+}
+
+func (suite *CoordinateMapTests) withEyedropper(eyedropper imageoutput.Eyedropper) *transformerEntity.Settings {
+	settings := *suite.settings
+	settings.Eyedropper = eyedropper
+	return &settings
+}
+
+func (suite *CoordinateMapTests) TestColoringAMapMatchesTransformingEachImage(checker *C) {
+	transformer := transformerEntity.FormulaTransformer{}
+	coordinateMap, err := transformer.BuildCoordinateMapWithContext(context.Background(), suite.settings)
+	checker.Assert(err, IsNil)
+	checker.Assert(coordinateMap.Width(), Equals, 13)
+	checker.Assert(coordinateMap.Height(), Equals, 9)
+
+	eyedroppers := []imageoutput.Eyedropper{
+		imageoutput.EyedropperBuilder().WithRightSide(3).WithBottomSide(3).WithImage(coordinateMapSourceImage(1)).Build(),
+		imageoutput.EyedropperBuilder().WithRightSide(3).WithBottomSide(3).WithImage(coordinateMapSourceImage(2)).Build(),
+		imageoutput.PolarEyedropperBuilder().WithCenter(1.5, 1.5).WithRadius(1.5, 1.5).WithImage(coordinateMapSourceImage(3)).Build(),
+		imageoutput.DomainColoringEyedropperBuilder().Build(),
+	}
+	for index, eyedropper := range eyedroppers {
+		expectedImage := transformer.Transform(suite.withEyedropper(eyedropper))
+		coloredImage, err := transformer.ColorCoordinateMapWithContext(context.Background(), coordinateMap, suite.withEyedropper(eyedropper))
+		checker.Assert(err, IsNil)
+		checker.Assert(coloredImage.Pix, DeepEquals, expectedImage.Pix, Commentf("eyedropper %d", index))
+	}
+}
+
+func (suite *CoordinateMapTests) TestSeveralGoroutinesCanColorTheSameMap(checker *C) {
+	transformer := transformerEntity.FormulaTransformer{}
+	coordinateMap, err := transformer.BuildCoordinateMapWithContext(context.Background(), suite.settings)
+	checker.Assert(err, IsNil)
+
+	eyedropperCount := 6
+	expectedImages := make([]*image.NRGBA, eyedropperCount)
+	coloredImages := make([]*image.NRGBA, eyedropperCount)
+	settings := make([]*transformerEntity.Settings, eyedropperCount)
+	for index := range settings {
+		settings[index] = suite.withEyedropper(
+			imageoutput.EyedropperBuilder().WithRightSide(3).WithBottomSide(3).WithImage(coordinateMapSourceImage(index + 1)).Build(),
+		)
+		expectedImages[index] = transformer.Transform(settings[index])
+	}
+
+	var waitGroup sync.WaitGroup
+	for index := range settings {
+		waitGroup.Add(1)
+		go func(index int) {
+			defer waitGroup.Done()
+			coloredImages[index], _ = transformer.ColorCoordinateMapWithContext(context.Background(), coordinateMap, settings[index])
+		}(index)
+	}
+	waitGroup.Wait()
+
+	for index := range settings {
+		checker.Assert(coloredImages[index].Pix, DeepEquals, expectedImages[index].Pix, Commentf("eyedropper %d", index))
+	}
+}
+
+func (suite *CoordinateMapTests) TestBuildingSkipsTheEyedropperAndColoringSkipsTheFormula(checker *C) {
+	transformer := transformerEntity.FormulaTransformer{}
+	mockEyedropper := imageoutputfakes.FakeEyedropper{}
+	mockEyedropper.ConvertCoordinatesToColorsReturns(&[]color.Color{})
+	settings := suite.withEyedropper(&mockEyedropper)
+
+	buildStages := map[transformerEntity.Stage]bool{}
+	settings.Progress = func(event transformerEntity.ProgressEvent) {
+		buildStages[event.Stage] = true
+	}
+	coordinateMap, err := transformer.BuildCoordinateMapWithContext(context.Background(), settings)
+	checker.Assert(err, IsNil)
+	checker.Assert(mockEyedropper.ConvertCoordinatesToColorsCallCount(), Equals, 0)
+	checker.Assert(buildStages, DeepEquals, map[transformerEntity.Stage]bool{
+		transformerEntity.StageViewportScaling:   true,
+		transformerEntity.StageFormulaEvaluation: true,
+		transformerEntity.StageThresholding:      true,
+	})
+
+	colorStages := map[transformerEntity.Stage]bool{}
+	settings.Progress = func(event transformerEntity.ProgressEvent) {
+		colorStages[event.Stage] = true
+	}
+	_, err = transformer.ColorCoordinateMapWithContext(context.Background(), coordinateMap, settings)
+	checker.Assert(err, IsNil)
+	checker.Assert(mockEyedropper.ConvertCoordinatesToColorsCallCount(), Equals, 1)
+	checker.Assert(colorStages, DeepEquals, map[transformerEntity.Stage]bool{
+		transformerEntity.StageEyedropping: true,
+	})
+}
+
+func (suite *CoordinateMapTests) TestCancelledContextStopsColoring(checker *C) {
+	transformer := transformerEntity.FormulaTransformer{}
+	coordinateMap, err := transformer.BuildCoordinateMapWithContext(context.Background(), suite.settings)
+	checker.Assert(err, IsNil)
+
+	mockEyedropper := imageoutputfakes.FakeEyedropper{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	coloredImage, err := transformer.ColorCoordinateMapWithContext(ctx, coordinateMap, suite.withEyedropper(&mockEyedropper))
+	checker.Assert(err, Equals, context.Canceled)
+	checker.Assert(coloredImage, IsNil)
+	checker.Assert(mockEyedropper.ConvertCoordinatesToColorsCallCount(), Equals, 0)
+}
@@ -1,44 +1,385 @@
 package transformer
 
 import (
+	"context"
+	"errors"
 	"github.com/chadius/creatingsymmetry/entities/formula"
 	"github.com/chadius/creatingsymmetry/entities/imageoutput"
 	"github.com/chadius/creatingsymmetry/entities/mathutility"
 	"image"
+	"image/color"
+	"math"
+	"runtime"
+	"sync"
 )
 
+// bandsPerWorker splits the rows into more bands than workers, so workers that finish early can take more bands.
+const bandsPerWorker = 4
+
 // FormulaTransformer turns one image stream into another using a oldformula
 type FormulaTransformer struct {
 }
 
 // Transform converts the input image using the given oldformula.
+//   Rows are split into bands and rendered concurrently, the result is the same no matter how many workers are used.
 func (f *FormulaTransformer) Transform(settings *Settings) *image.NRGBA {
-	coordinateCollection := f.createCollectionBasedOnOutputImageSize(settings)
-	f.scaleCoordinatesToViewport(settings, coordinateCollection)
-	f.transformCoordinatesUsingFormula(settings, coordinateCollection)
+	outputImage, _ := f.TransformWithContext(context.Background(), settings)
+	return outputImage
+}
+
+// TransformWithContext converts the input image using the given formula, like Transform.
+//   It stops as soon as the context is cancelled and returns the context's error.
+//   If settings.Progress is set, it is told about the progress of each stage.
+func (f *FormulaTransformer) TransformWithContext(ctx context.Context, settings *Settings) (*image.NRGBA, error) {
+	coordinateMap, err := f.BuildCoordinateMapWithContext(ctx, settings)
+	if err != nil {
+		return nil, err
+	}
+	return f.ColorCoordinateMapWithContext(ctx, coordinateMap, settings)
+}
+
+// BuildCoordinateMapWithContext scales every sample to the viewport, applies the formula and then the coordinate threshold.
+//   The Eyedropper, InputImage and background settings are not used, so the map can be colored many times.
+//   Returns the context's error if it was cancelled first.
+func (f *FormulaTransformer) BuildCoordinateMapWithContext(ctx context.Context, settings *Settings) (*CoordinateMap, error) {
+	progressLock := &sync.Mutex{}
+	plan, err := f.planAndTransformSamples(ctx, settings, progressLock)
+	if err != nil {
+		return nil, err
+	}
+	coordinateCollection := plan.collection()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	thresholdProgress := newStageProgress(progressLock, settings.Progress, StageThresholding, 1)
+	thresholdProgress.begin()
 	settings.CoordinateThreshold.FilterAndMarkMappedCoordinateCollection(coordinateCollection)
-	settings.Eyedropper.ConvertCoordinatesToColors(coordinateCollection)
-	return f.outputToImage(settings, coordinateCollection)
+	thresholdProgress.finish()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return &CoordinateMap{
+		plan:       plan,
+		collection: coordinateCollection,
+	}, nil
+}
+
+// ColorCoordinateMapWithContext colors the map with the Eyedropper, fills the backgrounds and draws the image.
+//   Only the Eyedropper, background and Progress settings are used, the map already has everything else.
+//   The map can be colored again with another eyedropper or source image.
+//   Several goroutines can color the same map at the same time, see CoordinateMap.
+//   Returns the context's error if it was cancelled first.
+func (f *FormulaTransformer) ColorCoordinateMapWithContext(ctx context.Context, coordinateMap *CoordinateMap, settings *Settings) (*image.NRGBA, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	coordinateCollection := coordinateMap.coordinatesToColor()
+	eyedropperProgress := newStageProgress(&sync.Mutex{}, settings.Progress, StageEyedropping, 1)
+	eyedropperProgress.begin()
+	colorData := settings.Eyedropper.ConvertCoordinatesToColors(coordinateCollection)
+	imageoutput.FillBackground(coordinateCollection, colorData, settings.FilteredBackground, settings.UnmappedBackground)
+	eyedropperProgress.finish()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return f.outputToImage(coordinateMap.plan, colorData), nil
+}
+
+// TransformCoordinatesWithContext scales every sample to the viewport and applies the formula,
+//   without thresholding or eyedropping. The CoordinateThreshold, Eyedropper and InputImage settings are not used.
+//   Returns the context's error if it was cancelled first.
+func (f *FormulaTransformer) TransformCoordinatesWithContext(ctx context.Context, settings *Settings) (*imageoutput.CoordinateCollection, error) {
+	plan, err := f.planAndTransformSamples(ctx, settings, &sync.Mutex{})
+	if err != nil {
+		return nil, err
+	}
+	return plan.collection(), nil
 }
 
-func (f *FormulaTransformer) createCollectionBasedOnOutputImageSize(settings *Settings) *imageoutput.CoordinateCollection {
-	coordinates := []*imageoutput.MappedCoordinate{}
-	for inputImageY := 0; inputImageY < settings.OutputHeight; inputImageY++ {
-		for inputImageX := 0; inputImageX < settings.OutputWidth; inputImageX++ {
-			coordinates = append(
-				coordinates,
-				imageoutput.NewMappedCoordinateUsingInputImageCoordinates(inputImageX, inputImageY),
-			)
+// TransformRowsWithContext renders the image like TransformWithContext, a band of rows at a time,
+//   and gives each band to writeRows from top to bottom. Each band's bounds start at its first row.
+//   Only one band of samples is kept in memory, so very large images can be written while they are rendered.
+//   Bands hold StreamingRows rows, or about streamingSamplesPerBand samples and at least one row for each worker.
+//   If the eyedropper stretches across the range of the coordinates, a first pass measures the range
+//   and a second pass applies the formula again to color each band. Otherwise the formula is only applied once.
+//   Progress is reported as each band finishes, so the stages overlap.
+//   Returns an error before rendering if a setting needs every coordinate at once, see CanStream.
+//   Stops at the first error from writeRows, or when the context is cancelled.
+func (f *FormulaTransformer) TransformRowsWithContext(ctx context.Context, settings *Settings, writeRows func(rows *image.NRGBA) error) error {
+	if err := f.CanStream(settings); err != nil {
+		return err
+	}
+	eyedropper := settings.Eyedropper.(imageoutput.StreamingEyedropper)
+
+	passCount := 1
+	if eyedropper.NeedsStatistics() {
+		passCount = 2
+	}
+	rowsPerBand := f.streamingRowsPerBand(settings)
+	bandCount := (settings.OutputHeight + rowsPerBand - 1) / rowsPerBand
+	progressLock := &sync.Mutex{}
+	progress := &bandProgress{
+		scaling:     newStageProgress(progressLock, settings.Progress, StageViewportScaling, settings.OutputHeight*passCount),
+		formula:     newStageProgress(progressLock, settings.Progress, StageFormulaEvaluation, settings.OutputHeight*passCount),
+		thresholds:  newStageProgress(progressLock, settings.Progress, StageThresholding, bandCount*passCount),
+		eyedropping: newStageProgress(progressLock, settings.Progress, StageEyedropping, bandCount),
+	}
+
+	var colorEyedropper imageoutput.Eyedropper = eyedropper
+	if eyedropper.NeedsStatistics() {
+		statistics := imageoutput.NewTransformedStatistics()
+		err := f.forEachBand(ctx, settings, rowsPerBand, progress, func(plan *samplePlan) error {
+			statistics.Add(plan.collection())
+			return nil
+		})
+		if err != nil {
+			return err
 		}
+		colorEyedropper = eyedropper.WithStatistics(statistics)
 	}
 
-	return imageoutput.CoordinateCollectionBuilder().WithCoordinates(&coordinates).Build()
+	err := f.forEachBand(ctx, settings, rowsPerBand, progress, func(plan *samplePlan) error {
+		progress.eyedropping.begin()
+		collection := plan.collection()
+		colorData := colorEyedropper.ConvertCoordinatesToColors(collection)
+		imageoutput.FillBackground(collection, colorData, settings.FilteredBackground, settings.UnmappedBackground)
+		progress.eyedropping.add(1)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return writeRows(f.outputToImage(plan, colorData))
+	})
+	if err != nil {
+		return err
+	}
+	progress.finish()
+	return nil
 }
 
-func (f *FormulaTransformer) scaleCoordinatesToViewport(settings *Settings, coordinateCollection *imageoutput.CoordinateCollection) {
-	for _, coordinate := range *coordinateCollection.Coordinates() {
+// CanStream returns an error explaining why the settings cannot be rendered a few rows at a time, or nil if they can.
+//   Adaptive supersampling, auto thresholds, percentile eyedropper ranges and histogram equalization
+//   all need every coordinate at once.
+func (f *FormulaTransformer) CanStream(settings *Settings) error {
+	if f.usesAdaptiveSupersampling(settings) {
+		return errors.New("cannot stream: adaptive supersampling compares pixels across the whole image")
+	}
+	if !imageoutput.CoordinateThresholdCanStream(settings.CoordinateThreshold) {
+		return errors.New("cannot stream: auto thresholds use percentiles of every coordinate")
+	}
+	eyedropper, canColorBands := settings.Eyedropper.(imageoutput.StreamingEyedropper)
+	if !canColorBands || !eyedropper.CanStream() {
+		return errors.New("cannot stream: the eyedropper range uses percentiles or histogram equalization of every coordinate")
+	}
+	return nil
+}
+
+// streamingSamplesPerBand is about how many samples a band holds while streaming.
+const streamingSamplesPerBand = 1 << 19
+
+// streamingRowsPerBand uses the StreamingRows setting if there is one.
+//   Otherwise it fits about streamingSamplesPerBand samples in each band, but gives every worker at least one row.
+func (f *FormulaTransformer) streamingRowsPerBand(settings *Settings) int {
+	rowsPerBand := settings.StreamingRows
+	if rowsPerBand <= 0 {
+		samplesPerPixel := 1
+		if settings.Supersampling > 1 {
+			samplesPerPixel = settings.Supersampling * settings.Supersampling
+		}
+		rowsPerBand = streamingSamplesPerBand / (settings.OutputWidth * samplesPerPixel)
+		if rowsPerBand < f.workerCount(settings) {
+			rowsPerBand = f.workerCount(settings)
+		}
+	}
+
+	if rowsPerBand > settings.OutputHeight {
+		rowsPerBand = settings.OutputHeight
+	}
+	return rowsPerBand
+}
+
+// bandProgress reports the progress of each stage while streaming.
+type bandProgress struct {
+	scaling     *stageProgress
+	formula     *stageProgress
+	thresholds  *stageProgress
+	eyedropping *stageProgress
+}
+
+// finish marks every stage as complete.
+func (b *bandProgress) finish() {
+	b.scaling.finish()
+	b.formula.finish()
+	b.thresholds.finish()
+	b.eyedropping.finish()
+}
+
+// forEachBand samples each band of rows from top to bottom, applies the formula and the threshold,
+//   then calls useBand. Stops at the first error from useBand, or when the context is cancelled.
+func (f *FormulaTransformer) forEachBand(ctx context.Context, settings *Settings, rowsPerBand int, progress *bandProgress, useBand func(plan *samplePlan) error) error {
+	for firstRow := 0; firstRow < settings.OutputHeight; firstRow += rowsPerBand {
+		rowCount := rowsPerBand
+		if firstRow+rowCount > settings.OutputHeight {
+			rowCount = settings.OutputHeight - firstRow
+		}
+
+		plan := f.createFirstSamplePlan(settings, firstRow, rowCount)
+		if err := f.transformSamples(ctx, settings, plan, progress.scaling, progress.formula); err != nil {
+			return err
+		}
+
+		progress.thresholds.begin()
+		settings.CoordinateThreshold.FilterAndMarkMappedCoordinateCollection(plan.collection())
+		progress.thresholds.add(1)
+
+		if err := useBand(plan); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+// planAndTransformSamples chooses the samples in each pixel, then scales them to the viewport and applies the formula.
+//   Adaptive supersampling transforms one sample per pixel first, then adds more samples where they are needed.
+func (f *FormulaTransformer) planAndTransformSamples(ctx context.Context, settings *Settings, progressLock *sync.Mutex) (*samplePlan, error) {
+	passCount := 1
+	if f.usesAdaptiveSupersampling(settings) {
+		passCount = 2
+	}
+	scalingProgress := newStageProgress(progressLock, settings.Progress, StageViewportScaling, settings.OutputHeight*passCount)
+	formulaProgress := newStageProgress(progressLock, settings.Progress, StageFormulaEvaluation, settings.OutputHeight*passCount)
+
+	plan := f.createFirstSamplePlan(settings, 0, settings.OutputHeight)
+	err := f.transformSamples(ctx, settings, plan, scalingProgress, formulaProgress)
+	if err != nil {
+		return nil, err
+	}
+
+	if !f.usesAdaptiveSupersampling(settings) {
+		return plan, nil
+	}
+	pixelIsMarked := markPixelsThatNeedMoreSamples(plan, f.adaptiveThreshold(settings))
+	refinedPlan := newSamplePlan(settings.OutputWidth, 0, settings.OutputHeight, func(pixelIndex int) int {
+		if pixelIsMarked[pixelIndex] {
+			return settings.Supersampling
+		}
+		return 0
+	})
+	err = f.transformSamples(ctx, settings, refinedPlan, scalingProgress, formulaProgress)
+	if err != nil {
+		return nil, err
+	}
+	return plan.replacePixels(refinedPlan, pixelIsMarked), nil
+}
+
+// createFirstSamplePlan takes one sample in the center of each pixel, or a grid of samples when supersampling.
+//   Adaptive supersampling starts with one sample per pixel.
+//   The plan covers rowCount rows, starting at firstRow.
+func (f *FormulaTransformer) createFirstSamplePlan(settings *Settings, firstRow, rowCount int) *samplePlan {
+	samplesPerSide := 1
+	if settings.Supersampling > 1 && !settings.AdaptiveSupersampling {
+		samplesPerSide = settings.Supersampling
+	}
+	return newSamplePlan(settings.OutputWidth, firstRow, rowCount, func(pixelIndex int) int {
+		return samplesPerSide
+	})
+}
+
+func (f *FormulaTransformer) usesAdaptiveSupersampling(settings *Settings) bool {
+	return settings.AdaptiveSupersampling && settings.Supersampling > 1
+}
+
+func (f *FormulaTransformer) adaptiveThreshold(settings *Settings) float64 {
+	if settings.AdaptiveThreshold > 0 {
+		return settings.AdaptiveThreshold
+	}
+	return DefaultAdaptiveThreshold
+}
+
+// transformSamples scales every sample in the plan to the viewport and then applies the formula.
+func (f *FormulaTransformer) transformSamples(ctx context.Context, settings *Settings, plan *samplePlan, scalingProgress, formulaProgress *stageProgress) error {
+	err := f.forEachRow(ctx, settings, plan, scalingProgress, func(row []*imageoutput.MappedCoordinate) {
+		f.scaleCoordinatesToViewport(settings, row)
+	})
+	if err != nil {
+		return err
+	}
+
+	return f.forEachRow(ctx, settings, plan, formulaProgress, func(row []*imageoutput.MappedCoordinate) {
+		f.transformCoordinatesUsingFormula(settings, row)
+	})
+}
+
+// workerCount returns how many goroutines should render the image.
+func (f *FormulaTransformer) workerCount(settings *Settings) int {
+	if settings.WorkerCount > 0 {
+		return settings.WorkerCount
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// forEachRow calls renderRow on every row of coordinates, then reports the progress.
+//   Rows are grouped into bands and the bands are shared between a pool of workers.
+//   Each coordinate belongs to exactly one row, so renderRow can change the coordinates without locking.
+//   Returns the context's error if it was cancelled before every row was rendered.
+func (f *FormulaTransformer) forEachRow(ctx context.Context, settings *Settings, plan *samplePlan, progress *stageProgress, renderRow func(row []*imageoutput.MappedCoordinate)) error {
+	progress.begin()
+	renderBand := func(firstRow, lastRow int) {
+		for rowIndex := firstRow; rowIndex < lastRow; rowIndex++ {
+			if ctx.Err() != nil {
+				return
+			}
+			renderRow(plan.row(rowIndex))
+			progress.add(1)
+		}
+	}
+
+	workerCount := f.workerCount(settings)
+	if workerCount == 1 || plan.height <= 1 {
+		renderBand(0, plan.height)
+		return ctx.Err()
+	}
+
+	rowsPerBand := plan.height / (workerCount * bandsPerWorker)
+	if rowsPerBand < 1 {
+		rowsPerBand = 1
+	}
+
+	bandFirstRows := make(chan int)
+	var workers sync.WaitGroup
+	for worker := 0; worker < workerCount; worker++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for firstRow := range bandFirstRows {
+				lastRow := firstRow + rowsPerBand
+				if lastRow > plan.height {
+					lastRow = plan.height
+				}
+				renderBand(firstRow, lastRow)
+			}
+		}()
+	}
+
+sendBands:
+	for firstRow := 0; firstRow < plan.height; firstRow += rowsPerBand {
+		select {
+		case bandFirstRows <- firstRow:
+		case <-ctx.Done():
+			break sendBands
+		}
+	}
+	close(bandFirstRows)
+	workers.Wait()
+	return ctx.Err()
+}
+
+func (f *FormulaTransformer) scaleCoordinatesToViewport(settings *Settings, coordinates []*imageoutput.MappedCoordinate) {
+	for _, coordinate := range coordinates {
 		patternViewportX := mathutility.ScaleValueBetweenTwoRanges(
-			float64(coordinate.InputImageX()),
+			coordinate.InputImageSampleX(),
 			float64(0),
 			float64(settings.OutputWidth),
 			settings.PatternViewportXMin,
@@ -46,7 +387,7 @@ func (f *FormulaTransformer) scaleCoordinatesToViewport(settings *Settings, coor
 		)
 
 		patternViewportY := mathutility.ScaleValueBetweenTwoRanges(
-			float64(coordinate.InputImageY()),
+			coordinate.InputImageSampleY(),
 			float64(0),
 			float64(settings.OutputHeight),
 			settings.PatternViewportYMin,
@@ -56,32 +397,103 @@ func (f *FormulaTransformer) scaleCoordinatesToViewport(settings *Settings, coor
 	}
 }
 
-func (f *FormulaTransformer) transformCoordinatesUsingFormula(settings *Settings, coordinateCollection *imageoutput.CoordinateCollection) {
+func (f *FormulaTransformer) transformCoordinatesUsingFormula(settings *Settings, coordinates []*imageoutput.MappedCoordinate) {
 	if settings.Formula.Formula != nil {
-		f.transformCoordinatesForArbitraryFormula(settings.Formula.Formula, coordinateCollection)
+		f.transformCoordinatesForArbitraryFormula(settings.Formula.Formula, coordinates)
 	}
 }
 
-func (f *FormulaTransformer) transformCoordinatesForArbitraryFormula(arbitraryFormula formula.Arbitrary, coordinateCollection *imageoutput.CoordinateCollection) {
-	for _, coordinate := range *coordinateCollection.Coordinates() {
-		complexCoordinate := complex(coordinate.PatternViewportX(), coordinate.PatternViewportY())
-		transformedPoint := arbitraryFormula.Calculate(complexCoordinate)
-		coordinate.UpdateTransformedCoordinates(real(transformedPoint), imag(transformedPoint))
+// transformCoordinatesForArbitraryFormula applies the formula to each coordinate.
+//   Formulas that can calculate rows are given each run of evenly spaced coordinates at once.
+func (f *FormulaTransformer) transformCoordinatesForArbitraryFormula(arbitraryFormula formula.Arbitrary, coordinates []*imageoutput.MappedCoordinate) {
+	rowCalculator, canCalculateRows := arbitraryFormula.(formula.RowCalculator)
+	if !canCalculateRows {
+		for _, coordinate := range coordinates {
+			complexCoordinate := complex(coordinate.PatternViewportX(), coordinate.PatternViewportY())
+			transformedPoint := arbitraryFormula.Calculate(complexCoordinate)
+			coordinate.UpdateTransformedCoordinates(real(transformedPoint), imag(transformedPoint))
+		}
+		return
+	}
+
+	results := []complex128{}
+	for _, run := range evenlySpacedRuns(coordinates) {
+		first := run[0]
+		last := run[len(run)-1]
+		start := complex(first.PatternViewportX(), first.PatternViewportY())
+		step := complex(0, 0)
+		if len(run) > 1 {
+			step = complex((last.PatternViewportX()-first.PatternViewportX())/float64(len(run)-1), 0)
+		}
+
+		if cap(results) < len(run) {
+			results = make([]complex128, len(run))
+		}
+		results = results[:len(run)]
+		rowCalculator.CalculateRow(start, step, results)
+		for index, coordinate := range run {
+			coordinate.UpdateTransformedCoordinates(real(results[index]), imag(results[index]))
+		}
 	}
 }
 
-func (f *FormulaTransformer) outputToImage(settings *Settings, coordinateCollection *imageoutput.CoordinateCollection) *image.NRGBA {
-	outputImage := image.NewNRGBA(image.Rect(0, 0, settings.OutputWidth, settings.OutputHeight))
+// evenlySpacedRunTolerance is how far apart two steps between coordinates can be, as a fraction of the step,
+//   and still count as the same step. Scaling to the viewport rounds each coordinate a little differently.
+const evenlySpacedRunTolerance = 1e-9
 
-	colorData := settings.Eyedropper.ConvertCoordinatesToColors(coordinateCollection)
+// evenlySpacedRuns splits the coordinates into runs that share a viewport Y coordinate
+//   and move along X by the same step.
+//   Supersampled pixels store their samples next to each other, so samples at the same Y are gathered first.
+//   Every coordinate is in exactly one run.
+func evenlySpacedRuns(coordinates []*imageoutput.MappedCoordinate) [][]*imageoutput.MappedCoordinate {
+	coordinatesByY := map[float64][]*imageoutput.MappedCoordinate{}
+	orderOfY := []float64{}
+	for _, coordinate := range coordinates {
+		y := coordinate.PatternViewportY()
+		if _, alreadySeen := coordinatesByY[y]; !alreadySeen {
+			orderOfY = append(orderOfY, y)
+		}
+		coordinatesByY[y] = append(coordinatesByY[y], coordinate)
+	}
+
+	runs := [][]*imageoutput.MappedCoordinate{}
+	for _, y := range orderOfY {
+		coordinatesAtY := coordinatesByY[y]
+		runStart := 0
+		for runStart < len(coordinatesAtY) {
+			runEnd := runStart + 1
+			if runEnd < len(coordinatesAtY) {
+				step := coordinatesAtY[runEnd].PatternViewportX() - coordinatesAtY[runStart].PatternViewportX()
+				for runEnd < len(coordinatesAtY) && math.Abs(coordinatesAtY[runEnd].PatternViewportX()-coordinatesAtY[runEnd-1].PatternViewportX()-step) <= evenlySpacedRunTolerance*math.Abs(step) {
+					runEnd++
+				}
+			}
+			runs = append(runs, coordinatesAtY[runStart:runEnd])
+			runStart = runEnd
+		}
+	}
+	return runs
+}
+
+// outputToImage averages the colors of each pixel's samples and draws them.
+//   The image covers the plan's rows, so its bounds start at the plan's first row.
+//   Missing colors are treated as transparent.
+func (f *FormulaTransformer) outputToImage(plan *samplePlan, colorData *[]color.Color) *image.NRGBA {
+	outputImage := image.NewNRGBA(image.Rect(0, plan.firstRow, plan.width, plan.firstRow+plan.height))
+
+	for pixelIndex := 0; pixelIndex < plan.width*plan.height; pixelIndex++ {
+		firstSample, lastSample := plan.pixelSampleRange(pixelIndex)
+		if lastSample > len(*colorData) {
+			lastSample = len(*colorData)
+		}
+		if firstSample >= lastSample {
+			continue
+		}
 
-	for index, colorToAdd := range *colorData {
-		destinationPixelX := index % settings.OutputWidth
-		destinationPixelY := index / settings.OutputWidth
-		outputImage.Set(
-			destinationPixelX,
-			destinationPixelY,
-			colorToAdd,
+		outputImage.SetNRGBA(
+			pixelIndex%plan.width,
+			plan.firstRow+pixelIndex/plan.width,
+			averageColorsInLinearLight((*colorData)[firstSample:lastSample]),
 		)
 	}
 	return outputImage
@@ -1,9 +1,11 @@
 package transformer_test
 
 import (
+	"context"
 	"github.com/chadius/creatingsymmetry/creatingsymmetryfakes"
 	"github.com/chadius/creatingsymmetry/entities/command"
 	"github.com/chadius/creatingsymmetry/entities/formula"
+	"github.com/chadius/creatingsymmetry/entities/imageoutput"
 	"github.com/chadius/creatingsymmetry/entities/imageoutput/imageoutputfakes"
 	transformerEntity "github.com/chadius/creatingsymmetry/entities/transformer"
 	. "gopkg.in/check.v1"
@@ -79,7 +81,7 @@ func (suite *FormulaTests) SetUpTest(checker *C) {
 
 	suite.commandShouldBeAFormula = &command.CreateSymmetryPattern{
 		PatternViewport:     command.ComplexNumberCorners{},
-		CoordinateThreshold: command.ComplexNumberCorners{},
+		CoordinateThreshold: command.CoordinateThresholdSettings{},
 		Eyedropper:          nil,
 		Formula:             rosetteFormula,
 	}
@@ -130,3 +132,160 @@ func (suite *FormulaTests) TestTransformerOutputsToImageOfGivenSize(checker *C)
 	checker.Assert(outputImage.Bounds().Max.X, Equals, 3)
 	checker.Assert(outputImage.Bounds().Max.Y, Equals, 1)
 }
+
+func (suite *FormulaTests) TestTransformCoordinatesSkipsThresholdAndEyedropper(checker *C) {
+	mockCoordinateThreshold := imageoutputfakes.FakeCoordinateThreshold{}
+	mockEyedropper := imageoutputfakes.FakeEyedropper{}
+	transformer := transformerEntity.FormulaTransformer{}
+
+	collection, err := transformer.TransformCoordinatesWithContext(context.Background(), &transformerEntity.Settings{
+		PatternViewportXMin: 0,
+		PatternViewportXMax: 1,
+		PatternViewportYMin: 0,
+		PatternViewportYMax: 1,
+		Formula:             suite.commandShouldBeAFormula,
+		CoordinateThreshold: &mockCoordinateThreshold,
+		Eyedropper:          &mockEyedropper,
+		OutputWidth:         3,
+		OutputHeight:        2,
+	})
+
+	checker.Assert(err, IsNil)
+	checker.Assert(*collection.Coordinates(), HasLen, 6)
+	checker.Assert((*collection.Coordinates())[0].CanBeCompared(), Equals, true)
+	checker.Assert(mockCoordinateThreshold.FilterAndMarkMappedCoordinateCollectionCallCount(), Equals, 0)
+	checker.Assert(mockEyedropper.ConvertCoordinatesToColorsCallCount(), Equals, 0)
+}
+
+func (suite *FormulaTests) renderWithWorkers(workerCount int) *image.NRGBA {
+	sourceImage := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	for pixelIndex := 0; pixelIndex < 4; pixelIndex++ {
+		sourceImage.Set(pixelIndex%2, pixelIndex/2, suite.sourceImage.At(pixelIndex%2, pixelIndex/2))
+	}
+
+	transformer := transformerEntity.FormulaTransformer{}
+	return transformer.Transform(&transformerEntity.Settings{
+		PatternViewportXMin: -1,
+		PatternViewportXMax: 1,
+		PatternViewportYMin: -1,
+		PatternViewportYMax: 1,
+		InputImage:          sourceImage,
+		Formula:             suite.commandShouldBeAFormula,
+		CoordinateThreshold: imageoutput.CoordinateFilterBuilder().
+			WithMinimumX(-0.5).
+			WithMaximumX(0.5).
+			WithMinimumY(-0.5).
+			WithMaximumY(0.5).
+			Build(),
+		Eyedropper: imageoutput.EyedropperBuilder().
+			WithLeftSide(0).
+			WithRightSide(2).
+			WithTopSide(0).
+			WithBottomSide(2).
+			WithImage(sourceImage).
+			Build(),
+		OutputWidth:  37,
+		OutputHeight: 23,
+		WorkerCount:  workerCount,
+	})
+}
+
+func (suite *FormulaTests) TestParallelRenderingMatchesSerialRendering(checker *C) {
+	serialImage := suite.renderWithWorkers(1)
+	opaquePixelCount := 0
+	for pixelIndex := 3; pixelIndex < len(serialImage.Pix); pixelIndex += 4 {
+		if serialImage.Pix[pixelIndex] != 0 {
+			opaquePixelCount++
+		}
+	}
+	checker.Assert(opaquePixelCount > 0, Equals, true)
+
+	for _, workerCount := range []int{0, 2, 3, 8, 64} {
+		parallelImage := suite.renderWithWorkers(workerCount)
+		checker.Assert(parallelImage.Pix, DeepEquals, serialImage.Pix, Commentf("%d workers", workerCount))
+	}
+}
+
+func (suite *FormulaTests) TestCancelledContextStopsBeforeEyedropping(checker *C) {
+	mockCoordinateThreshold := imageoutputfakes.FakeCoordinateThreshold{}
+	mockEyedropper := imageoutputfakes.FakeEyedropper{}
+	mockEyedropper.ConvertCoordinatesToColorsReturns(&[]color.Color{})
+	transformer := transformerEntity.FormulaTransformer{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	formulaRowsCompleted := 0
+	outputImage, err := transformer.TransformWithContext(ctx, &transformerEntity.Settings{
+		PatternViewportXMin: 0,
+		PatternViewportXMax: 1,
+		PatternViewportYMin: 0,
+		PatternViewportYMax: 1,
+		InputImage:          suite.sourceImage,
+		Formula:             suite.commandShouldBeAFormula,
+		CoordinateThreshold: &mockCoordinateThreshold,
+		Eyedropper:          &mockEyedropper,
+		OutputWidth:         10,
+		OutputHeight:        100,
+		WorkerCount:         1,
+		Progress: func(event transformerEntity.ProgressEvent) {
+			if event.Stage == transformerEntity.StageFormulaEvaluation && event.Completed > 0 {
+				formulaRowsCompleted = event.Completed
+				cancel()
+			}
+		},
+	})
+
+	checker.Assert(err, Equals, context.Canceled)
+	checker.Assert(outputImage, IsNil)
+	checker.Assert(formulaRowsCompleted, Equals, 1)
+	checker.Assert(mockCoordinateThreshold.FilterAndMarkMappedCoordinateCollectionCallCount(), Equals, 0)
+	checker.Assert(mockEyedropper.ConvertCoordinatesToColorsCallCount(), Equals, 0)
+}
+
+func (suite *FormulaTests) TestProgressIsReportedForEachStage(checker *C) {
+	mockCoordinateThreshold := imageoutputfakes.FakeCoordinateThreshold{}
+	mockEyedropper := imageoutputfakes.FakeEyedropper{}
+	mockEyedropper.ConvertCoordinatesToColorsReturns(&[]color.Color{})
+	transformer := transformerEntity.FormulaTransformer{}
+
+	events := []transformerEntity.ProgressEvent{}
+	_, err := transformer.TransformWithContext(context.Background(), &transformerEntity.Settings{
+		PatternViewportXMin: 0,
+		PatternViewportXMax: 1,
+		PatternViewportYMin: 0,
+		PatternViewportYMax: 1,
+		InputImage:          suite.sourceImage,
+		Formula:             suite.commandShouldBeAFormula,
+		CoordinateThreshold: &mockCoordinateThreshold,
+		Eyedropper:          &mockEyedropper,
+		OutputWidth:         5,
+		OutputHeight:        30,
+		WorkerCount:         4,
+		Progress: func(event transformerEntity.ProgressEvent) {
+			events = append(events, event)
+		},
+	})
+	checker.Assert(err, IsNil)
+
+	stagesInOrder := []transformerEntity.Stage{}
+	lastEventByStage := map[transformerEntity.Stage]transformerEntity.ProgressEvent{}
+	for _, event := range events {
+		previousEvent, seenBefore := lastEventByStage[event.Stage]
+		if !seenBefore {
+			stagesInOrder = append(stagesInOrder, event.Stage)
+			checker.Assert(event.Completed, Equals, 0)
+		} else {
+			checker.Assert(event.Completed > previousEvent.Completed, Equals, true)
+		}
+		lastEventByStage[event.Stage] = event
+	}
+
+	checker.Assert(stagesInOrder, DeepEquals, []transformerEntity.Stage{
+		transformerEntity.StageViewportScaling,
+		transformerEntity.StageFormulaEvaluation,
+		transformerEntity.StageThresholding,
+		transformerEntity.StageEyedropping,
+	})
+	checker.Assert(lastEventByStage[transformerEntity.StageFormulaEvaluation].Completed, Equals, 30)
+	checker.Assert(lastEventByStage[transformerEntity.StageFormulaEvaluation].Fraction(), Equals, 1.0)
+	checker.Assert(lastEventByStage[transformerEntity.StageEyedropping].Fraction(), Equals, 1.0)
+}
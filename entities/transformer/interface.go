@@ -1,6 +1,7 @@
 package transformer
 
 import (
+	"context"
 	"github.com/chadius/creatingsymmetry/entities/command"
 	"github.com/chadius/creatingsymmetry/entities/imageoutput"
 	"image"
@@ -9,9 +10,37 @@ import (
 // Transformer turns one image stream into another
 type Transformer interface {
 	Transform(setting *Settings) *image.NRGBA
+	TransformWithContext(ctx context.Context, setting *Settings) (*image.NRGBA, error)
+}
+
+// CoordinateTransformer applies the formula to every sample without coloring them,
+//   so callers can look at where the coordinates land.
+type CoordinateTransformer interface {
+	TransformCoordinatesWithContext(ctx context.Context, setting *Settings) (*imageoutput.CoordinateCollection, error)
+}
+
+// RowTransformer renders the image a band of rows at a time, so very large images do not have to fit in memory.
+//   CanStream explains why the settings cannot be rendered that way.
+type RowTransformer interface {
+	TransformRowsWithContext(ctx context.Context, setting *Settings, writeRows func(rows *image.NRGBA) error) error
+	CanStream(setting *Settings) error
+}
+
+// CoordinateMapTransformer applies the formula once into a CoordinateMap, then colors the map as many times as needed.
+type CoordinateMapTransformer interface {
+	BuildCoordinateMapWithContext(ctx context.Context, setting *Settings) (*CoordinateMap, error)
+	ColorCoordinateMapWithContext(ctx context.Context, coordinateMap *CoordinateMap, setting *Settings) (*image.NRGBA, error)
 }
 
 // Settings are required to transform a given image.
+//   WorkerCount is the number of goroutines used to render the image. Use 0 to use one per available CPU.
+//   Progress is optional, it receives progress events while the image is rendered.
+//   Supersampling takes a grid of Supersampling by Supersampling samples in each pixel and averages them. 0 or 1 takes one sample.
+//   AdaptiveSupersampling only takes the grid of samples where neighboring pixels transform to very different coordinates.
+//   AdaptiveThreshold is the fraction of the transformed range that counts as very different. 0 uses DefaultAdaptiveThreshold.
+//   FilteredBackground is drawn behind coordinates the threshold removed, and UnmappedBackground behind coordinates at Infinity or NaN.
+//   Both are optional, those coordinates are transparent without them.
+//   StreamingRows is how many rows TransformRowsWithContext renders at a time. 0 chooses a size that fits in a bounded amount of memory.
 type Settings struct {
 	PatternViewportXMin float64
 	PatternViewportXMax float64
@@ -23,4 +52,15 @@ type Settings struct {
 	Eyedropper          imageoutput.Eyedropper
 	OutputWidth         int
 	OutputHeight        int
+	WorkerCount         int
+	Progress            ProgressReporter
+
+	Supersampling         int
+	AdaptiveSupersampling bool
+	AdaptiveThreshold     float64
+
+	FilteredBackground imageoutput.Background
+	UnmappedBackground imageoutput.Background
+
+	StreamingRows int
 }
@@ -0,0 +1,88 @@
+package transformer
+
+import "sync"
+
+// Stage names a step in the rendering pipeline.
+type Stage string
+
+// Stages are reported in this order.
+const (
+	StageViewportScaling   Stage = "viewport scaling"
+	StageFormulaEvaluation Stage = "formula evaluation"
+	StageThresholding      Stage = "thresholding"
+	StageEyedropping       Stage = "eyedropping"
+	StageEncoding          Stage = "encoding"
+)
+
+// ProgressEvent describes how much of a stage has been completed.
+//   Each stage starts with a Completed value of 0 and ends when Completed equals Total.
+type ProgressEvent struct {
+	Stage     Stage
+	Completed int
+	Total     int
+}
+
+// Fraction returns how much of the stage is complete, from 0 to 1.
+func (p ProgressEvent) Fraction() float64 {
+	if p.Total <= 0 {
+		return 1
+	}
+	return float64(p.Completed) / float64(p.Total)
+}
+
+// ProgressReporter receives progress events while an image is rendered.
+//   It is never called by more than one goroutine at a time.
+type ProgressReporter func(event ProgressEvent)
+
+// stageProgress counts completed work for one stage and reports it.
+//   Workers share the same stageProgress, so every report is made while holding the lock.
+type stageProgress struct {
+	lock      *sync.Mutex
+	reporter  ProgressReporter
+	stage     Stage
+	started   bool
+	completed int
+	total     int
+}
+
+// newStageProgress creates a stageProgress that has not started yet.
+//   Every stage in a render shares the same lock, so the reporter is never called at the same time.
+func newStageProgress(lock *sync.Mutex, reporter ProgressReporter, stage Stage, total int) *stageProgress {
+	return &stageProgress{
+		lock:      lock,
+		reporter:  reporter,
+		stage:     stage,
+		started:   false,
+		completed: 0,
+		total:     total,
+	}
+}
+
+// begin reports the stage has started. Only the first call is reported.
+func (s *stageProgress) begin() {
+	if s.started {
+		return
+	}
+	s.started = true
+	s.add(0)
+}
+
+// add marks more work as complete and reports the new total.
+func (s *stageProgress) add(completed int) {
+	if s.reporter == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.completed += completed
+	s.reporter(ProgressEvent{
+		Stage:     s.stage,
+		Completed: s.completed,
+		Total:     s.total,
+	})
+}
+
+// finish marks all of the work as complete. Call it after every worker has stopped.
+func (s *stageProgress) finish() {
+	s.add(s.total - s.completed)
+}
@@ -0,0 +1,207 @@
+package transformer_test
+
+import (
+	"context"
+	"errors"
+	"github.com/chadius/creatingsymmetry/entities/command"
+	"github.com/chadius/creatingsymmetry/entities/formula"
+	"github.com/chadius/creatingsymmetry/entities/imageoutput"
+	"github.com/chadius/creatingsymmetry/entities/imageoutput/imageoutputfakes"
+	transformerEntity "github.com/chadius/creatingsymmetry/entities/transformer"
+	. "gopkg.in/check.v1"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+type StreamingTests struct {
+	sourceImage    *image.NRGBA
+	rosetteCommand *command.CreateSymmetryPattern
+}
+
+var _ = Suite(&StreamingTests{})
+
+func (suite *StreamingTests) SetUpTest(checker *C) {
+	suite.sourceImage = image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	suite.sourceImage.SetNRGBA(0, 0, color.NRGBA{R: 255, A: 255})
+	suite.sourceImage.SetNRGBA(1, 0, color.NRGBA{G: 255, A: 255})
+	suite.sourceImage.SetNRGBA(0, 1, color.NRGBA{B: 255, A: 255})
+	suite.sourceImage.SetNRGBA(1, 1, color.NRGBA{R: 255, G: 255, A: 255})
+
+	rosetteFormula, _ := formula.NewBuilder().
+		Rosette().
+		AddTerm(
+			formula.NewTermBuilder().
+				Multiplier(complex(1, 0.5)).
+				PowerN(2).
+				PowerM(1).
+				Build(),
+		).
+		Build()
+	suite.rosetteCommand = &command.CreateSymmetryPattern{
+		Formula: rosetteFormula,
+	}
+}
+
+func (suite *StreamingTests) settings(eyedropper imageoutput.Eyedropper, supersampling, streamingRows int) *transformerEntity.Settings {
+	return &transformerEntity.Settings{
+		PatternViewportXMin: -1,
+		PatternViewportXMax: 1.2,
+		PatternViewportYMin: -0.8,
+		PatternViewportYMax: 1,
+		Formula:             suite.rosetteCommand,
+		CoordinateThreshold: imageoutput.NewDifferenceCoordinateThreshold(
+			imageoutput.NewDiscCoordinateThreshold(0, 0, 2.5),
+			imageoutput.NewDiscCoordinateThreshold(0.3, 0, 0.2),
+		),
+		Eyedropper:         eyedropper,
+		OutputWidth:        23,
+		OutputHeight:       17,
+		WorkerCount:        2,
+		Supersampling:      supersampling,
+		FilteredBackground: imageoutput.NewSolidColorBackground(color.NRGBA{R: 20, G: 40, B: 60, A: 255}),
+		StreamingRows:      streamingRows,
+	}
+}
+
+// streamingEyedroppers returns eyedroppers that need the range of every coordinate, and one that does not.
+func (suite *StreamingTests) streamingEyedroppers() map[string]imageoutput.Eyedropper {
+	return map[string]imageoutput.Eyedropper{
+		"rectangular": imageoutput.EyedropperBuilder().
+			WithLeftSide(0).
+			WithRightSide(2).
+			WithTopSide(0).
+			WithBottomSide(2).
+			WithSampling(imageoutput.BilinearSampling).
+			WithImage(suite.sourceImage).
+			Build(),
+		"polar": imageoutput.PolarEyedropperBuilder().
+			WithCenter(1, 1).
+			WithRadius(1, 1).
+			WithImage(suite.sourceImage).
+			Build(),
+		"gradient": imageoutput.GradientEyedropperBuilder().
+			WithScalar(imageoutput.RealGradientScalar).
+			WithStops(
+				imageoutput.NewGradientStop(0, color.NRGBA{R: 255, A: 255}),
+				imageoutput.NewGradientStop(1, color.NRGBA{B: 255, A: 255}),
+			).
+			Build(),
+		"domain coloring": imageoutput.DomainColoringEyedropperBuilder().Build(),
+	}
+}
+
+// streamIntoImage draws every band into one image, and checks the bands arrive from top to bottom.
+func streamIntoImage(checker *C, settings *transformerEntity.Settings) *image.NRGBA {
+	transformer := transformerEntity.FormulaTransformer{}
+	streamedImage := image.NewNRGBA(image.Rect(0, 0, settings.OutputWidth, settings.OutputHeight))
+	nextRow := 0
+	err := transformer.TransformRowsWithContext(context.Background(), settings, func(rows *image.NRGBA) error {
+		checker.Assert(rows.Bounds().Min.Y, Equals, nextRow)
+		nextRow = rows.Bounds().Max.Y
+		draw.Draw(streamedImage, rows.Bounds(), rows, rows.Bounds().Min, draw.Src)
+		return nil
+	})
+	checker.Assert(err, IsNil)
+	checker.Assert(nextRow, Equals, settings.OutputHeight)
+	return streamedImage
+}
+
+func (suite *StreamingTests) TestStreamingMatchesRenderingAllAtOnce(checker *C) {
+	transformer := transformerEntity.FormulaTransformer{}
+	for name, eyedropper := range suite.streamingEyedroppers() {
+		for _, supersampling := range []int{1, 2} {
+			expectedImage := transformer.Transform(suite.settings(eyedropper, supersampling, 0))
+			for _, streamingRows := range []int{1, 5, 0} {
+				streamedImage := streamIntoImage(checker, suite.settings(eyedropper, supersampling, streamingRows))
+				checker.Assert(streamedImage.Pix, DeepEquals, expectedImage.Pix, Commentf("%s eyedropper, %d samples, %d rows", name, supersampling, streamingRows))
+			}
+		}
+	}
+}
+
+// formulaEvaluationTotal streams the image and returns how many rows of formula evaluation were reported.
+func formulaEvaluationTotal(checker *C, settings *transformerEntity.Settings) int {
+	total := 0
+	settings.Progress = func(event transformerEntity.ProgressEvent) {
+		if event.Stage == transformerEntity.StageFormulaEvaluation {
+			total = event.Total
+		}
+	}
+	streamIntoImage(checker, settings)
+	return total
+}
+
+func (suite *StreamingTests) TestOnlyEyedroppersThatNeedTheRangeApplyTheFormulaTwice(checker *C) {
+	eyedroppers := suite.streamingEyedroppers()
+	checker.Assert(formulaEvaluationTotal(checker, suite.settings(eyedroppers["rectangular"], 1, 4)), Equals, 34)
+	checker.Assert(formulaEvaluationTotal(checker, suite.settings(eyedroppers["domain coloring"], 1, 4)), Equals, 17)
+
+	fixedRange := imageoutput.EyedropperBuilder().
+		WithLeftSide(0).
+		WithRightSide(2).
+		WithTopSide(0).
+		WithBottomSide(2).
+		WithTransformedRange(-1, 1, -1, 1).
+		WithImage(suite.sourceImage).
+		Build()
+	checker.Assert(formulaEvaluationTotal(checker, suite.settings(fixedRange, 1, 4)), Equals, 17)
+}
+
+func (suite *StreamingTests) TestSettingsThatNeedEveryCoordinateCannotStream(checker *C) {
+	transformer := transformerEntity.FormulaTransformer{}
+	eyedroppers := suite.streamingEyedroppers()
+
+	adaptive := suite.settings(eyedroppers["rectangular"], 2, 0)
+	adaptive.AdaptiveSupersampling = true
+	checker.Assert(transformer.CanStream(adaptive), ErrorMatches, "cannot stream: adaptive supersampling .*")
+
+	autoThreshold := suite.settings(eyedroppers["rectangular"], 1, 0)
+	autoThreshold.CoordinateThreshold = imageoutput.NewUnionCoordinateThreshold(
+		imageoutput.NewDiscCoordinateThreshold(0, 0, 1),
+		imageoutput.NewPercentileCoordinateThreshold(1, 99),
+	)
+	checker.Assert(transformer.CanStream(autoThreshold), ErrorMatches, "cannot stream: auto thresholds .*")
+
+	percentileRange := imageoutput.EyedropperBuilder().
+		WithRightSide(2).
+		WithBottomSide(2).
+		WithPercentileRange(5, 95).
+		WithImage(suite.sourceImage).
+		Build()
+	histogram := imageoutput.EyedropperBuilder().
+		WithRightSide(2).
+		WithBottomSide(2).
+		WithMappingCurves(imageoutput.LinearMappingCurve, imageoutput.HistogramEqualizedMappingCurve).
+		WithImage(suite.sourceImage).
+		Build()
+	for _, eyedropper := range []imageoutput.Eyedropper{percentileRange, histogram, &imageoutputfakes.FakeEyedropper{}} {
+		err := transformer.TransformRowsWithContext(context.Background(), suite.settings(eyedropper, 1, 0), func(rows *image.NRGBA) error {
+			checker.Fatal("no rows should be rendered")
+			return nil
+		})
+		checker.Assert(err, ErrorMatches, "cannot stream: the eyedropper .*")
+	}
+}
+
+func (suite *StreamingTests) TestStreamingStopsAtTheFirstWriteError(checker *C) {
+	transformer := transformerEntity.FormulaTransformer{}
+	bandsWritten := 0
+	err := transformer.TransformRowsWithContext(context.Background(), suite.settings(suite.streamingEyedroppers()["polar"], 1, 3), func(rows *image.NRGBA) error {
+		bandsWritten++
+		return errors.New("disk is full")
+	})
+	checker.Assert(err, ErrorMatches, "disk is full")
+	checker.Assert(bandsWritten, Equals, 1)
+}
+
+func (suite *StreamingTests) TestCancelledContextStopsStreaming(checker *C) {
+	transformer := transformerEntity.FormulaTransformer{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := transformer.TransformRowsWithContext(ctx, suite.settings(suite.streamingEyedroppers()["gradient"], 1, 3), func(rows *image.NRGBA) error {
+		checker.Fatal("no rows should be written")
+		return nil
+	})
+	checker.Assert(err, Equals, context.Canceled)
+}
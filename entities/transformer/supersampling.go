@@ -0,0 +1,207 @@
+package transformer
+
+import (
+	"github.com/chadius/creatingsymmetry/entities/imageoutput"
+	"image/color"
+	"math"
+)
+
+// DefaultAdaptiveThreshold is used when adaptive supersampling has no threshold.
+//   Neighbors that are more than 1% of the transformed range apart get extra samples.
+const DefaultAdaptiveThreshold = 0.01
+
+// samplePlan records which samples belong to each output pixel.
+//   Samples are stored row by row, and every sample in a pixel is stored next to each other.
+//   A plan can cover some of the rows of the output image, starting at firstRow.
+type samplePlan struct {
+	width              int
+	height             int
+	firstRow           int
+	coordinates        []*imageoutput.MappedCoordinate
+	firstSampleByPixel []int
+}
+
+// newSamplePlan creates a grid of samplesPerSide by samplesPerSide samples inside each pixel.
+//   Samples are taken at the center of each part of the grid, so a single sample is at the center of the pixel.
+//   Pixels with 0 samples per side have no samples.
+//   The plan covers height rows of the output image, starting at firstRow. Pixel indexes start at the first row.
+func newSamplePlan(width, firstRow, height int, samplesPerSide func(pixelIndex int) int) *samplePlan {
+	plan := &samplePlan{
+		width:              width,
+		height:             height,
+		firstRow:           firstRow,
+		coordinates:        []*imageoutput.MappedCoordinate{},
+		firstSampleByPixel: make([]int, width*height+1),
+	}
+
+	for pixelIndex := 0; pixelIndex < width*height; pixelIndex++ {
+		plan.firstSampleByPixel[pixelIndex] = len(plan.coordinates)
+		pixelSamplesPerSide := samplesPerSide(pixelIndex)
+		for sampleY := 0; sampleY < pixelSamplesPerSide; sampleY++ {
+			for sampleX := 0; sampleX < pixelSamplesPerSide; sampleX++ {
+				plan.coordinates = append(plan.coordinates, imageoutput.NewMappedCoordinateUsingInputImageSample(
+					pixelIndex%width,
+					firstRow+pixelIndex/width,
+					(float64(sampleX)+0.5)/float64(pixelSamplesPerSide),
+					(float64(sampleY)+0.5)/float64(pixelSamplesPerSide),
+				))
+			}
+		}
+	}
+	plan.firstSampleByPixel[width*height] = len(plan.coordinates)
+	return plan
+}
+
+// row returns every sample in the row.
+func (p *samplePlan) row(rowIndex int) []*imageoutput.MappedCoordinate {
+	return p.coordinates[p.firstSampleByPixel[rowIndex*p.width]:p.firstSampleByPixel[(rowIndex+1)*p.width]]
+}
+
+// pixelSampleRange returns the first sample index of the pixel and the index after its last sample.
+func (p *samplePlan) pixelSampleRange(pixelIndex int) (int, int) {
+	return p.firstSampleByPixel[pixelIndex], p.firstSampleByPixel[pixelIndex+1]
+}
+
+// collection returns all of the samples as a CoordinateCollection.
+func (p *samplePlan) collection() *imageoutput.CoordinateCollection {
+	return imageoutput.CoordinateCollectionBuilder().WithCoordinates(&p.coordinates).Build()
+}
+
+// replacePixels returns a new plan using the refined samples for the marked pixels and the original samples for the others.
+func (p *samplePlan) replacePixels(refinedPlan *samplePlan, pixelIsMarked []bool) *samplePlan {
+	mergedPlan := &samplePlan{
+		width:              p.width,
+		height:             p.height,
+		firstRow:           p.firstRow,
+		coordinates:        []*imageoutput.MappedCoordinate{},
+		firstSampleByPixel: make([]int, p.width*p.height+1),
+	}
+
+	for pixelIndex := 0; pixelIndex < p.width*p.height; pixelIndex++ {
+		mergedPlan.firstSampleByPixel[pixelIndex] = len(mergedPlan.coordinates)
+		samplesFrom := p
+		if pixelIsMarked[pixelIndex] {
+			samplesFrom = refinedPlan
+		}
+		firstSample, lastSample := samplesFrom.pixelSampleRange(pixelIndex)
+		mergedPlan.coordinates = append(mergedPlan.coordinates, samplesFrom.coordinates[firstSample:lastSample]...)
+	}
+	mergedPlan.firstSampleByPixel[p.width*p.height] = len(mergedPlan.coordinates)
+	return mergedPlan
+}
+
+// markPixelsThatNeedMoreSamples compares each pixel's transformed coordinate with the pixel to its right and below.
+//   Both pixels are marked if the difference is larger than the threshold, measured as a fraction of the transformed range.
+//   Pixels are also marked when one neighbor has a transformed coordinate at infinity and the other does not.
+//   The plan must have exactly one sample per pixel.
+func markPixelsThatNeedMoreSamples(plan *samplePlan, threshold float64) []bool {
+	rangeX, rangeY := transformedRange(plan.coordinates)
+	pixelIsMarked := make([]bool, plan.width*plan.height)
+
+	neighborsAreDifferent := func(first, second *imageoutput.MappedCoordinate) bool {
+		if first.CanBeCompared() != second.CanBeCompared() {
+			return true
+		}
+		if !first.CanBeCompared() {
+			return false
+		}
+		if rangeX > 0 && math.Abs(first.TransformedX()-second.TransformedX())/rangeX > threshold {
+			return true
+		}
+		return rangeY > 0 && math.Abs(first.TransformedY()-second.TransformedY())/rangeY > threshold
+	}
+
+	coordinates := plan.coordinates
+	for pixelIndex := range pixelIsMarked {
+		pixelX := pixelIndex % plan.width
+		pixelY := pixelIndex / plan.width
+		if pixelX+1 < plan.width && neighborsAreDifferent(coordinates[pixelIndex], coordinates[pixelIndex+1]) {
+			pixelIsMarked[pixelIndex] = true
+			pixelIsMarked[pixelIndex+1] = true
+		}
+		if pixelY+1 < plan.height && neighborsAreDifferent(coordinates[pixelIndex], coordinates[pixelIndex+plan.width]) {
+			pixelIsMarked[pixelIndex] = true
+			pixelIsMarked[pixelIndex+plan.width] = true
+		}
+	}
+	return pixelIsMarked
+}
+
+// transformedRange returns the width and height of the box holding every comparable transformed coordinate.
+func transformedRange(coordinates []*imageoutput.MappedCoordinate) (float64, float64) {
+	foundCandidate := false
+	minimumX, maximumX, minimumY, maximumY := 0.0, 0.0, 0.0, 0.0
+	for _, coordinate := range coordinates {
+		if !coordinate.CanBeCompared() {
+			continue
+		}
+		if !foundCandidate {
+			minimumX, maximumX = coordinate.TransformedX(), coordinate.TransformedX()
+			minimumY, maximumY = coordinate.TransformedY(), coordinate.TransformedY()
+			foundCandidate = true
+			continue
+		}
+		minimumX = math.Min(minimumX, coordinate.TransformedX())
+		maximumX = math.Max(maximumX, coordinate.TransformedX())
+		minimumY = math.Min(minimumY, coordinate.TransformedY())
+		maximumY = math.Max(maximumY, coordinate.TransformedY())
+	}
+	return maximumX - minimumX, maximumY - minimumY
+}
+
+// linearFromSRGB converts each 8 bit sRGB value into linear light, from 0 to 1.
+var linearFromSRGB = func() [256]float64 {
+	var table [256]float64
+	for value := range table {
+		channel := float64(value) / 255
+		if channel <= 0.04045 {
+			table[value] = channel / 12.92
+		} else {
+			table[value] = math.Pow((channel+0.055)/1.055, 2.4)
+		}
+	}
+	return table
+}()
+
+// sRGBFromLinear converts linear light, from 0 to 1, back into an 8 bit sRGB value.
+func sRGBFromLinear(linear float64) uint8 {
+	var channel float64
+	if linear <= 0.0031308 {
+		channel = linear * 12.92
+	} else {
+		channel = 1.055*math.Pow(linear, 1/2.4) - 0.055
+	}
+	return uint8(math.Round(math.Max(0, math.Min(1, channel)) * 255))
+}
+
+// averageColorsInLinearLight blends the colors in linear light.
+//   Each color is weighted by its alpha, so transparent samples reduce the alpha without darkening the color.
+//   A single color is returned unchanged.
+func averageColorsInLinearLight(colors []color.Color) color.NRGBA {
+	if len(colors) == 0 {
+		return color.NRGBA{}
+	}
+	if len(colors) == 1 {
+		return color.NRGBAModel.Convert(colors[0]).(color.NRGBA)
+	}
+
+	var red, green, blue, alpha float64
+	for _, sampleColor := range colors {
+		sample := color.NRGBAModel.Convert(sampleColor).(color.NRGBA)
+		sampleAlpha := float64(sample.A) / 255
+		red += linearFromSRGB[sample.R] * sampleAlpha
+		green += linearFromSRGB[sample.G] * sampleAlpha
+		blue += linearFromSRGB[sample.B] * sampleAlpha
+		alpha += sampleAlpha
+	}
+
+	if alpha == 0 {
+		return color.NRGBA{}
+	}
+	return color.NRGBA{
+		R: sRGBFromLinear(red / alpha),
+		G: sRGBFromLinear(green / alpha),
+		B: sRGBFromLinear(blue / alpha),
+		A: uint8(math.Round(alpha / float64(len(colors)) * 255)),
+	}
+}
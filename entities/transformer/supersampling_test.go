@@ -0,0 +1,254 @@
+package transformer_test
+
+import (
+	"github.com/chadius/creatingsymmetry/entities/command"
+	"github.com/chadius/creatingsymmetry/entities/formula"
+	"github.com/chadius/creatingsymmetry/entities/imageoutput"
+	"github.com/chadius/creatingsymmetry/entities/imageoutput/imageoutputfakes"
+	transformerEntity "github.com/chadius/creatingsymmetry/entities/transformer"
+	"github.com/chadius/creatingsymmetry/entities/utility"
+	. "gopkg.in/check.v1"
+	"image"
+	"image/color"
+	"math"
+)
+
+type SupersamplingTests struct {
+	rosetteCommand *command.CreateSymmetryPattern
+}
+
+var _ = Suite(&SupersamplingTests{})
+
+func (suite *SupersamplingTests) SetUpTest(checker *C) {
+	rosetteFormula, _ := formula.NewBuilder().
+		Rosette().
+		AddTerm(
+			formula.NewTermBuilder().
+				Multiplier(complex(1, 0)).
+				PowerN(6).
+				PowerM(0).
+				Build(),
+		).
+		Build()
+
+	suite.rosetteCommand = &command.CreateSymmetryPattern{
+		Formula: rosetteFormula,
+	}
+}
+
+// eyedropperThatRecordsSamples returns an eyedropper that colors samples white when the transformed X is positive,
+//   and black otherwise. Every collection it receives is recorded.
+func eyedropperThatRecordsSamples(collections *[][]*imageoutput.MappedCoordinate) *imageoutputfakes.FakeEyedropper {
+	eyedropper := &imageoutputfakes.FakeEyedropper{}
+	eyedropper.ConvertCoordinatesToColorsStub = func(collection *imageoutput.CoordinateCollection) *[]color.Color {
+		coordinates := *collection.Coordinates()
+		*collections = append(*collections, coordinates)
+		colors := []color.Color{}
+		for _, coordinate := range coordinates {
+			if coordinate.CanBeCompared() && coordinate.TransformedX() > 0 {
+				colors = append(colors, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+				continue
+			}
+			colors = append(colors, color.NRGBA{R: 0, G: 0, B: 0, A: 255})
+		}
+		return &colors
+	}
+	return eyedropper
+}
+
+func (suite *SupersamplingTests) settings(eyedropper imageoutput.Eyedropper, workerCount, supersampling int, adaptive bool) *transformerEntity.Settings {
+	return &transformerEntity.Settings{
+		PatternViewportXMin:   -1,
+		PatternViewportXMax:   1,
+		PatternViewportYMin:   -1,
+		PatternViewportYMax:   1,
+		Formula:               suite.rosetteCommand,
+		CoordinateThreshold:   &imageoutputfakes.FakeCoordinateThreshold{},
+		Eyedropper:            eyedropper,
+		OutputWidth:           24,
+		OutputHeight:          16,
+		WorkerCount:           workerCount,
+		Supersampling:         supersampling,
+		AdaptiveSupersampling: adaptive,
+	}
+}
+
+func countGrayPixels(outputImage *image.NRGBA) int {
+	grayPixelCount := 0
+	for pixelIndex := 0; pixelIndex < len(outputImage.Pix); pixelIndex += 4 {
+		if outputImage.Pix[pixelIndex] != 0 && outputImage.Pix[pixelIndex] != 255 {
+			grayPixelCount++
+		}
+	}
+	return grayPixelCount
+}
+
+func (suite *SupersamplingTests) TestOneSampleIsTakenAtEachPixelCenter(checker *C) {
+	collections := [][]*imageoutput.MappedCoordinate{}
+	transformer := transformerEntity.FormulaTransformer{}
+	settings := suite.settings(eyedropperThatRecordsSamples(&collections), 1, 0, false)
+	settings.OutputWidth = 2
+	settings.OutputHeight = 2
+	transformer.Transform(settings)
+
+	checker.Assert(collections, HasLen, 1)
+	checker.Assert(collections[0], HasLen, 4)
+	checker.Assert(collections[0][0].PatternViewportX(), Equals, -0.5)
+	checker.Assert(collections[0][0].PatternViewportY(), Equals, -0.5)
+	checker.Assert(collections[0][3].PatternViewportX(), Equals, 0.5)
+	checker.Assert(collections[0][3].PatternViewportY(), Equals, 0.5)
+}
+
+func (suite *SupersamplingTests) TestFixedSupersamplingTakesAGridOfSamplesInEachPixel(checker *C) {
+	collections := [][]*imageoutput.MappedCoordinate{}
+	transformer := transformerEntity.FormulaTransformer{}
+	settings := suite.settings(eyedropperThatRecordsSamples(&collections), 1, 3, false)
+	settings.OutputWidth = 1
+	settings.OutputHeight = 1
+	transformer.Transform(settings)
+
+	checker.Assert(collections, HasLen, 1)
+	checker.Assert(collections[0], HasLen, 9)
+	checker.Assert(collections[0][0].PatternViewportX(), utility.NumericallyCloseEnough{}, -2.0/3.0, 1e-6)
+	checker.Assert(collections[0][4].PatternViewportX(), utility.NumericallyCloseEnough{}, 0.0, 1e-6)
+	checker.Assert(collections[0][8].PatternViewportY(), utility.NumericallyCloseEnough{}, 2.0/3.0, 1e-6)
+}
+
+func (suite *SupersamplingTests) TestSamplesAreAveragedInLinearLight(checker *C) {
+	eyedropper := &imageoutputfakes.FakeEyedropper{}
+	eyedropper.ConvertCoordinatesToColorsStub = func(collection *imageoutput.CoordinateCollection) *[]color.Color {
+		colors := []color.Color{}
+		for sampleIndex := range *collection.Coordinates() {
+			if sampleIndex%2 == 0 {
+				colors = append(colors, color.NRGBA{R: 0, G: 0, B: 0, A: 255})
+				continue
+			}
+			colors = append(colors, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+		}
+		return &colors
+	}
+	transformer := transformerEntity.FormulaTransformer{}
+	settings := suite.settings(eyedropper, 1, 2, false)
+	settings.OutputWidth = 1
+	settings.OutputHeight = 1
+	outputImage := transformer.Transform(settings)
+
+	checker.Assert(outputImage.NRGBAAt(0, 0), Equals, color.NRGBA{R: 188, G: 188, B: 188, A: 255})
+}
+
+func (suite *SupersamplingTests) TestTransparentSamplesReduceAlphaWithoutDarkening(checker *C) {
+	eyedropper := &imageoutputfakes.FakeEyedropper{}
+	eyedropper.ConvertCoordinatesToColorsStub = func(collection *imageoutput.CoordinateCollection) *[]color.Color {
+		colors := []color.Color{}
+		for sampleIndex := range *collection.Coordinates() {
+			if sampleIndex%2 == 0 {
+				colors = append(colors, color.NRGBA{})
+				continue
+			}
+			colors = append(colors, color.NRGBA{R: 255, G: 0, B: 0, A: 255})
+		}
+		return &colors
+	}
+	transformer := transformerEntity.FormulaTransformer{}
+	settings := suite.settings(eyedropper, 1, 2, false)
+	settings.OutputWidth = 1
+	settings.OutputHeight = 1
+	outputImage := transformer.Transform(settings)
+
+	checker.Assert(outputImage.NRGBAAt(0, 0), Equals, color.NRGBA{R: 255, G: 0, B: 0, A: 128})
+}
+
+func (suite *SupersamplingTests) TestSupersamplingSmoothsEdges(checker *C) {
+	transformer := transformerEntity.FormulaTransformer{}
+	collections := [][]*imageoutput.MappedCoordinate{}
+
+	oneSampleImage := transformer.Transform(suite.settings(eyedropperThatRecordsSamples(&collections), 1, 1, false))
+	checker.Assert(countGrayPixels(oneSampleImage), Equals, 0)
+
+	supersampledImage := transformer.Transform(suite.settings(eyedropperThatRecordsSamples(&collections), 1, 4, false))
+	checker.Assert(countGrayPixels(supersampledImage) > 0, Equals, true)
+}
+
+func (suite *SupersamplingTests) TestAdaptiveSupersamplingOnlyAddsSamplesNearEdges(checker *C) {
+	transformer := transformerEntity.FormulaTransformer{}
+
+	fixedCollections := [][]*imageoutput.MappedCoordinate{}
+	fixedImage := transformer.Transform(suite.settings(eyedropperThatRecordsSamples(&fixedCollections), 1, 4, false))
+
+	adaptiveCollections := [][]*imageoutput.MappedCoordinate{}
+	settings := suite.settings(eyedropperThatRecordsSamples(&adaptiveCollections), 1, 4, true)
+	settings.AdaptiveThreshold = 0.001
+	adaptiveImage := transformer.Transform(settings)
+
+	pixelCount := 24 * 16
+	checker.Assert(adaptiveCollections, HasLen, 1)
+	checker.Assert(len(adaptiveCollections[0]) > pixelCount, Equals, true)
+	checker.Assert(len(adaptiveCollections[0]) < len(fixedCollections[0]), Equals, true)
+	checker.Assert(countGrayPixels(adaptiveImage) > 0, Equals, true)
+
+	differentPixelCount := 0
+	for pixelIndex := 0; pixelIndex < len(fixedImage.Pix); pixelIndex += 4 {
+		if math.Abs(float64(fixedImage.Pix[pixelIndex])-float64(adaptiveImage.Pix[pixelIndex])) > 1 {
+			differentPixelCount++
+		}
+	}
+	checker.Assert(differentPixelCount < pixelCount/10, Equals, true)
+}
+
+func (suite *SupersamplingTests) TestAdaptiveSupersamplingReportsBothPasses(checker *C) {
+	transformer := transformerEntity.FormulaTransformer{}
+	collections := [][]*imageoutput.MappedCoordinate{}
+	settings := suite.settings(eyedropperThatRecordsSamples(&collections), 2, 2, true)
+
+	lastEventByStage := map[transformerEntity.Stage]transformerEntity.ProgressEvent{}
+	settings.Progress = func(event transformerEntity.ProgressEvent) {
+		lastEventByStage[event.Stage] = event
+	}
+	transformer.Transform(settings)
+
+	checker.Assert(lastEventByStage[transformerEntity.StageViewportScaling].Completed, Equals, 32)
+	checker.Assert(lastEventByStage[transformerEntity.StageFormulaEvaluation].Completed, Equals, 32)
+	checker.Assert(lastEventByStage[transformerEntity.StageFormulaEvaluation].Fraction(), Equals, 1.0)
+}
+
+func (suite *SupersamplingTests) TestParallelSupersamplingMatchesSerialSupersampling(checker *C) {
+	transformer := transformerEntity.FormulaTransformer{}
+	for _, adaptive := range []bool{false, true} {
+		collections := [][]*imageoutput.MappedCoordinate{}
+		serialImage := transformer.Transform(suite.settings(eyedropperThatRecordsSamples(&collections), 1, 3, adaptive))
+		for _, workerCount := range []int{0, 2, 5} {
+			parallelImage := transformer.Transform(suite.settings(eyedropperThatRecordsSamples(&collections), workerCount, 3, adaptive))
+			checker.Assert(parallelImage.Pix, DeepEquals, serialImage.Pix, Commentf("%d workers, adaptive %t", workerCount, adaptive))
+		}
+	}
+}
+
+func (suite *SupersamplingTests) TestLatticeRowsMatchCalculatingEachSample(checker *C) {
+	hexagonalFormula, _ := formula.NewBuilder().
+		Hexagonal().
+		AddWavePacket(
+			formula.NewWavePacketBuilder().
+				Multiplier(complex(1, 0.5)).
+				AddTerm(formula.NewTermBuilder().PowerN(1).PowerM(-2).Build()).
+				AddTerm(formula.NewTermBuilder().PowerN(-2).PowerM(1).Build()).
+				Build(),
+		).
+		Build()
+	suite.rosetteCommand.Formula = hexagonalFormula
+	transformer := transformerEntity.FormulaTransformer{}
+
+	for _, supersampling := range []int{0, 3} {
+		for _, adaptive := range []bool{false, true} {
+			collections := [][]*imageoutput.MappedCoordinate{}
+			settings := suite.settings(eyedropperThatRecordsSamples(&collections), 2, supersampling, adaptive)
+			settings.OutputWidth = 150
+			transformer.Transform(settings)
+
+			for _, coordinate := range collections[0] {
+				expected := hexagonalFormula.Calculate(complex(coordinate.PatternViewportX(), coordinate.PatternViewportY()))
+				checker.Assert(coordinate.TransformedX(), utility.NumericallyCloseEnough{}, real(expected), 1e-9)
+				checker.Assert(coordinate.TransformedY(), utility.NumericallyCloseEnough{}, imag(expected), 1e-9)
+			}
+		}
+	}
+}
@@ -1,5 +1,11 @@
 package utility
 
+import (
+	"bytes"
+	"encoding/json"
+	"gopkg.in/yaml.v2"
+)
+
 // UnmarshalFunc abstracts how the byte stream will be unmarshalled.
 type UnmarshalFunc func([]byte, interface{}) error
 
@@ -8,3 +14,21 @@ type ComplexNumberForMarshal struct {
 	Real      float64 `json:"real" yaml:"real"`
 	Imaginary float64 `json:"imaginary" yaml:"imaginary"`
 }
+
+// DataIsJSON returns true if the data looks like a JSON document.
+//   JSON documents start with an object or an array, everything else is treated as YAML.
+func DataIsJSON(data []byte) bool {
+	trimmedData := bytes.TrimSpace(data)
+	if len(trimmedData) == 0 {
+		return false
+	}
+	return trimmedData[0] == '{' || trimmedData[0] == '['
+}
+
+// DetectUnmarshalFunc looks at the data and returns the UnmarshalFunc that can read it.
+func DetectUnmarshalFunc(data []byte) UnmarshalFunc {
+	if DataIsJSON(data) {
+		return json.Unmarshal
+	}
+	return yaml.Unmarshal
+}
@@ -0,0 +1,43 @@
+package utility_test
+
+import (
+	"github.com/chadius/creatingsymmetry/entities/utility"
+	. "gopkg.in/check.v1"
+)
+
+type DetectFormatTests struct{}
+
+var _ = Suite(&DetectFormatTests{})
+
+type detectFormatTestObject struct {
+	Name  string `json:"name" yaml:"name"`
+	Count int    `json:"count" yaml:"count"`
+}
+
+func (suite *DetectFormatTests) TestObjectsAndArraysAreJSON(checker *C) {
+	checker.Assert(utility.DataIsJSON([]byte(`{"name": "json"}`)), Equals, true)
+	checker.Assert(utility.DataIsJSON([]byte("\n\t  [1, 2]")), Equals, true)
+}
+
+func (suite *DetectFormatTests) TestEverythingElseIsYAML(checker *C) {
+	checker.Assert(utility.DataIsJSON([]byte("name: yaml\n")), Equals, false)
+	checker.Assert(utility.DataIsJSON([]byte("")), Equals, false)
+}
+
+func (suite *DetectFormatTests) TestDetectedUnmarshalFuncReadsJSONWithTabs(checker *C) {
+	data := []byte("{\n\t\"name\": \"json\",\n\t\"count\": 3\n}")
+	var object detectFormatTestObject
+	err := utility.DetectUnmarshalFunc(data)(data, &object)
+	checker.Assert(err, IsNil)
+	checker.Assert(object.Name, Equals, "json")
+	checker.Assert(object.Count, Equals, 3)
+}
+
+func (suite *DetectFormatTests) TestDetectedUnmarshalFuncReadsYAML(checker *C) {
+	data := []byte("name: yaml\ncount: 5\n")
+	var object detectFormatTestObject
+	err := utility.DetectUnmarshalFunc(data)(data, &object)
+	checker.Assert(err, IsNil)
+	checker.Assert(object.Name, Equals, "yaml")
+	checker.Assert(object.Count, Equals, 5)
+}
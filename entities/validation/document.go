@@ -0,0 +1,292 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/chadius/creatingsymmetry/entities/utility"
+	"gopkg.in/yaml.v2"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// DocumentParser reads a byte stream into a Document.
+type DocumentParser func(data []byte) (*Document, error)
+
+// Document is a parsed YAML or JSON byte stream.
+//   It remembers the line each field started on, so problems can point back to the original text.
+type Document struct {
+	data       []byte
+	isJSON     bool
+	root       interface{}
+	lineByPath map[string]int
+}
+
+// Parse detects if the data is JSON or YAML and reads it into a Document.
+func Parse(data []byte) (*Document, error) {
+	if utility.DataIsJSON(data) {
+		return ParseJSON(data)
+	}
+	return ParseYAML(data)
+}
+
+// ParseYAML reads YAML data into a Document.
+func ParseYAML(data []byte) (*Document, error) {
+	var root interface{}
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	return &Document{
+		data:       data,
+		isJSON:     false,
+		root:       normalizeYAMLValue(root),
+		lineByPath: indexYAMLLines(data),
+	}, nil
+}
+
+// ParseJSON reads JSON data into a Document.
+//   Numbers keep their original text, so whole number fields can reject values like 1.0 the way the decoder does.
+func ParseJSON(data []byte) (*Document, error) {
+	root, err := decodeJSONKeepingNumbers(data)
+	if err != nil {
+		if syntaxError, ok := err.(*json.SyntaxError); ok {
+			return nil, &Error{Problems: []Problem{{
+				Line:    lineAtOffset(data, syntaxError.Offset),
+				Message: syntaxError.Error(),
+			}}}
+		}
+		return nil, err
+	}
+	return &Document{
+		data:       data,
+		isJSON:     true,
+		root:       root,
+		lineByPath: indexJSONLines(data),
+	}, nil
+}
+
+// decodeJSONKeepingNumbers reads the JSON value, leaving numbers as json.Number instead of float64.
+//   Anything after the value is an error, reported the same way json.Unmarshal reports it.
+func decodeJSONKeepingNumbers(data []byte) (interface{}, error) {
+	var root interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&root); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		var discarded interface{}
+		return nil, json.Unmarshal(data, &discarded)
+	}
+	return root, nil
+}
+
+// Decode unmarshals the document into the target.
+func (d *Document) Decode(target interface{}) error {
+	if d.isJSON {
+		return json.Unmarshal(d.data, target)
+	}
+	return yaml.Unmarshal(d.data, target)
+}
+
+// LineOf returns the line the path starts on.
+//   If the path was not found, the closest parent's line is used instead. Returns 0 if nothing was found.
+func (d *Document) LineOf(path string) int {
+	for {
+		if line, found := d.lineByPath[path]; found {
+			return line
+		}
+		if path == "" {
+			return 0
+		}
+		path = parentPath(path)
+	}
+}
+
+// Locate fills in the line number of every problem and sorts them from top to bottom.
+func (d *Document) Locate(problems []Problem) []Problem {
+	locatedProblems := []Problem{}
+	for _, problem := range problems {
+		if problem.Line == 0 {
+			problem.Line = d.LineOf(problem.Path)
+		}
+		locatedProblems = append(locatedProblems, problem)
+	}
+	sortProblems(locatedProblems)
+	return locatedProblems
+}
+
+// CheckFields compares the document against the target's json and yaml tags.
+//   It returns a problem for each unknown key and for each value with the wrong type.
+func (d *Document) CheckFields(target interface{}) []Problem {
+	return checkValueAgainstType(d.root, reflect.TypeOf(target), "")
+}
+
+func checkValueAgainstType(value interface{}, expectedType reflect.Type, path string) []Problem {
+	if value == nil {
+		return nil
+	}
+
+	switch expectedType.Kind() {
+	case reflect.Ptr:
+		return checkValueAgainstType(value, expectedType.Elem(), path)
+	case reflect.Struct:
+		return checkObjectAgainstStruct(value, expectedType, path)
+	case reflect.Slice, reflect.Array:
+		list, isList := value.([]interface{})
+		if !isList {
+			return []Problem{NewProblemf(path, "expected a list, found %s", describeValue(value))}
+		}
+		problems := []Problem{}
+		for index, item := range list {
+			problems = append(problems, checkValueAgainstType(item, expectedType.Elem(), IndexPath(path, index))...)
+		}
+		return problems
+	case reflect.String:
+		if isObjectOrList(value) {
+			return []Problem{NewProblemf(path, "expected text, found %s", describeValue(value))}
+		}
+	case reflect.Bool:
+		if _, isBool := value.(bool); !isBool {
+			return []Problem{NewProblemf(path, "expected true or false, found %s", describeValue(value))}
+		}
+	case reflect.Float32, reflect.Float64:
+		if _, isNumber := numberValue(value); !isNumber {
+			return []Problem{NewProblemf(path, "expected a number, found %s", describeValue(value))}
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if !isWholeNumber(value) {
+			return []Problem{NewProblemf(path, "expected a whole number, found %s", describeValue(value))}
+		}
+	}
+	return nil
+}
+
+func checkObjectAgainstStruct(value interface{}, expectedType reflect.Type, path string) []Problem {
+	object, isObject := value.(map[string]interface{})
+	if !isObject {
+		return []Problem{NewProblemf(path, "expected an object, found %s", describeValue(value))}
+	}
+
+	fieldByKey := map[string]reflect.StructField{}
+	knownKeys := []string{}
+	for fieldIndex := 0; fieldIndex < expectedType.NumField(); fieldIndex++ {
+		field := expectedType.Field(fieldIndex)
+		key := keyForField(field)
+		if key == "" {
+			continue
+		}
+		fieldByKey[key] = field
+		knownKeys = append(knownKeys, key)
+	}
+
+	keys := []string{}
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	problems := []Problem{}
+	for _, key := range keys {
+		keyPath := JoinPath(path, key)
+		field, isKnown := fieldByKey[key]
+		if !isKnown {
+			problems = append(problems, NewProblemf(keyPath, "unknown field %q, expected one of: %s", key, strings.Join(knownKeys, ", ")))
+			continue
+		}
+		problems = append(problems, checkValueAgainstType(object[key], field.Type, keyPath)...)
+	}
+	return problems
+}
+
+// keyForField returns the key used to marshal the field, or an empty string if it is not marshaled.
+func keyForField(field reflect.StructField) string {
+	for _, tagName := range []string{"json", "yaml"} {
+		tag, hasTag := field.Tag.Lookup(tagName)
+		if !hasTag {
+			continue
+		}
+		key := strings.Split(tag, ",")[0]
+		if key == "-" {
+			return ""
+		}
+		if key != "" {
+			return key
+		}
+	}
+	if field.PkgPath != "" {
+		return ""
+	}
+	return strings.ToLower(field.Name)
+}
+
+// isWholeNumber returns true if the value can be decoded into an integer field.
+//   JSON numbers must be written without a fraction or exponent, YAML numbers only need to be whole.
+func isWholeNumber(value interface{}) bool {
+	if jsonNumber, isJSONNumber := value.(json.Number); isJSONNumber {
+		_, err := jsonNumber.Int64()
+		return err == nil
+	}
+	number, isNumber := numberValue(value)
+	return isNumber && number == math.Trunc(number)
+}
+
+func numberValue(value interface{}) (float64, bool) {
+	switch number := value.(type) {
+	case json.Number:
+		floatNumber, err := number.Float64()
+		return floatNumber, err == nil
+	case int:
+		return float64(number), true
+	case int64:
+		return float64(number), true
+	case uint64:
+		return float64(number), true
+	case float64:
+		return number, true
+	}
+	return 0, false
+}
+
+func isObjectOrList(value interface{}) bool {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		return true
+	}
+	return false
+}
+
+func describeValue(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "a list"
+	case string:
+		return fmt.Sprintf("%q", value)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// normalizeYAMLValue converts YAML maps into maps with string keys, so YAML and JSON documents look the same.
+func normalizeYAMLValue(value interface{}) interface{} {
+	switch typedValue := value.(type) {
+	case map[interface{}]interface{}:
+		object := map[string]interface{}{}
+		for key, item := range typedValue {
+			object[fmt.Sprintf("%v", key)] = normalizeYAMLValue(item)
+		}
+		return object
+	case []interface{}:
+		list := []interface{}{}
+		for _, item := range typedValue {
+			list = append(list, normalizeYAMLValue(item))
+		}
+		return list
+	}
+	return value
+}
@@ -0,0 +1,139 @@
+package validation_test
+
+import (
+	"github.com/chadius/creatingsymmetry/entities/validation"
+	. "gopkg.in/check.v1"
+)
+
+type documentTestInner struct {
+	Real      float64 `json:"real" yaml:"real"`
+	Imaginary float64 `json:"imaginary" yaml:"imaginary"`
+}
+
+type documentTestItem struct {
+	Multiplier *documentTestInner `json:"multiplier" yaml:"multiplier"`
+	Power      int                `json:"power" yaml:"power"`
+	Labels     []string           `json:"labels" yaml:"labels"`
+}
+
+type documentTestObject struct {
+	Name    string             `json:"name" yaml:"name"`
+	Enabled bool               `json:"enabled" yaml:"enabled"`
+	Items   []documentTestItem `json:"items" yaml:"items"`
+}
+
+type DocumentTests struct{}
+
+var _ = Suite(&DocumentTests{})
+
+func (suite *DocumentTests) TestValidYAMLHasNoProblems(checker *C) {
+	document, err := validation.ParseYAML([]byte(`
+name: example
+enabled: true
+items:
+  - power: 3
+    multiplier:
+      real: 1
+      imaginary: 2.5
+    labels: [a, b]
+`))
+	checker.Assert(err, IsNil)
+	checker.Assert(document.CheckFields(&documentTestObject{}), HasLen, 0)
+
+	var decoded documentTestObject
+	checker.Assert(document.Decode(&decoded), IsNil)
+	checker.Assert(decoded.Items[0].Multiplier.Imaginary, Equals, 2.5)
+}
+
+func (suite *DocumentTests) TestUnknownKeysAndWrongTypesAreReportedWithLines(checker *C) {
+	document, err := validation.ParseYAML([]byte(`name: example
+enabled: maybe
+items:
+  - power: 3.5
+    multipler:
+      real: 1
+  -
+    multiplier:
+      real: one
+`))
+	checker.Assert(err, IsNil)
+
+	problems := document.Locate(document.CheckFields(&documentTestObject{}))
+	checker.Assert(problems, HasLen, 4)
+	checker.Assert(problems[0].String(), Equals, `line 2: enabled: expected true or false, found "maybe"`)
+	checker.Assert(problems[1].String(), Equals, `line 4: items[0].power: expected a whole number, found 3.5`)
+	checker.Assert(problems[2].String(), Equals, `line 5: items[0].multipler: unknown field "multipler", expected one of: multiplier, power, labels`)
+	checker.Assert(problems[3].String(), Equals, `line 9: items[1].multiplier.real: expected a number, found "one"`)
+}
+
+func (suite *DocumentTests) TestJSONProblemsHaveLines(checker *C) {
+	document, err := validation.ParseJSON([]byte(`{
+	"name": "example",
+	"items": [
+		{"power": 1},
+		{
+			"power": 2,
+			"extra": true
+		}
+	]
+}`))
+	checker.Assert(err, IsNil)
+
+	problems := document.Locate(document.CheckFields(&documentTestObject{}))
+	checker.Assert(problems, HasLen, 1)
+	checker.Assert(problems[0].Path, Equals, "items[1].extra")
+	checker.Assert(problems[0].Line, Equals, 7)
+}
+
+func (suite *DocumentTests) TestJSONWholeNumbersCannotHaveAFraction(checker *C) {
+	document, err := validation.ParseJSON([]byte(`{
+	"items": [
+		{"power": 1.0},
+		{"power": 1.5},
+		{"power": 1e2},
+		{"power": 2, "multiplier": {"real": 1.0}}
+	]
+}`))
+	checker.Assert(err, IsNil)
+
+	problems := document.Locate(document.CheckFields(&documentTestObject{}))
+	checker.Assert(problems, HasLen, 3)
+	checker.Assert(problems[0].String(), Equals, `line 3: items[0].power: expected a whole number, found 1.0`)
+	checker.Assert(problems[1].String(), Equals, `line 4: items[1].power: expected a whole number, found 1.5`)
+	checker.Assert(problems[2].String(), Equals, `line 5: items[2].power: expected a whole number, found 1e2`)
+}
+
+func (suite *DocumentTests) TestJSONSyntaxErrorsHaveLines(checker *C) {
+	_, err := validation.ParseJSON([]byte("{\n\"name\": \"example\",\n}"))
+	checker.Assert(err, FitsTypeOf, &validation.Error{})
+	checker.Assert(err.(*validation.Error).Problems[0].Line, Equals, 3)
+
+	_, err = validation.ParseJSON([]byte("{\n\"name\": \"example\"\n}\n}"))
+	checker.Assert(err, FitsTypeOf, &validation.Error{})
+	checker.Assert(err, ErrorMatches, `found 1 problem:\nline 4: invalid character '}' after top-level value`)
+}
+
+func (suite *DocumentTests) TestParseDetectsFormat(checker *C) {
+	jsonDocument, err := validation.Parse([]byte("{\n\t\"name\": \"json\"\n}"))
+	checker.Assert(err, IsNil)
+	checker.Assert(jsonDocument.LineOf("name"), Equals, 2)
+
+	yamlDocument, err := validation.Parse([]byte("\n\nname: yaml\n"))
+	checker.Assert(err, IsNil)
+	checker.Assert(yamlDocument.LineOf("name"), Equals, 3)
+}
+
+func (suite *DocumentTests) TestLocateUsesClosestParentLine(checker *C) {
+	document, err := validation.ParseYAML([]byte(`name: example
+items:
+  - labels: [a, b, c]
+`))
+	checker.Assert(err, IsNil)
+
+	problems := document.Locate([]validation.Problem{
+		validation.NewProblem("items[0].labels[2]", "bad label"),
+		validation.NewProblem("missing", "missing field"),
+	})
+	checker.Assert(problems[0].Line, Equals, 1)
+	checker.Assert(problems[1].Line, Equals, 3)
+}
@@ -0,0 +1,250 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// lineAtOffset returns the line number (starting at 1) that contains the byte offset.
+func lineAtOffset(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// indexJSONLines walks the JSON tokens and records the line each key and list item starts on.
+func indexJSONLines(data []byte) map[string]int {
+	lineByPath := map[string]int{"": 1}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+
+	var walkValue func(path string) error
+	walkValue = func(path string) error {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		delimiter, isDelimiter := token.(json.Delim)
+		if !isDelimiter {
+			return nil
+		}
+
+		if delimiter == '{' {
+			for decoder.More() {
+				keyToken, err := decoder.Token()
+				if err != nil {
+					return err
+				}
+				keyPath := JoinPath(path, keyToken.(string))
+				lineByPath[keyPath] = lineAtOffset(data, decoder.InputOffset())
+				if err := walkValue(keyPath); err != nil {
+					return err
+				}
+			}
+		}
+
+		if delimiter == '[' {
+			for index := 0; decoder.More(); index++ {
+				itemPath := IndexPath(path, index)
+				lineByPath[itemPath] = lineAtOffset(data, startOfNextJSONValue(data, decoder.InputOffset()))
+				if err := walkValue(itemPath); err != nil {
+					return err
+				}
+			}
+		}
+
+		_, err = decoder.Token()
+		return err
+	}
+
+	walkValue("")
+	return lineByPath
+}
+
+// startOfNextJSONValue skips whitespace and separators to find where the next value begins.
+func startOfNextJSONValue(data []byte, offset int64) int64 {
+	for offset < int64(len(data)) && strings.IndexByte(" \t\r\n,:", data[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+// yamlFrame is a mapping or sequence that is still open while scanning YAML lines.
+type yamlFrame struct {
+	indent     int
+	path       string
+	isSequence bool
+	itemCount  int
+}
+
+// yamlLineIndexer records the line of each key and sequence item in block style YAML.
+//   Flow style collections like [1, 2] are not split apart, their items use the line of their key.
+type yamlLineIndexer struct {
+	lineByPath        map[string]int
+	frames            []*yamlFrame
+	pendingPath       string
+	blockScalarIndent int
+}
+
+func indexYAMLLines(data []byte) map[string]int {
+	indexer := &yamlLineIndexer{
+		lineByPath:        map[string]int{"": 1},
+		frames:            []*yamlFrame{},
+		pendingPath:       "",
+		blockScalarIndent: -1,
+	}
+
+	for lineIndex, rawLine := range strings.Split(string(data), "\n") {
+		indexer.scanLine(rawLine, lineIndex+1)
+	}
+	return indexer.lineByPath
+}
+
+func (y *yamlLineIndexer) scanLine(rawLine string, lineNumber int) {
+	line := strings.TrimRight(removeYAMLComment(rawLine), " \t\r")
+	content := strings.TrimLeft(line, " ")
+	if content == "" || content == "---" || content == "..." {
+		return
+	}
+	indent := len(line) - len(content)
+
+	if y.blockScalarIndent >= 0 {
+		if indent > y.blockScalarIndent {
+			return
+		}
+		y.blockScalarIndent = -1
+	}
+
+	for len(y.frames) > 0 {
+		top := y.frames[len(y.frames)-1]
+		sequenceEnded := top.indent == indent && top.isSequence && !isYAMLSequenceItem(content)
+		if top.indent > indent || sequenceEnded {
+			y.frames = y.frames[:len(y.frames)-1]
+			continue
+		}
+		break
+	}
+
+	y.scanContent(content, indent, lineNumber)
+}
+
+func (y *yamlLineIndexer) scanContent(content string, column int, lineNumber int) {
+	top := y.topFrame()
+
+	if isYAMLSequenceItem(content) {
+		frame := top
+		if frame == nil || frame.indent != column || !frame.isSequence {
+			frame = y.pushFrame(column, true)
+		}
+		itemPath := IndexPath(frame.path, frame.itemCount)
+		frame.itemCount++
+		y.lineByPath[itemPath] = lineNumber
+		y.pendingPath = itemPath
+
+		itemContent := strings.TrimLeft(content[1:], " ")
+		if itemContent != "" {
+			y.scanContent(itemContent, column+len(content)-len(itemContent), lineNumber)
+		}
+		return
+	}
+
+	key, value, isKey := splitYAMLKey(content)
+	if !isKey {
+		return
+	}
+
+	frame := top
+	if frame == nil || frame.indent != column || frame.isSequence {
+		frame = y.pushFrame(column, false)
+	}
+	keyPath := JoinPath(frame.path, key)
+	y.lineByPath[keyPath] = lineNumber
+	y.pendingPath = keyPath
+
+	if strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">") {
+		y.blockScalarIndent = column
+	}
+}
+
+func (y *yamlLineIndexer) topFrame() *yamlFrame {
+	if len(y.frames) == 0 {
+		return nil
+	}
+	return y.frames[len(y.frames)-1]
+}
+
+// pushFrame opens a new mapping or sequence that belongs to the most recent key or item.
+func (y *yamlLineIndexer) pushFrame(column int, isSequence bool) *yamlFrame {
+	frame := &yamlFrame{
+		indent:     column,
+		path:       y.pendingPath,
+		isSequence: isSequence,
+	}
+	y.frames = append(y.frames, frame)
+	return frame
+}
+
+func isYAMLSequenceItem(content string) bool {
+	return content == "-" || strings.HasPrefix(content, "- ")
+}
+
+// splitYAMLKey splits "key: value" into the key and the value.
+//   Returns false if the content is not a key, like a plain scalar or a flow collection.
+func splitYAMLKey(content string) (string, string, bool) {
+	if strings.HasPrefix(content, "{") || strings.HasPrefix(content, "[") {
+		return "", "", false
+	}
+
+	var quote byte
+	for index := 0; index < len(content); index++ {
+		character := content[index]
+		if quote != 0 {
+			if character == quote {
+				quote = 0
+			}
+			continue
+		}
+		if (character == '"' || character == '\'') && index == 0 {
+			quote = character
+			continue
+		}
+		if character != ':' {
+			continue
+		}
+		if index+1 < len(content) && content[index+1] != ' ' && content[index+1] != '\t' {
+			continue
+		}
+
+		key := strings.Trim(strings.TrimSpace(content[:index]), `"'`)
+		value := strings.TrimSpace(content[index+1:])
+		return key, value, true
+	}
+	return "", "", false
+}
+
+// removeYAMLComment removes # comments that are not inside quotes.
+func removeYAMLComment(line string) string {
+	var quote byte
+	for index := 0; index < len(line); index++ {
+		character := line[index]
+		if quote != 0 {
+			if character == quote {
+				quote = 0
+			}
+			continue
+		}
+		startsToken := index == 0 || line[index-1] == ' ' || line[index-1] == '\t'
+		if (character == '"' || character == '\'') && startsToken {
+			quote = character
+			continue
+		}
+		if character == '#' && startsToken {
+			return line[:index]
+		}
+	}
+	return line
+}
@@ -0,0 +1,74 @@
+package validation_test
+
+import (
+	"github.com/chadius/creatingsymmetry/entities/validation"
+	. "gopkg.in/check.v1"
+)
+
+type LineIndexTests struct{}
+
+var _ = Suite(&LineIndexTests{})
+
+func (suite *LineIndexTests) TestYAMLSequencesAtTheSameIndentAsTheirKey(checker *C) {
+	document, err := validation.ParseYAML([]byte(`formula:
+  terms:
+  - power_n: 3
+    coefficient_relationships:
+      - -M-N # comment
+      - "+M+NF(N+M)"
+  - power_n: 1
+  desired_symmetry: p2
+`))
+	checker.Assert(err, IsNil)
+
+	checker.Assert(document.LineOf("formula"), Equals, 1)
+	checker.Assert(document.LineOf("formula.terms"), Equals, 2)
+	checker.Assert(document.LineOf("formula.terms[0]"), Equals, 3)
+	checker.Assert(document.LineOf("formula.terms[0].power_n"), Equals, 3)
+	checker.Assert(document.LineOf("formula.terms[0].coefficient_relationships[1]"), Equals, 6)
+	checker.Assert(document.LineOf("formula.terms[1].power_n"), Equals, 7)
+	checker.Assert(document.LineOf("formula.desired_symmetry"), Equals, 8)
+}
+
+func (suite *LineIndexTests) TestYAMLItemsOnTheirOwnLine(checker *C) {
+	document, err := validation.ParseYAML([]byte(`wave_packets:
+  -
+    multiplier:
+      real: 1
+    terms:
+      -
+        power_n: 2
+`))
+	checker.Assert(err, IsNil)
+
+	checker.Assert(document.LineOf("wave_packets[0]"), Equals, 2)
+	checker.Assert(document.LineOf("wave_packets[0].multiplier.real"), Equals, 4)
+	checker.Assert(document.LineOf("wave_packets[0].terms[0].power_n"), Equals, 7)
+}
+
+func (suite *LineIndexTests) TestYAMLBlockScalarsAreSkipped(checker *C) {
+	document, err := validation.ParseYAML([]byte(`description: |
+  not_a_key: at all
+name: example
+`))
+	checker.Assert(err, IsNil)
+
+	checker.Assert(document.LineOf("name"), Equals, 3)
+	checker.Assert(document.LineOf("not_a_key"), Equals, 1)
+}
+
+func (suite *LineIndexTests) TestJSONListItems(checker *C) {
+	document, err := validation.ParseJSON([]byte(`{
+  "relationships": [
+    "-M-N",
+    "+M+N"
+  ],
+  "nested": {"deep": {"value": 1}}
+}`))
+	checker.Assert(err, IsNil)
+
+	checker.Assert(document.LineOf("relationships"), Equals, 2)
+	checker.Assert(document.LineOf("relationships[0]"), Equals, 3)
+	checker.Assert(document.LineOf("relationships[1]"), Equals, 4)
+	checker.Assert(document.LineOf("nested.deep.value"), Equals, 6)
+}
@@ -0,0 +1,98 @@
+package validation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Problem describes one mistake found in a document.
+//   Path names the field using dots for keys and brackets for list items, like formula.terms[0].power_n
+//   Line is the line number in the original document, or 0 if it is unknown.
+type Problem struct {
+	Path    string
+	Line    int
+	Message string
+}
+
+// NewProblem returns a new Problem at the given path. The line is filled in by a Document.
+func NewProblem(path, message string) Problem {
+	return Problem{
+		Path:    path,
+		Message: message,
+	}
+}
+
+// NewProblemf returns a new Problem at the given path, formatting the message.
+func NewProblemf(path, format string, arguments ...interface{}) Problem {
+	return NewProblem(path, fmt.Sprintf(format, arguments...))
+}
+
+// String describes where the problem is and what is wrong.
+func (p Problem) String() string {
+	description := p.Message
+	if p.Path != "" {
+		description = p.Path + ": " + description
+	}
+	if p.Line > 0 {
+		description = fmt.Sprintf("line %d: %s", p.Line, description)
+	}
+	return description
+}
+
+// Error collects all of the problems found while validating a document.
+type Error struct {
+	Problems []Problem
+}
+
+// Error lists every problem on its own line.
+func (e *Error) Error() string {
+	descriptions := []string{}
+	for _, problem := range e.Problems {
+		descriptions = append(descriptions, problem.String())
+	}
+
+	if len(descriptions) == 1 {
+		return fmt.Sprintf("found 1 problem:\n%s", descriptions[0])
+	}
+	return fmt.Sprintf("found %d problems:\n%s", len(descriptions), strings.Join(descriptions, "\n"))
+}
+
+// sortProblems orders problems by line, then by path, so they read top to bottom.
+func sortProblems(problems []Problem) {
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Line != problems[j].Line {
+			return problems[i].Line < problems[j].Line
+		}
+		return problems[i].Path < problems[j].Path
+	})
+}
+
+// JoinPath adds the key to the parent path.
+func JoinPath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// IndexPath adds the list index to the parent path.
+func IndexPath(parent string, index int) string {
+	return fmt.Sprintf("%s[%d]", parent, index)
+}
+
+// parentPath removes the last key or list index from the path.
+func parentPath(path string) string {
+	if strings.HasSuffix(path, "]") {
+		openBracket := strings.LastIndex(path, "[")
+		if openBracket >= 0 {
+			return path[:openBracket]
+		}
+	}
+
+	lastDot := strings.LastIndex(path, ".")
+	if lastDot < 0 {
+		return ""
+	}
+	return path[:lastDot]
+}
@@ -0,0 +1,44 @@
+package validation_test
+
+import (
+	"github.com/chadius/creatingsymmetry/entities/validation"
+	. "gopkg.in/check.v1"
+	"testing"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type ProblemTests struct{}
+
+var _ = Suite(&ProblemTests{})
+
+func (suite *ProblemTests) TestProblemDescribesLineAndPath(checker *C) {
+	problem := validation.Problem{Path: "formula.type", Line: 7, Message: "unknown formula type"}
+	checker.Assert(problem.String(), Equals, "line 7: formula.type: unknown formula type")
+}
+
+func (suite *ProblemTests) TestProblemWithoutLineOrPath(checker *C) {
+	checker.Assert(validation.NewProblem("formula", "missing").String(), Equals, "formula: missing")
+	checker.Assert(validation.NewProblem("", "empty document").String(), Equals, "empty document")
+}
+
+func (suite *ProblemTests) TestErrorListsEveryProblem(checker *C) {
+	err := &validation.Error{Problems: []validation.Problem{
+		{Path: "a", Line: 1, Message: "first"},
+		{Path: "b[0]", Line: 3, Message: "second"},
+	}}
+	checker.Assert(err.Error(), Equals, "found 2 problems:\nline 1: a: first\nline 3: b[0]: second")
+}
+
+func (suite *ProblemTests) TestErrorWithOneProblem(checker *C) {
+	err := &validation.Error{Problems: []validation.Problem{
+		validation.NewProblemf("a.b", "expected %d", 3),
+	}}
+	checker.Assert(err.Error(), Equals, "found 1 problem:\na.b: expected 3")
+}
+
+func (suite *ProblemTests) TestJoinPaths(checker *C) {
+	checker.Assert(validation.JoinPath("", "formula"), Equals, "formula")
+	checker.Assert(validation.JoinPath("formula", "terms"), Equals, "formula.terms")
+	checker.Assert(validation.IndexPath("formula.terms", 2), Equals, "formula.terms[2]")
+}